@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hungtcs/monkey-lang/lint"
+	"github.com/hungtcs/monkey-lang/project"
+)
+
+// monkey check [path ...]
+//
+// 不执行程序，检查源码中的语法错误、未使用的 let、未定义的标识符、不可达的代码和明显错误的实参个数，见 lint 包。
+// path 可以是文件或目录，默认为当前目录。文件所在项目的 monkey.toml 中 [lint] disable 列出的检查被关闭。
+// 发现问题时退出码为 1。
+func checkCmd(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	flags.Parse(args)
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	files, err := sourceFiles(paths)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	status := 0
+	for _, filename := range files {
+		m, _, err := project.Load(filepath.Dir(filename))
+		var src []byte
+		if err == nil {
+			src, err = os.ReadFile(filename)
+		}
+		var diags []lint.Diagnostic
+		if err == nil {
+			diags, err = lint.Source(filename, string(src))
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+		disabled := make(map[string]bool)
+		if m != nil {
+			for _, check := range m.Lint.Disable {
+				disabled[check] = true
+			}
+		}
+		for _, d := range diags {
+			if disabled[d.Check] {
+				continue
+			}
+			fmt.Println(d)
+			status = 1
+		}
+	}
+	return status
+}
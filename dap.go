@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/hungtcs/monkey-lang/dap"
+)
+
+// monkey dap: 在标准输入输出上运行 Debug Adapter Protocol 服务
+func dapCmd(args []string) int {
+	flags := flag.NewFlagSet("dap", flag.ExitOnError)
+	flags.Parse(args)
+
+	if err := dap.Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
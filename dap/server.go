@@ -0,0 +1,425 @@
+// Package dap 通过 Debug Adapter Protocol 暴露调试器，
+// 使 VS Code 等编辑器可以使用标准的调试界面调试 Monkey 脚本。
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/hungtcs/monkey-lang/debug"
+	"github.com/hungtcs/monkey-lang/monkey"
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// Monkey 程序是单线程的，DAP 中始终只有一个线程
+const threadID = 1
+
+// Server 是一个 DAP 会话，一次会话调试一个程序
+type Server struct {
+	r *bufio.Reader
+
+	wmu sync.Mutex // 保护 w 和 seq，事件可能从程序所在的 goroutine 发出
+	w   io.Writer
+	seq int
+
+	mu          sync.Mutex
+	path        string
+	program     *syntax.Program
+	stopOnEntry bool
+	breakpoints map[string][]int32 // 源文件路径 => 行号
+	launched    bool
+	configured  bool
+	debugger    *debug.Debugger
+	root        *monkey.Env
+	stop        *debug.Stop
+	refs        []any // variablesReference - 1 => *monkey.Env 或 monkey.Value
+	done        chan struct{}
+}
+
+// Serve 在 r 和 w 上运行一个 DAP 会话，直到客户端断开连接
+func Serve(r io.Reader, w io.Writer) error {
+	s := &Server{r: bufio.NewReader(r), w: w}
+	return s.serve()
+}
+
+func (s *Server) serve() error {
+	for {
+		msg, err := readMessage(s.r)
+		if err == io.EOF {
+			s.terminate()
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if msg.Type != "request" {
+			continue
+		}
+		body, err := s.handle(msg)
+		if err != nil {
+			s.send(&response{Type: "response", RequestSeq: msg.Seq, Command: msg.Command, Message: err.Error()})
+			continue
+		}
+		s.send(&response{Type: "response", RequestSeq: msg.Seq, Success: true, Command: msg.Command, Body: body})
+
+		switch msg.Command {
+		case "initialize":
+			s.event("initialized", nil)
+		case "launch", "configurationDone":
+			s.maybeStart()
+		case "terminate":
+			s.terminate()
+		case "disconnect":
+			s.terminate()
+			return nil
+		}
+	}
+}
+
+func (s *Server) handle(msg *message) (any, error) {
+	switch msg.Command {
+	case "initialize":
+		return map[string]any{
+			"supportsConfigurationDoneRequest": true,
+			"supportsEvaluateForHovers":        true,
+			"supportsTerminateRequest":         true,
+		}, nil
+
+	case "launch":
+		var args launchArgs
+		if err := json.Unmarshal(msg.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return nil, s.launch(args)
+
+	case "setBreakpoints":
+		var args setBreakpointsArgs
+		if err := json.Unmarshal(msg.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.setBreakpoints(args), nil
+
+	case "configurationDone":
+		s.mu.Lock()
+		s.configured = true
+		s.mu.Unlock()
+		return nil, nil
+
+	case "threads":
+		return map[string]any{
+			"threads": []map[string]any{{"id": threadID, "name": "main"}},
+		}, nil
+
+	case "stackTrace":
+		return s.stackTrace(), nil
+
+	case "scopes":
+		var args frameArgs
+		if err := json.Unmarshal(msg.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.scopes(args.FrameID)
+
+	case "variables":
+		var args variablesArgs
+		if err := json.Unmarshal(msg.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.variables(args.VariablesReference)
+
+	case "evaluate":
+		var args evaluateArgs
+		if err := json.Unmarshal(msg.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.evaluate(args)
+
+	case "continue":
+		return map[string]any{"allThreadsContinued": true}, s.resume(debug.Continue)
+	case "next":
+		return nil, s.resume(debug.Next)
+	case "stepIn":
+		return nil, s.resume(debug.StepIn)
+	case "stepOut":
+		return nil, s.resume(debug.StepOut)
+
+	case "pause":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.debugger != nil {
+			s.debugger.Pause()
+		}
+		return nil, nil
+
+	case "terminate", "disconnect":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported request %q", msg.Command)
+}
+
+func (s *Server) launch(args launchArgs) error {
+	data, err := os.ReadFile(args.Program)
+	if err != nil {
+		return err
+	}
+	program, err := syntax.NewFileParser(args.Program, string(data)).Parse()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.path = args.Program
+	s.program = program
+	s.stopOnEntry = args.StopOnEntry
+	s.launched = true
+	return nil
+}
+
+func (s *Server) setBreakpoints(args setBreakpointsArgs) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.breakpoints == nil {
+		s.breakpoints = make(map[string][]int32)
+	}
+	lines := make([]int32, len(args.Breakpoints))
+	verified := make([]breakpoint, len(args.Breakpoints))
+	for i, bp := range args.Breakpoints {
+		lines[i] = bp.Line
+		verified[i] = breakpoint{Verified: true, Line: bp.Line}
+	}
+	s.breakpoints[args.Source.Path] = lines
+	if s.debugger != nil {
+		s.debugger.SetBreakpoints(args.Source.Path, lines)
+	}
+	return map[string]any{"breakpoints": verified}
+}
+
+// 客户端发送 launch 和 configurationDone 之后才开始执行程序
+func (s *Server) maybeStart() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.launched || !s.configured || s.debugger != nil {
+		return
+	}
+
+	s.root = monkey.NewEnv(nil)
+	s.debugger = debug.New(s.root, s.stopOnEntry)
+	for path, lines := range s.breakpoints {
+		s.debugger.SetBreakpoints(path, lines)
+	}
+	s.done = make(chan struct{})
+
+	thread := &monkey.Thread{
+		Name:     s.path,
+		Debugger: s.debugger,
+		Print: func(_ *monkey.Thread, msg string) {
+			s.event("output", map[string]any{"category": "stdout", "output": msg})
+		},
+	}
+	go s.forwardStops()
+	go s.run(thread, s.program, s.root)
+}
+
+func (s *Server) run(thread *monkey.Thread, program *syntax.Program, env *monkey.Env) {
+	defer close(s.done)
+
+	exitCode := 0
+	if _, err := monkey.EvalThread(thread, program, env); err != nil && !errors.Is(err, debug.ErrTerminated) {
+		s.event("output", map[string]any{"category": "stderr", "output": err.Error() + "\n"})
+		exitCode = 1
+	}
+	s.event("exited", map[string]any{"exitCode": exitCode})
+	s.event("terminated", nil)
+}
+
+// 将程序的暂停通知转发给客户端
+func (s *Server) forwardStops() {
+	for {
+		select {
+		case stop := <-s.debugger.Stops():
+			s.mu.Lock()
+			s.stop = &stop
+			s.refs = nil
+			s.mu.Unlock()
+			s.event("stopped", map[string]any{
+				"reason":            string(stop.Reason),
+				"threadId":          threadID,
+				"allThreadsStopped": true,
+			})
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *Server) resume(cmd debug.Command) error {
+	s.mu.Lock()
+	if s.stop == nil {
+		s.mu.Unlock()
+		return fmt.Errorf("program is not paused")
+	}
+	s.stop = nil
+	s.refs = nil
+	debugger := s.debugger
+	s.mu.Unlock()
+
+	debugger.Resume(cmd)
+	return nil
+}
+
+// 终止程序，无论它处于暂停中还是正在运行
+func (s *Server) terminate() {
+	s.mu.Lock()
+	s.stop = nil
+	s.refs = nil
+	debugger := s.debugger
+	s.mu.Unlock()
+
+	if debugger != nil {
+		debugger.Terminate()
+	}
+}
+
+func (s *Server) stackTrace() any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	frames := make([]stackFrame, 0)
+	if s.stop != nil {
+		for i, frame := range s.stop.Frames {
+			path, err := filepath.Abs(frame.File)
+			if err != nil {
+				path = frame.File
+			}
+			frames = append(frames, stackFrame{
+				ID:     i + 1,
+				Name:   frame.Name,
+				Source: source{Name: filepath.Base(path), Path: path},
+				Line:   frame.Line,
+				Column: frame.Col,
+			})
+		}
+	}
+	return map[string]any{"stackFrames": frames, "totalFrames": len(frames)}
+}
+
+func (s *Server) frame(id int) (*debug.Frame, error) {
+	if s.stop == nil {
+		return nil, fmt.Errorf("program is not paused")
+	}
+	if id < 1 || id > len(s.stop.Frames) {
+		return nil, fmt.Errorf("invalid frame id %d", id)
+	}
+	return &s.stop.Frames[id-1], nil
+}
+
+func (s *Server) scopes(frameID int) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	frame, err := s.frame(frameID)
+	if err != nil {
+		return nil, err
+	}
+	scopes := []scope{}
+	if frame.Env != nil && frame.Env != s.root {
+		scopes = append(scopes, scope{Name: "Locals", VariablesReference: s.ref(frame.Env)})
+	}
+	scopes = append(scopes, scope{Name: "Globals", VariablesReference: s.ref(s.root)})
+	return map[string]any{"scopes": scopes}, nil
+}
+
+// 为作用域或容器值分配一个 variablesReference，暂停结束后失效
+func (s *Server) ref(v any) int {
+	s.refs = append(s.refs, v)
+	return len(s.refs)
+}
+
+func (s *Server) variables(ref int) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ref < 1 || ref > len(s.refs) {
+		return nil, fmt.Errorf("invalid variables reference %d", ref)
+	}
+	vars := make([]variable, 0)
+	switch v := s.refs[ref-1].(type) {
+	case *monkey.Env:
+		for _, name := range v.Names() {
+			val, _ := v.Get(name)
+			vars = append(vars, s.variable(name, val))
+		}
+	case *monkey.Array:
+		for i := 0; i < v.Len(); i++ {
+			vars = append(vars, s.variable(fmt.Sprintf("[%d]", i), v.Index(i)))
+		}
+	case *monkey.Map:
+		for _, entry := range v.Items() {
+			vars = append(vars, s.variable(entry.Key.String(), entry.Value))
+		}
+	}
+	return map[string]any{"variables": vars}, nil
+}
+
+// 变量的值只是预览，数组和 map 的内容可以在客户端中展开查看
+var variableFormat = monkey.FormatOptions{MaxDepth: 2, MaxItems: 20}
+
+func (s *Server) variable(name string, val monkey.Value) variable {
+	v := variable{Name: name, Value: monkey.Format(val, variableFormat), Type: val.Type()}
+	switch val.(type) {
+	case *monkey.Array, *monkey.Map:
+		v.VariablesReference = s.ref(val)
+	}
+	return v
+}
+
+func (s *Server) evaluate(args evaluateArgs) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 程序运行时求值会与求值器并发访问环境，所以只允许在暂停时求值
+	if s.stop == nil {
+		return nil, fmt.Errorf("program is not paused")
+	}
+	env := s.root
+	if args.FrameID != 0 {
+		frame, err := s.frame(args.FrameID)
+		if err != nil {
+			return nil, err
+		}
+		if frame.Env != nil {
+			env = frame.Env
+		}
+	}
+
+	val, err := monkey.EvalExprString(args.Expression, env)
+	if err != nil {
+		return nil, err
+	}
+	v := s.variable("", val)
+	return map[string]any{"result": v.Value, "type": v.Type, "variablesReference": v.VariablesReference}, nil
+}
+
+func (s *Server) send(msg any) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	s.seq++
+	switch msg := msg.(type) {
+	case *response:
+		msg.Seq = s.seq
+	case *event:
+		msg.Seq = s.seq
+	}
+	writeMessage(s.w, msg)
+}
+
+func (s *Server) event(name string, body any) {
+	s.send(&event{Type: "event", Event: name, Body: body})
+}
@@ -0,0 +1,258 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type client struct {
+	t   *testing.T
+	w   io.Writer
+	r   *bufio.Reader
+	seq int
+}
+
+func (c *client) request(command string, args any) {
+	c.seq++
+	msg := map[string]any{"seq": c.seq, "type": "request", "command": command}
+	if args != nil {
+		msg["arguments"] = args
+	}
+	if err := writeMessage(c.w, msg); err != nil {
+		c.t.Fatalf("write %s: %s", command, err)
+	}
+}
+
+type reply struct {
+	Type    string         `json:"type"`
+	Command string         `json:"command"`
+	Event   string         `json:"event"`
+	Success bool           `json:"success"`
+	Message string         `json:"message"`
+	Body    map[string]any `json:"body"`
+}
+
+// 读取消息直到遇到指定的响应或事件
+func (c *client) read(typ, name string) *reply {
+	for {
+		raw, err := readFrame(c.r)
+		if err != nil {
+			c.t.Fatalf("waiting for %s %q: %s", typ, name, err)
+		}
+		var msg reply
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			c.t.Fatal(err)
+		}
+		if msg.Type == typ && (msg.Command == name || msg.Event == name) {
+			return &msg
+		}
+	}
+}
+
+// 读取消息直到遇到指定的响应或事件，返回其 body
+func (c *client) expect(typ, name string) map[string]any {
+	msg := c.read(typ, name)
+	if typ == "response" && !msg.Success {
+		c.t.Fatalf("%s failed: %s", name, msg.Message)
+	}
+	return msg.Body
+}
+
+// 读取指定的响应，它必须是失败的，返回错误信息
+func (c *client) expectError(command string) string {
+	msg := c.read("response", command)
+	if msg.Success {
+		c.t.Fatalf("expected %s to fail", command)
+	}
+	return msg.Message
+}
+
+// 启动一个 DAP 会话并完成初始化
+func startSession(t *testing.T) (*client, <-chan error) {
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- Serve(serverR, serverW) }()
+
+	c := &client{t: t, w: clientW, r: bufio.NewReader(clientR)}
+	c.request("initialize", map[string]any{"adapterID": "monkey"})
+	c.expect("response", "initialize")
+	c.expect("event", "initialized")
+	return c, done
+}
+
+func writeFile(t *testing.T, filename, src string) {
+	if err := os.WriteFile(filename, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDebugSession(t *testing.T) {
+	dir := t.TempDir()
+	program := filepath.Join(dir, "main.mky")
+	src := "let add = fn(a, b) {\n  let sum = a + b;\n  sum\n};\nprint(add(1, 2));\n"
+	writeFile(t, program, src)
+	c, done := startSession(t)
+
+	c.request("launch", map[string]any{"program": program})
+	c.expect("response", "launch")
+	c.request("setBreakpoints", map[string]any{
+		"source":      map[string]any{"path": program},
+		"breakpoints": []map[string]any{{"line": 3}},
+	})
+	c.expect("response", "setBreakpoints")
+	c.request("configurationDone", nil)
+	c.expect("response", "configurationDone")
+
+	stopped := c.expect("event", "stopped")
+	if stopped["reason"] != "breakpoint" {
+		t.Fatalf("stopped reason wrong. got=%v", stopped["reason"])
+	}
+
+	c.request("stackTrace", map[string]any{"threadId": threadID})
+	trace := c.expect("response", "stackTrace")
+	frames := trace["stackFrames"].([]any)
+	if len(frames) != 2 {
+		t.Fatalf("wrong number of frames. got=%d", len(frames))
+	}
+	top := frames[0].(map[string]any)
+	if top["name"] != "add" || top["line"].(float64) != 3 {
+		t.Fatalf("top frame wrong. got=%v", top)
+	}
+
+	c.request("scopes", map[string]any{"frameId": 1})
+	scopes := c.expect("response", "scopes")["scopes"].([]any)
+	locals := scopes[0].(map[string]any)
+	c.request("variables", map[string]any{"variablesReference": locals["variablesReference"]})
+	vars := c.expect("response", "variables")["variables"].([]any)
+	got := map[string]any{}
+	for _, v := range vars {
+		v := v.(map[string]any)
+		got[v["name"].(string)] = v["value"]
+	}
+	if got["a"] != "1" || got["b"] != "2" || got["sum"] != "3" {
+		t.Fatalf("locals wrong. got=%v", got)
+	}
+
+	c.request("evaluate", map[string]any{"expression": "sum * 10", "frameId": 1})
+	if result := c.expect("response", "evaluate")["result"]; result != "30" {
+		t.Fatalf("evaluate result wrong. got=%v", result)
+	}
+
+	c.request("continue", map[string]any{"threadId": threadID})
+	c.expect("response", "continue")
+	output := c.expect("event", "output")
+	if output["output"] != "3\n" {
+		t.Fatalf("output wrong. got=%q", output["output"])
+	}
+	c.expect("event", "terminated")
+
+	c.request("disconnect", nil)
+	c.expect("response", "disconnect")
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not exit after disconnect")
+	}
+}
+
+func TestDebugSessionImport(t *testing.T) {
+	dir := t.TempDir()
+	program := filepath.Join(dir, "main.mky")
+	lib := filepath.Join(dir, "lib.mky")
+	writeFile(t, program, "let lib = import(\"./lib.mky\");\nlet y = 0;\nlet x = lib[\"f\"](2);\nprint(x);\n")
+	writeFile(t, lib, "let f = fn(n) {\n  n * 2\n};\n")
+
+	c, done := startSession(t)
+	c.request("launch", map[string]any{"program": program})
+	c.expect("response", "launch")
+	// 两个文件的断点互不影响，main.mky 的第 2 行没有断点
+	for path, line := range map[string]int{program: 3, lib: 2} {
+		c.request("setBreakpoints", map[string]any{
+			"source":      map[string]any{"path": path},
+			"breakpoints": []map[string]any{{"line": line}},
+		})
+		c.expect("response", "setBreakpoints")
+	}
+	c.request("configurationDone", nil)
+	c.expect("response", "configurationDone")
+
+	type location struct {
+		path string
+		line float64
+	}
+	trace := func() []location {
+		c.request("stackTrace", map[string]any{"threadId": threadID})
+		var locations []location
+		for _, frame := range c.expect("response", "stackTrace")["stackFrames"].([]any) {
+			frame := frame.(map[string]any)
+			src := frame["source"].(map[string]any)
+			locations = append(locations, location{src["path"].(string), frame["line"].(float64)})
+		}
+		return locations
+	}
+
+	c.expect("event", "stopped")
+	if got := trace(); len(got) != 1 || got[0] != (location{program, 3}) {
+		t.Fatalf("expected to stop at %s:3, got %v", program, got)
+	}
+	c.request("continue", map[string]any{"threadId": threadID})
+	c.expect("response", "continue")
+
+	c.expect("event", "stopped")
+	expected := []location{{lib, 2}, {program, 3}}
+	if got := trace(); len(got) != 2 || got[0] != expected[0] || got[1] != expected[1] {
+		t.Fatalf("wrong stack trace.\nexpected=%v\ngot=%v", expected, got)
+	}
+	c.request("continue", map[string]any{"threadId": threadID})
+	c.expect("response", "continue")
+	c.expect("event", "terminated")
+
+	c.request("disconnect", nil)
+	c.expect("response", "disconnect")
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDebugSessionDisconnect(t *testing.T) {
+	program := filepath.Join(t.TempDir(), "main.mky")
+	writeFile(t, program, "let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } };\nfib(50);\n")
+
+	c, done := startSession(t)
+	c.request("launch", map[string]any{"program": program})
+	c.expect("response", "launch")
+	c.request("configurationDone", nil)
+	c.expect("response", "configurationDone")
+
+	// 程序运行时不能求值，即使是在全局作用域中
+	c.request("evaluate", map[string]any{"expression": "fib"})
+	if msg := c.expectError("evaluate"); msg != "program is not paused" {
+		t.Fatalf("wrong evaluate error. got=%q", msg)
+	}
+
+	// 断开连接时终止正在运行的程序
+	c.request("disconnect", nil)
+	c.expect("response", "disconnect")
+	terminated := make(chan struct{})
+	go func() {
+		c.expect("event", "terminated")
+		close(terminated)
+	}()
+	select {
+	case <-terminated:
+	case <-time.After(5 * time.Second):
+		t.Fatal("program was not terminated after disconnect")
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
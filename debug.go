@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hungtcs/monkey-lang/debug/console"
+)
+
+// monkey debug [-break lines] file
+//
+// 在终端中调试 file，程序在第一条语句之前暂停，输入 help 查看调试命令。
+// -break 是逗号分隔的初始断点的行号。
+func debugCmd(args []string) int {
+	flags := flag.NewFlagSet("debug", flag.ExitOnError)
+	breaks := flags.String("break", "", "comma-separated `lines` to set breakpoints on before starting")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		usage()
+		return 2
+	}
+
+	var lines []int32
+	for _, s := range strings.Split(*breaks, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		line, err := strconv.ParseInt(s, 10, 32)
+		if err != nil || line < 1 {
+			fmt.Fprintf(os.Stderr, "invalid breakpoint line %q\n", s)
+			return 2
+		}
+		lines = append(lines, int32(line))
+	}
+
+	filename := flags.Arg(0)
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := console.Run(filename, string(src), lines, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
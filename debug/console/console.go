@@ -0,0 +1,348 @@
+// Package console 在终端中交互式地调试 Monkey 程序：在程序及其导入的模块中按行设置断点，单步执行，
+// 查看调用栈、各帧的作用域和表达式的值。它是 debug 包的一个前端，命令的风格与 gdb 类似。
+package console
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hungtcs/monkey-lang/debug"
+	"github.com/hungtcs/monkey-lang/monkey"
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+const help = `commands:
+  c, continue          run until the next breakpoint
+  n, next              run to the next statement in the current function
+  s, step              run to the next statement, stepping into calls
+  o, out               run until the current function returns
+  b, break [file:]line set a breakpoint, or list breakpoints without arguments
+  clear [[file:]line]  delete a breakpoint, or all breakpoints
+                       file defaults to the file of the selected frame
+  p, print expr        evaluate expr in the selected frame
+  locals               list the variables of the selected frame
+  bt, backtrace        print the call stack
+  f, frame n           select frame n of the call stack
+  l, list              print the source around the selected frame
+  q, quit              terminate the program
+an empty line repeats the previous command
+`
+
+// 变量的值只是预览，过长的数组和 map 会被截断
+var valueFormat = monkey.FormatOptions{MaxDepth: 2, MaxItems: 20}
+
+// source 是一个源文件
+type source struct {
+	lines []string       // 源码的各行，用于显示暂停的位置
+	stmts map[int32]bool // 有语句开始的行，只有这些行上的断点会生效
+}
+
+func newSource(src string, program *syntax.Program) *source {
+	f := &source{
+		lines: strings.Split(strings.TrimSuffix(src, "\n"), "\n"),
+		stmts: make(map[int32]bool),
+	}
+	if program == nil {
+		return f
+	}
+	syntax.Inspect(program, func(n syntax.Node) bool {
+		if stmt, ok := n.(syntax.Stmt); ok {
+			start, _ := stmt.Span()
+			f.stmts[start.Line] = true
+		}
+		return true
+	})
+	return f
+}
+
+// session 是一次调试会话
+type session struct {
+	in       *bufio.Scanner
+	out      io.Writer
+	filename string
+	sources  map[string]*source        // 以绝对路径为键，导入的模块在用到时才读取
+	bps      map[string]map[int32]bool // 以绝对路径为键
+	debugger *debug.Debugger
+	stop     *debug.Stop
+	frame    int    // 选中的帧在 stop.Frames 中的下标
+	last     string // 上一条命令，空行时重复执行
+}
+
+// Run 调试名为 filename 的源码 src。程序在第一条语句之前暂停，之后从 in 逐行读取调试命令，
+// 程序的输出和调试器的信息都写入 out。breakpoints 是 filename 中初始的断点所在的行号。
+// 程序正常结束或者被 quit 终止时返回 nil，否则返回语法错误或者运行时错误；in 结束时终止程序。
+func Run(filename, src string, breakpoints []int32, in io.Reader, out io.Writer) error {
+	program, err := syntax.NewFileParser(filename, src).Parse()
+	if err != nil {
+		return err
+	}
+
+	s := &session{
+		in:       bufio.NewScanner(in),
+		out:      out,
+		filename: filename,
+		sources:  map[string]*source{absPath(filename): newSource(src, program)},
+		bps:      make(map[string]map[int32]bool),
+	}
+	for _, line := range breakpoints {
+		s.setBreakpoint(absPath(filename), line)
+	}
+
+	root := monkey.NewEnv(nil)
+	s.debugger = debug.New(root, true)
+	for file := range s.bps {
+		s.debugger.SetBreakpoints(file, s.breakpoints(file))
+	}
+	thread := &monkey.Thread{
+		Name:     filename,
+		Debugger: s.debugger,
+		Print:    func(_ *monkey.Thread, msg string) { io.WriteString(out, msg) },
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := monkey.EvalThread(thread, program, root)
+		done <- err
+	}()
+
+	for {
+		select {
+		case stop := <-s.debugger.Stops():
+			s.stop, s.frame = &stop, 0
+			s.where()
+			cmd := s.prompt()
+			s.stop = nil
+			s.debugger.Resume(cmd)
+		case err := <-done:
+			if errors.Is(err, debug.ErrTerminated) {
+				return nil
+			}
+			if err == nil {
+				fmt.Fprintln(out, "program exited")
+			}
+			return err
+		}
+	}
+}
+
+// 读取并执行命令，直到遇到恢复执行的命令
+func (s *session) prompt() debug.Command {
+	for {
+		io.WriteString(s.out, "(debug) ")
+		if !s.in.Scan() {
+			io.WriteString(s.out, "\n")
+			return debug.Terminate
+		}
+		line := strings.TrimSpace(s.in.Text())
+		if line == "" {
+			line = s.last
+		}
+		s.last = line
+		if cmd, resume := s.command(line); resume {
+			return cmd
+		}
+	}
+}
+
+// 执行一条命令，resume 为 true 时程序应该以 cmd 恢复执行
+func (s *session) command(line string) (cmd debug.Command, resume bool) {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	switch name {
+	case "":
+	case "c", "continue":
+		return debug.Continue, true
+	case "n", "next":
+		return debug.Next, true
+	case "s", "step":
+		return debug.StepIn, true
+	case "o", "out":
+		return debug.StepOut, true
+	case "q", "quit":
+		return debug.Terminate, true
+	case "b", "break":
+		if arg == "" {
+			files := make([]string, 0, len(s.bps))
+			for file := range s.bps {
+				files = append(files, file)
+			}
+			sort.Strings(files)
+			for _, file := range files {
+				for _, line := range s.breakpoints(file) {
+					fmt.Fprintf(s.out, "breakpoint at %s:%d\n", s.display(file), line)
+				}
+			}
+			break
+		}
+		if file, line, ok := s.location(arg); ok && s.setBreakpoint(file, line) {
+			s.debugger.SetBreakpoints(file, s.breakpoints(file))
+		}
+	case "clear":
+		if arg == "" {
+			for file := range s.bps {
+				s.debugger.SetBreakpoints(file, nil)
+			}
+			s.bps = make(map[string]map[int32]bool)
+		} else if file, line, ok := s.location(arg); ok {
+			delete(s.bps[file], line)
+			s.debugger.SetBreakpoints(file, s.breakpoints(file))
+		}
+	case "p", "print":
+		val, err := monkey.EvalExprString(arg, s.env())
+		if err != nil {
+			fmt.Fprintln(s.out, err)
+			break
+		}
+		fmt.Fprintln(s.out, monkey.Format(val, valueFormat))
+	case "locals":
+		env := s.env()
+		for _, name := range env.Names() {
+			val, _ := env.Get(name)
+			fmt.Fprintf(s.out, "%s = %s\n", name, monkey.Format(val, valueFormat))
+		}
+	case "bt", "backtrace":
+		for i, frame := range s.stop.Frames {
+			mark := " "
+			if i == s.frame {
+				mark = "*"
+			}
+			fmt.Fprintf(s.out, "%s #%d %s at %s:%d\n", mark, i, frame.Name, s.display(frame.File), frame.Line)
+		}
+	case "f", "frame":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 || n >= len(s.stop.Frames) {
+			fmt.Fprintf(s.out, "invalid frame %q, want 0 to %d\n", arg, len(s.stop.Frames)-1)
+			break
+		}
+		s.frame = n
+		s.where()
+	case "l", "list":
+		frame := s.stop.Frames[s.frame]
+		s.list(frame.File, frame.Line, 5)
+	case "h", "help":
+		io.WriteString(s.out, help)
+	default:
+		fmt.Fprintf(s.out, "unknown command %q, type help for a list of commands\n", name)
+	}
+	return 0, false
+}
+
+// 解析命令中的 [file:]line，返回文件的绝对路径和行号。
+// 相对路径与 import 一样相对于被调试的文件所在的目录解析，省略文件时使用选中的帧所在的文件
+func (s *session) location(arg string) (string, int32, bool) {
+	file := s.filename
+	if s.stop != nil {
+		file = s.stop.Frames[s.frame].File
+	}
+	if i := strings.LastIndex(arg, ":"); i >= 0 {
+		file, arg = arg[:i], arg[i+1:]
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(s.filename), file)
+		}
+	}
+	n, err := strconv.ParseInt(arg, 10, 32)
+	if err != nil || n < 1 {
+		fmt.Fprintf(s.out, "invalid line number %q\n", arg)
+		return "", 0, false
+	}
+	return absPath(file), int32(n), true
+}
+
+// 在文件 file 的第 line 行设置断点，file 无法读取时返回 false
+func (s *session) setBreakpoint(file string, line int32) bool {
+	src, err := s.source(file)
+	if err != nil {
+		fmt.Fprintln(s.out, err)
+		return false
+	}
+	if s.bps[file] == nil {
+		s.bps[file] = make(map[int32]bool)
+	}
+	s.bps[file][line] = true
+	if !src.stmts[line] {
+		fmt.Fprintf(s.out, "warning: no statement starts on line %d of %s, the breakpoint will not be hit\n", line, s.display(file))
+	}
+	return true
+}
+
+// 返回文件 file 中按行号排列的断点
+func (s *session) breakpoints(file string) []int32 {
+	lines := make([]int32, 0, len(s.bps[file]))
+	for line := range s.bps[file] {
+		lines = append(lines, line)
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i] < lines[j] })
+	return lines
+}
+
+// 返回选中的帧的作用域
+func (s *session) env() *monkey.Env {
+	return s.stop.Frames[s.frame].Env
+}
+
+// 输出暂停的原因和选中的帧正在执行的语句所在的行
+func (s *session) where() {
+	frame := s.stop.Frames[s.frame]
+	if s.frame == 0 {
+		fmt.Fprintf(s.out, "stopped at %s:%d:%d in %s (%s)\n", s.display(frame.File), frame.Line, frame.Col, frame.Name, s.stop.Reason)
+	} else {
+		fmt.Fprintf(s.out, "frame #%d %s at %s:%d:%d\n", s.frame, frame.Name, s.display(frame.File), frame.Line, frame.Col)
+	}
+	s.list(frame.File, frame.Line, 0)
+}
+
+// 输出文件 file 的第 line 行及其前后各 context 行源码，第 line 行用 => 标出
+func (s *session) list(file string, line int32, context int32) {
+	src, err := s.source(absPath(file))
+	if err != nil {
+		fmt.Fprintln(s.out, err)
+		return
+	}
+	for n := max(line-context, 1); n <= line+context && int(n) <= len(src.lines); n++ {
+		mark := "  "
+		if n == line {
+			mark = "=>"
+		}
+		fmt.Fprintf(s.out, "%s %4d  %s\n", mark, n, src.lines[n-1])
+	}
+}
+
+// 返回绝对路径为 file 的源文件，第一次用到时读取并解析
+func (s *session) source(file string) (*source, error) {
+	if src, ok := s.sources[file]; ok {
+		return src, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	// 有语法错误的文件无法导入，其中的断点也不会生效
+	program, _ := syntax.NewFileParser(file, string(data)).Parse()
+	src := newSource(string(data), program)
+	s.sources[file] = src
+	return src, nil
+}
+
+// 尽量使用相对于当前目录的路径显示文件
+func (s *session) display(file string) string {
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, absPath(file)); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return file
+}
+
+func absPath(file string) string {
+	path, err := filepath.Abs(file)
+	if err != nil {
+		return filepath.Clean(file)
+	}
+	return path
+}
@@ -0,0 +1,122 @@
+package console
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const program = `let add = fn(a, b) {
+  let sum = a + b
+  sum
+}
+let x = add(1, 2)
+print(x)
+`
+
+func TestRun(t *testing.T) {
+	commands := []string{
+		"c",        // 运行到第 2 行的断点
+		"bt",       //
+		"p a * 10", //
+		"f 1",      // 选中调用者的帧
+		"locals",   //
+		"n",        // 第 3 行
+		"",         // 重复 next，返回到第 6 行
+		"p x",      //
+		"c",        //
+	}
+	var out strings.Builder
+	err := Run("main.mky", program, []int32{2}, strings.NewReader(strings.Join(commands, "\n")+"\n"), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"stopped at main.mky:1:1 in <toplevel> (entry)\n=>    1  let add = fn(a, b) {\n",
+		"stopped at main.mky:2:3 in add (breakpoint)\n=>    2    let sum = a + b\n",
+		"* #0 add at main.mky:2\n  #1 <toplevel> at main.mky:5\n",
+		"(debug) 10\n",
+		"frame #1 <toplevel> at main.mky:5:9\n",
+		"add = fn(a, b)",
+		"stopped at main.mky:3:3 in add (step)\n",
+		"stopped at main.mky:6:1 in <toplevel> (step)\n",
+		"(debug) 3\n(debug) 3\nprogram exited\n",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected output to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+	// 选中调用者的帧之后 locals 列出的是全局变量，此时 x 还没有定义
+	if strings.Contains(out.String(), "\nx = ") {
+		t.Errorf("unexpected x in locals:\n%s", out.String())
+	}
+}
+
+func TestRunQuit(t *testing.T) {
+	var out strings.Builder
+	err := Run("main.mky", program, nil, strings.NewReader("b 4\nb x\nq\n"), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"warning: no statement starts on line 4",
+		`invalid line number "x"`,
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected output to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "program exited") {
+		t.Errorf("expected the program to be terminated, got:\n%s", out.String())
+	}
+
+	// 运行时错误由 Run 返回，输入结束时终止程序
+	err = Run("main.mky", "let a = 1\nb", nil, strings.NewReader("c\n"), &out)
+	if err == nil || !strings.Contains(err.Error(), "identifier not found: b") {
+		t.Errorf("expected a runtime error, got %v", err)
+	}
+	if err := Run("main.mky", program, nil, strings.NewReader(""), &out); err != nil {
+		t.Errorf("expected the program to be terminated at the end of input, got %v", err)
+	}
+}
+
+func TestRunImport(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.mky")
+	lib := filepath.Join(dir, "lib.mky")
+	src := "let lib = import(\"./lib.mky\")\nlet y = lib[\"f\"](1)\nprint(y)\n"
+	if err := os.WriteFile(lib, []byte("let f = fn(n) {\n  let m = n * 2\n  m\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	commands := []string{
+		"b lib.mky:2", // 相对于 main.mky 所在的目录
+		"c",           // 运行到 lib.mky 第 2 行的断点
+		"bt",          //
+		"l",           //
+		"c",           // main.mky 第 3 行的断点，不会停在 lib.mky 的第 3 行
+		"b",           //
+		"c",           //
+	}
+	var out strings.Builder
+	err := Run(main, src, []int32{3}, strings.NewReader(strings.Join(commands, "\n")+"\n"), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"stopped at " + lib + ":2:3 in lib[f] (breakpoint)\n",
+		"* #0 lib[f] at " + lib + ":2\n  #1 <toplevel> at " + main + ":2\n",
+		"     1  let f = fn(n) {\n=>    2    let m = n * 2\n      3    m\n",
+		"stopped at " + main + ":3:1 in <toplevel> (breakpoint)\n",
+		"breakpoint at " + lib + ":2\nbreakpoint at " + main + ":3\n",
+		"(debug) 2\nprogram exited\n",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected output to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "stopped at "+lib+":3") {
+		t.Errorf("unexpected stop in lib.mky:\n%s", out.String())
+	}
+}
@@ -0,0 +1,216 @@
+// Package debug 实现了与前端无关的调试器核心：断点、单步执行和作用域检查。
+// 具体的交互方式（如 DAP 协议）由其他包在此基础上实现。
+package debug
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+
+	"github.com/hungtcs/monkey-lang/monkey"
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// ErrTerminated 在调试会话被前端终止时由求值器返回
+var ErrTerminated = errors.New("debug session terminated")
+
+// Reason 表示程序暂停的原因
+type Reason string
+
+const (
+	ReasonEntry      Reason = "entry"
+	ReasonBreakpoint Reason = "breakpoint"
+	ReasonStep       Reason = "step"
+	ReasonPause      Reason = "pause"
+)
+
+// Command 是前端发给暂停中的程序的恢复指令
+type Command int
+
+const (
+	Continue  Command = iota // 继续执行直到下一个断点
+	Next                     // 执行到当前函数的下一条语句
+	StepIn                   // 执行到下一条语句，包括进入函数调用
+	StepOut                  // 执行到调用者的下一条语句
+	Terminate                // 终止程序
+)
+
+// Frame 是暂停时调用栈中的一帧
+type Frame struct {
+	Name string
+	File string // 帧正在执行的语句或调用所在的文件，与语法树中位置的文件名相同
+	Line int32
+	Col  int32
+	Env  *monkey.Env
+}
+
+// Stop 描述了一次暂停
+type Stop struct {
+	Reason Reason
+	Stmt   syntax.Stmt
+	Frames []Frame // 最内层的帧在前
+}
+
+// Debugger 实现了 monkey.Debugger
+type Debugger struct {
+	mu          sync.Mutex
+	breakpoints map[string]map[int32]bool // 以文件的绝对路径为键
+	files       map[string]string         // 位置中的文件名 => 绝对路径
+	mode        Command
+	depth       int  // 单步开始时的调用深度
+	pause       bool // 前端请求在下一条语句暂停
+	entry       bool // 在第一条语句之前暂停
+	root        *monkey.Env
+
+	stops  chan Stop
+	resume chan Command
+	quit   chan struct{} // Terminate 时关闭
+	once   sync.Once
+}
+
+// New 创建一个调试器，root 为程序顶层作用域。
+// stopOnEntry 为 true 时在第一条语句之前暂停。
+func New(root *monkey.Env, stopOnEntry bool) *Debugger {
+	return &Debugger{
+		breakpoints: make(map[string]map[int32]bool),
+		files:       make(map[string]string),
+		mode:        Continue,
+		entry:       stopOnEntry,
+		root:        root,
+		stops:       make(chan Stop),
+		resume:      make(chan Command),
+		quit:        make(chan struct{}),
+	}
+}
+
+// SetBreakpoints 将文件 file 中的断点替换为给定的行号，其他文件中的断点不变。
+// 相对路径相对于当前目录解析，与导入的模块所在的文件按绝对路径比较。
+func (d *Debugger) SetBreakpoints(file string, lines []int32) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	bps := make(map[int32]bool, len(lines))
+	for _, line := range lines {
+		bps[line] = true
+	}
+	d.breakpoints[d.abs(file)] = bps
+}
+
+// 返回文件名的绝对路径，调用者持有 d.mu
+func (d *Debugger) abs(file string) string {
+	path, ok := d.files[file]
+	if !ok {
+		var err error
+		if path, err = filepath.Abs(file); err != nil {
+			path = filepath.Clean(file)
+		}
+		d.files[file] = path
+	}
+	return path
+}
+
+// Pause 请求程序在下一条语句之前暂停
+func (d *Debugger) Pause() {
+	d.mu.Lock()
+	d.pause = true
+	d.mu.Unlock()
+}
+
+// Stops 返回程序暂停时发送通知的 channel，程序结束后前端应停止接收
+func (d *Debugger) Stops() <-chan Stop {
+	return d.stops
+}
+
+// Resume 恢复暂停中的程序
+func (d *Debugger) Resume(cmd Command) {
+	select {
+	case d.resume <- cmd:
+	case <-d.quit:
+	}
+}
+
+// Terminate 终止程序：暂停中的程序立即结束，正在运行的程序在执行下一条语句之前结束。
+// 之后求值器返回 ErrTerminated，不会再有新的暂停
+func (d *Debugger) Terminate() {
+	d.once.Do(func() { close(d.quit) })
+}
+
+// BeforeStmt implements monkey.Debugger.
+func (d *Debugger) BeforeStmt(thread *monkey.Thread, stmt syntax.Stmt, env *monkey.Env) error {
+	select {
+	case <-d.quit:
+		return ErrTerminated
+	default:
+	}
+
+	stack := thread.CallStack()
+	start, _ := stmt.Span()
+
+	reason, stop := d.shouldStop(len(stack), start)
+	if !stop {
+		return nil
+	}
+
+	select {
+	case d.stops <- Stop{Reason: reason, Stmt: stmt, Frames: d.frames(stack, start, env)}:
+	case <-d.quit:
+		return ErrTerminated
+	}
+	var cmd Command
+	select {
+	case cmd = <-d.resume:
+	case <-d.quit:
+		cmd = Terminate
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if cmd == Terminate {
+		return ErrTerminated
+	}
+	d.mode = cmd
+	d.depth = len(stack)
+	return nil
+}
+
+func (d *Debugger) shouldStop(depth int, pos syntax.Position) (Reason, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.entry {
+		d.entry = false
+		return ReasonEntry, true
+	}
+	if d.pause {
+		d.pause = false
+		return ReasonPause, true
+	}
+	if d.breakpoints[d.abs(pos.Filename())][pos.Line] {
+		return ReasonBreakpoint, true
+	}
+	switch d.mode {
+	case StepIn:
+		return ReasonStep, true
+	case Next:
+		return ReasonStep, depth <= d.depth
+	case StepOut:
+		return ReasonStep, depth < d.depth
+	}
+	return "", false
+}
+
+// 根据调用栈构造暂停时的帧列表，每一帧的位置是其正在执行的语句或调用
+func (d *Debugger) frames(stack []monkey.Frame, pos syntax.Position, env *monkey.Env) []Frame {
+	frames := make([]Frame, 0, len(stack)+1)
+	for i := len(stack) - 1; i >= 0; i-- {
+		frame := Frame{Name: stack[i].Name, File: pos.Filename(), Line: pos.Line, Col: pos.Col, Env: stack[i].Env}
+		if i == len(stack)-1 {
+			frame.Env = env
+		}
+		frames = append(frames, frame)
+		pos = stack[i].Pos
+	}
+	if len(stack) > 0 {
+		env = d.root
+	}
+	return append(frames, Frame{Name: "<toplevel>", File: pos.Filename(), Line: pos.Line, Col: pos.Col, Env: env})
+}
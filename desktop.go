@@ -0,0 +1,6 @@
+//go:build desktop
+
+package main
+
+// 以 -tags desktop 构建时启用剪贴板和桌面通知内置函数
+import _ "github.com/hungtcs/monkey-lang/lib/desktop"
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/hungtcs/monkey-lang/doc"
+)
+
+// monkey doc [-html] [module ...]
+//
+// 没有参数时列出所有内置函数的文档，否则列出每个内置模块（例如 math）或模块文件中公开的绑定的文档。
+// 默认输出纯文本，-html 时输出一个 HTML 页面。
+func docCmd(args []string) int {
+	flags := flag.NewFlagSet("doc", flag.ExitOnError)
+	html := flags.Bool("html", false, "render the documentation as an HTML page")
+	flags.Parse(args)
+
+	var sections []doc.Section
+	if flags.NArg() == 0 {
+		sections = append(sections, doc.Section{Title: "Builtins", Entries: doc.Builtins()})
+	}
+	for _, filename := range flags.Args() {
+		if entries, ok := doc.BuiltinModule(filename); ok {
+			sections = append(sections, doc.Section{Title: "module " + filename, Entries: entries})
+			continue
+		}
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		entries, err := doc.Module(filename, string(src))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		sections = append(sections, doc.Section{Title: filename, Entries: entries})
+	}
+
+	write := doc.WriteText
+	if *html {
+		write = doc.WriteHTML
+	}
+	if err := write(os.Stdout, sections); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
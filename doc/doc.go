@@ -0,0 +1,216 @@
+// Package doc 提取内置函数和 Monkey 模块的文档，并以文本或 HTML 的形式输出。
+//
+// 内置函数和内置模块的文档来自 monkey.Docs。模块的文档来自紧挨在顶层声明之前的 // 注释：
+//
+//	// 返回 a 与 b 的和
+//	export let add = fn(a, b) { a + b };
+//
+// 模块中有 export 声明时只列出被导出的绑定，否则列出所有顶层的 let、fn 和 record 声明。
+package doc
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/hungtcs/monkey-lang/monkey"
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// Entry 是一个内置函数或模块中的一个绑定的文档
+type Entry struct {
+	Name      string
+	Signature string // 调用方式，例如 add(a, b)；不是函数时与 Name 相同
+	Doc       string // 说明，没有文档时为空字符串
+}
+
+// Section 是一组文档，例如所有的内置函数或一个模块中的绑定
+type Section struct {
+	Title   string
+	Entries []Entry
+}
+
+// Builtins 按名称的顺序返回 monkey.Universe 中所有内置函数的文档
+func Builtins() []Entry {
+	entries := make([]Entry, 0, len(monkey.Universe))
+	for name := range monkey.Universe {
+		entry := builtinEntry(name, name)
+		if entry.Signature == name {
+			entry.Signature = name + "(...)"
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// BuiltinModule 按名称的顺序返回内置模块 name 中所有绑定的文档，不存在该模块时返回 false
+func BuiltinModule(name string) ([]Entry, bool) {
+	module, ok := monkey.BuiltinModules[name]
+	if !ok {
+		return nil, false
+	}
+	var entries []Entry
+	for _, member := range module.Exports() {
+		entries = append(entries, builtinEntry(member, name+"."+member))
+	}
+	return entries, true
+}
+
+// 返回以 key 在 monkey.Docs 中记录的文档，没有文档时签名为 name
+func builtinEntry(name, key string) Entry {
+	entry := Entry{Name: name, Signature: key}
+	if doc, ok := monkey.Docs[key]; ok {
+		signature, rest, _ := strings.Cut(doc, "\n")
+		entry.Signature = signature
+		entry.Doc = strings.TrimSpace(rest)
+	}
+	return entry
+}
+
+// Module 按声明的顺序返回模块源码 src 中公开的绑定的文档，filename 用于语法错误的位置
+func Module(filename, src string) ([]Entry, error) {
+	program, err := syntax.NewFileParser(filename, src).Parse()
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(src, "\n")
+
+	exported := false
+	for _, stmt := range program.Stmts {
+		if _, ok := stmt.(*syntax.ExportStmt); ok {
+			exported = true
+			break
+		}
+	}
+
+	var entries []Entry
+	for _, stmt := range program.Stmts {
+		var entry Entry
+		switch stmt := stmt.(type) {
+		case *syntax.ExportStmt:
+			entry = letEntry(stmt.Let)
+		case *syntax.LetStmt:
+			if exported {
+				continue
+			}
+			entry = letEntry(stmt)
+		case *syntax.FunctionStmt:
+			if exported {
+				continue
+			}
+			entry = Entry{Name: stmt.Fn.Name.Value, Signature: signature(stmt.Fn.Name.Value, stmt.Fn)}
+		case *syntax.RecordStmt:
+			if exported {
+				continue
+			}
+			entry = Entry{Name: stmt.Name.Value, Signature: stmt.String()}
+		default:
+			continue
+		}
+		start, _ := stmt.Span()
+		entry.Doc = comment(lines, int(start.Line))
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func letEntry(let *syntax.LetStmt) Entry {
+	name := let.Name.Value
+	entry := Entry{Name: name, Signature: name}
+	if fn, ok := let.Value.(*syntax.FunctionLiteral); ok {
+		entry.Signature = signature(name, fn)
+	}
+	return entry
+}
+
+// 返回以 name 调用 fn 的方式，例如 add(a, b) 或 async load(path)
+func signature(name string, fn *syntax.FunctionLiteral) string {
+	params := make([]string, len(fn.Params))
+	for i, param := range fn.Params {
+		params[i] = param.Value
+	}
+	if fn.Variadic {
+		params[len(params)-1] += "..."
+	}
+	sig := fmt.Sprintf("%s(%s)", name, strings.Join(params, ", "))
+	if fn.Async {
+		sig = "async " + sig
+	}
+	return sig
+}
+
+// 返回第 line 行（从 1 开始）之前连续的 // 注释，去掉注释符号后以换行连接
+func comment(lines []string, line int) string {
+	var doc []string
+	for i := line - 2; i >= 0; i-- {
+		text := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(text, "//") {
+			break
+		}
+		text = strings.TrimPrefix(text, "//")
+		doc = append(doc, strings.TrimPrefix(text, " "))
+	}
+	for i, j := 0, len(doc)-1; i < j; i, j = i+1, j-1 {
+		doc[i], doc[j] = doc[j], doc[i]
+	}
+	return strings.Join(doc, "\n")
+}
+
+// WriteText 以纯文本的形式输出 sections，每个条目的说明缩进一级
+func WriteText(w io.Writer, sections []Section) error {
+	var out bytes.Buffer
+	for i, section := range sections {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+		fmt.Fprintln(&out, section.Title)
+		fmt.Fprintln(&out, strings.Repeat("=", len(section.Title)))
+		for _, entry := range section.Entries {
+			fmt.Fprintln(&out)
+			fmt.Fprintln(&out, entry.Signature)
+			for _, line := range strings.Split(entry.Doc, "\n") {
+				if line != "" {
+					fmt.Fprintln(&out, "    "+line)
+				}
+			}
+		}
+	}
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+var htmlTemplate = template.Must(template.New("doc").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Monkey documentation</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: 2em auto; }
+code { font-size: 1.1em; }
+dd { margin-bottom: 1em; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+{{- range .}}
+<h2>{{.Title}}</h2>
+<dl>
+{{- range .Entries}}
+<dt id="{{.Name}}"><code>{{.Signature}}</code></dt>
+<dd>{{.Doc}}</dd>
+{{- end}}
+</dl>
+{{- end}}
+</body>
+</html>
+`))
+
+// WriteHTML 以单个 HTML 页面的形式输出 sections
+func WriteHTML(w io.Writer, sections []Section) error {
+	return htmlTemplate.Execute(w, sections)
+}
@@ -0,0 +1,121 @@
+package doc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const module = `// 返回 a 与 b 的和
+//
+// 两个参数都必须是数字
+export let add = fn(a, b) { a + b };
+
+// 不会出现在文档中
+let hidden = 1;
+
+let gap = 2;
+// 版本号
+export let version = "1.0";
+
+export let load = async fn(path, opts...) { path };
+`
+
+func TestModule(t *testing.T) {
+	entries, err := Module("m.mky", module)
+	if err != nil {
+		t.Fatalf("Module returned error: %s", err)
+	}
+	expected := []Entry{
+		{Name: "add", Signature: "add(a, b)", Doc: "返回 a 与 b 的和\n\n两个参数都必须是数字"},
+		{Name: "version", Signature: "version", Doc: "版本号"},
+		{Name: "load", Signature: "async load(path, opts...)", Doc: ""},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("wrong number of entries. expected=%d, got=%+v", len(expected), entries)
+	}
+	for i, entry := range entries {
+		if entry != expected[i] {
+			t.Errorf("entries[%d]: expected %+v. got=%+v", i, expected[i], entry)
+		}
+	}
+}
+
+func TestModuleWithoutExports(t *testing.T) {
+	src := "// 点\nrecord Point {x, y}\nfn norm(p) { p.x }\nlet origin = Point(0, 0);"
+	entries, err := Module("m.mky", src)
+	if err != nil {
+		t.Fatalf("Module returned error: %s", err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Signature)
+	}
+	if strings.Join(got, "; ") != "record Point {x, y}; norm(p); origin" {
+		t.Errorf("wrong entries. got=%q", got)
+	}
+	if entries[0].Doc != "点" {
+		t.Errorf("wrong doc for Point. got=%q", entries[0].Doc)
+	}
+
+	if _, err := Module("bad.mky", "let = 1;"); err == nil || !strings.Contains(err.Error(), "bad.mky:1:5") {
+		t.Errorf("expected a syntax error with position. got=%v", err)
+	}
+}
+
+func TestBuiltins(t *testing.T) {
+	for _, entry := range Builtins() {
+		if entry.Name == "len" {
+			if entry.Signature != "len(x)" || entry.Doc == "" {
+				t.Errorf("wrong entry for len. got=%+v", entry)
+			}
+			return
+		}
+	}
+	t.Errorf("len is missing from Builtins")
+}
+
+func TestBuiltinModule(t *testing.T) {
+	entries, ok := BuiltinModule("math")
+	if !ok {
+		t.Fatalf("math module not found")
+	}
+	var sqrt Entry
+	for _, entry := range entries {
+		if entry.Name == "sqrt" {
+			sqrt = entry
+		}
+	}
+	if sqrt.Signature != "math.sqrt(x)" || sqrt.Doc == "" {
+		t.Errorf("wrong entry for sqrt. got=%+v", sqrt)
+	}
+	if _, ok := BuiltinModule("no_such_module"); ok {
+		t.Errorf("expected no_such_module to be missing")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	sections := []Section{{Title: "m.mky", Entries: []Entry{
+		{Name: "add", Signature: "add(a, b)", Doc: "返回 a 与 b 的和\n\n<b>"},
+		{Name: "x", Signature: "x"},
+	}}}
+
+	var text bytes.Buffer
+	if err := WriteText(&text, sections); err != nil {
+		t.Fatal(err)
+	}
+	expected := "m.mky\n=====\n\nadd(a, b)\n    返回 a 与 b 的和\n    <b>\n\nx\n"
+	if text.String() != expected {
+		t.Errorf("wrong text output.\nexpected=%q\ngot=%q", expected, text.String())
+	}
+
+	var html bytes.Buffer
+	if err := WriteHTML(&html, sections); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"<h2>m.mky</h2>", `<dt id="add"><code>add(a, b)</code></dt>`, "&lt;b&gt;"} {
+		if !strings.Contains(html.String(), want) {
+			t.Errorf("HTML output does not contain %q:\n%s", want, html.String())
+		}
+	}
+}
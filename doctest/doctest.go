@@ -0,0 +1,140 @@
+// Package doctest 提取并验证 Monkey 源码注释中的示例：
+//
+//	// >>> add(1, 2)
+//	// => 3
+//
+// 示例在文件执行完毕后的全局作用域中求值，其结果按 REPL 的方式打印后与期望值比较。
+package doctest
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hungtcs/monkey-lang/monkey"
+	"github.com/hungtcs/monkey-lang/mutate"
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+const (
+	examplePrefix = "// >>>"
+	resultPrefix  = "// =>"
+)
+
+// Example 是一对 `// >>> expr` 和 `// => expected` 注释
+type Example struct {
+	Source string // 待求值的表达式
+	Want   string // 期望的结果
+	Line   int    // `// >>>` 所在的行号
+}
+
+// Failure 是一个未通过的示例
+type Failure struct {
+	Example *Example
+	Got     string
+}
+
+func (f *Failure) String() string {
+	return fmt.Sprintf("%d: %s\n\twant: %s\n\tgot:  %s", f.Example.Line, f.Example.Source, f.Example.Want, f.Got)
+}
+
+// Extract 返回 src 中的所有示例，缺少 `// =>` 的示例会被忽略
+func Extract(src string) []*Example {
+	var examples []*Example
+	var cur *Example
+	for i, line := range strings.Split(src, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, examplePrefix):
+			cur = &Example{
+				Source: strings.TrimSpace(strings.TrimPrefix(line, examplePrefix)),
+				Line:   i + 1,
+			}
+		case strings.HasPrefix(line, resultPrefix) && cur != nil:
+			cur.Want = strings.TrimSpace(strings.TrimPrefix(line, resultPrefix))
+			examples = append(examples, cur)
+			cur = nil
+		default:
+			cur = nil
+		}
+	}
+	return examples
+}
+
+// Run 执行 program，然后在其全局作用域中逐个验证 examples。
+// 程序本身执行失败时返回错误，print 的输出写入 stdout。
+func Run(filename string, program *syntax.Program, examples []*Example, stdout io.Writer) ([]*Failure, error) {
+	thread := &monkey.Thread{
+		Name:  filename,
+		Print: func(_ *monkey.Thread, msg string) { io.WriteString(stdout, msg) },
+	}
+	return RunThread(thread, program, examples)
+}
+
+// RunThread 与 Run 相同，但在 thread 中执行，调用者可以设置 MaxSteps 等限制。
+// 程序和每个示例分别求值，限制对它们分别生效
+func RunThread(thread *monkey.Thread, program *syntax.Program, examples []*Example) ([]*Failure, error) {
+	env := monkey.NewEnv(nil)
+	if _, err := monkey.EvalThread(thread, program, env); err != nil {
+		return nil, err
+	}
+
+	var failures []*Failure
+	for _, example := range examples {
+		if got := eval(thread, example.Source, env); got != example.Want {
+			failures = append(failures, &Failure{Example: example, Got: got})
+		}
+	}
+	return failures, nil
+}
+
+// 对示例求值，错误以 "error: ..." 的形式参与比较，以便示例描述预期的错误
+func eval(thread *monkey.Thread, src string, env *monkey.Env) string {
+	program, err := syntax.NewParser(src).Parse()
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	value, err := monkey.EvalThread(thread, program, env)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return value.String()
+}
+
+// 变异体每次求值最多执行的语句数至少是原程序的 mutantStepFactor 倍，并且不少于 minMutantSteps
+const (
+	mutantStepFactor = 10
+	minMutantSteps   = 10000
+)
+
+// Mutate 对 program 的每个变异体运行 examples，返回所有的变异体和其中存活的变异体，即示例全部通过的变异体。
+// 变异可能使程序无限递归或者运行极长的时间，因此变异体的求值限制了执行的语句数，
+// 超出限制与其他错误一样说明变异被示例发现。原程序执行失败时返回错误
+func Mutate(filename string, program *syntax.Program, examples []*Example) (mutants, survived []*mutate.Mutant, err error) {
+	var steps int64
+	thread := &monkey.Thread{
+		Name:  filename,
+		Print: func(*monkey.Thread, string) {},
+		Hooks: &monkey.Hooks{OnStmt: func(*monkey.Thread, syntax.Stmt, error) { steps++ }},
+	}
+	if _, err := RunThread(thread, program, examples); err != nil {
+		return nil, nil, err
+	}
+	maxSteps := max(mutantStepFactor*steps, minMutantSteps)
+
+	mutants = mutate.Mutants(program)
+	for _, mutant := range mutants {
+		mutant.Apply()
+		thread := &monkey.Thread{
+			Name:     filename,
+			Print:    func(*monkey.Thread, string) {},
+			MaxSteps: maxSteps,
+		}
+		failures, err := RunThread(thread, program, examples)
+		mutant.Revert()
+		if err == nil && len(failures) == 0 {
+			survived = append(survived, mutant)
+		}
+	}
+	return mutants, survived, nil
+}
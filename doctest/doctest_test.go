@@ -0,0 +1,90 @@
+package doctest
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+const source = `// >>> add(1, 2)
+// => 3
+// >>> add(1)
+// this line breaks the example
+let add = fn(a, b) { a + b };
+
+// >>> square(3)
+// => 10
+let square = fn(x) { x * x };
+`
+
+func TestExtract(t *testing.T) {
+	examples := Extract(source)
+	if len(examples) != 2 {
+		t.Fatalf("wrong number of examples. got=%d", len(examples))
+	}
+	if examples[0].Source != "add(1, 2)" || examples[0].Want != "3" || examples[0].Line != 1 {
+		t.Errorf("examples[0] wrong. got=%+v", examples[0])
+	}
+	if examples[1].Source != "square(3)" || examples[1].Want != "10" || examples[1].Line != 7 {
+		t.Errorf("examples[1] wrong. got=%+v", examples[1])
+	}
+}
+
+func TestRun(t *testing.T) {
+	program, err := syntax.NewParser(source).Parse()
+	if err != nil {
+		t.Fatalf("parser error: %s", err)
+	}
+
+	failures, err := Run("test.mky", program, Extract(source), io.Discard)
+	if err != nil {
+		t.Fatalf("run error: %s", err)
+	}
+	if len(failures) != 1 {
+		t.Fatalf("wrong number of failures. got=%d", len(failures))
+	}
+	if failures[0].Example.Source != "square(3)" || failures[0].Got != "9" {
+		t.Errorf("failure wrong. got=%+v", failures[0])
+	}
+}
+
+func TestMutate(t *testing.T) {
+	// 把 total 中的 d - 1 变异为 d + 1 之后，每深入一层 pow2 的工作量都翻倍，
+	// 远在达到调用深度的限制之前就需要运行极长的时间
+	src := `// >>> total(3)
+// => 14
+let pow2 = fn(d) { if (d == 0) { 1 } else { pow2(d - 1) + pow2(d - 1) } };
+let total = fn(d) { if (d == 0) { 0 } else { pow2(d) + total(d - 1) } };
+`
+	program, err := syntax.NewParser(src).Parse()
+	if err != nil {
+		t.Fatalf("parser error: %s", err)
+	}
+	original := program.String()
+
+	type result struct {
+		mutants, survived int
+		err               error
+	}
+	done := make(chan result, 1)
+	go func() {
+		mutants, survived, err := Mutate("total.mky", program, Extract(src))
+		done <- result{len(mutants), len(survived), err}
+	}()
+	select {
+	case res := <-done:
+		if res.err != nil {
+			t.Fatal(res.err)
+		}
+		if res.mutants == 0 || res.survived != 0 {
+			t.Errorf("expected all mutants to be killed. got %d of %d survived", res.survived, res.mutants)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("mutation testing did not finish")
+	}
+	if program.String() != original {
+		t.Errorf("program not restored. got=%s", program)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hungtcs/monkey-lang/project"
+	"github.com/hungtcs/monkey-lang/syntax/format"
+)
+
+// monkey fmt [-w] [-d] [path ...]
+//
+// 格式化 Monkey 源码，path 可以是文件或目录。默认把格式化的结果写到标准输出，
+// -w 时改写格式发生变化的文件，-d 时输出格式化前后的差异。没有 path 时格式化标准输入。
+// 文件所在项目的 monkey.toml 中的 [fmt] indent 指定缩进的空格数，标准输入使用当前目录所在的项目。
+func fmtCmd(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := flags.Bool("w", false, "write the result to the source file instead of standard output")
+	diff := flags.Bool("d", false, "print diffs instead of the formatted source")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "cannot use -w with standard input")
+			return 2
+		}
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		opts, err := formatOptions(".")
+		if err == nil {
+			err = formatFile(opts, "<stdin>", src, os.Stdout, false, *diff)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	files, err := sourceFiles(flags.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	status := 0
+	for _, filename := range files {
+		opts, err := formatOptions(filepath.Dir(filename))
+		var src []byte
+		if err == nil {
+			src, err = os.ReadFile(filename)
+		}
+		if err == nil {
+			err = formatFile(opts, filename, src, os.Stdout, *write, *diff)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+// 返回 dir 所在项目的格式化选项，不在项目中时使用默认选项
+func formatOptions(dir string) (format.Options, error) {
+	m, _, err := project.Load(dir)
+	if err != nil || m == nil {
+		return format.Options{}, err
+	}
+	return format.Options{Indent: m.Fmt.Indent}, nil
+}
+
+// 以 opts 格式化名为 filename 的源码 src，按 write 和 diff 改写文件或输出结果
+func formatFile(opts format.Options, filename string, src []byte, w io.Writer, write, diff bool) error {
+	res, err := opts.Source(filename, src)
+	if err != nil {
+		return err
+	}
+	if diff && !bytes.Equal(src, res) {
+		io.WriteString(w, unifiedDiff(filename, string(src), string(res)))
+	}
+	if write {
+		if bytes.Equal(src, res) {
+			return nil
+		}
+		info, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filename, res, info.Mode().Perm())
+	}
+	if !diff {
+		_, err = w.Write(res)
+	}
+	return err
+}
+
+// 返回从 a 到 b 的 unified 格式的差异，每处修改前后保留 3 行上下文
+func unifiedDiff(filename, a, b string) string {
+	const context = 3
+	x, y := splitLines(a), splitLines(b)
+
+	// lcs[i][j] 是 x[i:] 与 y[j:] 的最长公共子序列的长度
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// 逐行的编辑：' ' 表示相同，'-' 表示删除 x 中的行，'+' 表示插入 y 中的行
+	type edit struct {
+		op   byte
+		line string
+		i, j int // 这一行之前 x 和 y 中已经处理的行数
+	}
+	var edits []edit
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			edits = append(edits, edit{' ', x[i], i, j})
+			i, j = i+1, j+1
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{'-', x[i], i, j})
+			i++
+		default:
+			edits = append(edits, edit{'+', y[j], i, j})
+			j++
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", filename, filename)
+	for k := 0; k < len(edits); {
+		if edits[k].op == ' ' {
+			k++
+			continue
+		}
+		// 一个 hunk 从修改之前的 context 行开始，直到之后连续 2*context 行都没有修改
+		start := max(k-context, 0)
+		end := k
+		for end < len(edits) {
+			if edits[end].op != ' ' {
+				end++
+				continue
+			}
+			n := 0
+			for end+n < len(edits) && edits[end+n].op == ' ' {
+				n++
+			}
+			if end+n == len(edits) || n > 2*context {
+				end = min(end+context, len(edits))
+				break
+			}
+			end += n
+		}
+
+		var xn, yn int
+		for _, e := range edits[start:end] {
+			if e.op != '+' {
+				xn++
+			}
+			if e.op != '-' {
+				yn++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(edits[start].i, xn), hunkRange(edits[start].j, yn))
+		for _, e := range edits[start:end] {
+			out.WriteByte(e.op)
+			out.WriteString(e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		k = end
+	}
+	return out.String()
+}
+
+// 返回 hunk 头部中从第 start 行（从 0 开始）开始的 n 行的范围
+func hunkRange(start, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if n == 1 {
+		return fmt.Sprint(start + 1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, n)
+}
+
+// 把 s 分成行，每一行包括结尾的换行
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/hungtcs/monkey-lang/monkey"
+	"github.com/hungtcs/monkey-lang/project"
+)
+
+// monkey get [module ...]
+//
+// 预先下载远程模块到缓存目录，并将其校验和记录到项目根目录下的 monkey.lock，
+// 不在项目中时记录到当前目录下的 monkey.lock。不指定模块时下载当前项目 monkey.toml 中的所有依赖。
+func getCmd(args []string) int {
+	flags := flag.NewFlagSet("get", flag.ExitOnError)
+	flags.Parse(args)
+
+	modules := flags.Args()
+	importer := monkey.NewImporter()
+	if len(modules) == 0 {
+		m, _, err := project.Load(".")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if m == nil {
+			fmt.Fprintf(os.Stderr, "no module given and no %s found\n", project.ManifestName)
+			return 2
+		}
+		modules = m.Dependencies
+	}
+
+	status := 0
+	for _, path := range modules {
+		filename, err := importer.Fetch(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+		fmt.Printf("%s -> %s\n", path, filename)
+	}
+	return status
+}
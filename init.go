@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hungtcs/monkey-lang/project"
+)
+
+const mainTemplate = `let greet = fn(name) { "hello, " + name };
+
+print(greet("world"));
+`
+
+// monkey init [dir]
+//
+// 在 dir（默认为当前目录）中创建 monkey.toml 和入口文件 main.mky
+func initCmd(args []string) int {
+	flags := flag.NewFlagSet("init", flag.ExitOnError)
+	name := flags.String("name", "", "project name (default: the directory name)")
+	flags.Parse(args)
+
+	dir := "."
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		dir = flags.Arg(0)
+	default:
+		usage()
+		return 2
+	}
+	if err := initProject(dir, *name); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func initProject(dir, name string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = filepath.Base(dir)
+	}
+	manifest := filepath.Join(dir, project.ManifestName)
+	if _, err := os.Stat(manifest); err == nil {
+		return fmt.Errorf("%s already exists", manifest)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	m := project.New(name)
+	if err := os.WriteFile(manifest, []byte(m.String()), 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("created %s\n", manifest)
+
+	// 不覆盖已有的入口文件
+	entry := filepath.Join(dir, m.Entry)
+	if _, err := os.Stat(entry); os.IsNotExist(err) {
+		if err := os.WriteFile(entry, []byte(mainTemplate), 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("created %s\n", entry)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// Package interp 是嵌入 Monkey 的稳定入口。Monkey 的公开 API 分为三层：
+//
+//   - syntax：词法分析、语法分析和语法树，用于编辑器、格式化和静态检查等只需要源码的工具
+//   - monkey：值、作用域、Thread 和求值器，用于编写内置函数和需要控制求值细节的宿主程序
+//   - interp：执行脚本的高层接口，即 Interpreter、选项和执行限制
+//
+// 大多数宿主程序只需要 interp：
+//
+//	value, err := interp.Run(`1 + 2`, interp.WithLimits(interp.Limits{MaxSteps: 10000}))
+//
+// 这里的类型是 monkey 包中对应类型的别名，两个包的值可以混用；
+// monkey 中原有的 NewInterpreter、Run 等名称继续保留。
+package interp
+
+import (
+	"context"
+	"io"
+
+	"github.com/hungtcs/monkey-lang/monkey"
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+type (
+	// Interpreter 保存全局作用域和执行的配置，见 monkey.Interpreter
+	Interpreter = monkey.Interpreter
+	// Option 是 New 和 Run 的选项
+	Option = monkey.Option
+	// Limits 是一次 Run 的执行限制
+	Limits = monkey.Limits
+
+	// Value 是脚本中的值
+	Value = monkey.Value
+	// Thread 是一次求值的执行状态，内置函数通过它访问宿主程序的配置
+	Thread = monkey.Thread
+	// Env 是一个作用域
+	Env = monkey.Env
+	// Policy 是允许脚本使用的能力
+	Policy = monkey.Policy
+)
+
+// 可以用 errors.Is 判断 Run 返回的错误的种类
+var (
+	ErrParse             = monkey.ErrParse             // 语法错误
+	ErrRuntime           = monkey.ErrRuntime           // 运行时错误
+	ErrCancelled         = monkey.ErrCancelled         // Context 被取消
+	ErrResourceExhausted = monkey.ErrResourceExhausted // 超出执行限制
+)
+
+// New 返回一个按 opts 配置的 Interpreter
+func New(opts ...Option) *Interpreter {
+	return monkey.NewInterpreter(opts...)
+}
+
+// Run 使用 opts 创建一个 Interpreter 并执行 src，返回最后一个表达式的值
+func Run(src string, opts ...Option) (Value, error) {
+	return monkey.Run(src, opts...)
+}
+
+// WithFilename 设置源码的文件名，用于错误的位置和解析相对路径的 import
+func WithFilename(filename string) Option {
+	return monkey.WithFilename(filename)
+}
+
+// WithGlobals 预先在全局作用域中定义 globals 中的变量，Go 的值由 monkey.FromGo 转换
+func WithGlobals(globals map[string]any) Option {
+	return monkey.WithGlobals(globals)
+}
+
+// WithBuiltin 在全局作用域中定义名为 name 的内置函数
+func WithBuiltin(name string, fn func(thread *Thread, args ...Value) (Value, error)) Option {
+	return monkey.WithBuiltin(name, fn)
+}
+
+// WithStdout 设置 print 等内置函数的输出
+func WithStdout(w io.Writer) Option {
+	return monkey.WithStdout(w)
+}
+
+// WithPolicy 设置允许脚本使用的能力
+func WithPolicy(policy *Policy) Option {
+	return monkey.WithPolicy(policy)
+}
+
+// WithLimits 设置每次 Run 的执行限制
+func WithLimits(limits Limits) Option {
+	return monkey.WithLimits(limits)
+}
+
+// WithLanguage 设置解析源码时启用的可选语言特性
+func WithLanguage(opts syntax.LanguageOptions) Option {
+	return monkey.WithLanguage(opts)
+}
+
+// WithContext 设置 Run 使用的 Context
+func WithContext(ctx context.Context) Option {
+	return monkey.WithContext(ctx)
+}
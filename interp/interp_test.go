@@ -0,0 +1,32 @@
+package interp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hungtcs/monkey-lang/monkey"
+)
+
+func TestRun(t *testing.T) {
+	var out strings.Builder
+	in := New(
+		WithGlobals(map[string]any{"n": 20}),
+		WithBuiltin("inc", func(thread *Thread, args ...Value) (Value, error) {
+			return args[0].(monkey.Int) + 1, nil
+		}),
+		WithStdout(&out),
+	)
+	val, err := in.Run(`print("hi"); inc(n) + 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val.String() != "22" || out.String() != "hi\n" {
+		t.Errorf("got value %s and output %q", val, out.String())
+	}
+
+	_, err = Run(`let f = fn() { f() }; f()`, WithLimits(Limits{MaxSteps: 100}))
+	if !errors.Is(err, ErrResourceExhausted) || !errors.Is(err, monkey.ErrResourceExhausted) {
+		t.Errorf("expected ErrResourceExhausted, got %v", err)
+	}
+}
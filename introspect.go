@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hungtcs/monkey-lang/monkey"
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// monkey introspect-ops
+//
+// 输出 Markdown 表格，列出每个运算符支持哪些操作数类型以及结果的类型。
+// 表格由解释器的实现生成，不会与实际行为不一致。
+func introspectOpsCmd(args []string) int {
+	flags := flag.NewFlagSet("introspect-ops", flag.ExitOnError)
+	flags.Parse(args)
+	if flags.NArg() != 0 {
+		usage()
+		return 2
+	}
+	writeOperatorTables(os.Stdout, monkey.Operators(), monkey.OperandTypes())
+	return 0
+}
+
+func writeOperatorTables(w io.Writer, ops []monkey.OperatorSupport, types []string) {
+	type key struct {
+		op          syntax.Token
+		left, right string
+	}
+	results := make(map[key]string)
+	for _, op := range ops {
+		results[key{op.Op, op.Left, op.Right}] = op.Result
+	}
+
+	// 一元运算符：每行一种操作数类型，每列一个运算符
+	fmt.Fprintln(w, "## Unary operators")
+	fmt.Fprintln(w)
+	header := []string{"operand"}
+	for _, op := range monkey.UnaryOperators {
+		header = append(header, fmt.Sprintf("`%sx`", op))
+	}
+	writeRow(w, header)
+	writeSeparator(w, len(header))
+	for _, typ := range types {
+		row := []string{typ}
+		for _, op := range monkey.UnaryOperators {
+			row = append(row, results[key{op, "", typ}])
+		}
+		writeRow(w, row)
+	}
+
+	// 二元运算符：每个运算符一张表，行是左操作数，列是右操作数
+	for _, op := range append(monkey.BinaryOperators, monkey.CompareOperators...) {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "## `x %s y`\n", op)
+		fmt.Fprintln(w)
+		writeRow(w, append([]string{"x \\ y"}, types...))
+		writeSeparator(w, len(types)+1)
+		for _, left := range types {
+			row := []string{left}
+			for _, right := range types {
+				row = append(row, results[key{op, left, right}])
+			}
+			writeRow(w, row)
+		}
+	}
+}
+
+func writeRow(w io.Writer, cells []string) {
+	fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+}
+
+// 输出 Markdown 表头下面的分隔行
+func writeSeparator(w io.Writer, columns int) {
+	fmt.Fprintf(w, "|%s\n", strings.Repeat(" --- |", columns))
+}
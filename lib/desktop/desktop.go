@@ -0,0 +1,157 @@
+// Package desktop 提供桌面自动化的内置函数，它们通过系统自带的命令行工具实现：
+//
+//	clipboard_read()            读取剪贴板中的文本
+//	clipboard_write(text)       将文本写入剪贴板
+//	notify(title, body)         发送桌面通知
+//
+// 该包只在以 -tags desktop 构建 monkey 时被导入。使用剪贴板需要 Policy
+// 允许 "clipboard" 能力，发送通知需要 "notify" 能力。
+package desktop
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/hungtcs/monkey-lang/monkey"
+)
+
+const (
+	CapClipboard monkey.Capability = "clipboard" // clipboard_read() 和 clipboard_write()
+	CapNotify    monkey.Capability = "notify"    // notify()
+)
+
+func init() {
+	monkey.Capabilities = append(monkey.Capabilities, CapClipboard, CapNotify)
+	monkey.Universe["clipboard_read"] = monkey.NewBuiltinFunction("clipboard_read", clipboardRead)
+	monkey.Universe["clipboard_write"] = monkey.NewBuiltinFunction("clipboard_write", clipboardWrite)
+	monkey.Universe["notify"] = monkey.NewBuiltinFunction("notify", notify)
+	monkey.Docs["clipboard_read"] = "clipboard_read()\n\n读取剪贴板中的文本"
+	monkey.Docs["clipboard_write"] = "clipboard_write(text)\n\n将文本写入剪贴板"
+	monkey.Docs["notify"] = "notify(title, body)\n\n发送桌面通知"
+}
+
+// command 返回执行操作 op（"read"、"write" 或 "notify"）的命令行，测试时可以替换
+var command = systemCommand
+
+func systemCommand(op string, args ...string) ([]string, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		switch op {
+		case "read":
+			return []string{"pbpaste"}, nil
+		case "write":
+			return []string{"pbcopy"}, nil
+		case "notify":
+			script := fmt.Sprintf("display notification %s with title %s", appleString(args[1]), appleString(args[0]))
+			return []string{"osascript", "-e", script}, nil
+		}
+	case "linux", "freebsd", "openbsd", "netbsd":
+		wayland := os.Getenv("WAYLAND_DISPLAY") != ""
+		switch op {
+		case "read":
+			if wayland {
+				return []string{"wl-paste", "--no-newline"}, nil
+			}
+			return []string{"xclip", "-selection", "clipboard", "-o"}, nil
+		case "write":
+			if wayland {
+				return []string{"wl-copy"}, nil
+			}
+			return []string{"xclip", "-selection", "clipboard"}, nil
+		case "notify":
+			return []string{"notify-send", args[0], args[1]}, nil
+		}
+	case "windows":
+		switch op {
+		case "read":
+			return []string{"powershell", "-NoProfile", "-Command", "Get-Clipboard -Raw"}, nil
+		case "write":
+			return []string{"clip"}, nil
+		}
+	}
+	return nil, fmt.Errorf("%s is not supported on %s", op, runtime.GOOS)
+}
+
+// 将字符串编码为 AppleScript 字符串字面量
+func appleString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// 执行命令，stdin 作为命令的标准输入，返回命令的标准输出
+func run(op string, stdin string, args ...string) (string, error) {
+	argv, err := command(op, args...)
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %v: %s", argv[0], err, msg)
+		}
+		return "", fmt.Errorf("%s: %v", argv[0], err)
+	}
+	return stdout.String(), nil
+}
+
+// clipboard_read()
+func clipboardRead(thread *monkey.Thread, args ...monkey.Value) (monkey.Value, error) {
+	if err := thread.Require(CapClipboard, "clipboard_read"); err != nil {
+		return nil, err
+	}
+	if len(args) != 0 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=0", len(args))
+	}
+	text, err := run("read", "")
+	if err != nil {
+		return nil, err
+	}
+	return monkey.String(text), nil
+}
+
+// clipboard_write(text)
+func clipboardWrite(thread *monkey.Thread, args ...monkey.Value) (monkey.Value, error) {
+	if err := thread.Require(CapClipboard, "clipboard_write"); err != nil {
+		return nil, err
+	}
+	if len(args) != 1 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	text, ok := args[0].(monkey.String)
+	if !ok {
+		return nil, fmt.Errorf("argument to `clipboard_write` must be string, got %s", args[0].Type())
+	}
+	if _, err := run("write", string(text)); err != nil {
+		return nil, err
+	}
+	return monkey.Null, nil
+}
+
+// notify(title, body)
+func notify(thread *monkey.Thread, args ...monkey.Value) (monkey.Value, error) {
+	if err := thread.Require(CapNotify, "notify"); err != nil {
+		return nil, err
+	}
+	if len(args) != 2 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		s, ok := arg.(monkey.String)
+		if !ok {
+			return nil, fmt.Errorf("arguments to `notify` must be strings, got %s", arg.Type())
+		}
+		strs[i] = string(s)
+	}
+	if _, err := run("notify", "", strs...); err != nil {
+		return nil, err
+	}
+	return monkey.Null, nil
+}
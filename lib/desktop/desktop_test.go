@@ -0,0 +1,75 @@
+package desktop
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hungtcs/monkey-lang/monkey"
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+func testEval(t *testing.T, input string, policy *monkey.Policy) (string, error) {
+	t.Helper()
+	program, err := syntax.NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("parser error: %s", err)
+	}
+	val, err := monkey.EvalThread(&monkey.Thread{Policy: policy}, program, monkey.NewEnv(nil))
+	if err != nil {
+		return "", err
+	}
+	return val.String(), nil
+}
+
+func TestDesktop(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("requires /bin/sh")
+	}
+	// 用读写文件的 shell 命令代替系统剪贴板和通知工具
+	dir := t.TempDir()
+	clipboard := filepath.Join(dir, "clipboard")
+	notes := filepath.Join(dir, "notes")
+	command = func(op string, args ...string) ([]string, error) {
+		switch op {
+		case "read":
+			return []string{"cat", clipboard}, nil
+		case "write":
+			return []string{"sh", "-c", `cat > "$0"`, clipboard}, nil
+		default:
+			return []string{"sh", "-c", `echo "$1: $2" >> "$0"`, notes, args[0], args[1]}, nil
+		}
+	}
+	defer func() { command = systemCommand }()
+
+	policy := monkey.NewPolicy(CapClipboard, CapNotify)
+	got, err := testEval(t, `clipboard_write("hello"); clipboard_read()`, policy)
+	if err != nil || got != "hello" {
+		t.Errorf("clipboard round trip: got=%q, %v", got, err)
+	}
+	if _, err := testEval(t, `notify("Build", "done")`, policy); err != nil {
+		t.Errorf("notify: %v", err)
+	}
+	if data, _ := os.ReadFile(notes); string(data) != "Build: done\n" {
+		t.Errorf("notify wrote %q", data)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+		policy   *monkey.Policy
+	}{
+		{`clipboard_read()`, `requires the "clipboard" capability`, monkey.NewPolicy(CapNotify)},
+		{`clipboard_write("x")`, `requires the "clipboard" capability`, nil},
+		{`notify("a", "b")`, `requires the "notify" capability`, monkey.NewPolicy(CapClipboard)},
+		{`clipboard_write(1)`, "must be string", policy},
+		{`notify("a")`, "wrong number of arguments", policy},
+	}
+	for _, tt := range tests {
+		_, err := testEval(t, tt.input, tt.policy)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected %q. got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
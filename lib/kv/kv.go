@@ -0,0 +1,194 @@
+// Package kv 提供持久化的键值存储内置函数 kv_open，数据保存在单个 bolt 文件中，
+// 适合脚本在多次运行之间保存少量状态。导入该包即可注册：
+//
+//	import _ "github.com/hungtcs/monkey-lang/lib/kv"
+//
+// kv_open(path) 返回一个存储对象，通过下标访问它的方法，键和值都是字符串：
+//
+//	let store = kv_open("state.db");
+//	store["put"]("user:alice", "30");
+//	store["get"]("user:alice");   // "30"，不存在时返回 null
+//	store["scan"]("user:");       // [["user:alice", "30"]]，按键排序
+//	store["delete"]("user:alice");
+//	store["close"]();
+//
+// 同一个文件同时只能被一个存储对象打开，用完后应当调用 close。
+// 使用 kv_open 需要 Policy 允许 "kv" 能力。
+package kv
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/hungtcs/monkey-lang/monkey"
+	bolt "go.etcd.io/bbolt"
+)
+
+// CapKV 是使用 kv_open 所需的能力
+const CapKV monkey.Capability = "kv"
+
+// 所有键值对都保存在这个 bucket 中
+var bucket = []byte("monkey")
+
+// 等待其他进程释放文件锁的最长时间
+const lockTimeout = time.Second
+
+func init() {
+	monkey.Capabilities = append(monkey.Capabilities, CapKV)
+	monkey.Universe["kv_open"] = monkey.NewBuiltinFunction("kv_open", open)
+	monkey.Docs["kv_open"] = "kv_open(path)\n\n打开（必要时创建）存储文件，返回一个存储对象，它有 get、put、delete、scan 和 close 方法"
+}
+
+// kv_open(path) 打开（必要时创建）存储文件
+func open(thread *monkey.Thread, args ...monkey.Value) (monkey.Value, error) {
+	if err := thread.Require(CapKV, "kv_open"); err != nil {
+		return nil, err
+	}
+	if len(args) != 1 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	path, ok := args[0].(monkey.String)
+	if !ok {
+		return nil, fmt.Errorf("argument to `kv_open` must be string, got %s", args[0].Type())
+	}
+	db, err := bolt.Open(string(path), 0o644, &bolt.Options{Timeout: lockTimeout})
+	if err == bolt.ErrTimeout {
+		return nil, fmt.Errorf("kv_open: %s is locked by another store", path)
+	} else if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{path: string(path), db: db}, nil
+}
+
+// Store 是 kv_open 返回的存储对象
+type Store struct {
+	path   string
+	db     *bolt.DB
+	closed bool
+}
+
+// Get implements monkey.Mapping.
+func (s *Store) Get(key monkey.Value) (_ monkey.Value, _ bool, err error) {
+	name, _ := key.(monkey.String)
+	var fn func(thread *monkey.Thread, args ...monkey.Value) (monkey.Value, error)
+	switch name {
+	case "get":
+		fn = func(thread *monkey.Thread, args ...monkey.Value) (monkey.Value, error) {
+			keys, err := s.args("get", args, 1)
+			if err != nil {
+				return nil, err
+			}
+			var result monkey.Value = monkey.Null
+			err = s.db.View(func(tx *bolt.Tx) error {
+				if v := tx.Bucket(bucket).Get(keys[0]); v != nil {
+					result = monkey.String(v)
+				}
+				return nil
+			})
+			return result, err
+		}
+	case "put":
+		fn = func(thread *monkey.Thread, args ...monkey.Value) (monkey.Value, error) {
+			kv, err := s.args("put", args, 2)
+			if err != nil {
+				return nil, err
+			}
+			return monkey.Null, s.db.Update(func(tx *bolt.Tx) error {
+				return tx.Bucket(bucket).Put(kv[0], kv[1])
+			})
+		}
+	case "delete":
+		fn = func(thread *monkey.Thread, args ...monkey.Value) (monkey.Value, error) {
+			keys, err := s.args("delete", args, 1)
+			if err != nil {
+				return nil, err
+			}
+			return monkey.Null, s.db.Update(func(tx *bolt.Tx) error {
+				return tx.Bucket(bucket).Delete(keys[0])
+			})
+		}
+	case "scan":
+		fn = func(thread *monkey.Thread, args ...monkey.Value) (monkey.Value, error) {
+			prefix, err := s.args("scan", args, 1)
+			if err != nil {
+				return nil, err
+			}
+			var pairs []monkey.Value
+			err = s.db.View(func(tx *bolt.Tx) error {
+				c := tx.Bucket(bucket).Cursor()
+				for k, v := c.Seek(prefix[0]); k != nil && bytes.HasPrefix(k, prefix[0]); k, v = c.Next() {
+					pairs = append(pairs, monkey.NewArray([]monkey.Value{monkey.String(k), monkey.String(v)}))
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			return monkey.NewArray(pairs), nil
+		}
+	case "close":
+		fn = func(thread *monkey.Thread, args ...monkey.Value) (monkey.Value, error) {
+			if len(args) != 0 {
+				return nil, fmt.Errorf("wrong number of arguments. got=%d, want=0", len(args))
+			}
+			s.closed = true
+			return monkey.Null, s.db.Close()
+		}
+	default:
+		return monkey.Null, false, nil
+	}
+	return monkey.NewBuiltinFunction(string(name), fn), true, nil
+}
+
+// 检查参数的个数和类型，并将它们转换为字节串
+func (s *Store) args(fn string, args []monkey.Value, want int) ([][]byte, error) {
+	if s.closed {
+		return nil, fmt.Errorf("`%s` called on closed store %s", fn, s.path)
+	}
+	if len(args) != want {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=%d", len(args), want)
+	}
+	result := make([][]byte, len(args))
+	for i, arg := range args {
+		str, ok := arg.(monkey.String)
+		if !ok {
+			return nil, fmt.Errorf("arguments to `%s` must be strings, got %s", fn, arg.Type())
+		}
+		result[i] = []byte(str)
+	}
+	if fn != "scan" && len(result[0]) == 0 {
+		return nil, fmt.Errorf("`%s` requires a non-empty key", fn)
+	}
+	return result, nil
+}
+
+// Hash implements monkey.Value.
+func (s *Store) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: kv.store")
+}
+
+// String implements monkey.Value.
+func (s *Store) String() string {
+	return fmt.Sprintf("<kv.store %q>", s.path)
+}
+
+// Truth implements monkey.Value.
+func (s *Store) Truth() bool {
+	return true
+}
+
+// Type implements monkey.Value.
+func (s *Store) Type() string {
+	return "kv.store"
+}
+
+var _ monkey.Mapping = (*Store)(nil)
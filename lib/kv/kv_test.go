@@ -0,0 +1,73 @@
+package kv
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hungtcs/monkey-lang/monkey"
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+func testEval(t *testing.T, input string, env *monkey.Env, policy *monkey.Policy) (string, error) {
+	t.Helper()
+	program, err := syntax.NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("parser error: %s", err)
+	}
+	val, err := monkey.EvalThread(&monkey.Thread{Policy: policy}, program, env)
+	if err != nil {
+		return "", err
+	}
+	return val.String(), nil
+}
+
+func TestKV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	setup := `let store = kv_open("` + filepath.ToSlash(path) + `");`
+
+	_, err := testEval(t, setup+`
+store["put"]("user:bob", "17");
+store["put"]("user:alice", "30");
+store["put"]("color", "red");
+store["close"]();
+`, monkey.NewEnv(nil), monkey.NewPolicy(CapKV))
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`store["get"]("user:alice")`, "30"},
+		{`store["get"]("missing")`, "null"},
+		{`store["scan"]("user:")`, "[[user:alice, 30], [user:bob, 17]]"},
+		{`len(store["scan"](""))`, "3"},
+		{`store["delete"]("color"); store["get"]("color")`, "null"},
+		{`store["put"]("color", "blue"); store["get"]("color")`, "blue"},
+		{`store["put"]("n", 1)`, "arguments to `put` must be strings, got int"},
+		{`store["get"]("")`, "requires a non-empty key"},
+		{`store["close"](); store["get"]("color")`, "called on closed store"},
+		{`store["missing"]`, "null"},
+	}
+	for _, tt := range tests {
+		env := monkey.NewEnv(nil)
+		got, err := testEval(t, setup+tt.input, env, monkey.NewPolicy(CapKV))
+		if err != nil {
+			got = err.Error()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+		// 用例出错时也要关闭存储，释放文件锁
+		testEval(t, `store["close"]()`, env, nil)
+	}
+}
+
+func TestKVPolicy(t *testing.T) {
+	_, err := testEval(t, `kv_open("state.db")`, monkey.NewEnv(nil), nil)
+	if err == nil || !strings.Contains(err.Error(), `requires the "kv" capability`) {
+		t.Errorf("expected policy error. got=%v", err)
+	}
+}
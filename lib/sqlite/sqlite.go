@@ -0,0 +1,324 @@
+// Package sqlite 提供访问 SQLite 数据库的内置函数 sqlite_open，
+// 使用纯 Go 实现的驱动，不依赖 cgo。导入该包即可注册：
+//
+//	import _ "github.com/hungtcs/monkey-lang/lib/sqlite"
+//
+// sqlite_open(path) 返回一个数据库对象，通过下标访问它的方法：
+//
+//	let db = sqlite_open("data.db");
+//	db["exec"]("create table users (name text, age int)");
+//	db["exec"]("insert into users values (?, ?)", "alice", 30);
+//	db["query"]("select * from users where age > ?", 18);  // [{"name": "alice", "age": 30}]
+//	let stmt = db["prepare"]("select name from users where age = ?");
+//	stmt["query"](30);
+//	stmt["close"]();
+//	db["close"]();
+//
+// query 和 exec 的第一个参数可以是 context() 返回的 Context，Context 被取消时中止查询。
+//
+// 使用 sqlite_open 需要 Policy 允许 "sqlite" 能力。
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/hungtcs/monkey-lang/monkey"
+	_ "modernc.org/sqlite"
+)
+
+// CapSQLite 是使用 sqlite_open 所需的能力
+const CapSQLite monkey.Capability = "sqlite"
+
+func init() {
+	monkey.Capabilities = append(monkey.Capabilities, CapSQLite)
+	monkey.Universe["sqlite_open"] = monkey.NewBuiltinFunction("sqlite_open", open)
+	monkey.Docs["sqlite_open"] = "sqlite_open(path)\n\n打开（必要时创建）数据库文件，path 为 \":memory:\" 时使用内存数据库"
+}
+
+// sqlite_open(path) 打开（必要时创建）数据库文件，path 为 ":memory:" 时使用内存数据库
+func open(thread *monkey.Thread, args ...monkey.Value) (monkey.Value, error) {
+	if err := thread.Require(CapSQLite, "sqlite_open"); err != nil {
+		return nil, err
+	}
+	if len(args) != 1 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	path, ok := args[0].(monkey.String)
+	if !ok {
+		return nil, fmt.Errorf("argument to `sqlite_open` must be string, got %s", args[0].Type())
+	}
+	db, err := sql.Open("sqlite", string(path))
+	if err != nil {
+		return nil, err
+	}
+	// 内存数据库每个连接都是独立的，只使用一个连接以便多次调用看到同一个数据库
+	db.SetMaxOpenConns(1)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &DB{path: string(path), db: db}, nil
+}
+
+// DB 是 sqlite_open 返回的数据库对象
+type DB struct {
+	path string
+	db   *sql.DB
+}
+
+// Get implements monkey.Mapping.
+func (d *DB) Get(key monkey.Value) (_ monkey.Value, _ bool, err error) {
+	name, _ := key.(monkey.String)
+	var fn func(thread *monkey.Thread, args ...monkey.Value) (monkey.Value, error)
+	switch name {
+	case "query":
+		fn = func(thread *monkey.Thread, args ...monkey.Value) (monkey.Value, error) {
+			ctx, args := monkey.SplitContext(args)
+			query, params, err := queryArgs("query", args)
+			if err != nil {
+				return nil, err
+			}
+			rows, err := d.db.QueryContext(ctx, query, params...)
+			if err != nil {
+				return nil, err
+			}
+			return scanRows(rows)
+		}
+	case "exec":
+		fn = func(thread *monkey.Thread, args ...monkey.Value) (monkey.Value, error) {
+			ctx, args := monkey.SplitContext(args)
+			query, params, err := queryArgs("exec", args)
+			if err != nil {
+				return nil, err
+			}
+			result, err := d.db.ExecContext(ctx, query, params...)
+			if err != nil {
+				return nil, err
+			}
+			return execResult(result)
+		}
+	case "prepare":
+		fn = func(thread *monkey.Thread, args ...monkey.Value) (monkey.Value, error) {
+			query, params, err := queryArgs("prepare", args)
+			if err != nil {
+				return nil, err
+			}
+			if len(params) != 0 {
+				return nil, fmt.Errorf("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			stmt, err := d.db.Prepare(query)
+			if err != nil {
+				return nil, err
+			}
+			return &Stmt{query: query, stmt: stmt}, nil
+		}
+	case "close":
+		fn = func(thread *monkey.Thread, args ...monkey.Value) (monkey.Value, error) {
+			if len(args) != 0 {
+				return nil, fmt.Errorf("wrong number of arguments. got=%d, want=0", len(args))
+			}
+			return monkey.Null, d.db.Close()
+		}
+	default:
+		return monkey.Null, false, nil
+	}
+	return monkey.NewBuiltinFunction(string(name), fn), true, nil
+}
+
+// Hash implements monkey.Value.
+func (d *DB) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: sqlite.db")
+}
+
+// String implements monkey.Value.
+func (d *DB) String() string {
+	return fmt.Sprintf("<sqlite.db %q>", d.path)
+}
+
+// Truth implements monkey.Value.
+func (d *DB) Truth() bool {
+	return true
+}
+
+// Type implements monkey.Value.
+func (d *DB) Type() string {
+	return "sqlite.db"
+}
+
+// Stmt 是 db["prepare"] 返回的预编译语句
+type Stmt struct {
+	query string
+	stmt  *sql.Stmt
+}
+
+// Get implements monkey.Mapping.
+func (s *Stmt) Get(key monkey.Value) (_ monkey.Value, _ bool, err error) {
+	name, _ := key.(monkey.String)
+	var fn func(thread *monkey.Thread, args ...monkey.Value) (monkey.Value, error)
+	switch name {
+	case "query":
+		fn = func(thread *monkey.Thread, args ...monkey.Value) (monkey.Value, error) {
+			ctx, args := monkey.SplitContext(args)
+			params, err := goValues(args)
+			if err != nil {
+				return nil, err
+			}
+			rows, err := s.stmt.QueryContext(ctx, params...)
+			if err != nil {
+				return nil, err
+			}
+			return scanRows(rows)
+		}
+	case "exec":
+		fn = func(thread *monkey.Thread, args ...monkey.Value) (monkey.Value, error) {
+			ctx, args := monkey.SplitContext(args)
+			params, err := goValues(args)
+			if err != nil {
+				return nil, err
+			}
+			result, err := s.stmt.ExecContext(ctx, params...)
+			if err != nil {
+				return nil, err
+			}
+			return execResult(result)
+		}
+	case "close":
+		fn = func(thread *monkey.Thread, args ...monkey.Value) (monkey.Value, error) {
+			if len(args) != 0 {
+				return nil, fmt.Errorf("wrong number of arguments. got=%d, want=0", len(args))
+			}
+			return monkey.Null, s.stmt.Close()
+		}
+	default:
+		return monkey.Null, false, nil
+	}
+	return monkey.NewBuiltinFunction(string(name), fn), true, nil
+}
+
+// Hash implements monkey.Value.
+func (s *Stmt) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: sqlite.stmt")
+}
+
+// String implements monkey.Value.
+func (s *Stmt) String() string {
+	return fmt.Sprintf("<sqlite.stmt %q>", s.query)
+}
+
+// Truth implements monkey.Value.
+func (s *Stmt) Truth() bool {
+	return true
+}
+
+// Type implements monkey.Value.
+func (s *Stmt) Type() string {
+	return "sqlite.stmt"
+}
+
+// 拆分 (sql, params...) 形式的参数
+func queryArgs(fn string, args []monkey.Value) (string, []any, error) {
+	if len(args) < 1 {
+		return "", nil, fmt.Errorf("wrong number of arguments. got=%d, want at least 1", len(args))
+	}
+	query, ok := args[0].(monkey.String)
+	if !ok {
+		return "", nil, fmt.Errorf("first argument to `%s` must be string, got %s", fn, args[0].Type())
+	}
+	params, err := goValues(args[1:])
+	return string(query), params, err
+}
+
+// 将 Monkey 值转换为 SQL 参数
+func goValues(args []monkey.Value) ([]any, error) {
+	params := make([]any, len(args))
+	for i, arg := range args {
+		switch arg := arg.(type) {
+		case monkey.Int:
+			params[i] = int64(arg)
+		case monkey.Float:
+			params[i] = float64(arg)
+		case monkey.String:
+			params[i] = string(arg)
+		case monkey.Bool:
+			params[i] = bool(arg)
+		case monkey.NullType:
+			params[i] = nil
+		default:
+			return nil, fmt.Errorf("unsupported SQL parameter type %s", arg.Type())
+		}
+	}
+	return params, nil
+}
+
+// 将查询结果转换为 map 的数组，map 以列名为键
+func scanRows(rows *sql.Rows) (monkey.Value, error) {
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []monkey.Value
+	for rows.Next() {
+		values := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		row := monkey.NewMap()
+		for i, column := range columns {
+			row.SetKey(monkey.String(column), monkeyValue(values[i]))
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return monkey.NewArray(result), nil
+}
+
+func monkeyValue(v any) monkey.Value {
+	switch v := v.(type) {
+	case int64:
+		return monkey.Int(v)
+	case float64:
+		return monkey.Float(v)
+	case string:
+		return monkey.String(v)
+	case []byte:
+		return monkey.String(v)
+	case bool:
+		return monkey.Bool(v)
+	case time.Time:
+		return monkey.String(v.Format(time.RFC3339Nano))
+	case nil:
+		return monkey.Null
+	}
+	return monkey.String(fmt.Sprint(v))
+}
+
+// exec 返回 {"rows_affected": n, "last_insert_id": id}
+func execResult(result sql.Result) (monkey.Value, error) {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	lastID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	m := monkey.NewMap()
+	m.SetKey(monkey.String("rows_affected"), monkey.Int(affected))
+	m.SetKey(monkey.String("last_insert_id"), monkey.Int(lastID))
+	return m, nil
+}
+
+var (
+	_ monkey.Mapping = (*DB)(nil)
+	_ monkey.Mapping = (*Stmt)(nil)
+)
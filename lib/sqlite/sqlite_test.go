@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hungtcs/monkey-lang/monkey"
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+func testEval(t *testing.T, input string, policy *monkey.Policy) (string, error) {
+	t.Helper()
+	program, err := syntax.NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("parser error: %s", err)
+	}
+	val, err := monkey.EvalThread(&monkey.Thread{Policy: policy}, program, monkey.NewEnv(nil))
+	if err != nil {
+		return "", err
+	}
+	return val.String(), nil
+}
+
+func TestSQLite(t *testing.T) {
+	setup := `
+let db = sqlite_open(":memory:");
+db["exec"]("create table users (name text, age integer, score real, note text)");
+db["exec"]("insert into users (name, age, score) values (?, ?, ?)", "alice", 30, 1.5);
+let r = db["exec"]("insert into users values (?, ?, ?, ?)", "bob", 17, 2, "hi");
+`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`r["rows_affected"]`, "1"},
+		{`r["last_insert_id"]`, "2"},
+		{`len(db["query"]("select * from users"))`, "2"},
+		{`db["query"]("select name from users where age > ?", 18)[0]["name"]`, "alice"},
+		{`db["query"]("select age from users where name = ?", "bob")[0]["age"]`, "17"},
+		{`db["query"]("select score from users where name = ?", "bob")[0]["score"]`, "2.0"},
+		{`db["query"]("select note from users where name = ?", "alice")[0]["note"]`, "null"},
+		{`db["query"]("select * from users where age > 100")`, "[]"},
+		{`let s = db["prepare"]("select name from users where age = ?"); s["query"](17)[0]["name"]`, "bob"},
+		{`let s = db["prepare"]("delete from users where age < ?"); s["exec"](20)["rows_affected"]`, "1"},
+		{`db["close"](); db["query"]("select 1")`, "database is closed"},
+		{`let s = db["prepare"]("select 1"); s["close"](); s["query"]()`, "statement is closed"},
+		{`db["query"]("select * from missing")`, "no such table"},
+		{`db["query"]("select ?", [1])`, "unsupported SQL parameter type array"},
+		{`db["query"](context(), "select name from users where age = ?", 17)[0]["name"]`, "bob"},
+		{`let ctx = context(); ctx["cancel"](); db["exec"](ctx, "delete from users")`, "context canceled"},
+		{`db["missing"]`, "null"},
+	}
+	for _, tt := range tests {
+		got, err := testEval(t, setup+tt.input, monkey.NewPolicy(CapSQLite))
+		if err != nil {
+			got = err.Error()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestSQLitePolicy(t *testing.T) {
+	_, err := testEval(t, `sqlite_open(":memory:")`, nil)
+	if err == nil || !strings.Contains(err.Error(), `requires the "sqlite" capability`) {
+		t.Errorf("expected policy error. got=%v", err)
+	}
+	if _, err := monkey.ParsePolicy("sqlite"); err != nil {
+		t.Errorf("sqlite is not a known capability: %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hungtcs/monkey-lang/monkey"
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// monkey -n [flags] program [file ...]
+//
+// 对输入的每一行执行一次 program，执行前绑定以下全局变量：
+//
+//	line   当前行（不含换行符）
+//	nr     当前行号，从 1 开始
+//	fields 按空白（或 -F 指定的分隔符）切分后的字段数组
+//
+// 所有行共享同一个全局作用域，因此可以用 let 累积状态，例如：
+//
+//	monkey -n -begin 'let sum = 0' -end 'print(sum)' 'let sum = sum + len(fields)'
+func linesCmd(args []string) int {
+	flags := flag.NewFlagSet("-n", flag.ExitOnError)
+	sep := flags.String("F", "", "field `separator` (default: runs of white space)")
+	begin := flags.String("begin", "", "`program` to run before the first line")
+	end := flags.String("end", "", "`program` to run after the last line")
+	printValues := flags.Bool("p", false, "print the value of program for each line unless it is null")
+	flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		usage()
+		return 2
+	}
+	var programs [3]*syntax.Program
+	for i, src := range []string{*begin, flags.Arg(0), *end} {
+		program, err := syntax.NewParser(src).Parse()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		programs[i] = program
+	}
+
+	var inputs []io.Reader
+	for _, filename := range flags.Args()[1:] {
+		f, err := os.Open(filename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		inputs = append(inputs, f)
+	}
+	if len(inputs) == 0 {
+		inputs = append(inputs, os.Stdin)
+	}
+
+	thread := &monkey.Thread{Name: "-n"}
+	env := monkey.NewEnv(nil)
+	if _, err := monkey.EvalThread(thread, programs[0], env); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	scanner := bufio.NewScanner(io.MultiReader(inputs...))
+	scanner.Buffer(nil, 1<<20)
+	for nr := 1; scanner.Scan(); nr++ {
+		line := scanner.Text()
+		env.Set("line", monkey.String(line))
+		env.Set("nr", monkey.Int(nr))
+		env.Set("fields", splitFields(line, *sep))
+
+		value, err := monkey.EvalThread(thread, programs[1], env)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "line %d: %s\n", nr, err)
+			return 1
+		}
+		if *printValues && value != monkey.Null {
+			if s, ok := value.(monkey.String); ok {
+				fmt.Println(string(s))
+			} else {
+				fmt.Println(value)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if _, err := monkey.EvalThread(thread, programs[2], env); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func splitFields(line, sep string) monkey.Value {
+	var parts []string
+	if sep == "" {
+		parts = strings.Fields(line)
+	} else {
+		parts = strings.Split(line, sep)
+	}
+	items := make([]monkey.Value, len(parts))
+	for i, part := range parts {
+		items[i] = monkey.String(part)
+	}
+	return monkey.NewArray(items)
+}
@@ -0,0 +1,360 @@
+// Package lint 在不执行程序的情况下检查 Monkey 源码中可能的错误：
+//
+//   - unused：未使用的 let 绑定，名字以 _ 开头的除外
+//   - undefined：使用未定义的标识符
+//   - unreachable：return 之后不可达的代码
+//   - arity：调用源码中定义的函数或 record 时实参个数明显不对
+//
+// 每个问题都带有产生它的检查的名称，项目可以在 monkey.toml 的 [lint] disable 中关闭某些检查。
+//
+// 检查按照求值器的作用域规则进行：只有函数创建新的作用域，代码块中的 let 定义在所在的函数中；
+// 函数体中可以使用外层作用域中稍后才定义的名字，因为函数可能在定义之后才被调用。
+// 没有 export 声明的模块的所有顶层绑定都是公开的，因此只有模块中有 export 声明时才报告未使用的顶层 let。
+package lint
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hungtcs/monkey-lang/monkey"
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// 检查的名称
+const (
+	Unused      = "unused"
+	Undefined   = "undefined"
+	Unreachable = "unreachable"
+	Arity       = "arity"
+)
+
+// Checks 是所有检查的名称
+var Checks = []string{Unused, Undefined, Unreachable, Arity}
+
+// Diagnostic 是检查发现的一个问题，Check 是产生它的检查的名称，语法错误的 Check 为空
+type Diagnostic struct {
+	syntax.Diagnostic
+	Check string
+}
+
+// String 在 syntax.Diagnostic 的描述之后加上检查的名称
+func (d Diagnostic) String() string {
+	if d.Check == "" {
+		return d.Diagnostic.String()
+	}
+	return d.Diagnostic.String() + " (" + d.Check + ")"
+}
+
+// Source 解析并检查名为 filename 的源码 src，按位置的顺序返回所有的问题。
+// 源码有语法错误时只返回语法错误，见 syntax.Check。
+// predeclared 是除内置函数以外预先定义的全局变量，例如宿主程序通过 monkey.WithGlobals 定义的变量。
+func Source(filename, src string, predeclared ...string) ([]Diagnostic, error) {
+	errs, err := syntax.Check(src, filename)
+	if err != nil || len(errs) > 0 {
+		diags := make([]Diagnostic, len(errs))
+		for i, d := range errs {
+			diags[i] = Diagnostic{Diagnostic: d}
+		}
+		return diags, err
+	}
+	program, err := syntax.NewFileParser(filename, src).Parse()
+	if err != nil {
+		return nil, err
+	}
+	return Program(program, predeclared...), nil
+}
+
+// Program 检查已经解析的程序 program，按位置的顺序返回所有的问题
+func Program(program *syntax.Program, predeclared ...string) []Diagnostic {
+	c := &checker{predeclared: make(map[string]bool)}
+	for _, name := range predeclared {
+		c.predeclared[name] = true
+	}
+	exports := false
+	for _, stmt := range program.Stmts {
+		if _, ok := stmt.(*syntax.ExportStmt); ok {
+			exports = true
+		}
+	}
+
+	c.open()
+	c.stmts(program.Stmts)
+	c.close(exports)
+
+	sort.SliceStable(c.diags, func(i, j int) bool {
+		a, b := c.diags[i].Start, c.diags[j].Start
+		return a.Line < b.Line || a.Line == b.Line && a.Col < b.Col
+	})
+	return c.diags
+}
+
+// binding 是一个作用域中的一个名字，同一个名字在作用域中可以被定义多次
+type binding struct {
+	lets   []*syntax.Identifier    // 定义该名字的 let 语句中的名字，用于报告未使用的绑定
+	decls  int                     // 已经执行的定义的次数
+	fn     *syntax.FunctionLiteral // 最近一次定义的值是函数字面量时为该函数
+	fields int                     // 最近一次定义的是 record 时为字段数，否则为 -1
+	macro  bool                    // 最近一次定义的值是宏，调用它时实参是语法树，不做检查
+	used   bool
+	public bool // 被导出的绑定，以及参数、函数名等不报告未使用的绑定
+}
+
+// scope 是一个函数或程序顶层的作用域
+type scope struct {
+	outer    *scope
+	bindings map[string]*binding
+	funcs    []*syntax.FunctionLiteral // 等到作用域中的语句都检查完之后再检查的函数
+}
+
+type checker struct {
+	predeclared map[string]bool
+	scope       *scope
+	diags       []Diagnostic
+}
+
+func (c *checker) report(n syntax.Node, check, format string, args ...any) {
+	start, end := n.Span()
+	c.diags = append(c.diags, Diagnostic{
+		Diagnostic: syntax.Diagnostic{Start: start, End: end, Msg: fmt.Sprintf(format, args...)},
+		Check:      check,
+	})
+}
+
+func (c *checker) open() {
+	c.scope = &scope{outer: c.scope, bindings: make(map[string]*binding)}
+}
+
+// 检查当前作用域中定义的函数的函数体，然后离开当前作用域，unused 为 true 时报告其中未使用的 let 绑定。
+// 函数体在作用域中所有的语句之后检查，因此函数体中可以使用外层作用域中稍后才定义的名字
+func (c *checker) close(unused bool) {
+	s := c.scope
+	for len(s.funcs) > 0 {
+		fn := s.funcs[0]
+		s.funcs = s.funcs[1:]
+		c.function(fn)
+	}
+	c.scope = s.outer
+	if !unused {
+		return
+	}
+	for name, b := range s.bindings {
+		if b.used || b.public || name[0] == '_' {
+			continue
+		}
+		for _, ident := range b.lets {
+			c.report(ident, Unused, "%s declared and not used", name)
+		}
+	}
+}
+
+// 在当前的作用域中定义 name
+func (c *checker) declare(name string) *binding {
+	b, ok := c.scope.bindings[name]
+	if !ok {
+		b = &binding{}
+		c.scope.bindings[name] = b
+	}
+	b.decls++
+	b.fn, b.fields, b.macro = nil, -1, false
+	return b
+}
+
+// 查找标识符引用的绑定，local 表示绑定在当前的作用域中
+func (c *checker) lookup(name string) (b *binding, local bool) {
+	for s := c.scope; s != nil; s = s.outer {
+		if b, ok := s.bindings[name]; ok {
+			return b, s == c.scope
+		}
+	}
+	return nil, false
+}
+
+// 检查语句列表，报告第一条不可达的语句
+func (c *checker) stmts(stmts []syntax.Stmt) {
+	reported := false
+	for i, stmt := range stmts {
+		c.stmt(stmt)
+		if !reported && i+1 < len(stmts) && terminates(stmt) {
+			c.report(stmts[i+1], Unreachable, "unreachable code")
+			reported = true
+		}
+	}
+}
+
+func (c *checker) stmt(stmt syntax.Stmt) {
+	switch stmt := stmt.(type) {
+	case *syntax.LetStmt:
+		switch value := stmt.Value.(type) {
+		case *syntax.FunctionLiteral:
+			// let f = fn() { ... } 的函数体中 f 指向函数自身
+			b := c.declare(stmt.Name.Value)
+			b.lets = append(b.lets, stmt.Name)
+			b.fn = value
+			c.expr(value)
+		case *syntax.MacroLiteral:
+			b := c.declare(stmt.Name.Value)
+			b.lets = append(b.lets, stmt.Name)
+			b.macro = true
+		default:
+			c.expr(value)
+			b := c.declare(stmt.Name.Value)
+			b.lets = append(b.lets, stmt.Name)
+		}
+	case *syntax.ExportStmt:
+		c.stmt(stmt.Let)
+		c.scope.bindings[stmt.Let.Name.Value].public = true
+	case *syntax.ReturnStmt:
+		c.expr(stmt.Value)
+	case *syntax.ExprStmt:
+		c.expr(stmt.Expr)
+	case *syntax.FunctionStmt:
+		b := c.declare(stmt.Fn.Name.Value)
+		b.fn, b.public = stmt.Fn, true
+		c.expr(stmt.Fn)
+	case *syntax.RecordStmt:
+		b := c.declare(stmt.Name.Value)
+		b.fields, b.public = len(stmt.Fields), true
+	case *syntax.ImportStmt:
+		c.expr(stmt.Import)
+		c.declare(stmt.Alias.Value).public = true
+	case *syntax.FromImportStmt:
+		c.expr(stmt.Path)
+		for _, name := range stmt.Names {
+			c.declare(name.Value).public = true
+		}
+	case *syntax.BlockStmt:
+		c.stmts(stmt.Stmts)
+	}
+}
+
+func (c *checker) expr(e syntax.Expr) {
+	switch e := e.(type) {
+	case *syntax.Identifier:
+		if b, _ := c.lookup(e.Value); b != nil {
+			b.used = true
+		} else if _, ok := monkey.Universe[e.Value]; !ok && !c.predeclared[e.Value] {
+			c.report(e, Undefined, "identifier not found: %s", e.Value)
+		}
+	case *syntax.PrefixExpr:
+		c.expr(e.Right)
+	case *syntax.InfixExpr:
+		c.expr(e.Left)
+		c.expr(e.Right)
+	case *syntax.AwaitExpr:
+		c.expr(e.Value)
+	case *syntax.SpreadExpr:
+		c.expr(e.Value)
+	case *syntax.IfExpr:
+		c.expr(e.Cond)
+		c.stmt(e.Consequence)
+		if e.Alternative != nil {
+			c.stmt(e.Alternative)
+		}
+	case *syntax.ArrayLiteral:
+		for _, item := range e.Items {
+			c.expr(item)
+		}
+	case *syntax.MapLiteral:
+		for _, pair := range e.Pairs {
+			c.expr(pair.Key)
+			c.expr(pair.Value)
+		}
+	case *syntax.IndexExpr:
+		c.expr(e.Left)
+		c.expr(e.Index)
+	case *syntax.SliceExpr:
+		c.expr(e.Left)
+		if e.Start != nil {
+			c.expr(e.Start)
+		}
+		if e.End != nil {
+			c.expr(e.End)
+		}
+	case *syntax.DotExpr:
+		c.expr(e.Left)
+	case *syntax.ImportExpr:
+		c.expr(e.Path)
+	case *syntax.FunctionLiteral:
+		c.scope.funcs = append(c.scope.funcs, e)
+	case *syntax.CallExpr:
+		c.call(e)
+	}
+}
+
+// 在新的作用域中检查函数体，具名函数的名字和参数定义在函数的作用域中
+func (c *checker) function(fn *syntax.FunctionLiteral) {
+	c.open()
+	if fn.Name != nil {
+		b := c.declare(fn.Name.Value)
+		b.fn, b.public = fn, true
+	}
+	for _, param := range fn.Params {
+		c.declare(param.Value).public = true
+	}
+	c.stmts(fn.Body.Stmts)
+	c.close(true)
+}
+
+func (c *checker) call(call *syntax.CallExpr) {
+	var fn *syntax.FunctionLiteral
+	fields := -1
+	switch callee := call.Function.(type) {
+	case *syntax.Identifier:
+		// quote 的参数和宏调用的实参是语法树，不会被求值
+		if callee.Value == "quote" {
+			return
+		}
+		c.expr(callee)
+		if b, local := c.lookup(callee.Value); b != nil {
+			if b.macro {
+				return
+			}
+			// 外层作用域中的名字可能在调用之后才被重新定义
+			if local || b.decls == 1 {
+				fn, fields = b.fn, b.fields
+			}
+		}
+	case *syntax.FunctionLiteral:
+		fn = callee
+		c.expr(callee)
+	default:
+		c.expr(callee)
+	}
+	for _, arg := range call.Args {
+		c.expr(arg)
+		// 展开的实参个数只有在运行时才知道
+		if _, ok := arg.(*syntax.SpreadExpr); ok {
+			fn, fields = nil, -1
+		}
+	}
+
+	n := len(call.Args)
+	name := call.Function.String()
+	switch {
+	case fn != nil && fn.Variadic && n < len(fn.Params)-1:
+		c.report(call, Arity, "wrong number of arguments to %s: want at least %d, got=%d", name, len(fn.Params)-1, n)
+	case fn != nil && !fn.Variadic && n != len(fn.Params):
+		c.report(call, Arity, "wrong number of arguments to %s: want=%d, got=%d", name, len(fn.Params), n)
+	case fields >= 0 && n != fields:
+		c.report(call, Arity, "wrong number of arguments to %s: want=%d, got=%d", name, fields, n)
+	}
+}
+
+// 判断语句执行之后是否一定已经 return
+func terminates(stmt syntax.Stmt) bool {
+	switch stmt := stmt.(type) {
+	case *syntax.ReturnStmt:
+		return true
+	case *syntax.BlockStmt:
+		for _, s := range stmt.Stmts {
+			if terminates(s) {
+				return true
+			}
+		}
+	case *syntax.ExprStmt:
+		if e, ok := stmt.Expr.(*syntax.IfExpr); ok && e.Alternative != nil {
+			return terminates(e.Consequence) && terminates(e.Alternative)
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected []string
+	}{
+		// 没有问题的程序
+		{"let x = 1; print(x + len([]))", nil},
+		{"let f = fn(n) { if (n < 2) { return n } f(n - 1) }; f(3)", nil},
+		{"let f = fn() { g() }; let g = fn() { 1 }; f()", nil},
+		{"fn even(n) { if (n == 0) { true } else { odd(n - 1) } }\nfn odd(n) { if (n == 0) { false } else { even(n - 1) } }\neven(4)", nil},
+		{"if (true) { let y = 1 }; y", nil},
+		{"record Point {x, y}; Point(1, 2)", nil},
+		{"let sum = fn(xs...) { len(xs) }; sum(); sum(1, 2, 3)", nil},
+		{"let f = fn(a, b) { a + b }; let args = [1, 2]; f(args...)", nil},
+		{"let unless = macro(c, a, b) { quote(if (!(unquote(c))) { unquote(a) } else { unquote(b) }) }; unless(nope, 1, 2)", nil},
+		{"let _ = fn() { let _unused = 1; 2 }", nil},
+		{"import(\"math\") as m; from \"strings\" import (upper); m", nil},
+		{"let x = 1; let f = fn() { print(x); let x = 2; x }; f()", nil},
+
+		// 未定义的标识符
+		{"print(y)", []string{"1:7 identifier not found: y"}},
+		{"let x = x + 1; x", []string{"1:9 identifier not found: x"}},
+		{"let f = fn() { z }; f()", []string{"1:16 identifier not found: z"}},
+		{"a.b", []string{"1:1 identifier not found: a"}},
+
+		// 未使用的 let
+		{"let f = fn() { let a = 1; 2 }; f()", []string{"1:20 a declared and not used"}},
+		{"let f = fn(x) { if (x) { let t = 1 } x }; f(1)", []string{"1:30 t declared and not used"}},
+		{"export let a = 1; let b = 2", []string{"1:23 b declared and not used"}},
+		{"let b = 2", nil},
+
+		// 不可达的代码
+		{"let f = fn() { return 1; print(2); 3 }; f()", []string{"1:26 unreachable code"}},
+		{"let f = fn(x) { if (x) { return 1 } else { return 2 } x }; f(1)", []string{"1:55 unreachable code"}},
+		{"let f = fn(x) { if (x) { return 1 } x }; f(1)", nil},
+
+		// 实参个数
+		{"let f = fn(a, b) { a + b }; f(1)", []string{"1:29 wrong number of arguments to f: want=2, got=1"}},
+		{"fn f(a) { a }; f(1, 2)", []string{"1:16 wrong number of arguments to f: want=1, got=2"}},
+		{"let f = fn(a, xs...) { xs }; f()", []string{"1:30 wrong number of arguments to f: want at least 1, got=0"}},
+		{"record P {x}; P()", []string{"1:15 wrong number of arguments to P: want=1, got=0"}},
+		{"fn(a) { a }(1, 2)", []string{"1:1 wrong number of arguments to fn(a) {a}: want=1, got=2"}},
+		{"let f = fn(a) { a }; let g = fn() { f(1, 2) }; let f = fn(a, b) { a }; g()", nil},
+		{"let f = fn(a) { a }; f(1); let f = fn(a, b) { a }; f(1)", []string{"1:52 wrong number of arguments to f: want=2, got=1"}},
+	}
+	for _, tt := range tests {
+		diags, err := Source("", tt.src)
+		if err != nil {
+			t.Errorf("%q: %v", tt.src, err)
+			continue
+		}
+		var got []string
+		for _, d := range diags {
+			got = append(got, fmt.Sprintf("%d:%d %s", d.Start.Line, d.Start.Col, d.Msg))
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%q: expected %q, got %q", tt.src, tt.expected, got)
+		}
+	}
+}
+
+func TestSourcePredeclared(t *testing.T) {
+	diags, err := Source("main.mky", "user + 1", "user")
+	if err != nil || len(diags) != 0 {
+		t.Errorf("expected no diagnostics, got %v %v", diags, err)
+	}
+
+	// 有语法错误时只返回语法错误
+	diags, err = Source("main.mky", "let = 1; undefined")
+	if err != nil || len(diags) != 1 || diags[0].Start.String() != "main.mky:1:5" || diags[0].Check != "" {
+		t.Errorf("expected a syntax error, got %v %v", diags, err)
+	}
+}
+
+func TestSourceChecks(t *testing.T) {
+	diags, err := Source("main.mky", "export let a = 1; let b = c; let f = fn(x) { return x; 1 }; f()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range diags {
+		got = append(got, d.String())
+	}
+	expected := []string{
+		"main.mky:1:23 b declared and not used (unused)",
+		"main.mky:1:27 identifier not found: c (undefined)",
+		"main.mky:1:56 unreachable code (unreachable)",
+		"main.mky:1:61 wrong number of arguments to f: want=1, got=0 (arity)",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+var (
+	separator  = regexp.MustCompile(`(?m)^---$`)
+	annotation = regexp.MustCompile(`(?m)###.*$`)
+)
+
+// 一致性测试的语料中没有运行时错误的段不应该有任何问题
+func TestTestdata(t *testing.T) {
+	files, _ := filepath.Glob("../monkey/testdata/*.mky")
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, chunk := range separator.Split(string(data), -1) {
+			if strings.Contains(chunk, "### error") {
+				continue
+			}
+			diags, err := Source(file, annotation.ReplaceAllString(chunk, ""))
+			if err != nil || len(diags) > 0 {
+				t.Errorf("%s: unexpected diagnostics %v %v\n%s", file, diags, err, chunk)
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Package literate 从 Markdown 文档中提取 Monkey 代码块，
+// 用于将文档作为可执行的教程或测试。
+package literate
+
+import (
+	"strings"
+)
+
+// Block 是文档中的一个 ```monkey 代码块
+type Block struct {
+	Code string // 代码块内容
+	Line int    // 代码块内容第一行的行号，从 1 开始
+
+	Start, End int // 整个代码块（包括围栏）在文档中的字节偏移
+
+	// 紧随其后的 ```output 代码块，用于核对执行结果；没有时 Expected 为 nil
+	Expected         *string
+	OutStart, OutEnd int
+}
+
+type fence struct {
+	lang       string
+	body       string
+	line       int
+	start, end int
+	bodyStart  int
+}
+
+// Parse 返回 src 中所有语言为 monkey 的围栏代码块
+func Parse(src string) []*Block {
+	var blocks []*Block
+	fences := parseFences(src)
+	for i, f := range fences {
+		if f.lang != "monkey" {
+			continue
+		}
+		block := &Block{Code: f.body, Line: f.line, Start: f.start, End: f.end}
+		// output 代码块只能与代码块之间隔着空白
+		if i+1 < len(fences) && fences[i+1].lang == "output" &&
+			strings.TrimSpace(src[f.end:fences[i+1].start]) == "" {
+			out := fences[i+1]
+			block.Expected = &out.body
+			block.OutStart, block.OutEnd = out.start, out.end
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+func parseFences(src string) []fence {
+	var fences []fence
+	var cur *fence
+	offset := 0
+	for lineno := 1; offset < len(src); lineno++ {
+		end := strings.IndexByte(src[offset:], '\n')
+		if end < 0 {
+			end = len(src)
+		} else {
+			end += offset + 1
+		}
+		line := strings.TrimSpace(src[offset:end])
+
+		if cur == nil {
+			if strings.HasPrefix(line, "```") {
+				cur = &fence{
+					lang:      strings.TrimSpace(strings.TrimPrefix(line, "```")),
+					line:      lineno + 1,
+					start:     offset,
+					bodyStart: end,
+				}
+			}
+		} else if line == "```" {
+			cur.body = src[cur.bodyStart:offset]
+			cur.end = end
+			fences = append(fences, *cur)
+			cur = nil
+		}
+		offset = end
+	}
+	return fences
+}
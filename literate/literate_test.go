@@ -0,0 +1,53 @@
+package literate
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	input := "# Title\n" +
+		"```monkey\n" +
+		"let x = 1;\n" +
+		"x\n" +
+		"```\n" +
+		"\n" +
+		"```output\n" +
+		"1\n" +
+		"```\n" +
+		"```go\n" +
+		"package main\n" +
+		"```\n" +
+		"```monkey\n" +
+		"x + 1\n" +
+		"```\n" +
+		"text\n" +
+		"```output\n" +
+		"2\n" +
+		"```\n"
+
+	blocks := Parse(input)
+	if len(blocks) != 2 {
+		t.Fatalf("wrong number of blocks. got=%d", len(blocks))
+	}
+
+	first := blocks[0]
+	if first.Code != "let x = 1;\nx\n" {
+		t.Errorf("first.Code wrong. got=%q", first.Code)
+	}
+	if first.Line != 3 {
+		t.Errorf("first.Line wrong. got=%d", first.Line)
+	}
+	if first.Expected == nil || *first.Expected != "1\n" {
+		t.Errorf("first.Expected wrong. got=%v", first.Expected)
+	}
+	if input[first.Start:first.End] != "```monkey\nlet x = 1;\nx\n```\n" {
+		t.Errorf("first block range wrong. got=%q", input[first.Start:first.End])
+	}
+
+	// output 代码块与代码块之间隔着文本，不属于该代码块
+	second := blocks[1]
+	if second.Code != "x + 1\n" || second.Line != 14 {
+		t.Errorf("second block wrong. got=%q at line %d", second.Code, second.Line)
+	}
+	if second.Expected != nil {
+		t.Errorf("second.Expected should be nil. got=%q", *second.Expected)
+	}
+}
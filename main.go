@@ -5,12 +5,14 @@ import (
 	"os"
 	"os/user"
 
+	_ "github.com/hungtcs/monkey-lang/lib/kv"
+	_ "github.com/hungtcs/monkey-lang/lib/sqlite"
 	"github.com/hungtcs/monkey-lang/monkey"
 	"github.com/hungtcs/monkey-lang/repl"
 	"github.com/hungtcs/monkey-lang/syntax"
 )
 
-func startRepl() {
+func startRepl(language syntax.LanguageOptions) {
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
@@ -18,38 +20,73 @@ func startRepl() {
 	fmt.Printf("Hello %s! This is the Monkey programming language!\n", user.Username)
 	fmt.Printf("Feel free to type in commands\n")
 
-	if err := repl.Start(); err != nil {
+	if err := repl.StartThread(&monkey.Thread{Language: language}, monkey.NewEnv(nil)); err != nil {
 		panic(err)
 	}
 }
 
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: monkey [file]")
+	fmt.Fprintln(os.Stderr, "       monkey -n [flags] program [file ...]")
+	fmt.Fprintln(os.Stderr, "       monkey repl [flags] [file]")
+	fmt.Fprintln(os.Stderr, "       monkey init [flags] [dir]")
+	fmt.Fprintln(os.Stderr, "       monkey run [flags] [file | dir | -]")
+	fmt.Fprintln(os.Stderr, "       monkey run-md [flags] file.md")
+	fmt.Fprintln(os.Stderr, "       monkey test [flags] [path ...]")
+	fmt.Fprintln(os.Stderr, "       monkey fmt [-w] [-d] [path ...]")
+	fmt.Fprintln(os.Stderr, "       monkey check [path ...]")
+	fmt.Fprintln(os.Stderr, "       monkey get [module ...]")
+	fmt.Fprintln(os.Stderr, "       monkey debug [-break lines] file")
+	fmt.Fprintln(os.Stderr, "       monkey dap")
+	fmt.Fprintln(os.Stderr, "       monkey introspect-ops")
+	fmt.Fprintln(os.Stderr, "       monkey doc [-html] [module ...]")
+	fmt.Fprintln(os.Stderr, "       monkey --version")
+}
+
 func main() {
 	var args = os.Args[1:]
 	// start repl
 	if len(args) < 1 {
-		startRepl()
+		startRepl(syntax.LanguageOptions{})
 		return
 	}
+
+	switch args[0] {
+	case "-n":
+		os.Exit(linesCmd(args[1:]))
+	case "init":
+		os.Exit(initCmd(args[1:]))
+	case "repl":
+		os.Exit(replCmd(args[1:]))
+	case "run":
+		os.Exit(runCmd(args[1:]))
+	case "run-md":
+		os.Exit(runMarkdownCmd(args[1:]))
+	case "test":
+		os.Exit(testCmd(args[1:]))
+	case "fmt":
+		os.Exit(fmtCmd(args[1:]))
+	case "check":
+		os.Exit(checkCmd(args[1:]))
+	case "get":
+		os.Exit(getCmd(args[1:]))
+	case "debug":
+		os.Exit(debugCmd(args[1:]))
+	case "dap":
+		os.Exit(dapCmd(args[1:]))
+	case "introspect-ops":
+		os.Exit(introspectOpsCmd(args[1:]))
+	case "doc":
+		os.Exit(docCmd(args[1:]))
+	case "-version", "--version":
+		fmt.Println(monkey.ReadBuildInfo())
+		return
+	}
+
 	// eval a file
 	if len(args) == 1 {
-		var filename = args[0]
-		data, err := os.ReadFile(filename)
-		if err != nil {
-			panic(err)
-		}
-		parser := syntax.NewParser(string(data))
-		program, err := parser.Parse()
-		if err != nil {
-			fmt.Println(err)
-			return
-		}
-
-		value, err := monkey.Eval(program, monkey.NewEnv(nil))
-		if err != nil {
-			panic(err)
-		}
-		fmt.Println(value)
-		return
+		os.Exit(runCmd(args))
 	}
-	fmt.Println("usage: monkey [file]")
+	usage()
+	os.Exit(2)
 }
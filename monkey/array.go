@@ -0,0 +1,179 @@
+package monkey
+
+import "fmt"
+
+// 数组对脚本来说是不可变的，下面的函数都不修改参数，而是返回修改后的新数组。
+// 因此数组可以在 with_group 的任务和 async 函数之间安全地共享：
+//
+//	let a = [1, 2];
+//	let b = push(a, 3);  // a 仍然是 [1, 2]，b 是 [1, 2, 3]
+func init() {
+	Universe["push"] = NewBuiltinFunction("push", push)
+	Universe["pop"] = NewBuiltinFunction("pop", pop)
+	Universe["insert"] = NewBuiltinFunction("insert", insert)
+	Universe["remove"] = NewBuiltinFunction("remove", remove)
+	Universe["map"] = NewBuiltinFunction("map", mapArray)
+	Universe["filter"] = NewBuiltinFunction("filter", filter)
+	Universe["reduce"] = NewBuiltinFunction("reduce", reduce)
+}
+
+// push(arr, x...) 返回在 arr 末尾追加 x 之后的新数组
+func push(thread *Thread, args ...Value) (Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want at least 1", len(args))
+	}
+	arr, ok := args[0].(*Array)
+	if !ok {
+		return nil, fmt.Errorf("argument to `push` must be array, got %s", args[0].Type())
+	}
+	result := makeArray(len(arr.items) + len(args) - 1)
+	n := copy(result.items, arr.items)
+	copy(result.items[n:], args[1:])
+	return result, nil
+}
+
+// pop(arr) 返回去掉最后一个元素的新数组，最后一个元素可以通过 arr[-1] 得到
+func pop(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	arr, ok := args[0].(*Array)
+	if !ok {
+		return nil, fmt.Errorf("argument to `pop` must be array, got %s", args[0].Type())
+	}
+	if len(arr.items) == 0 {
+		return nil, fmt.Errorf("pop from empty array")
+	}
+	return arr.Slice(0, len(arr.items)-1), nil
+}
+
+// insert(arr, i, x) 返回在下标 i 之前插入 x 的新数组，i 等于 len(arr) 时追加到末尾
+func insert(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 3 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=3", len(args))
+	}
+	arr, ok := args[0].(*Array)
+	if !ok {
+		return nil, fmt.Errorf("argument to `insert` must be array, got %s", args[0].Type())
+	}
+	i, err := arrayIndex("insert", arr, args[1], len(arr.items)+1)
+	if err != nil {
+		return nil, err
+	}
+	result := makeArray(len(arr.items) + 1)
+	copy(result.items, arr.items[:i])
+	result.items[i] = args[2]
+	copy(result.items[i+1:], arr.items[i:])
+	return result, nil
+}
+
+// remove(arr, i) 返回去掉下标 i 处元素的新数组
+func remove(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	arr, ok := args[0].(*Array)
+	if !ok {
+		return nil, fmt.Errorf("argument to `remove` must be array, got %s", args[0].Type())
+	}
+	i, err := arrayIndex("remove", arr, args[1], len(arr.items))
+	if err != nil {
+		return nil, err
+	}
+	result := makeArray(len(arr.items) - 1)
+	copy(result.items, arr.items[:i])
+	copy(result.items[i:], arr.items[i+1:])
+	return result, nil
+}
+
+// map(arr, f) 返回对 arr 的每个元素调用 f(x) 的结果组成的新数组：
+//
+//	map([1, 2, 3], fn(x) { x * 2 })  // [2, 4, 6]
+func mapArray(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	arr, err := callbackArgs("map", args[0], args[1])
+	if err != nil {
+		return nil, err
+	}
+	result := makeArray(len(arr.items))
+	for i, item := range arr.items {
+		if result.items[i], err = Call(thread, args[1], item); err != nil {
+			return nil, fmt.Errorf("map: item %d: %w", i, err)
+		}
+	}
+	return result, nil
+}
+
+// filter(arr, f) 返回 arr 中 f(x) 为真的元素组成的新数组，元素的顺序不变
+func filter(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	arr, err := callbackArgs("filter", args[0], args[1])
+	if err != nil {
+		return nil, err
+	}
+	var items []Value
+	for i, item := range arr.items {
+		keep, err := Call(thread, args[1], item)
+		if err != nil {
+			return nil, fmt.Errorf("filter: item %d: %w", i, err)
+		}
+		if keep.Truth() {
+			items = append(items, item)
+		}
+	}
+	return NewArray(items), nil
+}
+
+// reduce(arr, init, f) 从 init 开始依次计算 acc = f(acc, x)，返回最后的 acc：
+//
+//	reduce([1, 2, 3], 0, fn(acc, x) { acc + x })  // 6
+func reduce(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 3 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=3", len(args))
+	}
+	arr, err := callbackArgs("reduce", args[0], args[2])
+	if err != nil {
+		return nil, err
+	}
+	acc := args[1]
+	for i, item := range arr.items {
+		if acc, err = Call(thread, args[2], acc, item); err != nil {
+			return nil, fmt.Errorf("reduce: item %d: %w", i, err)
+		}
+	}
+	return acc, nil
+}
+
+// 检查高阶函数 fn 的数组参数和回调函数参数
+func callbackArgs(fn string, arr, f Value) (*Array, error) {
+	a, ok := arr.(*Array)
+	if !ok {
+		return nil, fmt.Errorf("argument to `%s` must be array, got %s", fn, arr.Type())
+	}
+	switch f.(type) {
+	case *Function, Callable:
+	default:
+		return nil, fmt.Errorf("callback argument to `%s` must be function, got %s", fn, f.Type())
+	}
+	return a, nil
+}
+
+// 将 fn 的下标参数 v 转换为 [0, n) 之间的下标，负数与索引一样从 arr 的末尾开始计算
+func arrayIndex(fn string, arr *Array, v Value, n int) (int, error) {
+	i, ok := v.(Int)
+	if !ok {
+		return 0, fmt.Errorf("index argument to `%s` must be int, got %s", fn, v.Type())
+	}
+	index := int(i)
+	if index < 0 {
+		index += len(arr.items)
+	}
+	if index < 0 || index >= n {
+		return 0, fmt.Errorf("index out of range: %d with length %d", i, len(arr.items))
+	}
+	return index, nil
+}
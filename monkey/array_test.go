@@ -0,0 +1,121 @@
+package monkey
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestArrayBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`push([1, 2], 3)`, "[1, 2, 3]"},
+		{`push([], 1, 2)`, "[1, 2]"},
+		{`push([1])`, "[1]"},
+		{`let a = [1, 2]; let b = push(a, 3); a`, "[1, 2]"},
+		{`pop([1, 2, 3])`, "[1, 2]"},
+		{`let a = [1, 2]; pop(a); a`, "[1, 2]"},
+		{`insert([1, 3], 1, 2)`, "[1, 2, 3]"},
+		{`insert([1, 2], 2, 3)`, "[1, 2, 3]"},
+		{`insert([2, 3], 0, 1)`, "[1, 2, 3]"},
+		{`insert([1, 3], -1, 2)`, "[1, 2, 3]"},
+		{`remove([1, 2, 3], 1)`, "[1, 3]"},
+		{`remove([1, 2, 3], -1)`, "[1, 2]"},
+		{`let a = [1, 2, 3]; remove(a, 0); a`, "[1, 2, 3]"},
+		{`pop([])`, "pop from empty array"},
+		{`insert([1], 3, 2)`, "index out of range: 3 with length 1"},
+		{`remove([1], 1)`, "index out of range: 1 with length 1"},
+		{`remove([1], "a")`, "index argument to `remove` must be int, got string"},
+		{`push("a", 1)`, "argument to `push` must be array, got string"},
+		{`pop()`, "wrong number of arguments. got=0, want=1"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalThread(t, tt.input)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestHigherOrderBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`map([1, 2, 3], fn(x) { x * 2 })`, "[2, 4, 6]"},
+		{`map([], fn(x) { x })`, "[]"},
+		{`map(["a", "bc"], len)`, "[1, 2]"},
+		{`filter([1, 2, 3, 4], fn(x) { x % 2 == 0 })`, "[2, 4]"},
+		{`filter([1, 2], fn(x) { false })`, "[]"},
+		{`reduce([1, 2, 3], 0, fn(acc, x) { acc + x })`, "6"},
+		{`reduce([], "init", fn(acc, x) { acc + x })`, "init"},
+		{`reduce(["a", "b"], "", fn(acc, x) { x + acc })`, "ba"},
+		{`let n = 10; map([1], fn(x) { x + n })`, "[11]"},
+		{`reduce(map(filter([1, 2, 3, 4], fn(x) { x > 1 }), fn(x) { x * x }), 0, fn(a, b) { a + b })`, "29"},
+		{`map([1, 0], fn(x) { 1 / x })`, "map: item 1: "},
+		{`map([1, 0], fn(x) { 1 / x })`, "division by zero: 1 / 0"},
+		{`fn bad(x) { x + y }; filter([1], bad)`, "identifier not found: y"},
+		{`map([1], fn(a, b) { a })`, "wrong number of arguments: want=2, got=1"},
+		{`map({}, len)`, "argument to `map` must be array, got map"},
+		{`filter([1], 1)`, "callback argument to `filter` must be function, got int"},
+		{`reduce([1], fn(a, b) { a })`, "wrong number of arguments. got=2, want=3"},
+		{`try(fn() { map([0], fn(x) { 1 / x }) })["ok"]`, "false"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalThread(t, tt.input)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+// 保存 makeArray 的结果，避免数组被分配在栈上
+var arraySink *Array
+
+func TestSmallArray(t *testing.T) {
+	for n := 0; n <= smallArraySize+1; n++ {
+		allocs := testing.AllocsPerRun(100, func() { arraySink = makeArray(n) })
+		want := 1.0
+		if n > smallArraySize {
+			want = 2
+		}
+		if allocs != want {
+			t.Errorf("makeArray(%d): expected %v allocations. got=%v", n, want, allocs)
+		}
+	}
+
+	// 内联存储的容量等于长度，push 必须复制而不是写入原数组的存储
+	a := makeArray(2)
+	a.items[0], a.items[1] = Int(1), Int(2)
+	b, _ := push(nil, a, Int(3))
+	c, _ := push(nil, a, Int(4))
+	if a.String() != "[1, 2]" || b.String() != "[1, 2, 3]" || c.String() != "[1, 2, 4]" {
+		t.Errorf("push shared storage. got a=%s, b=%s, c=%s", a, b, c)
+	}
+}
+
+func BenchmarkArrayLiteral(b *testing.B) {
+	for _, size := range []int{2, smallArraySize, 4 * smallArraySize} {
+		items := make([]string, size)
+		for i := range items {
+			items[i] = fmt.Sprint(i)
+		}
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			benchmarkEval(b, fmt.Sprintf("[%s][0]", strings.Join(items, ", ")))
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package monkey
+
+import "fmt"
+
+// Future 是调用 async 函数得到的值，表示一个正在另一个 goroutine 中执行的调用。
+// 通过 await 等待它完成并得到结果，调用出错时 await 返回同样的错误。
+//
+//	let fetch = async fn(url) { http_get(url) };
+//	let a = fetch("https://a.example");
+//	let b = fetch("https://b.example");  // 两个请求并发执行
+//	await [a, b];
+type Future struct {
+	name  string
+	done  chan struct{}
+	value Value // done 关闭后有效
+	err   error
+}
+
+// 在新的 goroutine 中调用 async 函数 fn，立即返回它的 Future
+func startAsync(thread *Thread, frame Frame, fn *Function, args []Value) *Future {
+	future := &Future{name: frame.Name, done: make(chan struct{})}
+	// 与 with_group 的任务一样，使用自己的线程并共享调用者的配置，调用者被取消时它也被取消
+	task := &Thread{
+		Name:     thread.Name,
+		Provider: thread.Provider,
+		Print:    thread.Print,
+		Policy:   thread.Policy,
+		Importer: thread.importer(),
+		Hooks:    thread.Hooks,
+		Language: thread.Language,
+		cancel:   thread.cancel,
+		budget:   thread.budget,
+		file:     thread.file,
+	}
+	body := *fn
+	body.Async = false
+	go func() {
+		defer close(future.done)
+		defer recoverPanic(&future.err)
+		future.value, future.err = call(task, frame, &body, args)
+	}()
+	return future
+}
+
+// await 等待 future 完成。数组中的每个 future 都会被等待，其他值原样返回。
+func await(v Value) (Value, error) {
+	switch v := v.(type) {
+	case *Future:
+		<-v.done
+		return v.value, v.err
+	case *Array:
+		items := make([]Value, len(v.items))
+		for i, item := range v.items {
+			var err error
+			if items[i], err = await(item); err != nil {
+				return nil, err
+			}
+		}
+		return NewArray(items), nil
+	}
+	return v, nil
+}
+
+// Hash implements Value.
+func (f *Future) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: future")
+}
+
+// String implements Value.
+func (f *Future) String() string {
+	return fmt.Sprintf("<future %s>", f.name)
+}
+
+// Truth implements Value.
+func (f *Future) Truth() bool {
+	return true
+}
+
+// Type implements Value.
+func (f *Future) Type() string {
+	return "future"
+}
+
+var _ Value = (*Future)(nil)
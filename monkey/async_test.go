@@ -0,0 +1,38 @@
+package monkey
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAsyncAwait(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let f = async fn(x) { x * 2 }; await f(21)`, "42"},
+		{`let f = async fn(x) { x * 2 }; f(1)`, "<future f>"},
+		{`let f = async fn(x) { x * 2 }; await [f(1), f(2), 3]`, "[2, 4, 3]"},
+		{`let f = async fn(x) { return x + 1; 0 }; await f(1)`, "2"},
+		{`let f = async fn() { x }; let r = f(); 1`, "1"},
+		{`let f = async fn() { x }; await f()`, "identifier not found: x"},
+		{`let f = async fn(x) { x }; f()`, "wrong number of arguments"},
+		{`let n = 10; let f = async fn() { n + 1 }; await f()`, "11"},
+		{`let inner = async fn(x) { x + 1 }; let outer = async fn(x) { await inner(x) * 2 }; await outer(1)`, "4"},
+		{`await 5`, "5"},
+		{`async fn(x) { x }`, "async fn(x) {x}"},
+		{`try(fn() { await (async fn() { y })() })["error"]["message"]`, "identifier not found: y"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalPolicy(t, tt.input, nil)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package monkey
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+func init() {
+	Universe["atomic_int"] = NewBuiltinFunction("atomic_int", atomicInt)
+	Universe["mutex"] = NewBuiltinFunction("mutex", mutex)
+}
+
+// atomic_int() 或 atomic_int(n)
+//
+// 返回一个可以在 with_group 的多个任务之间共享的整数，初始值为 n（默认为 0）：
+//
+//	let count = atomic_int();
+//	count["add"](1);    // 返回相加后的值
+//	count["store"](5);
+//	count["load"]();    // 5
+func atomicInt(thread *Thread, args ...Value) (Value, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=0 or 1", len(args))
+	}
+	a := new(AtomicInt)
+	if len(args) == 1 {
+		n, ok := args[0].(Int)
+		if !ok {
+			return nil, fmt.Errorf("argument to `atomic_int` must be int, got %s", args[0].Type())
+		}
+		a.n.Store(int64(n))
+	}
+	return a, nil
+}
+
+// AtomicInt 是 atomic_int() 返回的整数
+type AtomicInt struct {
+	n atomic.Int64
+}
+
+// Get implements Mapping.
+func (a *AtomicInt) Get(key Value) (_ Value, _ bool, err error) {
+	name, _ := key.(String)
+	var fn func(thread *Thread, args ...Value) (Value, error)
+	switch name {
+	case "add":
+		fn = func(thread *Thread, args ...Value) (Value, error) {
+			delta, err := intArg("add", args)
+			if err != nil {
+				return nil, err
+			}
+			return Int(a.n.Add(int64(delta))), nil
+		}
+	case "load":
+		fn = func(thread *Thread, args ...Value) (Value, error) {
+			if len(args) != 0 {
+				return nil, fmt.Errorf("wrong number of arguments. got=%d, want=0", len(args))
+			}
+			return Int(a.n.Load()), nil
+		}
+	case "store":
+		fn = func(thread *Thread, args ...Value) (Value, error) {
+			n, err := intArg("store", args)
+			if err != nil {
+				return nil, err
+			}
+			a.n.Store(int64(n))
+			return Null, nil
+		}
+	default:
+		return Null, false, nil
+	}
+	return NewBuiltinFunction(string(name), fn), true, nil
+}
+
+func intArg(fn string, args []Value) (Int, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	n, ok := args[0].(Int)
+	if !ok {
+		return 0, fmt.Errorf("argument to `%s` must be int, got %s", fn, args[0].Type())
+	}
+	return n, nil
+}
+
+// Hash implements Value.
+func (a *AtomicInt) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: atomic_int")
+}
+
+// String implements Value.
+func (a *AtomicInt) String() string {
+	return fmt.Sprintf("atomic_int(%d)", a.n.Load())
+}
+
+// Truth implements Value.
+func (a *AtomicInt) Truth() bool {
+	return true
+}
+
+// Type implements Value.
+func (a *AtomicInt) Type() string {
+	return "atomic_int"
+}
+
+// mutex()
+//
+// 返回一个互斥锁，用于保护多个任务共享的状态：
+//
+//	let mu = mutex();
+//	mu["with_lock"](fn() { ... });  // 持有锁调用函数，出错时也会释放锁
+//	mu["lock"](); ...; mu["unlock"]();
+func mutex(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=0", len(args))
+	}
+	return &Mutex{ch: make(chan struct{}, 1)}, nil
+}
+
+// Mutex 是 mutex() 返回的互斥锁。
+// 使用带缓冲的 channel 实现，以便对未加锁的互斥锁解锁时返回错误而不是使程序崩溃。
+type Mutex struct {
+	ch chan struct{}
+}
+
+func (m *Mutex) lock() {
+	m.ch <- struct{}{}
+}
+
+func (m *Mutex) unlock() error {
+	select {
+	case <-m.ch:
+		return nil
+	default:
+		return fmt.Errorf("unlock of unlocked mutex")
+	}
+}
+
+// Get implements Mapping.
+func (m *Mutex) Get(key Value) (_ Value, _ bool, err error) {
+	name, _ := key.(String)
+	var fn func(thread *Thread, args ...Value) (Value, error)
+	switch name {
+	case "lock":
+		fn = func(thread *Thread, args ...Value) (Value, error) {
+			if len(args) != 0 {
+				return nil, fmt.Errorf("wrong number of arguments. got=%d, want=0", len(args))
+			}
+			m.lock()
+			return Null, nil
+		}
+	case "unlock":
+		fn = func(thread *Thread, args ...Value) (Value, error) {
+			if len(args) != 0 {
+				return nil, fmt.Errorf("wrong number of arguments. got=%d, want=0", len(args))
+			}
+			return Null, m.unlock()
+		}
+	case "with_lock":
+		fn = func(thread *Thread, args ...Value) (Value, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			m.lock()
+			defer m.unlock()
+			return Call(thread, args[0])
+		}
+	default:
+		return Null, false, nil
+	}
+	return NewBuiltinFunction(string(name), fn), true, nil
+}
+
+// Hash implements Value.
+func (m *Mutex) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: mutex")
+}
+
+// String implements Value.
+func (m *Mutex) String() string {
+	return "<mutex>"
+}
+
+// Truth implements Value.
+func (m *Mutex) Truth() bool {
+	return true
+}
+
+// Type implements Value.
+func (m *Mutex) Type() string {
+	return "mutex"
+}
+
+var (
+	_ Mapping = (*AtomicInt)(nil)
+	_ Mapping = (*Mutex)(nil)
+)
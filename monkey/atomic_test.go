@@ -0,0 +1,54 @@
+package monkey
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAtomicAndMutex(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let n = atomic_int(); n["add"](2); n["add"](3)`, "5"},
+		{`let n = atomic_int(7); n["load"]()`, "7"},
+		{`let n = atomic_int(); n["store"](4); n`, "atomic_int(4)"},
+		{`let n = atomic_int();
+		  let inc = fn() { n["add"](1) };
+		  with_group(fn(g) { g["spawn"](inc); g["spawn"](inc); g["spawn"](inc); g["spawn"](inc) });
+		  n["load"]()`, "4"},
+		{`atomic_int("1")`, "must be int"},
+		{`atomic_int()["add"](1.5)`, "argument to `add` must be int"},
+		{`let mu = mutex(); mu["with_lock"](fn() { 42 })`, "42"},
+		{`let mu = mutex(); mu["lock"](); mu["unlock"](); mu["lock"](); 1`, "1"},
+		{`mutex()["unlock"]()`, "unlock of unlocked mutex"},
+		{`let mu = mutex(); mu["with_lock"](fn() { x })`, "identifier not found: x"},
+		{`let w = worker("1"); w["send"](atomic_int())`, "cannot pass atomic_int between workers"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalPolicy(t, tt.input, nil)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	// 函数出错时 with_lock 也会释放锁
+	mu := &Mutex{ch: make(chan struct{}, 1)}
+	withLock, _, _ := mu.Get(String("with_lock"))
+	fail := NewBuiltinFunction("fail", func(thread *Thread, args ...Value) (Value, error) {
+		return nil, fmt.Errorf("fail")
+	})
+	if _, err := Call(&Thread{}, withLock, fail); err == nil {
+		t.Fatalf("expected error from with_lock")
+	}
+	if err := mu.unlock(); err == nil {
+		t.Errorf("mutex still locked after with_lock failed")
+	}
+}
@@ -0,0 +1,508 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// 宿主程序与脚本之间传递 Go 的值，例如：
+//
+//	env.Set("config", monkey.MustFromGo(cfg))
+//	env.Set("lookup", monkey.MustFromGo(func(name string) (int, error) { ... }))
+//	result, err := monkey.CallGo(thread, handler, "GET", 42)
+
+// FromGo 将 Go 的值转换为 Monkey 的值：
+//
+//	nil、空指针       null
+//	bool              bool
+//	整数              int，超出 int64 范围的无符号整数会报错
+//	浮点数            float
+//	string            string
+//	切片、数组        array
+//	map               map，按键排序以保证顺序确定
+//	结构体            map，键为导出字段的名称，可以用 `monkey:"name"` 标签改名，"-" 表示忽略
+//	指针、接口        指向的值
+//	函数              内置函数，参数和返回值的转换见 NewGoFunction，需要指定名称时使用 NewGoFunction
+//
+// 已经是 Value 的值原样返回。结构体和 map 在转换时被复制，脚本不能通过它们修改 Go 的值。
+func FromGo(v any) (Value, error) {
+	return fromGo(reflect.ValueOf(v), 0, make(goRefs))
+}
+
+// MustFromGo 与 FromGo 相同，但转换失败时 panic，用于在初始化时注入确定可以转换的值
+func MustFromGo(v any) Value {
+	val, err := FromGo(v)
+	if err != nil {
+		panic(err)
+	}
+	return val
+}
+
+// ToGo 将 Monkey 的值转换为 Go 的值：null 为 nil，bool、int、float、string 分别为
+// bool、int64、float64、string，array 为 []any，record 和键都是字符串的 map 为 map[string]any，
+// 其他 map 为 map[any]any。函数等其他值原样返回。
+func ToGo(v Value) any {
+	return toGo(v, make(map[Value]any))
+}
+
+// CallGo 在 thread 中调用 Monkey 函数 fn，参数由 FromGo 转换，返回值由 ToGo 转换
+func CallGo(thread *Thread, fn Value, args ...any) (any, error) {
+	values := make([]Value, len(args))
+	for i, arg := range args {
+		val, err := FromGo(arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %w", i, err)
+		}
+		values[i] = val
+	}
+	result, err := Call(thread, fn, values...)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	return ToGo(result), nil
+}
+
+// NewGoFunction 使用反射将 Go 函数 fn 包装为名为 name 的内置函数。
+// 调用时参数按 fn 的参数类型转换：Value 类型的参数原样传递，any 类型的参数由 ToGo 转换，
+// 其他类型要求值能够无损地转换，例如 int 参数只接受范围内的整数，结构体参数接受 map 或 record。
+// fn 的第一个参数可以是 *Thread，此时传入当前的 thread 而不占用脚本的参数；fn 可以是变参函数。
+// fn 可以没有返回值，或者返回一个值、一个 error、一个值和一个 error，返回的值由 FromGo 转换。
+func NewGoFunction(name string, fn any) (*BuiltinFunction, error) {
+	return newGoFunction(name, reflect.ValueOf(fn))
+}
+
+// Go 值嵌套的最大深度，防止过深的嵌套导致栈溢出
+const maxGoDepth = 1000
+
+var errNestedTooDeep = errors.New("cannot convert Go value: nested too deeply")
+
+// goRef 标识一个指针或 map 指向的值
+type goRef struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// goRefs 是正在转换的指针和 map，再次遇到它们说明值引用了自身
+type goRefs map[goRef]bool
+
+// 将指针或 map rv 标记为正在转换，转换结束后调用者从 refs 中删除返回的 goRef
+func (refs goRefs) enter(rv reflect.Value) (goRef, error) {
+	ref := goRef{rv.Pointer(), rv.Type()}
+	if refs[ref] {
+		return ref, fmt.Errorf("cannot convert Go value: %s refers to itself", rv.Type())
+	}
+	refs[ref] = true
+	return ref, nil
+}
+
+// fieldError 是转换结构体字段时的错误，path 是从最外层的结构体开始的字段路径
+type fieldError struct {
+	path []string
+	err  error
+}
+
+func (e *fieldError) Error() string {
+	return fmt.Sprintf("field %s: %v", strings.Join(e.path, "."), e.err)
+}
+
+func (e *fieldError) Unwrap() error {
+	return e.err
+}
+
+var (
+	valueType  = reflect.TypeOf((*Value)(nil)).Elem()
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+	threadType = reflect.TypeOf((*Thread)(nil))
+	anyType    = reflect.TypeOf((*any)(nil)).Elem()
+)
+
+func fromGo(rv reflect.Value, depth int, active goRefs) (Value, error) {
+	if !rv.IsValid() {
+		return Null, nil
+	}
+	if depth > maxGoDepth {
+		return nil, errNestedTooDeep
+	}
+	if rv.Type().Implements(valueType) && rv.CanInterface() {
+		if rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return Null, nil
+		}
+		return rv.Interface().(Value), nil
+	}
+	switch rv.Kind() {
+	case reflect.Bool:
+		return Bool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Int(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if rv.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("cannot convert Go value %d: out of int range", rv.Uint())
+		}
+		return Int(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return Float(rv.Float()), nil
+	case reflect.String:
+		return String(rv.String()), nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return NewArray(nil), nil
+		}
+		items := make([]Value, rv.Len())
+		for i := range items {
+			item, err := fromGo(rv.Index(i), depth+1, active)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return NewArray(items), nil
+	case reflect.Map:
+		ref, err := active.enter(rv)
+		if err != nil {
+			return nil, err
+		}
+		defer delete(active, ref)
+		return mapFromGo(rv, depth, active)
+	case reflect.Struct:
+		result := NewMap()
+		for i := 0; i < rv.NumField(); i++ {
+			field := rv.Type().Field(i)
+			name, ok := fieldName(field)
+			if !ok {
+				continue
+			}
+			val, err := fromGo(rv.Field(i), depth+1, active)
+			if errors.Is(err, errNestedTooDeep) {
+				// 嵌套过深时字段路径没有意义
+				return nil, err
+			} else if fe, ok := err.(*fieldError); ok {
+				fe.path = append([]string{field.Name}, fe.path...)
+				return nil, fe
+			} else if err != nil {
+				return nil, &fieldError{path: []string{field.Name}, err: err}
+			}
+			result.SetKey(String(name), val)
+		}
+		return result, nil
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return Null, nil
+		}
+		if rv.Kind() == reflect.Pointer {
+			ref, err := active.enter(rv)
+			if err != nil {
+				return nil, err
+			}
+			defer delete(active, ref)
+		}
+		return fromGo(rv.Elem(), depth+1, active)
+	case reflect.Func:
+		if rv.IsNil() {
+			return Null, nil
+		}
+		// 使用 Go 函数的名称，匿名函数没有有意义的名称
+		name := runtime.FuncForPC(rv.Pointer()).Name()
+		if i := strings.LastIndexByte(name, '.'); i >= 0 {
+			name = name[i+1:]
+		}
+		if strings.HasPrefix(name, "func") && strings.Trim(name[4:], "0123456789") == "" {
+			name = "function"
+		}
+		return newGoFunction(name, rv)
+	}
+	return nil, fmt.Errorf("cannot convert Go value of type %s", rv.Type())
+}
+
+func mapFromGo(rv reflect.Value, depth int, active goRefs) (Value, error) {
+	type entry struct{ key, value Value }
+	entries := make([]entry, 0, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		key, err := fromGo(iter.Key(), depth+1, active)
+		if err != nil {
+			return nil, err
+		}
+		val, err := fromGo(iter.Value(), depth+1, active)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{key, val})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		x, y := entries[i].key, entries[j].key
+		if x.Type() != y.Type() {
+			return x.Type() < y.Type()
+		}
+		less, err := Compare(syntax.LT, x, y)
+		if err != nil {
+			return x.String() < y.String()
+		}
+		return less.Truth()
+	})
+	result := NewMap()
+	for _, e := range entries {
+		if err := result.SetKey(e.key, e.value); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// 返回结构体字段在 map 中的键，不导出或标记为 "-" 的字段返回 false
+func fieldName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	tag := field.Tag.Get("monkey")
+	if tag == "-" {
+		return "", false
+	}
+	if tag != "" {
+		return tag, true
+	}
+	return field.Name, true
+}
+
+func toGo(v Value, seen map[Value]any) any {
+	switch v := v.(type) {
+	case NullType:
+		return nil
+	case Bool:
+		return bool(v)
+	case Int:
+		return int64(v)
+	case Float:
+		return float64(v)
+	case String:
+		return string(v)
+	case *Array:
+		items := make([]any, len(v.items))
+		for i, item := range v.items {
+			items[i] = toGo(item, seen)
+		}
+		return items
+	case *Record:
+		result := make(map[string]any, len(v.values))
+		for i, field := range v.typ.fields {
+			result[field] = toGo(v.values[i], seen)
+		}
+		return result
+	case *Map:
+		if result, ok := seen[v]; ok {
+			return result
+		}
+		items := v.Items()
+		stringKeys := true
+		for _, item := range items {
+			if _, ok := item.Key.(String); !ok {
+				stringKeys = false
+			}
+		}
+		if stringKeys {
+			result := make(map[string]any, len(items))
+			seen[v] = result
+			for _, item := range items {
+				result[string(item.Key.(String))] = toGo(item.Value, seen)
+			}
+			return result
+		}
+		result := make(map[any]any, len(items))
+		seen[v] = result
+		for _, item := range items {
+			result[toGo(item.Key, seen)] = toGo(item.Value, seen)
+		}
+		return result
+	}
+	return v
+}
+
+// 将 v 转换为类型 t 的 Go 值，不能无损转换时返回错误
+func toType(v Value, t reflect.Type) (reflect.Value, error) {
+	if t == anyType {
+		if v == Null {
+			return reflect.Zero(t), nil
+		}
+		return reflect.ValueOf(ToGo(v)), nil
+	}
+	if reflect.TypeOf(v).AssignableTo(t) {
+		return reflect.ValueOf(v), nil
+	}
+	fail := func() (reflect.Value, error) {
+		return reflect.Value{}, fmt.Errorf("cannot convert %s to Go type %s", v.Type(), t)
+	}
+	if v == Null {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+			return reflect.Zero(t), nil
+		}
+		return fail()
+	}
+	rv := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Bool:
+		b, ok := v.(Bool)
+		if !ok {
+			return fail()
+		}
+		rv.SetBool(bool(b))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := v.(Int)
+		if !ok || rv.OverflowInt(int64(i)) {
+			return fail()
+		}
+		rv.SetInt(int64(i))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, ok := v.(Int)
+		if !ok || i < 0 || rv.OverflowUint(uint64(i)) {
+			return fail()
+		}
+		rv.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		switch x := v.(type) {
+		case Float:
+			rv.SetFloat(float64(x))
+		case Int:
+			rv.SetFloat(float64(x))
+		default:
+			return fail()
+		}
+	case reflect.String:
+		s, ok := v.(String)
+		if !ok {
+			return fail()
+		}
+		rv.SetString(string(s))
+	case reflect.Slice, reflect.Array:
+		arr, ok := v.(*Array)
+		if !ok || (t.Kind() == reflect.Array && arr.Len() != t.Len()) {
+			return fail()
+		}
+		if t.Kind() == reflect.Slice {
+			rv.Set(reflect.MakeSlice(t, arr.Len(), arr.Len()))
+		}
+		for i, item := range arr.items {
+			elem, err := toType(item, t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("index %d: %w", i, err)
+			}
+			rv.Index(i).Set(elem)
+		}
+	case reflect.Map:
+		m, ok := v.(*Map)
+		if !ok {
+			return fail()
+		}
+		rv.Set(reflect.MakeMapWithSize(t, m.Len()))
+		for _, item := range m.Items() {
+			key, err := toType(item.Key, t.Key())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("key %s: %w", item.Key, err)
+			}
+			val, err := toType(item.Value, t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("key %s: %w", item.Key, err)
+			}
+			rv.SetMapIndex(key, val)
+		}
+	case reflect.Struct:
+		m, ok := v.(Mapping)
+		if !ok {
+			return fail()
+		}
+		for i := 0; i < t.NumField(); i++ {
+			name, ok := fieldName(t.Field(i))
+			if !ok {
+				continue
+			}
+			item, found, err := m.Get(String(name))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			if !found {
+				continue
+			}
+			val, err := toType(item, t.Field(i).Type)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("field %s: %w", name, err)
+			}
+			rv.Field(i).Set(val)
+		}
+	case reflect.Pointer:
+		elem, err := toType(v, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(elem)
+		return ptr, nil
+	default:
+		return fail()
+	}
+	return rv, nil
+}
+
+func newGoFunction(name string, fn reflect.Value) (*BuiltinFunction, error) {
+	t := fn.Type()
+	if t.Kind() != reflect.Func {
+		return nil, fmt.Errorf("cannot use Go value of type %s as function", t)
+	}
+	switch {
+	case t.NumOut() > 2,
+		t.NumOut() == 2 && t.Out(1) != errorType:
+		return nil, fmt.Errorf("Go function %s must return at most a value and an error", name)
+	}
+
+	params := make([]reflect.Type, t.NumIn())
+	for i := range params {
+		params[i] = t.In(i)
+	}
+	withThread := len(params) > 0 && params[0] == threadType
+	if withThread {
+		params = params[1:]
+	}
+	variadic := t.IsVariadic()
+
+	return NewBuiltinFunction(name, func(thread *Thread, args ...Value) (Value, error) {
+		n := len(params)
+		switch {
+		case variadic && len(args) < n-1:
+			return nil, fmt.Errorf("wrong number of arguments. got=%d, want at least %d", len(args), n-1)
+		case !variadic && len(args) != n:
+			return nil, fmt.Errorf("wrong number of arguments. got=%d, want=%d", len(args), n)
+		}
+		in := make([]reflect.Value, 0, len(args)+1)
+		if withThread {
+			in = append(in, reflect.ValueOf(thread))
+		}
+		for i, arg := range args {
+			pt := params[min(i, n-1)]
+			if variadic && i >= n-1 {
+				pt = pt.Elem()
+			}
+			val, err := toType(arg, pt)
+			if err != nil {
+				return nil, fmt.Errorf("argument %d to `%s`: %w", i, name, err)
+			}
+			in = append(in, val)
+		}
+
+		out := fn.Call(in)
+		if len(out) > 0 && out[len(out)-1].Type() == errorType {
+			if err, _ := out[len(out)-1].Interface().(error); err != nil {
+				return nil, err
+			}
+			out = out[:len(out)-1]
+		}
+		if len(out) == 0 {
+			return Null, nil
+		}
+		return fromGo(out[0], 0, make(goRefs))
+	}), nil
+}
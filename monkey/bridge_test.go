@@ -0,0 +1,199 @@
+package monkey
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+type bridgePoint struct {
+	X, Y   int
+	Label  string `monkey:"label"`
+	Hidden bool   `monkey:"-"`
+	secret int
+}
+
+func TestFromGo(t *testing.T) {
+	tests := []struct {
+		input    any
+		expected string
+	}{
+		{nil, "null"},
+		{(*int)(nil), "null"},
+		{true, "true"},
+		{uint8(7), "7"},
+		{1.5, "1.5"},
+		{"s", "s"},
+		{[]int{1, 2}, "[1, 2]"},
+		{[2]string{"a", "b"}, "[a, b]"},
+		{map[string]int{"b": 2, "a": 1}, "{a: 1, b: 2}"},
+		{map[int]bool{3: true, -1: false}, "{-1: false, 3: true}"},
+		{bridgePoint{X: 1, Y: 2, Label: "p", Hidden: true, secret: 3}, "{X: 1, Y: 2, label: p}"},
+		{&bridgePoint{X: 1}, "{X: 1, Y: 0, label: }"},
+		{[]any{1, "a", nil}, "[1, a, null]"},
+		{Int(5), "5"},
+	}
+	for _, tt := range tests {
+		val, err := FromGo(tt.input)
+		if err != nil {
+			t.Errorf("FromGo(%#v): %v", tt.input, err)
+			continue
+		}
+		if val.String() != tt.expected {
+			t.Errorf("FromGo(%#v): expected %q. got=%q", tt.input, tt.expected, val.String())
+		}
+	}
+
+	for _, input := range []any{uint64(1 << 63), make(chan int), complex(1, 2)} {
+		if _, err := FromGo(input); err == nil {
+			t.Errorf("FromGo(%#v): expected error", input)
+		}
+	}
+}
+
+type bridgeNode struct {
+	Name string
+	Next *bridgeNode
+	Meta struct{ Parent *bridgeNode }
+}
+
+func TestFromGoCycle(t *testing.T) {
+	self := &bridgeNode{Name: "a"}
+	self.Next = self
+	loop := &bridgeNode{Name: "a", Next: &bridgeNode{Name: "b"}}
+	loop.Next.Meta.Parent = loop
+	m := map[string]any{}
+	m["self"] = m
+
+	tests := []struct {
+		input    any
+		expected string
+	}{
+		{self, "field Next: cannot convert Go value: *monkey.bridgeNode refers to itself"},
+		{loop, "field Next.Meta.Parent: cannot convert Go value: *monkey.bridgeNode refers to itself"},
+		{m, "cannot convert Go value: map[string]interface {} refers to itself"},
+	}
+	for _, tt := range tests {
+		if _, err := FromGo(tt.input); err == nil || err.Error() != tt.expected {
+			t.Errorf("FromGo(%T): expected error %q, got %v", tt.input, tt.expected, err)
+		}
+	}
+
+	// 多次引用同一个值但没有形成环时可以转换
+	shared := &bridgeNode{Name: "b"}
+	val, err := FromGo([]*bridgeNode{shared, {Name: "a", Next: shared}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "[{Name: b, Next: null, Meta: {Parent: null}}, {Name: a, Next: {Name: b, Next: null, Meta: {Parent: null}}, Meta: {Parent: null}}]"; val.String() != expected {
+		t.Errorf("expected %s. got=%s", expected, val)
+	}
+
+	// 嵌套过深时不输出字段路径
+	var deep *bridgeNode
+	for i := 0; i < maxGoDepth; i++ {
+		deep = &bridgeNode{Next: deep}
+	}
+	if _, err := FromGo(deep); err == nil || err.Error() != "cannot convert Go value: nested too deeply" {
+		t.Errorf("expected nested too deeply error, got %v", err)
+	}
+}
+
+func TestToGo(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`let m = {}; m["x"]`, nil},
+		{`[1, 2.5, "a", true]`, []any{int64(1), 2.5, "a", true}},
+		{`{"a": [1]}`, map[string]any{"a": []any{int64(1)}}},
+		{`{1: "x"}`, map[any]any{int64(1): "x"}},
+		{`record P { x, y }; P(1, 2)`, map[string]any{"x": int64(1), "y": int64(2)}},
+	}
+	for _, tt := range tests {
+		val, err := testEvalThread(t, tt.input)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.input, err)
+		}
+		if got := ToGo(val); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s: expected %#v. got=%#v", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestGoFunction(t *testing.T) {
+	env := NewEnv(nil)
+	env.Set("add", MustFromGo(func(a, b int) int { return a + b }))
+	env.Set("join", MustFromGo(func(sep string, parts ...string) string { return strings.Join(parts, sep) }))
+	env.Set("norm", MustFromGo(func(p bridgePoint) float64 { return float64(p.X*p.X + p.Y*p.Y) }))
+	env.Set("fail", MustFromGo(func(msg string) (int, error) { return 0, errors.New(msg) }))
+	env.Set("depth", MustFromGo(func(thread *Thread) int { return len(thread.CallStack()) }))
+	env.Set("first", MustFromGo(func(v any) any { return v.([]any)[0] }))
+	env.Set("point", MustFromGo(bridgePoint{X: 3, Y: 4}))
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`add(1, 2)`, "3"},
+		{`join("-", "a", "b", "c")`, "a-b-c"},
+		{`join(",")`, ""},
+		{`norm(point)`, "25.0"},
+		{`norm({"X": 1})`, "1.0"},
+		{`depth()`, "1"},
+		{`first([7, 8])`, "7"},
+		{`fail("boom")`, "boom"},
+		{`add(1)`, "wrong number of arguments. got=1, want=2"},
+		{`add(1, "2")`, "argument 1 to `function`: cannot convert string to Go type int"},
+		{`norm({"X": "a"})`, "field X"},
+	}
+	for _, tt := range tests {
+		program, err := syntax.NewParser(tt.input).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		val, err := Eval(program, env)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	add, _ := NewGoFunction("add", func(a, b int) int { return a + b })
+	if _, err := Call(new(Thread), add, Int(1), Float(2)); err == nil || !strings.Contains(err.Error(), "argument 1 to `add`") {
+		t.Errorf("expected conversion error, got %v", err)
+	}
+	if _, err := NewGoFunction("f", func() (int, int) { return 0, 0 }); err == nil {
+		t.Errorf("expected error for two non-error results")
+	}
+}
+
+func TestCallGo(t *testing.T) {
+	program, err := syntax.NewParser(`fn(p, n) { {"sum": p["X"] + p["Y"] + n} }`).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	thread := new(Thread)
+	fn, err := EvalThread(thread, program, NewEnv(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := CallGo(thread, fn, bridgePoint{X: 1, Y: 2}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, map[string]any{"sum": int64(6)}) {
+		t.Errorf("got=%#v", got)
+	}
+	if _, err := CallGo(thread, fn, 1); !errors.Is(err, ErrRuntime) {
+		t.Errorf("expected runtime error, got %v", err)
+	}
+}
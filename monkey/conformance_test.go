@@ -0,0 +1,145 @@
+package monkey
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// 一致性测试的语料在 testdata/*.mky 中。每个文件由只包含 --- 的行分成若干段，
+// 每段在新的全局作用域中独立执行。以 ### 开头的注释是对执行结果的断言：
+//
+//	print(1 + 1)    ### output: 2
+//	let x = 1 / 0;  ### error: division by zero
+//
+// output 断言按顺序与 print 的输出逐行比较；error 断言的内容是正则表达式，
+// 该段必须在这一行出错，并且错误信息与它匹配。没有 error 断言的段必须执行成功。
+
+// 语料会在这里的每个引擎上执行，以保证各个引擎的语义一致
+var conformanceEngines = map[string]func(thread *Thread, program *syntax.Program, env *Env) error{
+	"eval": func(thread *Thread, program *syntax.Program, env *Env) error {
+		_, err := EvalThread(thread, program, env)
+		return err
+	},
+}
+
+var annotation = regexp.MustCompile(`###\s*(output|error):\s?(.*)$`)
+
+type conformanceChunk struct {
+	src       string // 去掉注释之后的源码，行号与原文件一致
+	output    []string
+	errLine   int
+	errReason *regexp.Regexp
+}
+
+func TestConformance(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.mky"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no conformance tests found in testdata")
+	}
+	for _, filename := range files {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks := readChunks(t, filename, string(data))
+		for name, engine := range conformanceEngines {
+			for _, chunk := range chunks {
+				runChunk(t, name, engine, filename, chunk)
+			}
+		}
+	}
+}
+
+// 将 src 分段并解析其中的断言
+func readChunks(t *testing.T, filename, src string) []*conformanceChunk {
+	var chunks []*conformanceChunk
+	chunk := &conformanceChunk{}
+	var lines []string
+	for i, line := range strings.Split(src, "\n") {
+		if strings.TrimSpace(line) == "---" {
+			chunk.src = strings.Join(lines, "\n")
+			chunks = append(chunks, chunk)
+			chunk = &conformanceChunk{}
+			// 用空行代替前面的段，使行号与原文件一致
+			lines = make([]string, i+1)
+			continue
+		}
+		if m := annotation.FindStringSubmatchIndex(line); m != nil {
+			kind, arg := line[m[2]:m[3]], line[m[4]:m[5]]
+			switch kind {
+			case "output":
+				chunk.output = append(chunk.output, arg)
+			case "error":
+				if chunk.errReason != nil {
+					t.Fatalf("%s:%d: more than one error annotation in a chunk", filename, i+1)
+				}
+				re, err := regexp.Compile(arg)
+				if err != nil {
+					t.Fatalf("%s:%d: invalid error pattern: %v", filename, i+1, err)
+				}
+				chunk.errLine, chunk.errReason = i+1, re
+			}
+			line = line[:m[0]]
+		}
+		lines = append(lines, line)
+	}
+	chunk.src = strings.Join(lines, "\n")
+	return append(chunks, chunk)
+}
+
+func runChunk(t *testing.T, engine string, run func(*Thread, *syntax.Program, *Env) error, filename string, chunk *conformanceChunk) {
+	t.Helper()
+	var out strings.Builder
+	thread := &Thread{Name: filename, Print: func(_ *Thread, msg string) { out.WriteString(msg) }}
+	program, err := syntax.NewFileParser(filename, chunk.src).Parse()
+	if err == nil {
+		err = run(thread, program, NewEnv(nil))
+	}
+
+	switch {
+	case err != nil && chunk.errReason == nil:
+		t.Errorf("%s: unexpected error: %v", engine, err)
+	case err == nil && chunk.errReason != nil:
+		t.Errorf("%s: %s:%d: expected error matching %q", engine, filename, chunk.errLine, chunk.errReason)
+	case err != nil:
+		if !chunk.errReason.MatchString(err.Error()) {
+			t.Errorf("%s: %s:%d: error %q does not match %q", engine, filename, chunk.errLine, err, chunk.errReason)
+		}
+		if line := errorLine(err); line != 0 && line != chunk.errLine {
+			t.Errorf("%s: %s:%d: error reported at line %d: %v", engine, filename, chunk.errLine, line, err)
+		}
+	}
+
+	got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if out.Len() == 0 {
+		got = nil
+	}
+	if strings.Join(got, "\n") != strings.Join(chunk.output, "\n") {
+		t.Errorf("%s: %s: wrong output.\nexpected=%q\ngot=%q", engine, filename, chunk.output, got)
+	}
+}
+
+// 返回错误所在的行号，错误没有位置时返回 0
+func errorLine(err error) int {
+	var evalErr *EvalError
+	var syntaxErr *syntax.Error
+	var syntaxErrs syntax.ErrorList
+	switch {
+	case errors.As(err, &evalErr):
+		return int(evalErr.Pos.Line)
+	case errors.As(err, &syntaxErrs) && len(syntaxErrs) > 0:
+		return int(syntaxErrs[0].Position.Line)
+	case errors.As(err, &syntaxErr):
+		return int(syntaxErr.Position.Line)
+	}
+	return 0
+}
@@ -0,0 +1,151 @@
+package monkey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+func init() {
+	Universe["context"] = NewBuiltinFunction("context", contextBuiltin)
+}
+
+// Context 是 context() 返回的取消令牌，对应 Go 的 context.Context。
+// 取消一个 Context 时，由它派生的所有 Context 也被取消。
+//
+//	let ctx = context(1000);             // 1 秒后自动取消
+//	let child = context(ctx);            // ctx 被取消时 child 也被取消
+//	w["receive"](ctx);                   // 阻塞的 IO 内置函数接受 Context 作为第一个参数
+//	ctx["run"](fn() { ... });            // ctx 被取消后，fn 在下一条语句之前终止
+//	ctx["cancel"](); ctx["done"]();      // true
+type Context struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// context([parent][, timeout])
+//
+// 创建一个新的 Context。parent 不为空时从 parent 派生，timeout 为毫秒数，
+// 超时后 Context 被自动取消。
+func contextBuiltin(thread *Thread, args ...Value) (Value, error) {
+	if len(args) > 2 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want at most 2", len(args))
+	}
+	parent := context.Background()
+	if len(args) > 0 {
+		if p, ok := args[0].(*Context); ok {
+			parent = p.ctx
+			args = args[1:]
+		}
+	}
+	c := new(Context)
+	switch len(args) {
+	case 0:
+		c.ctx, c.cancel = context.WithCancel(parent)
+	case 1:
+		ms, ok := args[0].(Int)
+		if !ok {
+			return nil, fmt.Errorf("argument to `context` must be context or int, got %s", args[0].Type())
+		}
+		c.ctx, c.cancel = context.WithTimeout(parent, time.Duration(ms)*time.Millisecond)
+	default:
+		return nil, fmt.Errorf("first argument to `context` must be context, got %s", args[0].Type())
+	}
+	return c, nil
+}
+
+// SplitContext 拆分内置函数的参数：第一个参数是 Context 时返回它对应的
+// context.Context 和剩余的参数，否则返回 context.Background() 和原参数。
+// 可能阻塞的 IO 内置函数使用它来接受一个可选的 Context。
+func SplitContext(args []Value) (context.Context, []Value) {
+	if len(args) > 0 {
+		if c, ok := args[0].(*Context); ok {
+			return c.ctx, args[1:]
+		}
+	}
+	return context.Background(), args
+}
+
+// Get implements Mapping.
+func (c *Context) Get(key Value) (_ Value, _ bool, err error) {
+	name, _ := key.(String)
+	var fn func(thread *Thread, args ...Value) (Value, error)
+	switch name {
+	case "cancel":
+		fn = func(thread *Thread, args ...Value) (Value, error) {
+			if len(args) != 0 {
+				return nil, fmt.Errorf("wrong number of arguments. got=%d, want=0", len(args))
+			}
+			c.cancel()
+			return Null, nil
+		}
+	case "done":
+		fn = func(thread *Thread, args ...Value) (Value, error) {
+			if len(args) != 0 {
+				return nil, fmt.Errorf("wrong number of arguments. got=%d, want=0", len(args))
+			}
+			return Bool(c.ctx.Err() != nil), nil
+		}
+	case "err":
+		fn = func(thread *Thread, args ...Value) (Value, error) {
+			if len(args) != 0 {
+				return nil, fmt.Errorf("wrong number of arguments. got=%d, want=0", len(args))
+			}
+			if err := c.ctx.Err(); err != nil {
+				return &Error{Msg: err.Error()}, nil
+			}
+			return Null, nil
+		}
+	case "run":
+		fn = c.run
+	default:
+		return Null, false, nil
+	}
+	return NewBuiltinFunction(string(name), fn), true, nil
+}
+
+// run(f, args...) 调用 f(args...)，Context 被取消后 f 在执行下一条语句之前终止，
+// 并返回 Context 的错误（context canceled 或 context deadline exceeded）
+func (c *Context) run(thread *Thread, args ...Value) (Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want at least 1", len(args))
+	}
+	outer := thread.cancel
+	scope := newCancelScope(outer)
+	scope.ctx = c.ctx
+
+	thread.cancel = scope
+	defer func() { thread.cancel = outer }()
+	value, err := Call(thread, args[0], args[1:]...)
+	// 外层作用域被取消时保留 errCancelled，让 with_group 等调用者识别
+	if errors.Is(err, errCancelled) && !outer.cancelled() && c.ctx.Err() != nil {
+		return nil, c.ctx.Err()
+	}
+	return value, err
+}
+
+// Hash implements Value.
+func (c *Context) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: context")
+}
+
+// String implements Value.
+func (c *Context) String() string {
+	return "<context>"
+}
+
+// Truth implements Value.
+func (c *Context) Truth() bool {
+	return true
+}
+
+// Type implements Value.
+func (c *Context) Type() string {
+	return "context"
+}
+
+var (
+	_ Value   = (*Context)(nil)
+	_ Mapping = (*Context)(nil)
+)
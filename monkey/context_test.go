@@ -0,0 +1,45 @@
+package monkey
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`context()`, "<context>"},
+		{`context()["done"]()`, "false"},
+		{`let ctx = context(); ctx["cancel"](); ctx["done"]()`, "true"},
+		{`let ctx = context(); ctx["cancel"](); ctx["err"]()`, "error(context canceled)"},
+		{`context()["err"]()`, "null"},
+		{`let ctx = context(); let child = context(ctx); ctx["cancel"](); child["done"]()`, "true"},
+		{`let ctx = context(); let child = context(ctx); child["cancel"](); ctx["done"]()`, "false"},
+		{`context(0)["err"]()["message"]`, "context deadline exceeded"},
+		{`context(context(), 0)["done"]()`, "true"},
+		{`context("1")`, "must be context or int"},
+		{`context(1, 2)`, "first argument to `context` must be context"},
+		{`context()["run"](fn(a, b) { a + b }, 1, 2)`, "3"},
+		{`let ctx = context(); ctx["run"](fn() { ctx["cancel"](); 1; 2 })`, "context canceled"},
+		{`context(0)["run"](fn() { 1 })`, "context deadline exceeded"},
+		{`let ctx = context(); ctx["run"](fn() { with_group(fn(g) { g["spawn"](fn() { ctx["cancel"](); 1; 2 }) }) })`, "[null]"},
+		{`let ctx = context(); try(fn() { ctx["run"](fn() { ctx["cancel"](); 1 }) })["error"]["message"]`, "context canceled"},
+		{`let w = worker("receive()"); let r = w["receive"](context(20)); w["close"](); r`, "context deadline exceeded"},
+		{`let w = worker("send(1)"); w["receive"](context())`, "1"},
+		{`let w = worker("1"); w["send"](context())`, "cannot pass context between workers"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalPolicy(t, tt.input, nil)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package monkey
+
+import (
+	"bufio"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 为 true 时字符串使用固定的哈希函数
+var fixedHashing atomic.Bool
+
+// Deterministic 是确定性执行模式的选项。使用相同选项执行相同的脚本时，
+// 输出在每次运行之间完全一致，可以用于对脚本驱动的系统做 golden file 测试。
+// with_group、async 函数和 worker 的执行顺序仍然取决于调度，不在保证范围之内。
+type Deterministic struct {
+	Seed  int64             // random() 的随机数种子
+	Start time.Time         // time() 第一次返回的时间，为零值时使用 Unix 纪元
+	Step  time.Duration     // 每次调用 time() 后时钟前进的时间，为 0 时使用 1 毫秒
+	Env   map[string]string // getenv() 可见的环境变量，不使用进程的环境变量
+	Stdin io.Reader         // input() 等读取的输入，为 nil 时没有任何输入
+}
+
+// Apply 让 thread 以确定性模式执行：使用 d 的 Provider，并固定字符串的哈希种子。
+// 哈希种子是进程范围的设置，一旦打开就对所有求值生效，并且不能关闭，
+// 因此应当在创建任何 map 之前调用 Apply。
+func (d *Deterministic) Apply(thread *Thread) {
+	fixedHashing.Store(true)
+	thread.Provider = d.Provider()
+}
+
+// Provider 返回按 d 提供时间、随机数、环境变量和输入的 Provider
+func (d *Deterministic) Provider() Provider {
+	p := &deterministicProvider{
+		now:    d.Start,
+		step:   d.Step,
+		env:    d.Env,
+		random: rand.New(rand.NewSource(d.Seed)),
+	}
+	if p.now.IsZero() {
+		p.now = time.Unix(0, 0).UTC()
+	}
+	if p.step == 0 {
+		p.step = time.Millisecond
+	}
+	if d.Stdin != nil {
+		p.stdin = bufio.NewReader(d.Stdin)
+	}
+	return p
+}
+
+type deterministicProvider struct {
+	mu     sync.Mutex
+	now    time.Time
+	step   time.Duration
+	env    map[string]string
+	random *rand.Rand
+	stdin  *bufio.Reader
+}
+
+// Now implements Provider.
+func (p *deterministicProvider) Now() (time.Time, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now
+	p.now = p.now.Add(p.step)
+	return now, nil
+}
+
+// Random implements Provider.
+func (p *deterministicProvider) Random() (int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.random.Int63(), nil
+}
+
+// Getenv implements Provider.
+func (p *deterministicProvider) Getenv(name string) (string, bool, error) {
+	val, found := p.env[name]
+	return val, found, nil
+}
+
+// ReadLine implements Provider.
+func (p *deterministicProvider) ReadLine() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stdin == nil {
+		return "", io.EOF
+	}
+	line, err := p.stdin.ReadString('\n')
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// ReadPassword implements Provider.
+func (p *deterministicProvider) ReadPassword() (string, error) {
+	return p.ReadLine()
+}
+
+var _ Provider = (*deterministicProvider)(nil)
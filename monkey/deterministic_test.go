@@ -0,0 +1,56 @@
+package monkey
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+func TestDeterministic(t *testing.T) {
+	input := `
+let m = {"a long key number one": 1, "a long key number two": 2, "b": 3, 4: "four", true: 5};
+print(m);
+print(time(), time(), random(), random(100));
+print(getenv("HOME"), getenv("USER"));
+print(input(), input(), input());
+`
+	run := func() string {
+		var out strings.Builder
+		thread := &Thread{Print: func(_ *Thread, msg string) { out.WriteString(msg) }}
+		d := &Deterministic{
+			Seed:  42,
+			Start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			Step:  time.Second,
+			Env:   map[string]string{"USER": "monkey"},
+			Stdin: strings.NewReader("first\nsecond"),
+		}
+		d.Apply(thread)
+		program, err := syntax.NewParser(input).Parse()
+		if err != nil {
+			t.Fatalf("parser error: %s", err)
+		}
+		if _, err := EvalThread(thread, program, NewEnv(nil)); err != nil {
+			t.Fatalf("eval error: %s", err)
+		}
+		return out.String()
+	}
+
+	first := run()
+	for i := 0; i < 10; i++ {
+		if got := run(); got != first {
+			t.Fatalf("run %d differs.\nfirst=%q\ngot=%q", i, first, got)
+		}
+	}
+	lines := strings.Split(first, "\n")
+	if want := "1704067200000 1704067201000 "; !strings.HasPrefix(lines[1], want) {
+		t.Errorf("expected clock to start at %q. got=%q", want, lines[1])
+	}
+	if want := "null monkey"; lines[2] != want {
+		t.Errorf("expected env %q. got=%q", want, lines[2])
+	}
+	if want := "first second null"; lines[3] != want {
+		t.Errorf("expected input %q. got=%q", want, lines[3])
+	}
+}
@@ -0,0 +1,151 @@
+package monkey
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+func init() {
+	Universe["diff"] = NewBuiltinFunction("diff", diff)
+}
+
+// diff(a, b)
+//
+// 比较 a 和 b，返回从 a 变为 b 的所有差异，每个差异是一个 map：
+//
+//	diff({"port": 80, "tags": ["a"]}, {"port": 8080, "tags": ["a", "b"], "tls": true})
+//	// [{"op": "changed", "path": "port", "old": 80, "new": 8080},
+//	//  {"op": "added", "path": "tags[1]", "new": "b"},
+//	//  {"op": "added", "path": "tls", "new": true}]
+//
+// op 为 "added"、"removed" 或 "changed"，map 和数组会逐层比较，
+// 路径的写法与 validate 相同，不是字符串的键写作 [key]。两个值相同时返回空数组。
+func diff(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	var changes []Value
+	for _, c := range Diff(args[0], args[1]) {
+		change := NewMap()
+		change.SetKey(String("op"), String(c.Op))
+		change.SetKey(String("path"), String(c.Path))
+		if c.Old != nil {
+			change.SetKey(String("old"), c.Old)
+		}
+		if c.New != nil {
+			change.SetKey(String("new"), c.New)
+		}
+		changes = append(changes, change)
+	}
+	return NewArray(changes), nil
+}
+
+// Change 是 Diff 找到的一处差异
+type Change struct {
+	Op   string // "added"、"removed" 或 "changed"
+	Path string // 差异所在的路径，根节点为空字符串
+	Old  Value  // 原来的值，Op 为 "added" 时为 nil
+	New  Value  // 新的值，Op 为 "removed" 时为 nil
+}
+
+// String 以 "changed port: 80 -> 8080" 的形式描述差异
+func (c Change) String() string {
+	path := c.Path
+	if path == "" {
+		path = "value"
+	}
+	switch c.Op {
+	case "added":
+		return fmt.Sprintf("added %s: %s", path, c.New)
+	case "removed":
+		return fmt.Sprintf("removed %s: %s", path, c.Old)
+	}
+	return fmt.Sprintf("changed %s: %s -> %s", path, c.Old, c.New)
+}
+
+// Diff 返回从 a 变为 b 的所有差异，map 的键按字符串顺序比较
+func Diff(a, b Value) []Change {
+	var changes []Change
+	diffValues(a, b, "", &changes)
+	return changes
+}
+
+func diffValues(a, b Value, path string, changes *[]Change) {
+	switch a := a.(type) {
+	case *Array:
+		if b, ok := b.(*Array); ok {
+			for i := 0; i < max(len(a.items), len(b.items)); i++ {
+				itemPath := fmt.Sprintf("%s[%d]", path, i)
+				switch {
+				case i >= len(b.items):
+					*changes = append(*changes, Change{Op: "removed", Path: itemPath, Old: a.items[i]})
+				case i >= len(a.items):
+					*changes = append(*changes, Change{Op: "added", Path: itemPath, New: b.items[i]})
+				default:
+					diffValues(a.items[i], b.items[i], itemPath, changes)
+				}
+			}
+			return
+		}
+	case *Map:
+		if b, ok := b.(*Map); ok {
+			diffMaps(a, b, path, changes)
+			return
+		}
+	}
+	if !equalValues(a, b) {
+		*changes = append(*changes, Change{Op: "changed", Path: path, Old: a, New: b})
+	}
+}
+
+func diffMaps(a, b *Map, path string, changes *[]Change) {
+	keys := make(map[uint32]Value)
+	for _, entry := range a.Items() {
+		hash, _ := entry.Key.Hash()
+		keys[hash] = entry.Key
+	}
+	for _, entry := range b.Items() {
+		hash, _ := entry.Key.Hash()
+		keys[hash] = entry.Key
+	}
+	sorted := make([]Value, 0, len(keys))
+	for _, key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].String() < sorted[j].String()
+	})
+
+	for _, key := range sorted {
+		keyPath := fmt.Sprintf("%s[%s]", path, key)
+		if s, ok := key.(String); ok {
+			keyPath = string(s)
+			if path != "" {
+				keyPath = path + "." + keyPath
+			}
+		}
+		old, inA, _ := a.Get(key)
+		val, inB, _ := b.Get(key)
+		switch {
+		case !inB:
+			*changes = append(*changes, Change{Op: "removed", Path: keyPath, Old: old})
+		case !inA:
+			*changes = append(*changes, Change{Op: "added", Path: keyPath, New: val})
+		default:
+			diffValues(old, val, keyPath, changes)
+		}
+	}
+}
+
+// 判断两个不是容器的值是否相同，不同类型的值总是不同，不能比较的值按同一性比较
+func equalValues(a, b Value) bool {
+	if a.Type() != b.Type() {
+		return false
+	}
+	if eq, err := Compare(syntax.EQ, a, b); err == nil {
+		return eq.Truth()
+	}
+	return a == b
+}
@@ -0,0 +1,59 @@
+package monkey
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`diff({"a": 1, "b": [1, 2]}, {"a": 1, "b": [1, 2]})`, "[]"},
+		{`diff(1, 1.0)`, "op: changed"},
+		{`diff("a", "a")`, "[]"},
+		{`diff(len, len)`, "[]"},
+		{`diff({"port": 80}, {"port": 8080})[0]["old"]`, "80"},
+		{`diff({"port": 80}, {"port": 8080})[0]["new"]`, "8080"},
+		{`diff({"a": 1}, {})[0]["op"]`, "removed"},
+		{`diff({}, {"a": 1})[0]["op"]`, "added"},
+		{`diff({"tls": {"cert": "x"}}, {"tls": {"cert": "y"}})[0]["path"]`, "tls.cert"},
+		{`diff([1, 2, 3], [1])[1]["path"]`, "[2]"},
+		{`diff({1: [1]}, {1: [1, 2]})[0]["path"]`, "[1][1]"},
+		{`len(diff({"a": 1, "b": 2, "c": 3}, {"a": 2, "c": 3, "d": 4}))`, "3"},
+		{`diff({"b": 1, "a": 1}, {})[0]["path"]`, "a"},
+		{`diff(1)`, "wrong number of arguments. got=1, want=2"},
+	}
+
+	for _, tt := range tests {
+		val, err := testEvalThread(t, tt.input)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestChangeString(t *testing.T) {
+	a := NewMap()
+	a.SetKey(String("port"), Int(80))
+	a.SetKey(String("host"), String("a"))
+	b := NewMap()
+	b.SetKey(String("port"), Int(8080))
+	b.SetKey(String("tls"), True)
+
+	var got []string
+	for _, c := range Diff(a, b) {
+		got = append(got, c.String())
+	}
+	expected := "removed host: a\nchanged port: 80 -> 8080\nadded tls: true"
+	if strings.Join(got, "\n") != expected {
+		t.Errorf("wrong changes.\nexpected=%q\ngot=%q", expected, strings.Join(got, "\n"))
+	}
+}
@@ -0,0 +1,77 @@
+package monkey
+
+// Docs 是内置函数的说明，以内置函数的名称为键，供 monkey doc 等工具展示。
+// 第一行是调用方式，空一行之后是说明。扩展包在注册内置函数时应当同时添加说明：
+//
+//	monkey.Universe["kv_open"] = monkey.NewBuiltinFunction("kv_open", open)
+//	monkey.Docs["kv_open"] = "kv_open(path)\n\n打开（必要时创建）存储文件，返回一个存储对象"
+//
+// 内置模块中的绑定以 "模块名.名称" 为键，例如 "math.sqrt"。
+var Docs = map[string]string{
+	"len":    "len(x)\n\n返回字符串的字符数、数组的元素个数或 map 的键值对个数",
+	"print":  "print(args...)\n\n以空格分隔输出所有参数并换行",
+	"time":   "time()\n\n返回当前时间的 Unix 毫秒数",
+	"random": "random() 或 random(n)\n\n返回一个非负的随机整数，指定 n 时返回 [0, n) 范围内的整数",
+	"getenv": "getenv(name)\n\n返回环境变量 name 的值，不存在时返回 null",
+	"input":  "input() 或 input(prompt)\n\n输出 prompt 后读取一行输入，输入结束时返回 null",
+	"int":    "int(x)\n\n将浮点数（向零取整）、字符串或布尔值转换为整数",
+	"float":  "float(x)\n\n将整数、字符串或布尔值转换为浮点数",
+
+	"sprintf": "sprintf(format, args...)\n\n按 format 格式化参数并返回字符串，支持 %d、%f、%s、%q、%v 和 %%，以及 Go 的标志、宽度和精度",
+	"printf":  "printf(format, args...)\n\n与 sprintf 相同，但输出格式化的结果，不会自动换行",
+
+	"push":   "push(arr, x...)\n\n返回在 arr 末尾追加 x 之后的新数组",
+	"pop":    "pop(arr)\n\n返回去掉最后一个元素的新数组",
+	"insert": "insert(arr, i, x)\n\n返回在下标 i 之前插入 x 的新数组",
+	"remove": "remove(arr, i)\n\n返回去掉下标 i 处元素的新数组",
+	"map":    "map(arr, f)\n\n返回对 arr 的每个元素调用 f(x) 的结果组成的新数组",
+	"filter": "filter(arr, f)\n\n返回 arr 中 f(x) 为真的元素组成的新数组",
+	"reduce": "reduce(arr, init, f)\n\n从 init 开始依次计算 acc = f(acc, x)，返回最后的 acc",
+
+	"keys":   "keys(m)\n\n按插入的顺序返回 map 的所有键",
+	"values": "values(m)\n\n按插入的顺序返回 map 的所有值",
+	"has":    "has(m, key)\n\n判断 map 中是否存在 key",
+	"delete": "delete(m, key)\n\n返回去掉 key 之后的新 map",
+
+	"error":      "error(msg)\n\n返回一个错误值，它不会中止求值",
+	"try":        "try(fn, args...)\n\n调用 fn(args...) 并捕获运行时错误，返回 {\"ok\": bool, \"value\": 结果, \"error\": 错误}",
+	"validate":   "validate(value, schema)\n\n按 schema 检查 value 的结构，返回描述所有不符合之处的数组",
+	"diff":       "diff(a, b)\n\n比较 a 和 b，返回从 a 变为 b 的所有差异",
+	"implements": "implements(value, capability)\n\n判断 value 是否支持调用、索引、遍历等能力",
+
+	"eval":     "eval(code) 或 eval(code, globals)\n\n在新的全局作用域中执行 code 并返回最后一个表达式的值",
+	"parse":    "parse(code)\n\n解析 code 并以 map 的形式返回语法树",
+	"scan":     "scan(input, pattern)\n\n按 pattern 匹配整个 input，返回以字段名为键的 map，不匹配时返回 null",
+	"template": "template(str, data)\n\n渲染模板 str，data 中的键值对在模板中作为变量使用",
+
+	"re_match":    "re_match(pattern, s)\n\n返回 s 中 pattern 的第一个匹配 {\"match\": 匹配, \"groups\": [分组], \"named\": {分组名: 分组}}，没有匹配时返回 null",
+	"re_find_all": "re_find_all(pattern, s)\n\n按出现的顺序返回 s 中 pattern 的所有匹配，每个匹配与 re_match 的结果相同",
+	"re_replace":  "re_replace(pattern, s, repl)\n\n将所有匹配替换为 repl，repl 可以是包含 $1、${name} 的字符串，或者接收匹配并返回字符串的函数",
+
+	"with_group": "with_group(fn)\n\n调用 fn(g)，g[\"spawn\"](f, args...) 启动的任务全部结束后返回它们的结果",
+	"context":    "context([parent][, timeout])\n\n创建一个可以取消的 Context，timeout 为毫秒数",
+	"atomic_int": "atomic_int() 或 atomic_int(n)\n\n返回一个可以在多个任务之间共享的整数",
+	"mutex":      "mutex()\n\n返回一个互斥锁，用于保护多个任务共享的状态",
+	"worker":     "worker(code) 或 worker(code, capabilities)\n\n在隔离的解释器中执行 code，双方只能通过消息通信",
+
+	"confirm":  "confirm(prompt) 或 confirm(prompt, default)\n\n询问一个是非问题并返回 true 或 false",
+	"select":   "select(prompt, options)\n\n列出带编号的选项，返回用户选择的那一项",
+	"password": "password(prompt)\n\n读取一行输入，在终端中输入的内容不会回显",
+
+	"quickcheck": "quickcheck(property, generators...)\n\n使用生成器生成的随机参数多次调用 property，失败时返回包含最小反例的错误",
+	"gen_int":    "gen_int() 或 gen_int(min, max)\n\n生成 [min, max] 范围内的整数",
+	"gen_string": "gen_string()\n\n生成由可打印 ASCII 字符组成的字符串",
+	"gen_array":  "gen_array(gen)\n\n生成元素由 gen 生成的数组",
+	"gen_map":    "gen_map(keyGen, valueGen)\n\n生成键值分别由 keyGen 和 valueGen 生成的 map",
+
+	"math.pi":     "math.pi\n\n圆周率 π",
+	"math.e":      "math.e\n\n自然对数的底 e",
+	"math.abs":    "math.abs(x)\n\n返回 x 的绝对值，类型与 x 相同",
+	"math.min":    "math.min(x, y...)\n\n返回参数中最小的数，有 NaN 时返回 NaN",
+	"math.max":    "math.max(x, y...)\n\n返回参数中最大的数，有 NaN 时返回 NaN",
+	"math.pow":    "math.pow(x, y)\n\n与 x ** y 相同，两个整数的结果是整数，y 为负数时结果是浮点数",
+	"math.sqrt":   "math.sqrt(x)\n\n返回 x 的平方根，x 为负数时报错",
+	"math.floor":  "math.floor(x)\n\n返回不大于 x 的最大整数",
+	"math.ceil":   "math.ceil(x)\n\n返回不小于 x 的最小整数",
+	"math.random": "math.random()\n\n返回 [0, 1) 范围内均匀分布的浮点数",
+}
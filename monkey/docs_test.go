@@ -0,0 +1,51 @@
+package monkey
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocs(t *testing.T) {
+	for name := range Universe {
+		doc, ok := Docs[name]
+		if !ok {
+			t.Errorf("builtin %s has no documentation in Docs", name)
+			continue
+		}
+		signature, rest, _ := strings.Cut(doc, "\n")
+		if !strings.HasPrefix(signature, name+"(") {
+			t.Errorf("documentation of %s must start with its signature. got=%q", name, signature)
+		}
+		if !strings.HasPrefix(rest, "\n") || strings.TrimSpace(rest) == "" {
+			t.Errorf("documentation of %s must have a description after an empty line. got=%q", name, doc)
+		}
+	}
+	for name, module := range BuiltinModules {
+		for _, member := range module.Exports() {
+			key := name + "." + member
+			doc, ok := Docs[key]
+			if !ok {
+				t.Errorf("builtin module member %s has no documentation in Docs", key)
+				continue
+			}
+			if signature, _, _ := strings.Cut(doc, "\n"); !strings.HasPrefix(signature, key) {
+				t.Errorf("documentation of %s must start with its signature. got=%q", key, signature)
+			}
+		}
+	}
+	for name := range Docs {
+		if module, member, ok := strings.Cut(name, "."); ok {
+			m, ok := BuiltinModules[module]
+			if ok {
+				_, ok = m.env.Get(member)
+			}
+			if !ok {
+				t.Errorf("Docs has documentation for unknown builtin module member %s", name)
+			}
+			continue
+		}
+		if _, ok := Universe[name]; !ok {
+			t.Errorf("Docs has documentation for unknown builtin %s", name)
+		}
+	}
+}
@@ -1,12 +1,22 @@
 package monkey
 
+import (
+	"sort"
+	"sync"
+)
+
+// Env 是一个作用域。with_group 启动的任务会在多个 goroutine 中共享闭包的作用域，
+// 因此对 store 的访问需要加锁。
 type Env struct {
+	mu    sync.RWMutex
 	store map[string]Value
 	outer *Env
 }
 
 func (e *Env) Get(name string) (Value, bool) {
+	e.mu.RLock()
 	val, ok := e.store[name]
+	e.mu.RUnlock()
 	if !ok && e.outer != nil {
 		val, ok = e.outer.Get(name)
 	}
@@ -14,7 +24,26 @@ func (e *Env) Get(name string) (Value, bool) {
 }
 
 func (e *Env) Set(name string, val Value) {
+	e.mu.Lock()
 	e.store[name] = val
+	e.mu.Unlock()
+}
+
+// Names 返回当前作用域（不包括外层作用域）中定义的所有名称，按字典序排列
+func (e *Env) Names() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Outer 返回外层作用域，最外层作用域返回 nil
+func (e *Env) Outer() *Env {
+	return e.outer
 }
 
 func NewEnv(outer *Env) *Env {
@@ -23,3 +52,22 @@ func NewEnv(outer *Env) *Env {
 		outer: outer,
 	}
 }
+
+// 不创建闭包的函数调用结束后，它的作用域放回 envPool 中，供之后的调用复用
+var envPool = sync.Pool{
+	New: func() any { return NewEnv(nil) },
+}
+
+// 从池中取出一个空的作用域
+func getEnv(outer *Env) *Env {
+	e := envPool.Get().(*Env)
+	e.outer = outer
+	return e
+}
+
+// 清空 e 并放回池中，调用方必须保证之后没有任何地方再引用 e
+func putEnv(e *Env) {
+	clear(e.store)
+	e.outer = nil
+	envPool.Put(e)
+}
@@ -0,0 +1,42 @@
+package monkey
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEnvPool(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn fib(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(15)", "610"},
+		{"let sq = fn(x) { let y = x * x; y }; sq(3) + sq(4)", "25"},
+		{"let adder = fn(x) { fn(y) { x + y } }; let a = adder(1); let b = adder(2); a(10) + b(20)", "33"},
+		{"let f = fn(x) { let g = fn() { x }; g }; let a = f(1); f(2); a()", "1"},
+		// 宏展开在不创建闭包的函数体中引入了闭包
+		{"let mk = macro(v) { quote(fn() { unquote(v) }) }; let f = fn(x) { mk(x) }; let a = f(1); let b = f(2); a() + b()", "3"},
+		{`let f = fn(x) { try(fn() { x / 0 })["error"]["message"] }; f(1); f(2)`, "division by zero: 2 / 0"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalThread(t, tt.input)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func BenchmarkCall(b *testing.B) {
+	b.Run("leaf", func(b *testing.B) {
+		benchmarkEval(b, "fn fib(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(15)")
+	})
+	b.Run("closure", func(b *testing.B) {
+		benchmarkEval(b, "fn fib(n) { let f = fn() { n }; if (n < 2) { f() } else { fib(n - 1) + fib(n - 2) } }; fib(15)")
+	})
+}
@@ -0,0 +1,94 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+)
+
+func init() {
+	Universe["error"] = NewBuiltinFunction("error", errorBuiltin)
+	Universe["try"] = NewBuiltinFunction("try", try)
+}
+
+// Error 是表示错误的值，由 error(msg) 创建，或由 try 捕获运行时错误得到。
+// 通过 e["message"] 获取错误信息。
+type Error struct {
+	Msg string
+}
+
+// Get implements Mapping.
+func (e *Error) Get(key Value) (_ Value, _ bool, err error) {
+	if key != String("message") {
+		return Null, false, nil
+	}
+	return String(e.Msg), true, nil
+}
+
+// Hash implements Value.
+func (e *Error) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: error")
+}
+
+// String implements Value.
+func (e *Error) String() string {
+	return "error(" + e.Msg + ")"
+}
+
+// Truth implements Value.
+func (e *Error) Truth() bool {
+	return true
+}
+
+// Type implements Value.
+func (e *Error) Type() string {
+	return "error"
+}
+
+// error(msg) 返回一个错误值，它不会中止求值，可以像其他值一样返回和传递
+func errorBuiltin(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	msg, ok := args[0].(String)
+	if !ok {
+		return nil, fmt.Errorf("argument to `error` must be string, got %s", args[0].Type())
+	}
+	return &Error{Msg: string(msg)}, nil
+}
+
+// try(fn, args...)
+//
+// 调用 fn(args...) 并捕获其中发生的运行时错误，返回 {"ok": bool, "value": 结果, "error": 错误}：
+//
+//	let r = try(fn() { 1 / 0 });
+//	if (!r["ok"]) { print(r["error"]["message"]) }
+//
+// 成功时 error 为 null，失败时 value 为 null。with_group 取消任务不会被 try 捕获。
+func try(thread *Thread, args ...Value) (Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want at least 1", len(args))
+	}
+	failed := thread.failedEnv
+	result := NewMap()
+	value, err := Call(thread, args[0], args[1:]...)
+	switch {
+	case err == nil:
+		result.SetKey(String("ok"), True)
+		result.SetKey(String("value"), value)
+		result.SetKey(String("error"), Null)
+	case errors.Is(err, errCancelled):
+		return nil, err
+	default:
+		// 错误已被处理，不再作为事后调试的现场
+		thread.failedEnv = failed
+		result.SetKey(String("ok"), False)
+		result.SetKey(String("value"), Null)
+		result.SetKey(String("error"), &Error{Msg: err.Error()})
+	}
+	return result, nil
+}
+
+var (
+	_ Value   = (*Error)(nil)
+	_ Mapping = (*Error)(nil)
+)
@@ -0,0 +1,39 @@
+package monkey
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestErrorAndTry(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`error("boom")`, "error(boom)"},
+		{`error("boom")["message"]`, "boom"},
+		{`error(1)`, "must be string"},
+		{`try(fn() { 42 })["ok"]`, "true"},
+		{`try(fn() { 42 })["value"]`, "42"},
+		{`try(fn() { 42 })["error"]`, "null"},
+		{`try(fn() { x })["ok"]`, "false"},
+		{`try(fn() { x })["error"]["message"]`, "identifier not found: x"},
+		{`try(fn(a, b) { a + b }, 1, 2)["value"]`, "3"},
+		{`try(fn() { error("soft") })["value"]["message"]`, "soft"},
+		{`let r = try(fn() { x }); if (r["ok"]) { 1 } else { 2 }`, "2"},
+		{`try(fn(g) { 1 })["error"]["message"]`, "wrong number of arguments"},
+		{`with_group(fn(g) { g["spawn"](fn() { x }); try(fn() { 1 }) })`, "task 0: <invalid>:1:38 identifier not found: x"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalPolicy(t, tt.input, nil)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
@@ -1,19 +1,149 @@
+// Package monkey 实现 Monkey 的值、作用域、Thread 和求值器，编写内置函数或者需要控制求值细节时使用它。
+// 只需要执行脚本的宿主程序应当使用更稳定的 interp 包。
 package monkey
 
 import (
 	"fmt"
+	"io"
+	"reflect"
+	"time"
 
 	"github.com/hungtcs/monkey-lang/syntax"
 )
 
+// Eval 使用一个新的 Thread 对 node 求值
 func Eval(node syntax.Node, env *Env) (_ Value, err error) {
+	return EvalThread(new(Thread), node, env)
+}
+
+// EvalThread 在给定的 thread 中对 node 求值
+func EvalThread(thread *Thread, node syntax.Node, env *Env) (_ Value, err error) {
+	if err := checkEvalArgs(thread, node, env); err != nil {
+		return nil, err
+	}
+	thread.failedEnv = nil
+	if hooks := thread.Hooks; hooks != nil {
+		if hooks.OnEvalStart != nil {
+			hooks.OnEvalStart(thread)
+		}
+		if hooks.OnEvalEnd != nil {
+			start := time.Now()
+			defer func() { hooks.OnEvalEnd(thread, time.Since(start), err) }()
+		}
+	}
+	defer recoverPanic(&err)
+	defer thread.startBudget()()
+	value, err := eval(thread, node, env)
+	return value, classifyError(err)
+}
+
+// EvalExprString 在 env 中求值单个表达式 src，用于调试器和编辑器查看暂停时作用域中表达式的值。
+// src 中的 let、return 等语句会返回错误，因此求值不会在 env 中定义新的变量，也不会改变控制流。
+// 求值使用一个新的 Thread，不会触发调试器的断点。
+func EvalExprString(src string, env *Env) (Value, error) {
+	program, err := syntax.NewParser(src).Parse()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(program.Stmts) == 0:
+		return nil, syntax.NewError(syntax.MakePosition(nil, 1, 1), "expected an expression, got nothing")
+	case len(program.Stmts) > 1:
+		start, _ := program.Stmts[1].Span()
+		return nil, syntax.NewError(start, "expected a single expression")
+	}
+	if _, ok := program.Stmts[0].(*syntax.ExprStmt); !ok {
+		start, _ := program.Stmts[0].Span()
+		return nil, syntax.NewError(start, fmt.Sprintf("expected an expression, got %s statement", program.Stmts[0].Literal()))
+	}
+	return EvalThread(new(Thread), program, env)
+}
+
+// ExecReader 从 r 中逐条读取并执行名为 filename 的程序的语句，返回最后一条语句的值。
+// 与先解析整个程序再执行不同，它可以执行管道和很大的生成文件，不需要把整个程序读入内存；
+// 但语法错误要执行到出错的语句时才会报告，之前的语句已经执行，宏也只能在定义之后使用。
+// OnParse 钩子在执行结束时被调用一次，时间是所有语句的解析时间之和。
+func ExecReader(thread *Thread, filename string, r io.Reader, env *Env) (_ Value, err error) {
+	if err := checkEvalArgs(thread, &syntax.Program{}, env); err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, runtimeError{fmt.Errorf("ExecReader: nil reader")}
+	}
+	thread.failedEnv = nil
+	if hooks := thread.Hooks; hooks != nil {
+		if hooks.OnEvalStart != nil {
+			hooks.OnEvalStart(thread)
+		}
+		if hooks.OnEvalEnd != nil {
+			start := time.Now()
+			defer func() { hooks.OnEvalEnd(thread, time.Since(start), err) }()
+		}
+	}
+
+	defer func() { err = classifyError(err) }()
+	defer recoverPanic(&err)
+	defer thread.startBudget()()
+
+	parser := syntax.NewParserReader(filename, r)
+	parser.Options = thread.Language
+	var parsing time.Duration
+	var parseErr error
+	defer func() {
+		if thread.Hooks != nil && thread.Hooks.OnParse != nil {
+			thread.Hooks.OnParse(thread, filename, parsing, parseErr)
+		}
+	}()
+
+	var value Value = Null
+	for {
+		start := time.Now()
+		stmt, err := parser.Next()
+		parsing += time.Since(start)
+		if err == io.EOF {
+			return value, nil
+		} else if err != nil {
+			parseErr = err
+			return nil, err
+		}
+
+		program := &syntax.Program{Stmts: []syntax.Stmt{stmt}}
+		if err := expandMacros(thread, program, env); err != nil {
+			return nil, err
+		}
+		if value, err = evalTopLevelStmt(thread, program.Stmts[0], env); err != nil {
+			return nil, err
+		}
+		if val, ok := value.(*returnValue); ok {
+			return val.Value, nil
+		}
+	}
+}
+
+// 检查导出的求值函数的参数，nil 的参数返回错误而不是 panic
+func checkEvalArgs(thread *Thread, node syntax.Node, env *Env) error {
+	switch {
+	case thread == nil:
+		return runtimeError{fmt.Errorf("eval: nil thread")}
+	case node == nil || reflect.ValueOf(node).IsNil():
+		return runtimeError{fmt.Errorf("eval: nil node")}
+	case env == nil:
+		return runtimeError{fmt.Errorf("eval: nil env")}
+	}
+	return nil
+}
+
+func eval(thread *Thread, node syntax.Node, env *Env) (_ Value, err error) {
 	switch node := node.(type) {
 
 	case *syntax.Program:
-		return evalProgram(node, env)
+		return evalProgram(thread, node, env)
 
 	case *syntax.ExprStmt:
-		return Eval(node.Expr, env)
+		return eval(thread, node.Expr, env)
+
+	case *syntax.FloatLiteral:
+		return Float(node.Value), nil
 
 	case *syntax.IntegerLiteral:
 		return Int(node.Value), nil
@@ -25,80 +155,181 @@ func Eval(node syntax.Node, env *Env) (_ Value, err error) {
 		return String(node.Value), nil
 
 	case *syntax.ArrayLiteral:
-		items, err := evalExprs(node.Items, env)
-		if err != nil {
-			return nil, err
+		arr := makeArray(len(node.Items))
+		for i, item := range node.Items {
+			if arr.items[i], err = eval(thread, item, env); err != nil {
+				return nil, err
+			}
+		}
+		if err := thread.allocate(arr); err != nil {
+			start, _ := node.Span()
+			return nil, thread.errorAt(start, err)
 		}
-		return &Array{items: items}, nil
+		return arr, nil
 
 	case *syntax.MapLiteral:
-		return evalMapLiteral(node, env)
+		return evalMapLiteral(thread, node, env)
 
 	case *syntax.IndexExpr:
-		left, err := Eval(node.Left, env)
+		left, err := eval(thread, node.Left, env)
+		if err != nil {
+			return nil, err
+		}
+		index, err := eval(thread, node.Index, env)
+		if err != nil {
+			return nil, err
+		}
+		val, err := parseIndexExpr(left, index)
+		if err != nil {
+			start, _ := node.Span()
+			return nil, thread.errorAt(start, err)
+		}
+		return val, nil
+
+	case *syntax.SliceExpr:
+		left, err := eval(thread, node.Left, env)
 		if err != nil {
 			return nil, err
 		}
-		index, err := Eval(node.Index, env)
+		var start, end Value
+		if node.Start != nil {
+			if start, err = eval(thread, node.Start, env); err != nil {
+				return nil, err
+			}
+		}
+		if node.End != nil {
+			if end, err = eval(thread, node.End, env); err != nil {
+				return nil, err
+			}
+		}
+		val, err := evalSliceExpr(left, start, end)
+		if err == nil {
+			err = thread.allocate(val)
+		}
+		if err != nil {
+			begin, _ := node.Span()
+			return nil, thread.errorAt(begin, err)
+		}
+		return val, nil
+
+	case *syntax.DotExpr:
+		left, err := eval(thread, node.Left, env)
 		if err != nil {
 			return nil, err
 		}
-		return parseIndexExpr(left, index)
+		val, err := evalDotExpr(left, node.Name.Value)
+		if err != nil {
+			return nil, thread.errorAt(node.Name.Pos, err)
+		}
+		return val, nil
 
 	case *syntax.PrefixExpr:
-		right, err := Eval(node.Right, env)
+		right, err := eval(thread, node.Right, env)
 		if err != nil {
 			return nil, err
 		}
-		return Unary(node.Op, right)
+		val, err := Unary(node.Op, right)
+		if err != nil {
+			return nil, thread.errorAt(node.Pos, err)
+		}
+		return val, nil
 
 	case *syntax.InfixExpr:
-		left, err := Eval(node.Left, env)
+		left, err := eval(thread, node.Left, env)
 		if err != nil {
 			return nil, err
 		}
-		right, err := Eval(node.Right, env)
+		// && 和 || 是短路求值的，结果总是布尔值
+		switch node.Op {
+		case syntax.AND:
+			if !left.Truth() {
+				return False, nil
+			}
+			right, err := eval(thread, node.Right, env)
+			if err != nil {
+				return nil, err
+			}
+			return Bool(right.Truth()), nil
+		case syntax.OR:
+			if left.Truth() {
+				return True, nil
+			}
+			right, err := eval(thread, node.Right, env)
+			if err != nil {
+				return nil, err
+			}
+			return Bool(right.Truth()), nil
+		}
+		right, err := eval(thread, node.Right, env)
 		if err != nil {
 			return nil, err
 		}
+		var val Value
 		switch node.Op {
 		case syntax.EQ, syntax.NE, syntax.GT, syntax.GE, syntax.LT, syntax.LE:
-			return Compare(node.Op, left, right)
+			val, err = Compare(node.Op, left, right)
 		default:
-			return Binary(node.Op, left, right)
+			if val, err = Binary(node.Op, left, right); err == nil {
+				err = thread.allocate(val)
+			}
 		}
+		if err != nil {
+			// 运算出错（例如除以零）时指出出错的运算符
+			return nil, thread.errorAt(node.OpPos, err)
+		}
+		return val, nil
 
 	case *syntax.BlockStmt:
-		return evalBlockStmt(node, env)
+		return evalBlockStmt(thread, node, env)
 
 	case *syntax.IfExpr:
-		cond, err := Eval(node.Cond, env)
+		cond, err := eval(thread, node.Cond, env)
 		if err != nil {
 			return nil, err
 		}
 		if cond.Truth() {
-			return evalBlockStmt(node.Consequence, env)
+			return evalBlockStmt(thread, node.Consequence, env)
 		}
 		if node.Alternative != nil {
-			return evalBlockStmt(node.Alternative, env)
+			return evalBlockStmt(thread, node.Alternative, env)
 		}
 		return Null, nil
 
 	case *syntax.ReturnStmt:
-		val, err := Eval(node.Value, env)
+		val, err := eval(thread, node.Value, env)
 		if err != nil {
 			return nil, err
 		}
 		return &returnValue{Value: val}, nil
 
 	case *syntax.LetStmt:
-		value, err := Eval(node.Value, env)
+		// let f = fn(n) { ... f(n - 1) ... } 与具名函数一样，函数体中的 f 总是指向函数自身，
+		// 即使之后 f 被重新绑定为其他值
+		if fn, ok := node.Value.(*syntax.FunctionLiteral); ok && fn.Name == nil {
+			env.Set(node.Name.Value, newRecursiveFunction(fn, node.Name.Value, env))
+			return Null, nil
+		}
+		value, err := eval(thread, node.Value, env)
 		if err != nil {
 			return nil, err
 		}
 		env.Set(node.Name.Value, value)
 		return Null, nil
 
+	case *syntax.RecordStmt:
+		env.Set(node.Name.Value, evalRecordStmt(node))
+		return Null, nil
+
+	case *syntax.FunctionStmt:
+		env.Set(node.Fn.Name.Value, newRecursiveFunction(node.Fn, node.Fn.Name.Value, env))
+		return Null, nil
+
+	case *syntax.ExportStmt:
+		if env.Outer() != nil {
+			return nil, thread.errorAt(node.Pos, fmt.Errorf("export is only allowed at the top level of a module"))
+		}
+		return eval(thread, node.Let, env)
+
 	case *syntax.Identifier:
 		if val, ok := env.Get(node.Value); ok {
 			return val, nil
@@ -106,35 +337,103 @@ func Eval(node syntax.Node, env *Env) (_ Value, err error) {
 		if val, ok := Universe[node.Value]; ok {
 			return val, nil
 		}
-		return nil, fmt.Errorf("identifier not found: %s", node.Value)
+		return nil, thread.errorAt(node.Pos, fmt.Errorf("identifier not found: %s", node.Value))
+
+	case *syntax.ImportExpr:
+		start, _ := node.Span()
+		module, err := evalImport(thread, start, node.Path, env)
+		if err != nil {
+			return nil, thread.errorAt(start, err)
+		}
+		return module, nil
+
+	case *syntax.ImportStmt:
+		start, _ := node.Span()
+		module, err := evalImport(thread, start, node.Import.Path, env)
+		if err != nil {
+			return nil, thread.errorAt(start, err)
+		}
+		env.Set(node.Alias.Value, module)
+		return Null, nil
+
+	case *syntax.FromImportStmt:
+		start, _ := node.Span()
+		module, err := evalImport(thread, start, node.Path, env)
+		if err != nil {
+			return nil, thread.errorAt(start, err)
+		}
+		for _, name := range node.Names {
+			val, ok, err := module.lookup(name.Value)
+			if err != nil {
+				return nil, thread.errorAt(name.Pos, err)
+			}
+			if !ok {
+				return nil, thread.errorAt(name.Pos, fmt.Errorf("module %s does not export %q", module.Name, name.Value))
+			}
+			env.Set(name.Value, val)
+		}
+		return Null, nil
 
 	case *syntax.FunctionLiteral:
-		return &Function{Params: node.Params, Body: node.Body, Env: env}, nil
+		if node.Name != nil {
+			// 具名的函数表达式只在自己的函数体中可以通过名字访问
+			return newRecursiveFunction(node, node.Name.Value, env), nil
+		}
+		return newFunction(node, env), nil
+
+	case *syntax.SpreadExpr:
+		start, _ := node.Span()
+		return nil, thread.errorAt(start, fmt.Errorf("%s: spread is only allowed in call arguments", node))
+
+	case *syntax.AwaitExpr:
+		val, err := eval(thread, node.Value, env)
+		if err != nil {
+			return nil, err
+		}
+		if val, err = await(val); err != nil {
+			return nil, thread.errorAt(node.Pos, err)
+		}
+		return val, nil
+
+	case *syntax.MacroLiteral:
+		return &Macro{Params: node.Params, Body: node.Body, Env: env}, nil
 
 	case *syntax.CallExpr:
-		function, err := Eval(node.Function, env)
+		if ident, ok := node.Function.(*syntax.Identifier); ok && ident.Value == "quote" {
+			return evalQuote(thread, node, env)
+		}
+		function, err := eval(thread, node.Function, env)
 		if err != nil {
 			return nil, err
 		}
 
 		// 对参数求值
-		args, err := evalExprs(node.Args, env)
+		args, err := evalArgs(thread, node.Args, env)
 		if err != nil {
 			return nil, err
 		}
 
 		// 函数调用
-		return Call(function, args...)
+		start, _ := node.Span()
+		val, err := call(thread, Frame{Name: node.Function.String(), Pos: start}, function, args)
+		if err != nil {
+			return nil, thread.errorAt(start, err)
+		}
+		return val, nil
 
 	}
 	return Null, nil
 }
 
-func evalProgram(program *syntax.Program, env *Env) (_ Value, err error) {
+func evalProgram(thread *Thread, program *syntax.Program, env *Env) (_ Value, err error) {
+	// 执行前先展开宏
+	if err := expandMacros(thread, program, env); err != nil {
+		return nil, err
+	}
+
 	var value Value = Null
 	for _, stmt := range program.Stmts {
-		value, err = Eval(stmt, env)
-		if err != nil {
+		if value, err = evalTopLevelStmt(thread, stmt, env); err != nil {
 			return nil, err
 		}
 		// return 则提前返回，不再往后执行
@@ -145,11 +444,30 @@ func evalProgram(program *syntax.Program, env *Env) (_ Value, err error) {
 	return value, nil
 }
 
-func evalBlockStmt(block *syntax.BlockStmt, env *Env) (_ Value, err error) {
-	var value Value
+// 执行程序顶层的一条语句，return 语句的结果仍然是 *returnValue
+func evalTopLevelStmt(thread *Thread, stmt syntax.Stmt, env *Env) (Value, error) {
+	if err := thread.beforeStmt(stmt, env); err != nil {
+		return nil, err
+	}
+	value, err := eval(thread, stmt, env)
+	thread.executed(stmt, err)
+	if err != nil {
+		thread.fail(env)
+		return nil, err
+	}
+	return value, nil
+}
+
+func evalBlockStmt(thread *Thread, block *syntax.BlockStmt, env *Env) (_ Value, err error) {
+	var value Value = Null
 	for _, stmt := range block.Stmts {
-		value, err = Eval(stmt, env)
+		if err := thread.beforeStmt(stmt, env); err != nil {
+			return nil, err
+		}
+		value, err = eval(thread, stmt, env)
+		thread.executed(stmt, err)
 		if err != nil {
+			thread.fail(env)
 			return nil, err
 		}
 
@@ -161,35 +479,90 @@ func evalBlockStmt(block *syntax.BlockStmt, env *Env) (_ Value, err error) {
 	return value, nil
 }
 
-func evalExprs(exprs []syntax.Expr, env *Env) (_ []Value, err error) {
-	var values = make([]Value, len(exprs))
-	for i, expr := range exprs {
-		values[i], err = Eval(expr, env)
+// 对调用的实参求值，xs... 形式的实参展开为数组中的各个元素
+func evalArgs(thread *Thread, exprs []syntax.Expr, env *Env) (_ []Value, err error) {
+	var values = make([]Value, 0, len(exprs))
+	for _, expr := range exprs {
+		spread, ok := expr.(*syntax.SpreadExpr)
+		if !ok {
+			val, err := eval(thread, expr, env)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, val)
+			continue
+		}
+		val, err := eval(thread, spread.Value, env)
 		if err != nil {
 			return nil, err
 		}
+		arr, ok := val.(*Array)
+		if !ok {
+			start, _ := spread.Span()
+			return nil, thread.errorAt(start, fmt.Errorf("cannot spread %s in call arguments, expected array", val.Type()))
+		}
+		values = append(values, arr.items...)
 	}
 	return values, nil
 }
 
-func evalMapLiteral(node *syntax.MapLiteral, env *Env) (_ Value, err error) {
-	entries := make(map[uint32]MapEntry)
-	for keyNode, valNode := range node.Pairs {
-		key, err := Eval(keyNode, env)
+func evalMapLiteral(thread *Thread, node *syntax.MapLiteral, env *Env) (_ Value, err error) {
+	m := newMap(len(node.Pairs))
+	for _, pair := range node.Pairs {
+		key, err := eval(thread, pair.Key, env)
 		if err != nil {
 			return nil, err
 		}
-		hash, err := key.Hash()
-		if err != nil {
-			return nil, err
+		if _, err := key.Hash(); err != nil {
+			start, _ := pair.Key.Span()
+			return nil, thread.errorAt(start, err)
 		}
-		val, err := Eval(valNode, env)
+		val, err := eval(thread, pair.Value, env)
 		if err != nil {
 			return nil, err
 		}
-		entries[hash] = MapEntry{Key: key, Value: val}
+		m.SetKey(key, val)
+	}
+	if err := thread.allocate(m); err != nil {
+		start, _ := node.Span()
+		return nil, thread.errorAt(start, err)
+	}
+	return m, nil
+}
+
+// 求值 left[start:end]，start 或 end 为 nil 表示省略。
+// 负数的下标与索引一样从末尾开始计算，超出范围的下标被截断到 [0, len]。
+func evalSliceExpr(left, start, end Value) (Value, error) {
+	seq, ok := left.(Sliceable)
+	if !ok {
+		return nil, fmt.Errorf("slice operator not supported: %s", left.Type())
+	}
+	n := seq.Len()
+	bound := func(v Value, def int) (int, error) {
+		if v == nil {
+			return def, nil
+		}
+		i, ok := v.(Int)
+		if !ok {
+			return 0, fmt.Errorf("invalid slice index type: %s", v.Type())
+		}
+		if i < 0 {
+			i += Int(n)
+		}
+		return int(max(0, min(i, Int(n)))), nil
+	}
+	lo, err := bound(start, 0)
+	if err != nil {
+		return nil, err
+	}
+	hi, err := bound(end, n)
+	if err != nil {
+		return nil, err
 	}
-	return &Map{entries: entries}, nil
+	if hi < lo {
+		hi = lo
+	}
+	return seq.Slice(lo, hi), nil
 }
 
 func parseIndexExpr(left, index Value) (_ Value, err error) {
@@ -211,6 +584,9 @@ func parseIndexExpr(left, index Value) (_ Value, err error) {
 			if iv < 0 {
 				iv = Int(left.Len()) + (iv)
 			}
+			if iv < 0 || int(iv) >= left.Len() {
+				return nil, fmt.Errorf("index out of range: %s with length %d", index, left.Len())
+			}
 			return left.Index(int(iv)), nil
 		}
 	}
@@ -250,6 +626,17 @@ func Binary(op syntax.Token, x, y Value) (_ Value, err error) {
 }
 
 func Compare(op syntax.Token, x, y Value) (_ Value, err error) {
+	// 整数与浮点数比较时按浮点数比较
+	if xf, ok := x.(Float); ok {
+		if _, ok := y.(Int); ok {
+			return xf.Compare(op, y)
+		}
+	}
+	if yf, ok := y.(Float); ok {
+		if xi, ok := x.(Int); ok {
+			return Float(xi).Compare(op, yf)
+		}
+	}
 	if isSameType(x, y) {
 		if x, ok := x.(Comparable); ok {
 			return x.Compare(op, y)
@@ -265,28 +652,78 @@ func Compare(op syntax.Token, x, y Value) (_ Value, err error) {
 	return nil, fmt.Errorf("invalid cmp operator: %s %s %s", x, syntax.EQ, y)
 }
 
-func Call(value Value, args ...Value) (_ Value, err error) {
+// Call 在 thread 中以 args 为参数调用 value
+func Call(thread *Thread, value Value, args ...Value) (_ Value, err error) {
+	defer recoverPanic(&err)
+	if thread == nil || value == nil {
+		return nil, runtimeError{fmt.Errorf("Call: nil thread or value")}
+	}
+	var name = value.Type()
 	switch value := value.(type) {
+	case Callable:
+		name = value.Name()
 	case *Function:
-		// 扩展函数 env
-		fnEnv := NewEnv(value.Env)
-		for idx, param := range value.Params {
-			fnEnv.Set(param.Value, args[idx])
+		if value.Name != "" {
+			name = value.Name
 		}
+	}
+	return call(thread, Frame{Name: name}, value, args)
+}
+
+func call(thread *Thread, frame Frame, value Value, args []Value) (_ Value, err error) {
+	if len(thread.stack) >= maxCallDepth {
+		return nil, exhausted("maximum call depth (%d) exceeded", maxCallDepth)
+	}
+	switch value := value.(type) {
+	case *Function:
+		if err := value.checkArgs(len(args)); err != nil {
+			return nil, err
+		}
+		if value.Async {
+			return startAsync(thread, frame, value, args), nil
+		}
+		// 扩展函数 env。不创建闭包的函数在调用结束后没有值引用它的作用域，
+		// 作用域可以放回池中复用；调试器会保存各帧的作用域，此时不复用
+		pooled := value.noClosures && thread.Debugger == nil
+		var fnEnv *Env
+		if pooled {
+			fnEnv = getEnv(value.Env)
+		} else {
+			fnEnv = NewEnv(value.Env)
+		}
+		value.bindArgs(fnEnv, args)
+		frame.Env = fnEnv
+		thread.push(frame)
+		defer thread.pop()
 		// 执行函数体
-		result, err := evalBlockStmt(value.Body, fnEnv)
+		result, err := evalBlockStmt(thread, value.Body, fnEnv)
 		if err != nil {
+			// 出错时 FailedEnv 可能引用 fnEnv，不能复用
 			return nil, err
 		}
+		if pooled && thread.failedEnv != fnEnv {
+			putEnv(fnEnv)
+		}
 		// 对返回值解包
 		if rv, ok := result.(*returnValue); ok {
 			return rv.Value, nil
 		}
-		return value, nil
+		return result, nil
 
 	case *BuiltinFunction:
-		return value.CallInternal(args...)
+		if thread.Hooks != nil && thread.Hooks.OnBuiltinCall != nil {
+			if err := thread.Hooks.OnBuiltinCall(thread, value.Name(), args); err != nil {
+				return nil, err
+			}
+		}
+		thread.push(frame)
+		defer thread.pop()
+		return allocated(thread)(value.CallInternal(thread, args...))
 
+	case Callable:
+		thread.push(frame)
+		defer thread.pop()
+		return allocated(thread)(value.CallInternal(thread, args...))
 	}
 	return nil, fmt.Errorf("invalid call of non-function (%s)", value.Type())
 }
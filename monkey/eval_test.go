@@ -0,0 +1,325 @@
+package monkey
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+func TestExecReader(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		output   string
+	}{
+		{"let x = 1;\nprint(x + 1);\nx * 10", "10", "2\n"},
+		{"let m = macro(a) { quote(unquote(a) * 2) };\nm(21)", "42", ""},
+		{"print(\"a\");\nreturn 5;\nprint(\"b\")", "5", "a\n"},
+		{"fn f(n) {\n\tif (n == 0) { 0 } else { n + f(n - 1) }\n}\nf(10)", "55", ""},
+		{"", "null", ""},
+		// 出错之前的语句已经执行
+		{"print(\"a\");\nlet = 1;\nprint(\"b\")", "main.mky:2:5 expected next token", "a\n"},
+		{"print(\"a\");\n1 / 0;\nprint(\"b\")", "main.mky:2:3 division by zero", "a\n"},
+		// 宏只能在定义之后使用
+		{"m(1);\nlet m = macro(a) { a };", "identifier not found: m", ""},
+	}
+	for _, tt := range tests {
+		var output strings.Builder
+		thread := &Thread{Name: "main.mky", Print: func(thread *Thread, msg string) { output.WriteString(msg) }}
+		val, err := ExecReader(thread, "main.mky", strings.NewReader(tt.input), NewEnv(nil))
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%q: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+		if output.String() != tt.output {
+			t.Errorf("%q: expected output %q. got=%q", tt.input, tt.output, output.String())
+		}
+	}
+}
+
+func TestExecReaderHooks(t *testing.T) {
+	var events []string
+	hooks := &Hooks{
+		OnParse: func(thread *Thread, filename string, d time.Duration, err error) {
+			events = append(events, "parse "+filename)
+		},
+		OnEvalStart: func(thread *Thread) { events = append(events, "start") },
+		OnEvalEnd:   func(thread *Thread, d time.Duration, err error) { events = append(events, "end") },
+	}
+	if _, err := ExecReader(&Thread{Hooks: hooks}, "main.mky", strings.NewReader("1;\n2;\n3"), NewEnv(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(events, ", "); got != "start, parse main.mky, end" {
+		t.Errorf("wrong hook events. got=%q", got)
+	}
+}
+
+// 求值手工构造的、缺少字段的语法树不会 panic
+func TestEvalMalformedAST(t *testing.T) {
+	nodes := []syntax.Node{
+		&syntax.Program{Stmts: []syntax.Stmt{nil}},
+		&syntax.LetStmt{},
+		&syntax.ReturnStmt{},
+		&syntax.ExprStmt{},
+		&syntax.PrefixExpr{},
+		&syntax.InfixExpr{},
+		&syntax.IfExpr{},
+		&syntax.FunctionLiteral{},
+		&syntax.CallExpr{},
+		&syntax.CallExpr{Function: &syntax.FunctionLiteral{}},
+		&syntax.ArrayLiteral{Items: []syntax.Expr{nil}},
+		&syntax.MapLiteral{Pairs: []syntax.MapPair{{}}},
+		&syntax.IndexExpr{},
+		&syntax.SliceExpr{},
+		&syntax.DotExpr{},
+		&syntax.RecordStmt{},
+		&syntax.ImportStmt{},
+		&syntax.FromImportStmt{},
+		&syntax.AwaitExpr{},
+		&syntax.SpreadExpr{},
+		(*syntax.Program)(nil),
+	}
+	for _, node := range nodes {
+		_, err := Eval(node, NewEnv(nil))
+		if err != nil && !errors.Is(err, ErrRuntime) {
+			t.Errorf("%T: expected runtime error, got %v", node, err)
+		}
+	}
+
+	if _, err := EvalThread(nil, &syntax.Program{}, NewEnv(nil)); err == nil {
+		t.Errorf("expected error for nil thread")
+	}
+	if _, err := Eval(&syntax.Program{}, nil); err == nil {
+		t.Errorf("expected error for nil env")
+	}
+	if _, err := Call(new(Thread), nil); err == nil {
+		t.Errorf("expected error for nil function")
+	}
+}
+
+// 没有 return 的函数返回函数体中最后一个表达式的值，而不是函数本身
+func TestCallResult(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`fn(a, b) { a + b }`, "3"},
+		{`fn(a, b) { let sum = a + b; sum * 10 }`, "30"},
+		{`fn(a, b) { if (a < b) { return b } a }`, "2"},
+		{`fn(a, b) { if (a > b) { a } else { b } }`, "2"},
+	}
+	for _, tt := range tests {
+		fn, err := EvalExprString(tt.input, NewEnv(nil))
+		if err != nil {
+			t.Fatalf("%s: %s", tt.input, err)
+		}
+		val, err := Call(new(Thread), fn, Int(1), Int(2))
+		if err != nil {
+			t.Errorf("%s: %s", tt.input, err)
+		} else if val.String() != tt.expected {
+			t.Errorf("%s: expected %s. got=%s", tt.input, tt.expected, val)
+		}
+	}
+}
+
+func TestPanicError(t *testing.T) {
+	env := NewEnv(nil)
+	env.Set("boom", NewBuiltinFunction("boom", func(thread *Thread, args ...Value) (Value, error) {
+		panic("boom")
+	}))
+	tests := []string{
+		`boom()`,
+		`let f = async fn() { boom() }; await f()`,
+		`with_group(fn(g) { g["spawn"](boom) })`,
+	}
+	for _, input := range tests {
+		program, err := syntax.NewParser(input).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Eval(program, env)
+		var panicErr *PanicError
+		if !errors.As(err, &panicErr) || panicErr.Value != "boom" || !errors.Is(err, ErrRuntime) {
+			t.Errorf("%s: expected PanicError, got %v", input, err)
+		}
+	}
+}
+
+// 单条语句执行的次数超过 n 时终止求值，用于让模糊测试中的死循环结束
+type stepLimit struct {
+	n int
+}
+
+func (s *stepLimit) BeforeStmt(thread *Thread, stmt syntax.Stmt, env *Env) error {
+	if s.n--; s.n < 0 {
+		return errors.New("step limit exceeded")
+	}
+	return nil
+}
+
+func FuzzEval(f *testing.F) {
+	files, _ := filepath.Glob("testdata/*.mky")
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(src))
+	}
+	seeds := []string{
+		`let f = fn(n) { if (n < 2) { n } else { f(n - 1) + f(n - 2) } }; f(10)`,
+		`let m = {"a": [1, 2.5, "x"]}; m["a"][1:] + [m["b"]]`,
+		`record P {x, y}; let p = P(1, 2); p.x * p["y"]`,
+		`let unless = macro(c, a, b) { quote(if (!(unquote(c))) { unquote(a) } else { unquote(b) }) }; unless(1 > 2, "a", "b")`,
+		`try(fn() { [][1] })["error"]`,
+		`sprintf("%05.1f|%-3s|%q", 1.5, "a", "b")`,
+		`re_replace("(\\w)", "ab", "$1$1")`,
+		`let f = fn(a, b...) { [a, b] }; f(1, [2, 3]...)`,
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		// 跳过会并发执行（不受步数限制）、访问文件或网络、读取标准输入的程序
+		for _, name := range []string{"spawn", "async", "worker", "import", "input", "confirm", "select", "password"} {
+			if strings.Contains(input, name) {
+				t.Skip()
+			}
+		}
+		program, err := syntax.NewParser(input).Parse()
+		if err != nil {
+			return
+		}
+		thread := &Thread{Debugger: &stepLimit{n: 10000}, Provider: &fixedProvider{seed: 1}}
+		_, err = EvalThread(thread, program, NewEnv(nil))
+		var panicErr *PanicError
+		if errors.As(err, &panicErr) {
+			t.Fatalf("%s\n%s", panicErr, panicErr.Stack)
+		}
+		if err != nil && !errors.Is(err, ErrRuntime) && !errors.Is(err, ErrResourceExhausted) && !errors.Is(err, ErrCancelled) {
+			t.Errorf("unclassified error: %v", err)
+		}
+	})
+}
+
+func TestMaxSteps(t *testing.T) {
+	tests := []struct {
+		input    string
+		maxSteps int64
+		expected string
+	}{
+		{`let f = fn(n) { if (n > 0) { f(n - 1) } else { "done" } }; f(10)`, 100, "done"},
+		{`let f = fn(n) { if (n > 0) { f(n - 1) } else { "done" } }; f(1000)`, 100, "step limit (100) exceeded"},
+		{`let f = fn() { f() }; try(f)`, 100, "step limit (100) exceeded"},
+		// eval 中执行的语句使用同一个预算
+		{`eval("let f = fn(n) { if (n > 0) { f(n - 1) } }; f(1000)")`, 100, "step limit (100) exceeded"},
+		{`await (async fn() { let f = fn(n) { if (n > 0) { f(n - 1) } }; f(1000) })()`, 100, "step limit (100) exceeded"},
+	}
+	for _, tt := range tests {
+		program, err := syntax.NewParser(tt.input).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		thread := &Thread{MaxSteps: tt.maxSteps, Policy: NewPolicy(CapEval)}
+		// 每次求值有独立的预算
+		for i := 0; i < 2; i++ {
+			val, err := EvalThread(thread, program, NewEnv(nil))
+			var got string
+			if err != nil {
+				got = err.Error()
+				if !errors.Is(err, ErrResourceExhausted) {
+					t.Errorf("%s: expected ErrResourceExhausted, got %v", tt.input, err)
+				}
+			} else {
+				got = val.String()
+			}
+			if !strings.Contains(got, tt.expected) {
+				t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+			}
+		}
+	}
+}
+
+func TestMaxAlloc(t *testing.T) {
+	tests := []struct {
+		input    string
+		maxAlloc int64
+		expected string
+	}{
+		{`let f = fn(s, n) { if (n > 0) { f(s + s, n - 1) } else { len(s) } }; f("ab", 4)`, 1000, "32"},
+		{`let f = fn(s, n) { if (n > 0) { f(s + s, n - 1) } else { len(s) } }; f("ab", 20)`, 1000, "memory limit (1000 bytes) exceeded"},
+		{`let f = fn(arr, n) { if (n > 0) { f(push(arr, arr), n - 1) } else { len(arr) } }; f([1, 2, 3], 100)`, 1000, "memory limit (1000 bytes) exceeded"},
+		{`let f = fn(n) { if (n > 0) { {"a": [1, 2, 3]}; f(n - 1) } }; f(100)`, 1000, "memory limit (1000 bytes) exceeded"},
+		{`"abcdef"[0:3] + "xyz"`, 100, "abcxyz"},
+		// 任务中的分配使用同一个预算
+		{`await (async fn() { let f = fn(s) { f(s + s) }; f("ab") })()`, 1000, "memory limit (1000 bytes) exceeded"},
+	}
+	for _, tt := range tests {
+		program, err := syntax.NewParser(tt.input).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		thread := &Thread{MaxAlloc: tt.maxAlloc}
+		// 每次求值有独立的预算
+		for i := 0; i < 2; i++ {
+			val, err := EvalThread(thread, program, NewEnv(nil))
+			var got string
+			if err != nil {
+				got = err.Error()
+				if !errors.Is(err, ErrResourceExhausted) {
+					t.Errorf("%s: expected ErrResourceExhausted, got %v", tt.input, err)
+				}
+			} else {
+				got = val.String()
+			}
+			if !strings.Contains(got, tt.expected) {
+				t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+			}
+		}
+	}
+}
+
+func TestEvalExprString(t *testing.T) {
+	env := NewEnv(nil)
+	env.Set("x", Int(2))
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`x * 10`, "20"},
+		{`[x, x + 1][1]`, "3"},
+		{`fn(a) { return a + x }(1)`, "3"},
+		{`x;`, "2"},
+		{`let y = 1`, "expected an expression, got let statement"},
+		{`return x`, "expected an expression, got return statement"},
+		{`x; x`, "1:4 expected a single expression"},
+		{``, "expected an expression, got nothing"},
+		{`x +`, "no prefix parse function"},
+		{`y`, "identifier not found: y"},
+	}
+	for _, tt := range tests {
+		val, err := EvalExprString(tt.input, env)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+	if _, ok := env.Get("y"); ok {
+		t.Errorf("EvalExprString defined a variable")
+	}
+}
@@ -0,0 +1,146 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// 求值返回给宿主程序的错误都包装了以下错误之一，可以用 errors.Is 区分错误的种类而不必匹配错误信息：
+//
+//	if errors.Is(err, monkey.ErrResourceExhausted) { ... }
+//
+// 超出资源限制的错误同时也是运行时错误；import 的模块中的语法错误是运行时错误，同时也包装了 ErrParse。
+var (
+	ErrParse             = syntax.ErrParse                  // 语法错误
+	ErrRuntime           = errors.New("runtime error")      // 脚本在运行时出错，通常可以用 errors.As 得到 *EvalError
+	ErrCancelled         = errors.New("cancelled")          // 求值被取消
+	ErrResourceExhausted = errors.New("resource exhausted") // 超出了调用深度等资源限制
+)
+
+// EvalError 是求值过程中发生的运行时错误，记录了出错的位置和当时的调用栈
+type EvalError struct {
+	Pos   syntax.Position
+	Msg   string
+	Stack []Frame // 出错时的调用栈，最外层的调用在前，在顶层出错时为空
+
+	cause error
+}
+
+// Error implements error.
+func (e *EvalError) Error() string {
+	return fmt.Sprintf("%v %s", e.Pos, e.Msg)
+}
+
+// Unwrap 返回引起该错误的原始错误
+func (e *EvalError) Unwrap() error {
+	return e.cause
+}
+
+// Is 使 errors.Is(e, ErrRuntime) 成立
+func (e *EvalError) Is(target error) bool {
+	return target == ErrRuntime
+}
+
+// Backtrace 返回错误信息和调用栈，最内层的调用在前：
+//
+//	main.mky:2:14 identifier not found: y
+//		at f (main.mky:4:1)
+func (e *EvalError) Backtrace() string {
+	var out strings.Builder
+	out.WriteString(e.Error())
+	for i := len(e.Stack) - 1; i >= 0; i-- {
+		fmt.Fprintf(&out, "\n\tat %s (%v)", e.Stack[i].Name, e.Stack[i].Pos)
+	}
+	return out.String()
+}
+
+// 将 pos 处发生的错误包装为 EvalError。已经是 EvalError 的错误保留最初的位置，
+// 任务被取消的错误不是运行时错误，保持原样。
+func (t *Thread) errorAt(pos syntax.Position, err error) error {
+	var evalErr *EvalError
+	if errors.As(err, &evalErr) || errors.Is(err, errCancelled) {
+		return err
+	}
+	return &EvalError{Pos: pos, Msg: err.Error(), Stack: t.CallStack(), cause: err}
+}
+
+// 超出资源限制的错误，错误信息与 fmt.Errorf 相同
+type exhaustedError struct {
+	msg string
+}
+
+func exhausted(format string, args ...any) error {
+	return &exhaustedError{fmt.Sprintf(format, args...)}
+}
+
+// Error implements error.
+func (e *exhaustedError) Error() string {
+	return e.msg
+}
+
+// Is 使 errors.Is(e, ErrResourceExhausted) 成立
+func (e *exhaustedError) Is(target error) bool {
+	return target == ErrResourceExhausted
+}
+
+// 没有位置的运行时错误，例如宏展开时的错误
+type runtimeError struct {
+	error
+}
+
+// Unwrap 返回原始错误
+func (e runtimeError) Unwrap() error {
+	return e.error
+}
+
+// Is 使 errors.Is(e, ErrRuntime) 成立
+func (e runtimeError) Is(target error) bool {
+	return target == ErrRuntime
+}
+
+// 保证返回给宿主程序的错误包装了 ErrParse、ErrRuntime 等错误之一
+func classifyError(err error) error {
+	switch {
+	case err == nil,
+		errors.Is(err, ErrParse),
+		errors.Is(err, ErrRuntime),
+		errors.Is(err, ErrCancelled),
+		errors.Is(err, ErrResourceExhausted):
+		return err
+	}
+	return runtimeError{err}
+}
+
+// PanicError 是求值时发生的意外 panic 转换成的错误。它通常表示解释器或宿主程序注册的
+// Go 函数中的缺陷，而不是脚本的错误。导出的求值函数不会让 panic 越过包的边界。
+type PanicError struct {
+	Value any    // panic 的参数
+	Stack []byte // 发生 panic 的 goroutine 的调用栈
+}
+
+// Error implements error.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("internal error: %v", e.Value)
+}
+
+// Unwrap 在 panic 的参数是 error 时返回它
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// Is 使 errors.Is(e, ErrRuntime) 成立
+func (e *PanicError) Is(target error) bool {
+	return target == ErrRuntime
+}
+
+// 将意外的 panic 转换为 PanicError 保存到 *err，用于导出的函数和新启动的 goroutine
+func recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = &PanicError{Value: r, Stack: debug.Stack()}
+	}
+}
@@ -0,0 +1,98 @@
+package monkey
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+func TestEvalErrorPosition(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"foo", "main.mky:1:1 identifier not found: foo"},
+		{"let x = 1;\nx + bar", "main.mky:2:5 identifier not found: bar"},
+		{"1 + true", "main.mky:1:3 unknown binary operator"},
+		{"-true", "main.mky:1:1 unknown unary operator: -"},
+		{"let f = fn() { 1 / 0 };\nf()", "main.mky:1:18 division by zero: 1 / 0"},
+		{"{[1]: 2}", "main.mky:1:2 unhashable type: array"},
+		{"len(1, 2)", "main.mky:1:1 wrong number of arguments"},
+		{"try(fn() { x })[\"error\"][\"message\"]", "main.mky:1:12 identifier not found: x"},
+	}
+
+	for _, tt := range tests {
+		program, err := syntax.NewFileParser("main.mky", tt.input).Parse()
+		if err != nil {
+			t.Fatalf("parser error: %s", err)
+		}
+		val, err := EvalThread(&Thread{}, program, NewEnv(nil))
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestEvalErrorStack(t *testing.T) {
+	input := `let f = fn(x) {
+  x + y
+};
+let g = fn() { f(1) };
+g()`
+	program, err := syntax.NewFileParser("main.mky", input).Parse()
+	if err != nil {
+		t.Fatalf("parser error: %s", err)
+	}
+	_, err = EvalThread(&Thread{}, program, NewEnv(nil))
+	var evalErr *EvalError
+	if !errors.As(err, &evalErr) {
+		t.Fatalf("expected *EvalError. got=%T (%v)", err, err)
+	}
+	if evalErr.Msg != "identifier not found: y" {
+		t.Errorf("wrong message. got=%q", evalErr.Msg)
+	}
+	expected := "main.mky:2:7 identifier not found: y\n\tat f (main.mky:4:16)\n\tat g (main.mky:5:1)"
+	if evalErr.Backtrace() != expected {
+		t.Errorf("wrong backtrace.\nexpected=%q\ngot=%q", expected, evalErr.Backtrace())
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	kinds := []error{ErrParse, ErrRuntime, ErrCancelled, ErrResourceExhausted}
+	tests := []struct {
+		input string
+		kinds []error
+	}{
+		{`let = 1;`, []error{ErrParse}},
+		{`x`, []error{ErrRuntime}},
+		{`let m = macro(x) { x }; m(1, 2)`, []error{ErrRuntime}},
+		{`let f = fn() { f() }; f()`, []error{ErrRuntime, ErrResourceExhausted}},
+		{`with_group(fn(g) { g["spawn"](fn() { 1 + x }) })`, []error{ErrRuntime}},
+	}
+	for _, tt := range tests {
+		_, err := ExecReader(new(Thread), "test.mky", strings.NewReader(tt.input), NewEnv(nil))
+		if err == nil {
+			t.Fatalf("%s: expected error", tt.input)
+		}
+		for _, kind := range kinds {
+			want := false
+			for _, k := range tt.kinds {
+				want = want || k == kind
+			}
+			if errors.Is(err, kind) != want {
+				t.Errorf("%s: errors.Is(%q, %v) = %v", tt.input, err, kind, !want)
+			}
+		}
+	}
+	if !errors.Is(errCancelled, ErrCancelled) {
+		t.Errorf("errCancelled does not wrap ErrCancelled")
+	}
+}
@@ -0,0 +1,241 @@
+package monkey
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LockfileName 是记录远程模块校验和的锁文件的默认名称
+const LockfileName = "monkey.lock"
+
+// FindLockfile 返回 dir 所在项目的锁文件，即项目根目录（见 FindProjectRoot）下的 monkey.lock；
+// dir 不在任何项目中时返回 dir 下的 monkey.lock
+func FindLockfile(dir string) string {
+	if root, ok := FindProjectRoot(dir); ok {
+		return filepath.Join(root, LockfileName)
+	}
+	return filepath.Join(dir, LockfileName)
+}
+
+// DefaultCacheDir 返回远程模块的默认缓存目录：
+// 环境变量 MONKEYCACHE 指定的目录，或用户缓存目录下的 monkey/pkg
+func DefaultCacheDir() (string, error) {
+	if dir := os.Getenv("MONKEYCACHE"); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "monkey", "pkg"), nil
+}
+
+// RegistryPrefix 是注册表路径的前缀，例如 pkg:example.com/user/lib.mky
+const RegistryPrefix = "pkg:"
+
+// 远程模块以 https URL（https://example.com/lib.mky）或
+// 以 pkg: 开头的注册表路径（pkg:example.com/user/lib.mky）表示，其他路径都是本地文件
+func isRemote(path string) bool {
+	return strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "http://") || strings.HasPrefix(path, RegistryPrefix)
+}
+
+// 返回远程模块的规范名称（去掉协议或前缀）和下载地址。
+// 注册表路径默认通过 https 下载，设置了 MONKEYPROXY 时从代理下载。
+func remoteLocation(path string) (name, url string) {
+	if name, ok := strings.CutPrefix(path, RegistryPrefix); ok {
+		if proxy := os.Getenv("MONKEYPROXY"); proxy != "" {
+			return name, strings.TrimSuffix(proxy, "/") + "/" + name
+		}
+		return name, "https://" + name
+	}
+	_, name, _ = strings.Cut(path, "://")
+	return name, path
+}
+
+// 检查远程模块的规范名称，名称中不能有空的段、. 或 ..，以免缓存文件被写到缓存目录之外
+func checkRemoteName(name string) error {
+	for _, seg := range strings.Split(name, "/") {
+		if seg == "" || seg == "." || seg == ".." || strings.Contains(seg, `\`) {
+			return fmt.Errorf("invalid remote module path %q", name)
+		}
+	}
+	return nil
+}
+
+// 返回远程模块在缓存目录中的文件路径，不在缓存中时下载它。
+// 模块内容的校验和必须与锁文件中记录的一致，锁文件中没有记录时则添加记录；
+// 下载的内容通过校验之后才会写入缓存。
+func (im *Importer) fetch(path string) (string, error) {
+	// http:// 与 https:// 的模块共用缓存和锁文件中的名称，不允许明文下载的内容占据它们
+	if strings.HasPrefix(path, "http://") {
+		return "", fmt.Errorf("insecure remote module %q, use https://", path)
+	}
+	name, url := remoteLocation(path)
+	if err := checkRemoteName(name); err != nil {
+		return "", err
+	}
+
+	dir := im.CacheDir
+	if dir == "" {
+		var err error
+		if dir, err = DefaultCacheDir(); err != nil {
+			return "", err
+		}
+	}
+	filename := filepath.Join(dir, filepath.FromSlash(name))
+	if rel, err := filepath.Rel(dir, filename); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid remote module path %q", name)
+	}
+
+	data, err := os.ReadFile(filename)
+	cached := err == nil
+	if os.IsNotExist(err) {
+		data, err = download(url)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if im.Lockfile != "" {
+		if err := verifyChecksum(im.Lockfile, name, data); err != nil {
+			return "", err
+		}
+	}
+	if !cached {
+		if err := writeFileAtomic(filename, data); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Abs(filename)
+}
+
+// 先写入同一目录下的临时文件再重命名，缓存中不会出现不完整的文件
+func writeFileAtomic(filename string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(filename), ".download-*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// 下载远程模块使用的客户端，整个请求（包括读取响应）的超时时间是 30 秒
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// 远程模块的大小上限
+var maxModuleSize int64 = 16 << 20
+
+func download(url string) ([]byte, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxModuleSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", url, err)
+	}
+	if int64(len(data)) > maxModuleSize {
+		return nil, fmt.Errorf("fetching %s: module is larger than %d bytes", url, maxModuleSize)
+	}
+	return data, nil
+}
+
+// Checksum 返回模块内容的校验和
+func Checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// 检查 data 的校验和是否与锁文件中 name 的记录一致，没有记录时写入锁文件
+func verifyChecksum(lockfile, name string, data []byte) error {
+	sums, err := ReadLockfile(lockfile)
+	if err != nil {
+		return err
+	}
+	sum := Checksum(data)
+	if want, ok := sums[name]; ok {
+		if want != sum {
+			return fmt.Errorf("checksum mismatch for %s\n\t%s: %s\n\tdownloaded: %s", name, lockfile, want, sum)
+		}
+		return nil
+	}
+	sums[name] = sum
+	return WriteLockfile(lockfile, sums)
+}
+
+// ReadLockfile 读取锁文件，每行的格式为 "<module> <checksum>"；文件不存在时返回空表
+func ReadLockfile(filename string) (map[string]string, error) {
+	sums := make(map[string]string)
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return sums, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: malformed line %q", filename, lineno, line)
+		}
+		sums[fields[0]] = fields[1]
+	}
+	return sums, scanner.Err()
+}
+
+// WriteLockfile 按模块名排序写入锁文件
+func WriteLockfile(filename string, sums map[string]string) error {
+	names := make([]string, 0, len(sums))
+	for name := range sums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&out, "%s %s\n", name, sums[name])
+	}
+	return os.WriteFile(filename, []byte(out.String()), 0o644)
+}
+
+// Fetch 将远程模块下载到缓存目录并记录到锁文件中，返回缓存文件的路径
+func (im *Importer) Fetch(path string) (string, error) {
+	if !isRemote(path) {
+		return "", fmt.Errorf("%s is not a remote module", path)
+	}
+	return im.fetch(path)
+}
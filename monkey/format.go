@@ -0,0 +1,100 @@
+package monkey
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// FormatOptions 控制 Format 的输出，零值表示不做限制并输出在一行中
+type FormatOptions struct {
+	MaxDepth int    // 最多展开的数组、map 和 record 的层数，更深的值输出为 [...] 或 {...}
+	MaxItems int    // 每个数组、map 或 record 最多输出的元素个数，其余的元素输出为 ...(n more)
+	Indent   string // 不为空时每个元素单独一行，并按所在的层数重复 Indent 缩进
+}
+
+// Format 按 opts 输出 v，不限制时与 v.String() 相同。
+// 嵌入者可以用它在日志等地方输出脚本的值而不必担心输出过长，
+// 引用自身的 map 也只输出为 {...} 而不会无限递归：
+//
+//	monkey.Format(val, monkey.FormatOptions{MaxDepth: 3, MaxItems: 10})
+func Format(v Value, opts FormatOptions) string {
+	f := &formatter{opts: opts, active: make(map[Value]bool)}
+	f.value(v, 0)
+	return f.out.String()
+}
+
+type formatter struct {
+	opts   FormatOptions
+	out    bytes.Buffer
+	active map[Value]bool // 正在输出的容器，用于发现循环引用
+}
+
+// 输出位于第 depth 层的值 v
+func (f *formatter) value(v Value, depth int) {
+	switch v := v.(type) {
+	case *Array:
+		f.container(v, "[", "]", len(v.items), depth, func(i int) {
+			f.value(v.items[i], depth+1)
+		})
+	case *Map:
+		items := v.Items()
+		f.container(v, "{", "}", len(items), depth, func(i int) {
+			f.value(items[i].Key, depth+1)
+			f.out.WriteString(": ")
+			f.value(items[i].Value, depth+1)
+		})
+	case *Record:
+		f.container(v, v.typ.name+"{", "}", len(v.values), depth, func(i int) {
+			f.out.WriteString(v.typ.fields[i])
+			f.out.WriteString(": ")
+			f.value(v.values[i], depth+1)
+		})
+	default:
+		f.out.WriteString(v.String())
+	}
+}
+
+// 输出有 n 个元素的容器 v，item 输出第 i 个元素
+func (f *formatter) container(v Value, open, close string, n, depth int, item func(i int)) {
+	if n == 0 {
+		f.out.WriteString(open + close)
+		return
+	}
+	if f.active[v] || (f.opts.MaxDepth > 0 && depth >= f.opts.MaxDepth) {
+		f.out.WriteString(open + "..." + close)
+		return
+	}
+	f.active[v] = true
+	defer delete(f.active, v)
+
+	shown := n
+	if f.opts.MaxItems > 0 && n > f.opts.MaxItems {
+		shown = f.opts.MaxItems
+	}
+	f.out.WriteString(open)
+	for i := 0; i < shown; i++ {
+		f.separator(i, depth)
+		item(i)
+	}
+	if shown < n {
+		f.separator(shown, depth)
+		fmt.Fprintf(&f.out, "...(%d more)", n-shown)
+	}
+	if f.opts.Indent != "" {
+		f.out.WriteString("\n" + strings.Repeat(f.opts.Indent, depth))
+	}
+	f.out.WriteString(close)
+}
+
+// 输出第 depth 层的容器中第 i 个元素之前的分隔符
+func (f *formatter) separator(i, depth int) {
+	if i > 0 {
+		f.out.WriteString(",")
+	}
+	if f.opts.Indent != "" {
+		f.out.WriteString("\n" + strings.Repeat(f.opts.Indent, depth+1))
+	} else if i > 0 {
+		f.out.WriteString(" ")
+	}
+}
@@ -0,0 +1,38 @@
+package monkey
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		input    string
+		opts     FormatOptions
+		expected string
+	}{
+		{`[1, [2, [3]], {"a": 4}]`, FormatOptions{}, `[1, [2, [3]], {a: 4}]`},
+		{`[1, [2, [3]], {"a": 4}]`, FormatOptions{MaxDepth: 2}, `[1, [2, [...]], {a: 4}]`},
+		{`[1, [2, [3]], {"a": 4}]`, FormatOptions{MaxDepth: 1}, `[1, [...], {...}]`},
+		{`[1, 2, 3, 4, 5]`, FormatOptions{MaxItems: 2}, `[1, 2, ...(3 more)]`},
+		{`{"a": 1, "b": 2}`, FormatOptions{MaxItems: 1}, `{a: 1, ...(1 more)}`},
+		{`[[], {}]`, FormatOptions{MaxDepth: 1}, `[[], {}]`},
+		{`record P { x, y }; P(1, [2])`, FormatOptions{MaxDepth: 1}, `P{x: 1, y: [...]}`},
+		{`"s"`, FormatOptions{MaxDepth: 1}, `s`},
+		{`[1, {"a": [2]}]`, FormatOptions{Indent: "  "}, "[\n  1,\n  {\n    a: [\n      2\n    ]\n  }\n]"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalThread(t, tt.input)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.input, err)
+		}
+		if got := Format(val, tt.opts); got != tt.expected {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestFormatCycle(t *testing.T) {
+	m := NewMap()
+	m.SetKey(String("self"), m)
+	if got := Format(NewArray([]Value{m}), FormatOptions{}); got != "[{self: {...}}]" {
+		t.Errorf("got=%q", got)
+	}
+}
@@ -0,0 +1,184 @@
+package monkey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+func init() {
+	Universe["with_group"] = NewBuiltinFunction("with_group", withGroup)
+}
+
+// 任务因为所在的组被取消而终止时返回的错误
+var errCancelled = fmt.Errorf("task %w", ErrCancelled)
+
+// cancelScope 是可以被取消的求值范围，取消外层范围时内层范围也视为已取消
+type cancelScope struct {
+	parent *cancelScope
+	done   chan struct{}
+	once   sync.Once
+	ctx    context.Context // 不为 nil 时，ctx 被取消也视为已取消
+}
+
+func newCancelScope(parent *cancelScope) *cancelScope {
+	return &cancelScope{parent: parent, done: make(chan struct{})}
+}
+
+func (s *cancelScope) cancel() {
+	s.once.Do(func() { close(s.done) })
+}
+
+func (s *cancelScope) cancelled() bool {
+	for ; s != nil; s = s.parent {
+		if s.ctx != nil && s.ctx.Err() != nil {
+			return true
+		}
+		select {
+		case <-s.done:
+			return true
+		default:
+		}
+	}
+	return false
+}
+
+// with_group(fn)
+//
+// 以一个任务组 g 调用 fn，fn 中可以通过 g["spawn"](f, args...) 启动并发执行的任务，
+// 任务中也可以继续启动新的任务。fn 返回后 with_group 等待组内所有任务结束，
+// 并按启动顺序返回它们的结果：
+//
+//	with_group(fn(g) {
+//	  g["spawn"](fetch, "a");
+//	  g["spawn"](fetch, "b");
+//	});  // [fetch("a"), fetch("b")]
+//
+// fn 或任意一个任务出错时，组内其他任务会在执行下一条语句之前被取消，
+// with_group 返回所有的错误（不包括被取消的任务）。
+func withGroup(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	// 任务共享调用者的 Importer，在启动任务之前创建以免并发初始化
+	thread.importer()
+	g := &Group{thread: thread, scope: newCancelScope(thread.cancel)}
+	_, err := Call(thread, args[0], g)
+	if err != nil {
+		g.scope.cancel()
+	}
+	g.wg.Wait()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.finished = true
+	if err != nil && !errors.Is(err, errCancelled) {
+		g.errs = append([]error{err}, g.errs...)
+	}
+	switch {
+	case len(g.errs) == 1:
+		return nil, g.errs[0]
+	case len(g.errs) > 1:
+		// errors.Is 和 errors.As 可以匹配其中任意一个错误
+		return nil, fmt.Errorf("%d errors in group:\n%w", len(g.errs), errors.Join(g.errs...))
+	case err != nil:
+		return nil, err
+	}
+	return NewArray(g.results), nil
+}
+
+// Group 是 with_group 传给 fn 的任务组
+type Group struct {
+	thread *Thread // 调用 with_group 的线程
+	scope  *cancelScope
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	results  []Value
+	errs     []error
+	finished bool
+}
+
+// Get implements Mapping.
+func (g *Group) Get(key Value) (_ Value, _ bool, err error) {
+	if key != String("spawn") {
+		return Null, false, nil
+	}
+	return NewBuiltinFunction("spawn", g.spawn), true, nil
+}
+
+// spawn(f, args...) 在新的 goroutine 中调用 f(args...)
+func (g *Group) spawn(thread *Thread, args ...Value) (Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want at least 1", len(args))
+	}
+	switch args[0].(type) {
+	case *Function, *BuiltinFunction:
+	default:
+		return nil, fmt.Errorf("first argument to `spawn` must be function, got %s", args[0].Type())
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.finished {
+		return nil, fmt.Errorf("spawn on a group that has already finished")
+	}
+	index := len(g.results)
+	g.results = append(g.results, Null)
+	g.wg.Add(1)
+
+	// 每个任务使用自己的线程，共享调用者的配置
+	task := &Thread{
+		Name:     g.thread.Name,
+		Provider: g.thread.Provider,
+		Print:    g.thread.Print,
+		Policy:   g.thread.Policy,
+		Importer: g.thread.Importer,
+		Hooks:    g.thread.Hooks,
+		Language: g.thread.Language,
+		cancel:   g.scope,
+		budget:   g.thread.budget,
+		file:     thread.file,
+	}
+	go func() {
+		defer g.wg.Done()
+		result, err := Call(task, args[0], args[1:]...)
+
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		switch {
+		case err == nil:
+			g.results[index] = result
+		case !errors.Is(err, errCancelled):
+			g.errs = append(g.errs, fmt.Errorf("task %d: %w", index, err))
+			g.scope.cancel()
+		}
+	}()
+	return Null, nil
+}
+
+// Hash implements Value.
+func (g *Group) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: group")
+}
+
+// String implements Value.
+func (g *Group) String() string {
+	return "<group>"
+}
+
+// Truth implements Value.
+func (g *Group) Truth() bool {
+	return true
+}
+
+// Type implements Value.
+func (g *Group) Type() string {
+	return "group"
+}
+
+var (
+	_ Value   = (*Group)(nil)
+	_ Mapping = (*Group)(nil)
+)
@@ -0,0 +1,103 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+func TestWithGroup(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`with_group(fn(g) { g["spawn"](fn(x) { x * 2 }, 1); g["spawn"](fn(x) { x * 2 }, 2) })`, "[2, 4]"},
+		{`with_group(fn(g) { 1 })`, "[]"},
+		{`let n = 10; with_group(fn(g) { g["spawn"](fn() { n + 1 }) })`, "[11]"},
+		{`with_group(fn(g) { g["spawn"](fn() { g["spawn"](fn() { 2 }); 1 }) })`, "[1, 2]"},
+		{`with_group(fn(g) { g["spawn"](fn() { 1 }); g["spawn"](fn() { x }) })`, "task 1: <invalid>:1:62 identifier not found: x"},
+		{`with_group(fn(g) { y })`, "identifier not found: y"},
+		{`with_group(fn(g) { g["spawn"](1) })`, "must be function"},
+		{`let h = 0; with_group(fn(g) { let h = g; 1 }); 1`, "1"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalPolicy(t, tt.input, nil)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestWithGroupCancel(t *testing.T) {
+	var marked atomic.Bool
+	env := NewEnv(nil)
+	// wait 阻塞到所在的组被取消
+	env.Set("wait", NewBuiltinFunction("wait", func(thread *Thread, args ...Value) (Value, error) {
+		for !thread.cancel.cancelled() {
+			time.Sleep(time.Millisecond)
+		}
+		return Null, nil
+	}))
+	env.Set("mark", NewBuiltinFunction("mark", func(thread *Thread, args ...Value) (Value, error) {
+		marked.Store(true)
+		return Null, nil
+	}))
+	env.Set("boom", NewBuiltinFunction("boom", func(thread *Thread, args ...Value) (Value, error) {
+		return nil, fmt.Errorf("boom")
+	}))
+
+	program, err := syntax.NewParser(`with_group(fn(g) {
+  g["spawn"](fn() { wait(); mark() });
+  g["spawn"](fn() { boom() });
+})`).Parse()
+	if err != nil {
+		t.Fatalf("parser error: %s", err)
+	}
+	_, err = EvalThread(&Thread{}, program, env)
+	if err == nil || err.Error() != "task 1: <invalid>:3:21 boom" {
+		t.Errorf("expected only the failing task's error. got=%v", err)
+	}
+	if marked.Load() {
+		t.Errorf("cancelled task kept running")
+	}
+}
+
+func TestWithGroupErrors(t *testing.T) {
+	// 两个任务都在对方出错之前开始执行，因此两个错误都会被返回
+	var started sync.WaitGroup
+	started.Add(2)
+	fail := func(err error) *BuiltinFunction {
+		return NewBuiltinFunction("fail", func(thread *Thread, args ...Value) (Value, error) {
+			started.Done()
+			started.Wait()
+			return nil, err
+		})
+	}
+	env := NewEnv(nil)
+	env.Set("exhaust", fail(exhausted("step limit (1) exceeded")))
+	env.Set("cancel", fail(fmt.Errorf("stopped: %w", ErrCancelled)))
+
+	program, err := syntax.NewParser(`with_group(fn(g) { g["spawn"](exhaust); g["spawn"](cancel) })`).Parse()
+	if err != nil {
+		t.Fatalf("parser error: %s", err)
+	}
+	_, err = EvalThread(&Thread{}, program, env)
+	if err == nil || !strings.Contains(err.Error(), "2 errors in group") {
+		t.Fatalf("expected two errors. got=%v", err)
+	}
+	if !errors.Is(err, ErrResourceExhausted) || !errors.Is(err, ErrCancelled) {
+		t.Errorf("errors of the tasks cannot be matched with errors.Is. got=%v", err)
+	}
+}
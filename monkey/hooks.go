@@ -0,0 +1,54 @@
+package monkey
+
+import (
+	"time"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// Hooks 是宿主程序的遥测回调，可以用来统计脚本的资源使用、实施配额或输出指标。
+// 所有回调都可以为 nil。with_group、async 函数和 worker 中的求值会使用同一个 Hooks，
+// 因此回调可能在多个 goroutine 中被同时调用。
+type Hooks struct {
+	// OnParse 在解释器解析一段源码之后被调用，包括 Parse、import 的模块、
+	// eval 和 parse 的参数、worker 的代码以及模板。filename 是源码的名称，
+	// 不是文件时为 "<eval>" 这样的描述，err 是解析的错误。
+	OnParse func(thread *Thread, filename string, d time.Duration, err error)
+
+	// OnEvalStart 和 OnEvalEnd 在 EvalThread 开始和结束时被调用，
+	// d 是求值花费的时间，err 是求值的错误
+	OnEvalStart func(thread *Thread)
+	OnEvalEnd   func(thread *Thread, d time.Duration, err error)
+
+	// OnBuiltinCall 在调用内置函数之前被调用，返回错误时不执行调用，该错误作为调用的结果
+	OnBuiltinCall func(thread *Thread, name string, args []Value) error
+
+	// OnStmt 在每条语句执行之后被调用，包括函数体和代码块中的语句，err 是语句的错误。
+	// 可以通过 stmt 的类型区分 let、return、表达式等语句，例如教学工具可以借此统计学生最常出错的语句。
+	// 内层的语句出错时，包含它的语句会以同一个错误再报告一次。回调只在宿主程序中执行，不会发送到任何地方
+	OnStmt func(thread *Thread, stmt syntax.Stmt, err error)
+}
+
+// Parse 解析名为 filename 的源码 src，并调用 thread 的 OnParse 钩子
+func Parse(thread *Thread, filename, src string) (*syntax.Program, error) {
+	start := time.Now()
+	parser := syntax.NewFileParser(filename, src)
+	parser.Options = thread.Language
+	program, err := parser.Parse()
+	thread.parsed(filename, start, err)
+	return program, err
+}
+
+// 报告一条执行完的语句
+func (t *Thread) executed(stmt syntax.Stmt, err error) {
+	if t.Hooks != nil && t.Hooks.OnStmt != nil {
+		t.Hooks.OnStmt(t, stmt, err)
+	}
+}
+
+// 报告一次从 start 开始的解析
+func (t *Thread) parsed(filename string, start time.Time, err error) {
+	if t.Hooks != nil && t.Hooks.OnParse != nil {
+		t.Hooks.OnParse(t, filename, time.Since(start), err)
+	}
+}
@@ -0,0 +1,104 @@
+package monkey
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+func TestHooks(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+	record := func(format string, args ...any) {
+		mu.Lock()
+		events = append(events, fmt.Sprintf(format, args...))
+		mu.Unlock()
+	}
+	hooks := &Hooks{
+		OnParse: func(thread *Thread, filename string, d time.Duration, err error) {
+			record("parse %s %v", filename, err != nil)
+		},
+		OnEvalStart: func(thread *Thread) {
+			record("start %s", thread.Name)
+		},
+		OnEvalEnd: func(thread *Thread, d time.Duration, err error) {
+			if d < 0 {
+				t.Errorf("negative eval duration %v", d)
+			}
+			record("end %s %v", thread.Name, err)
+		},
+		OnBuiltinCall: func(thread *Thread, name string, args []Value) error {
+			record("call %s %d", name, len(args))
+			if name == "random" {
+				return fmt.Errorf("quota exceeded for %s", name)
+			}
+			return nil
+		},
+	}
+
+	thread := &Thread{Name: "main", Hooks: hooks, Policy: NewPolicy(CapEval)}
+	program, err := Parse(thread, "main.mky", `len("abc") + eval("1 +")`)
+	if err != nil {
+		t.Fatalf("parser error: %s", err)
+	}
+	_, err = EvalThread(thread, program, NewEnv(nil))
+	if err == nil || !strings.Contains(err.Error(), "no prefix parse function") {
+		t.Fatalf("expected parse error from eval. got=%v", err)
+	}
+	expected := []string{
+		"parse main.mky false",
+		"start main",
+		"call len 1",
+		"call eval 1",
+		"parse <eval> true",
+		"end main " + err.Error(),
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("wrong events.\nexpected=%q\ngot=%q", expected, events)
+	}
+
+	// 钩子返回的错误会阻止调用，并被任务继承
+	events = nil
+	program, _ = Parse(thread, "main.mky", `with_group(fn(g) { g["spawn"](random) })`)
+	_, err = EvalThread(thread, program, NewEnv(nil))
+	if err == nil || !strings.Contains(err.Error(), "task 0: quota exceeded for random") {
+		t.Errorf("expected quota error. got=%v", err)
+	}
+}
+
+func TestOnStmt(t *testing.T) {
+	var events []string
+	hooks := &Hooks{
+		OnStmt: func(thread *Thread, stmt syntax.Stmt, err error) {
+			events = append(events, fmt.Sprintf("%T %v", stmt, err != nil))
+		},
+	}
+	thread := &Thread{Hooks: hooks}
+	program, err := Parse(thread, "main.mky", `let f = fn(x) { let y = x * 2; y / x }; f(2); f(0)`)
+	if err != nil {
+		t.Fatalf("parser error: %s", err)
+	}
+	_, err = EvalThread(thread, program, NewEnv(nil))
+	if err == nil || !strings.Contains(err.Error(), "division by zero") {
+		t.Fatalf("expected division by zero. got=%v", err)
+	}
+	expected := []string{
+		"*syntax.LetStmt false",
+		"*syntax.LetStmt false",
+		"*syntax.ExprStmt false",
+		"*syntax.ExprStmt false",
+		"*syntax.LetStmt false",
+		"*syntax.ExprStmt true",
+		"*syntax.ExprStmt true",
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("wrong events.\nexpected=%q\ngot=%q", expected, events)
+	}
+}
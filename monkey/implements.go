@@ -0,0 +1,69 @@
+package monkey
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func init() {
+	Universe["implements"] = NewBuiltinFunction("implements", implements)
+}
+
+// 脚本可以检查的能力，以及判断一个值是否具有该能力的函数
+var capabilities = map[string]func(Value) bool{
+	// 可以调用，例如函数、内置函数和记录类型
+	"callable": func(v Value) bool {
+		_, fn := v.(*Function)
+		_, callable := v.(Callable)
+		return fn || callable
+	},
+	// 支持 v[i] 整数下标和 len(v)，例如字符串和数组
+	"indexable": func(v Value) bool { _, ok := v.(Indexable); return ok },
+	// 支持 v[i:j] 切片
+	"sliceable": func(v Value) bool { _, ok := v.(Sliceable); return ok },
+	// 支持 v[key] 按键取值，例如 map、记录和模块
+	"mapping": func(v Value) bool { _, ok := v.(Mapping); return ok },
+	// 有确定的长度，可以逐个访问其中的元素
+	"iterable": func(v Value) bool {
+		_, indexable := v.(Indexable)
+		_, sequence := v.(Sequence)
+		return indexable || sequence
+	},
+	// 支持 == 和 != 比较
+	"comparable": func(v Value) bool {
+		_, comparable := v.(Comparable)
+		_, ordered := v.(TotallyOrdered)
+		return comparable || ordered
+	},
+	// 可以作为 map 的键
+	"hashable": func(v Value) bool { _, err := v.Hash(); return err == nil },
+}
+
+// implements(value, capability)
+//
+// 判断 value 是否具有名为 capability 的能力，通用的库可以据此处理不同类型的值，
+// 而不必枚举具体的类型：
+//
+//	if (implements(x, "callable")) { x() } else { x }
+//
+// capability 可以是 callable、comparable、hashable、indexable、iterable、mapping 或 sliceable。
+func implements(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	name, ok := args[1].(String)
+	if !ok {
+		return nil, fmt.Errorf("argument to `implements` must be string, got %s", args[1].Type())
+	}
+	check, ok := capabilities[string(name)]
+	if !ok {
+		names := make([]string, 0, len(capabilities))
+		for name := range capabilities {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown capability %q, want one of %s", name, strings.Join(names, ", "))
+	}
+	return Bool(check(args[0])), nil
+}
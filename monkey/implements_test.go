@@ -0,0 +1,45 @@
+package monkey
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImplements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`implements(len, "callable")`, "true"},
+		{`implements(fn() { 1 }, "callable")`, "true"},
+		{`record P {x}; implements(P, "callable")`, "true"},
+		{`implements(1, "callable")`, "false"},
+		{`implements("abc", "indexable")`, "true"},
+		{`implements([1], "sliceable")`, "true"},
+		{`implements({}, "indexable")`, "false"},
+		{`implements({}, "mapping")`, "true"},
+		{`record P {x}; implements(P(1), "mapping")`, "true"},
+		{`implements({}, "iterable")`, "true"},
+		{`implements(1, "iterable")`, "false"},
+		{`implements(1.5, "comparable")`, "true"},
+		{`implements([1], "comparable")`, "false"},
+		{`implements("a", "hashable")`, "true"},
+		{`implements([1], "hashable")`, "false"},
+		{`implements(len, "hashable")`, "false"},
+		{`implements(1, "number")`, `unknown capability "number", want one of callable, comparable, hashable, indexable, iterable, mapping, sliceable`},
+		{`implements(1, 2)`, "argument to `implements` must be string, got int"},
+		{`{fn() { 1 }: 1}`, "unhashable type: function"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalThread(t, tt.input)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package monkey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// Interpreter 是嵌入 Monkey 的入口，它保存全局作用域和执行的配置，
+// 多次调用 Run 共享同一个全局作用域：
+//
+//	in := monkey.NewInterpreter(
+//		monkey.WithGlobals(map[string]any{"user": user}),
+//		monkey.WithStdout(&buf),
+//		monkey.WithLimits(monkey.Limits{MaxSteps: 100000, Timeout: time.Second}),
+//	)
+//	value, err := in.Run(`"hello, " + user["Name"]`)
+//
+// Interpreter 同一时刻只能在一个 goroutine 中使用。
+type Interpreter struct {
+	filename string
+	env      *Env
+	thread   *Thread
+	limits   Limits
+	ctx      context.Context
+	err      error // 应用选项时发生的错误，由 Run 返回
+}
+
+// Option 是 NewInterpreter 和 Run 的选项
+type Option func(in *Interpreter)
+
+// Limits 是一次 Run 的执行限制，零值表示不限制。超出限制时 Run 返回包装了 ErrResourceExhausted 的错误。
+type Limits struct {
+	MaxSteps int64         // 最多执行的语句数，包括 async 函数和 with_group 的任务中执行的语句
+	MaxAlloc int64         // 最多分配的字符串、数组和 map 的近似字节数，见 Thread.MaxAlloc
+	Timeout  time.Duration // 最长的执行时间，超时后在下一条语句之前终止
+}
+
+// WithFilename 设置源码的文件名，用于错误的位置和解析相对路径的 import，默认为 "main.mky"
+func WithFilename(filename string) Option {
+	return func(in *Interpreter) {
+		in.filename = filename
+		in.thread.Name = filename
+	}
+}
+
+// WithGlobals 预先在全局作用域中定义 globals 中的变量，Go 的值由 FromGo 转换
+func WithGlobals(globals map[string]any) Option {
+	return func(in *Interpreter) {
+		for name, v := range globals {
+			val, err := FromGo(v)
+			if err != nil {
+				in.err = errors.Join(in.err, fmt.Errorf("global %s: %w", name, err))
+				continue
+			}
+			in.env.Set(name, val)
+		}
+	}
+}
+
+// WithBuiltin 在全局作用域中定义名为 name 的内置函数，它只对该 Interpreter 可见
+func WithBuiltin(name string, fn func(thread *Thread, args ...Value) (Value, error)) Option {
+	return func(in *Interpreter) {
+		in.env.Set(name, NewBuiltinFunction(name, fn))
+	}
+}
+
+// WithStdout 设置 print 等内置函数的输出，默认为标准输出
+func WithStdout(w io.Writer) Option {
+	return func(in *Interpreter) {
+		in.thread.Print = func(_ *Thread, msg string) { io.WriteString(w, msg) }
+	}
+}
+
+// WithPolicy 设置允许脚本使用的能力，默认不允许任何需要授权的能力
+func WithPolicy(policy *Policy) Option {
+	return func(in *Interpreter) {
+		in.thread.Policy = policy
+	}
+}
+
+// WithLimits 设置每次 Run 的执行限制
+func WithLimits(limits Limits) Option {
+	return func(in *Interpreter) {
+		in.limits = limits
+		in.thread.MaxSteps = limits.MaxSteps
+		in.thread.MaxAlloc = limits.MaxAlloc
+	}
+}
+
+// WithLanguage 设置解析源码时启用的可选语言特性
+func WithLanguage(opts syntax.LanguageOptions) Option {
+	return func(in *Interpreter) {
+		in.thread.Language = opts
+	}
+}
+
+// WithContext 设置 Run 使用的 Context，ctx 被取消后在下一条语句之前终止求值，
+// 此时 Run 返回包装了 ErrCancelled 的错误
+func WithContext(ctx context.Context) Option {
+	return func(in *Interpreter) {
+		in.ctx = ctx
+	}
+}
+
+// NewInterpreter 返回一个按 opts 配置的 Interpreter
+func NewInterpreter(opts ...Option) *Interpreter {
+	in := &Interpreter{
+		filename: "main.mky",
+		env:      NewEnv(nil),
+		thread:   &Thread{Name: "main.mky"},
+		ctx:      context.Background(),
+	}
+	for _, opt := range opts {
+		opt(in)
+	}
+	return in
+}
+
+// Run 使用 opts 创建一个 Interpreter 并执行 src，返回最后一个表达式的值
+func Run(src string, opts ...Option) (Value, error) {
+	return NewInterpreter(opts...).Run(src)
+}
+
+// Env 返回 Interpreter 的全局作用域，可以在两次 Run 之间读取或修改全局变量
+func (in *Interpreter) Env() *Env {
+	return in.env
+}
+
+// Thread 返回 Interpreter 执行脚本使用的 Thread，用于设置 Hooks 等没有对应选项的配置
+func (in *Interpreter) Thread() *Thread {
+	return in.thread
+}
+
+// Run 在 Interpreter 的全局作用域中执行 src，返回最后一个表达式的值
+func (in *Interpreter) Run(src string) (Value, error) {
+	if in.err != nil {
+		return nil, in.err
+	}
+	program, err := Parse(in.thread, in.filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := in.ctx, context.CancelFunc(func() {})
+	if in.limits.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, in.limits.Timeout)
+	}
+	defer cancel()
+	in.thread.cancel = &cancelScope{done: make(chan struct{}), ctx: ctx}
+	defer func() { in.thread.cancel = nil }()
+
+	value, err := EvalThread(in.thread, program, in.env)
+	if errors.Is(err, ErrCancelled) && in.ctx.Err() == nil && ctx.Err() == context.DeadlineExceeded {
+		return nil, exhausted("execution timed out after %v", in.limits.Timeout)
+	}
+	return value, err
+}
@@ -0,0 +1,75 @@
+package monkey
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+func TestInterpreter(t *testing.T) {
+	var out strings.Builder
+	in := NewInterpreter(
+		WithFilename("script.mky"),
+		WithGlobals(map[string]any{"user": struct{ Name string }{"ada"}, "n": 2}),
+		WithBuiltin("double", func(thread *Thread, args ...Value) (Value, error) {
+			return Binary(syntax.STAR, args[0], Int(2))
+		}),
+		WithStdout(&out),
+	)
+	if _, err := in.Run(`let greeting = "hello, " + user["Name"]; print(greeting);`); err != nil {
+		t.Fatal(err)
+	}
+	// 全局变量在多次 Run 之间保留
+	val, err := in.Run(`double(len(greeting)) + n`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val.String() != "22" || out.String() != "hello, ada\n" {
+		t.Errorf("got value %s and output %q", val, out.String())
+	}
+	if _, err := in.Run(`x`); err == nil || !strings.Contains(err.Error(), "script.mky:1:1") {
+		t.Errorf("expected error in script.mky, got %v", err)
+	}
+
+	if _, err := Run(`let = 1`); !errors.Is(err, ErrParse) {
+		t.Errorf("expected parse error, got %v", err)
+	}
+	if _, err := Run(`1`, WithGlobals(map[string]any{"c": make(chan int)})); err == nil || !strings.Contains(err.Error(), "global c") {
+		t.Errorf("expected conversion error, got %v", err)
+	}
+}
+
+func TestInterpreterLimits(t *testing.T) {
+	loop := `let f = fn(n) { if (n > 0) { f(n - 1) } }; f(100000)`
+
+	_, err := Run(loop, WithLimits(Limits{MaxSteps: 1000}))
+	if !errors.Is(err, ErrResourceExhausted) || !strings.Contains(err.Error(), "step limit (1000) exceeded") {
+		t.Errorf("expected step limit error, got %v", err)
+	}
+
+	// 任务中执行的语句同样计入上限
+	tasks := `with_group(fn(g) { g["spawn"](fn() { let f = fn(n) { if (n > 0) { f(n - 1) } }; f(5000) }) })`
+	if _, err := Run(tasks, WithLimits(Limits{MaxSteps: 1000})); !errors.Is(err, ErrResourceExhausted) {
+		t.Errorf("expected step limit error in task, got %v", err)
+	}
+
+	slow := `let f = fn(n) { if (n > 0) { f(n - 1); f(n - 1) } }; f(40)`
+	_, err = Run(slow, WithLimits(Limits{Timeout: 20 * time.Millisecond}))
+	if !errors.Is(err, ErrResourceExhausted) || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Run(slow, WithContext(ctx)); !errors.Is(err, ErrCancelled) {
+		t.Errorf("expected cancelled error, got %v", err)
+	}
+
+	if val, err := Run(`1 + 1`, WithLimits(Limits{MaxSteps: 10, Timeout: time.Second})); err != nil || val.String() != "2" {
+		t.Errorf("got %v, %v", val, err)
+	}
+}
@@ -1,9 +1,15 @@
 package monkey
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
 
 var Universe = map[string]*BuiltinFunction{
-	"len": NewBuiltinFunction("len", func(args ...Value) (Value, error) {
+	"len": NewBuiltinFunction("len", func(thread *Thread, args ...Value) (Value, error) {
 		if len(args) != 1 {
 			return nil, fmt.Errorf("wrong number of arguments. got=%d, want=1", len(args))
 		}
@@ -15,12 +21,123 @@ var Universe = map[string]*BuiltinFunction{
 			return nil, fmt.Errorf("argument to `len` not supported, got %s", arg0.Type())
 		}
 	}),
-	"print": NewBuiltinFunction("print", func(args ...Value) (Value, error) {
+	"print": NewBuiltinFunction("print", func(thread *Thread, args ...Value) (Value, error) {
 		str := make([]any, len(args))
 		for i, arg := range args {
 			str[i] = arg.String()
 		}
-		fmt.Println(str...)
+		if thread.Print != nil {
+			thread.Print(thread, fmt.Sprintln(str...))
+		} else {
+			fmt.Println(str...)
+		}
 		return Null, nil
 	}),
+	"time": NewBuiltinFunction("time", func(thread *Thread, args ...Value) (Value, error) {
+		if len(args) != 0 {
+			return nil, fmt.Errorf("wrong number of arguments. got=%d, want=0", len(args))
+		}
+		now, err := thread.provider().Now()
+		if err != nil {
+			return nil, err
+		}
+		return Int(now.UnixMilli()), nil
+	}),
+	"random": NewBuiltinFunction("random", func(thread *Thread, args ...Value) (Value, error) {
+		if len(args) > 1 {
+			return nil, fmt.Errorf("wrong number of arguments. got=%d, want=0 or 1", len(args))
+		}
+		n, err := thread.provider().Random()
+		if err != nil {
+			return nil, err
+		}
+		if len(args) == 0 {
+			return Int(n), nil
+		}
+		max, ok := args[0].(Int)
+		if !ok || max <= 0 {
+			return nil, fmt.Errorf("argument to `random` must be a positive int, got %s", args[0])
+		}
+		return Int(n) % max, nil
+	}),
+	"getenv": NewBuiltinFunction("getenv", func(thread *Thread, args ...Value) (Value, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("wrong number of arguments. got=%d, want=1", len(args))
+		}
+		name, ok := args[0].(String)
+		if !ok {
+			return nil, fmt.Errorf("argument to `getenv` must be string, got %s", args[0].Type())
+		}
+		val, found, err := thread.provider().Getenv(string(name))
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return Null, nil
+		}
+		return String(val), nil
+	}),
+	"input": NewBuiltinFunction("input", func(thread *Thread, args ...Value) (Value, error) {
+		if len(args) > 1 {
+			return nil, fmt.Errorf("wrong number of arguments. got=%d, want=0 or 1", len(args))
+		}
+		if len(args) == 1 {
+			if thread.Print != nil {
+				thread.Print(thread, args[0].String())
+			} else {
+				fmt.Print(args[0].String())
+			}
+		}
+		line, err := thread.provider().ReadLine()
+		if err == io.EOF {
+			return Null, nil
+		} else if err != nil {
+			return nil, err
+		}
+		return String(line), nil
+	}),
+	"int": NewBuiltinFunction("int", func(thread *Thread, args ...Value) (Value, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("wrong number of arguments. got=%d, want=1", len(args))
+		}
+		switch x := args[0].(type) {
+		case Int:
+			return x, nil
+		case Float:
+			// 向零取整
+			if math.IsNaN(float64(x)) || math.IsInf(float64(x), 0) || x >= math.MaxInt64 || x < math.MinInt64 {
+				return nil, fmt.Errorf("cannot convert %s to int", x)
+			}
+			return Int(x), nil
+		case String:
+			n, err := strconv.ParseInt(strings.TrimSpace(string(x)), 0, 64)
+			if err != nil {
+				return nil, fmt.Errorf("cannot convert %q to int", string(x))
+			}
+			return Int(n), nil
+		case Bool:
+			return Int(b2i(bool(x))), nil
+		}
+		return nil, fmt.Errorf("argument to `int` not supported, got %s", args[0].Type())
+	}),
+	"float": NewBuiltinFunction("float", func(thread *Thread, args ...Value) (Value, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("wrong number of arguments. got=%d, want=1", len(args))
+		}
+		switch x := args[0].(type) {
+		case Float:
+			return x, nil
+		case Int:
+			return Float(x), nil
+		case String:
+			f, err := strconv.ParseFloat(strings.TrimSpace(string(x)), 64)
+			if err != nil {
+				return nil, fmt.Errorf("cannot convert %q to float", string(x))
+			}
+			return Float(f), nil
+		case Bool:
+			return Float(b2i(bool(x))), nil
+		}
+		return nil, fmt.Errorf("argument to `float` not supported, got %s", args[0].Type())
+	}),
 }
@@ -0,0 +1,629 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// Macro 是宏字面量求值得到的值。
+//
+// 程序执行前，对宏的直接调用会被展开：宏的参数是实参的语法树（与 parse() 返回的格式相同），
+// 宏的返回值必须是语法树，它会替换原来的调用表达式。宏体中通常使用 quote 构造语法树：
+//
+//	let unless = macro(cond, then, otherwise) {
+//		quote(if (!(unquote(cond))) { unquote(then) } else { unquote(otherwise) })
+//	};
+type Macro struct {
+	Params []*syntax.Identifier
+	Body   *syntax.BlockStmt
+	Env    *Env
+}
+
+// Hash implements Value.
+func (m *Macro) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: macro")
+}
+
+// String implements Value.
+func (m *Macro) String() string {
+	params := make([]string, len(m.Params))
+	for i, p := range m.Params {
+		params[i] = p.String()
+	}
+	return "macro(" + strings.Join(params, ", ") + ") " + m.Body.String()
+}
+
+// Truth implements Value.
+func (m *Macro) Truth() bool {
+	return true
+}
+
+// Type implements Value.
+func (m *Macro) Type() string {
+	return "macro"
+}
+
+// 宏展开的最大嵌套深度，防止宏无限地展开为对自身的调用
+const maxMacroDepth = 1000
+
+// 宏返回的和展开之后的语法树的最大深度。解析器生成的语法树不超过 syntax.DefaultMaxDepth 的两倍，
+// 限制宏生成的语法树使得求值和输出语法树时的递归深度同样有上限
+const maxSyntaxDepth = 2 * syntax.DefaultMaxDepth
+
+var errSyntaxDepth = exhausted("syntax tree too deeply nested (max depth %d)", maxSyntaxDepth)
+
+// 定义程序顶层的宏并展开所有宏调用，展开结果直接替换程序中的调用表达式
+func expandMacros(thread *Thread, program *syntax.Program, env *Env) error {
+	for _, stmt := range program.Stmts {
+		if let, ok := stmt.(*syntax.LetStmt); ok {
+			if lit, ok := let.Value.(*syntax.MacroLiteral); ok {
+				env.Set(let.Name.Value, &Macro{Params: lit.Params, Body: lit.Body, Env: env})
+			}
+		}
+	}
+	x := &expander{thread: thread, env: env}
+	defer func() {
+		// 出错时语法树也可能已经被部分修改
+		if x.expanded > 0 {
+			program.Expanded = true
+		}
+	}()
+	for i, stmt := range program.Stmts {
+		var err error
+		if program.Stmts[i], err = x.stmt(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type expander struct {
+	thread   *Thread
+	env      *Env
+	depth    int
+	nesting  int // 当前表达式在语法树中的深度
+	expanded int // 已经展开的宏调用的次数
+}
+
+func (x *expander) stmt(stmt syntax.Stmt) (_ syntax.Stmt, err error) {
+	switch stmt := stmt.(type) {
+	case *syntax.LetStmt:
+		// 宏的定义本身不展开
+		if _, ok := stmt.Value.(*syntax.MacroLiteral); !ok {
+			stmt.Value, err = x.expr(stmt.Value)
+		}
+	case *syntax.ExportStmt:
+		_, err = x.stmt(stmt.Let)
+	case *syntax.ReturnStmt:
+		stmt.Value, err = x.expr(stmt.Value)
+	case *syntax.ExprStmt:
+		stmt.Expr, err = x.expr(stmt.Expr)
+	case *syntax.BlockStmt:
+		for i := range stmt.Stmts {
+			if stmt.Stmts[i], err = x.stmt(stmt.Stmts[i]); err != nil {
+				return nil, err
+			}
+		}
+	case *syntax.ImportStmt:
+		_, err = x.expr(stmt.Import)
+	case *syntax.FunctionStmt:
+		_, err = x.expr(stmt.Fn)
+	}
+	return stmt, err
+}
+
+func (x *expander) expr(expr syntax.Expr) (_ syntax.Expr, err error) {
+	if x.nesting++; x.nesting > maxSyntaxDepth {
+		return nil, errSyntaxDepth
+	}
+	defer func() { x.nesting-- }()
+
+	switch expr := expr.(type) {
+	case *syntax.CallExpr:
+		if ident, ok := expr.Function.(*syntax.Identifier); ok {
+			if val, ok := x.env.Get(ident.Value); ok {
+				if macro, ok := val.(*Macro); ok {
+					return x.expand(ident.Value, macro, expr.Args)
+				}
+			}
+		}
+		if expr.Function, err = x.expr(expr.Function); err != nil {
+			return nil, err
+		}
+		err = x.exprs(expr.Args)
+	case *syntax.PrefixExpr:
+		expr.Right, err = x.expr(expr.Right)
+	case *syntax.InfixExpr:
+		if expr.Left, err = x.expr(expr.Left); err != nil {
+			return nil, err
+		}
+		expr.Right, err = x.expr(expr.Right)
+	case *syntax.IfExpr:
+		if expr.Cond, err = x.expr(expr.Cond); err != nil {
+			return nil, err
+		}
+		if _, err = x.stmt(expr.Consequence); err != nil {
+			return nil, err
+		}
+		if expr.Alternative != nil {
+			_, err = x.stmt(expr.Alternative)
+		}
+	case *syntax.FunctionLiteral:
+		expanded := x.expanded
+		_, err = x.stmt(expr.Body)
+		if x.expanded != expanded {
+			// 展开的结果可能包含函数字面量
+			expr.NoClosures = false
+		}
+	case *syntax.AwaitExpr:
+		expr.Value, err = x.expr(expr.Value)
+	case *syntax.SpreadExpr:
+		expr.Value, err = x.expr(expr.Value)
+	case *syntax.ArrayLiteral:
+		err = x.exprs(expr.Items)
+	case *syntax.MapLiteral:
+		for i := range expr.Pairs {
+			pair := &expr.Pairs[i]
+			if pair.Key, err = x.expr(pair.Key); err != nil {
+				return nil, err
+			}
+			if pair.Value, err = x.expr(pair.Value); err != nil {
+				return nil, err
+			}
+		}
+	case *syntax.IndexExpr:
+		if expr.Left, err = x.expr(expr.Left); err != nil {
+			return nil, err
+		}
+		expr.Index, err = x.expr(expr.Index)
+	case *syntax.SliceExpr:
+		if expr.Left, err = x.expr(expr.Left); err != nil {
+			return nil, err
+		}
+		if expr.Start != nil {
+			if expr.Start, err = x.expr(expr.Start); err != nil {
+				return nil, err
+			}
+		}
+		if expr.End != nil {
+			expr.End, err = x.expr(expr.End)
+		}
+	case *syntax.DotExpr:
+		expr.Left, err = x.expr(expr.Left)
+	case *syntax.ImportExpr:
+		expr.Path, err = x.expr(expr.Path)
+	}
+	return expr, err
+}
+
+func (x *expander) exprs(exprs []syntax.Expr) (err error) {
+	for i := range exprs {
+		if exprs[i], err = x.expr(exprs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 以未求值的语法树为参数调用宏，并继续展开宏返回的语法树
+func (x *expander) expand(name string, macro *Macro, args []syntax.Expr) (syntax.Expr, error) {
+	if len(args) != len(macro.Params) {
+		return nil, fmt.Errorf("macro %s: wrong number of arguments: want=%d, got=%d", name, len(macro.Params), len(args))
+	}
+	if x.depth++; x.depth > maxMacroDepth {
+		return nil, exhausted("macro %s: expansion too deep", name)
+	}
+	defer func() { x.depth-- }()
+	x.expanded++
+
+	env := NewEnv(macro.Env)
+	for i, param := range macro.Params {
+		env.Set(param.Value, astValue(args[i]))
+	}
+	result, err := evalBlockStmt(x.thread, macro.Body, env)
+	if err != nil {
+		return nil, fmt.Errorf("macro %s: %w", name, err)
+	}
+	if rv, ok := result.(*returnValue); ok {
+		result = rv.Value
+	}
+
+	node, err := astNode(result)
+	if err != nil {
+		return nil, fmt.Errorf("macro %s: %w", name, err)
+	}
+	if stmt, ok := node.(*syntax.ExprStmt); ok {
+		node = stmt.Expr
+	}
+	expr, ok := node.(syntax.Expr)
+	if !ok {
+		return nil, fmt.Errorf("macro %s: must return an expression, got %s", name, nodeType(result))
+	}
+	return x.expr(expr)
+}
+
+// quote(expr) 返回 expr 的语法树而不对其求值，
+// 其中的 unquote(x) 会被替换为 x 的值：语法树原样插入，其他值转换为对应的字面量。
+func evalQuote(thread *Thread, node *syntax.CallExpr, env *Env) (Value, error) {
+	if len(node.Args) != 1 {
+		return nil, fmt.Errorf("wrong number of arguments to `quote`. got=%d, want=1", len(node.Args))
+	}
+	return unquote(thread, astValue(node.Args[0]), env)
+}
+
+// 将语法树中的 unquote(x) 替换为 x 的值
+func unquote(thread *Thread, tree Value, env *Env) (Value, error) {
+	switch tree := tree.(type) {
+	case *Array:
+		items := make([]Value, len(tree.items))
+		for i, item := range tree.items {
+			var err error
+			if items[i], err = unquote(thread, item, env); err != nil {
+				return nil, err
+			}
+		}
+		return NewArray(items), nil
+
+	case *Map:
+		if nodeType(tree) == "CallExpr" {
+			fn, _, _ := tree.Get(String("function"))
+			args, _, _ := tree.Get(String("args"))
+			if nodeType(fn) == "Identifier" && field(fn, "name") == String("unquote") {
+				args := args.(*Array).items
+				if len(args) != 1 {
+					return nil, fmt.Errorf("wrong number of arguments to `unquote`. got=%d, want=1", len(args))
+				}
+				node, err := astNode(args[0])
+				if err != nil {
+					return nil, err
+				}
+				val, err := eval(thread, node, env)
+				if err != nil {
+					return nil, err
+				}
+				return literalValue(val)
+			}
+		}
+		m := NewMap()
+		for _, entry := range tree.Items() {
+			val, err := unquote(thread, entry.Value, env)
+			if err != nil {
+				return nil, err
+			}
+			m.SetKey(entry.Key, val)
+		}
+		return m, nil
+	}
+	return tree, nil
+}
+
+// 将 unquote 的结果转换为语法树：语法树原样返回，其他值转换为字面量
+func literalValue(val Value) (Value, error) {
+	if nodeType(val) != "" {
+		return val, nil
+	}
+	node := func(typ string, value Value) Value {
+		m := NewMap()
+		m.SetKey(String("type"), String(typ))
+		m.SetKey(String("value"), value)
+		return m
+	}
+	switch val := val.(type) {
+	case Int:
+		return node("IntegerLiteral", val), nil
+	case Float:
+		return node("FloatLiteral", val), nil
+	case String:
+		return node("StringLiteral", val), nil
+	case Bool:
+		return node("Boolean", val), nil
+	case *Array:
+		items := make([]Value, len(val.items))
+		for i, item := range val.items {
+			var err error
+			if items[i], err = literalValue(item); err != nil {
+				return nil, err
+			}
+		}
+		m := NewMap()
+		m.SetKey(String("type"), String("ArrayLiteral"))
+		m.SetKey(String("items"), NewArray(items))
+		return m, nil
+	}
+	return nil, fmt.Errorf("cannot unquote value of type %s", val.Type())
+}
+
+// 返回语法树节点的类型，不是语法树节点时返回空字符串
+func nodeType(v Value) string {
+	if _, ok := v.(*Map); !ok {
+		return ""
+	}
+	typ, _ := field(v, "type").(String)
+	return string(typ)
+}
+
+// 返回语法树节点的字段，字段不存在时返回 Null
+func field(node Value, key string) Value {
+	m, ok := node.(*Map)
+	if !ok {
+		return Null
+	}
+	val, _, _ := m.Get(String(key))
+	return val
+}
+
+// astNode 将 parse() 格式的语法树转换回语法树节点，是 astValue 的逆操作。
+// 转换得到的节点中，只有带导出位置字段的节点保留 "line" 和 "col"。
+func astNode(v Value) (syntax.Node, error) {
+	return convertNode(v, 0)
+}
+
+// 转换深度为 depth 的节点，深度超过 maxSyntaxDepth 时返回 errSyntaxDepth
+func convertNode(v Value, depth int) (syntax.Node, error) {
+	if depth >= maxSyntaxDepth {
+		return nil, errSyntaxDepth
+	}
+	typ := nodeType(v)
+	if typ == "" {
+		return nil, fmt.Errorf("expected syntax tree, got %s", v.Type())
+	}
+	var pos syntax.Position
+	if line, ok := field(v, "line").(Int); ok {
+		col, _ := field(v, "col").(Int)
+		pos = syntax.MakePosition(nil, int32(line), int32(col))
+	}
+
+	c := converter{depth: depth + 1}
+	var node syntax.Node
+	switch typ {
+	case "Program":
+		node = &syntax.Program{Stmts: c.stmts(field(v, "stmts"))}
+	case "LetStmt":
+		node = &syntax.LetStmt{Pos: pos, Name: c.ident(field(v, "name")), Value: c.expr(field(v, "value"))}
+	case "ExportStmt":
+		let, _ := c.node(field(v, "let")).(*syntax.LetStmt)
+		if let == nil && c.err == nil {
+			c.err = fmt.Errorf("ExportStmt: expected LetStmt")
+		}
+		node = &syntax.ExportStmt{Pos: pos, Let: let}
+	case "ReturnStmt":
+		node = &syntax.ReturnStmt{Pos: pos, Value: c.expr(field(v, "value"))}
+	case "ExprStmt":
+		node = &syntax.ExprStmt{Expr: c.expr(field(v, "expr"))}
+	case "BlockStmt":
+		node = c.block(v)
+
+	case "Identifier":
+		ident := c.ident(field(v, "name"))
+		if ident != nil {
+			ident.Pos = pos
+		}
+		node = ident
+	case "IntegerLiteral":
+		n, ok := field(v, "value").(Int)
+		if !ok {
+			return nil, fmt.Errorf("IntegerLiteral: value must be int")
+		}
+		node = &syntax.IntegerLiteral{Pos: pos, Raw: n.String(), Value: int64(n)}
+	case "FloatLiteral":
+		f, ok := field(v, "value").(Float)
+		if !ok {
+			return nil, fmt.Errorf("FloatLiteral: value must be float")
+		}
+		node = &syntax.FloatLiteral{Pos: pos, Raw: f.String(), Value: float64(f)}
+	case "StringLiteral":
+		s, ok := field(v, "value").(String)
+		if !ok {
+			return nil, fmt.Errorf("StringLiteral: value must be string")
+		}
+		node = &syntax.StringLiteral{Value: string(s)}
+	case "Boolean":
+		b, ok := field(v, "value").(Bool)
+		if !ok {
+			return nil, fmt.Errorf("Boolean: value must be bool")
+		}
+		node = &syntax.Boolean{Value: bool(b)}
+	case "ArrayLiteral":
+		node = &syntax.ArrayLiteral{Items: c.exprs(field(v, "items"))}
+	case "MapLiteral":
+		var pairs []syntax.MapPair
+		for _, pair := range c.list(field(v, "pairs")) {
+			kv, ok := pair.(*Array)
+			if !ok || len(kv.items) != 2 {
+				return nil, fmt.Errorf("MapLiteral: pairs must be [key, value] arrays")
+			}
+			pairs = append(pairs, syntax.MapPair{Key: c.expr(kv.items[0]), Value: c.expr(kv.items[1])})
+		}
+		node = &syntax.MapLiteral{Pairs: pairs}
+	case "PrefixExpr":
+		node = &syntax.PrefixExpr{Pos: pos, Op: c.op(field(v, "op")), Right: c.expr(field(v, "right"))}
+	case "InfixExpr":
+		node = &syntax.InfixExpr{Left: c.expr(field(v, "left")), Op: c.op(field(v, "op")), Right: c.expr(field(v, "right"))}
+	case "IfExpr":
+		expr := &syntax.IfExpr{Cond: c.expr(field(v, "cond")), Consequence: c.block(field(v, "consequence"))}
+		if alt := field(v, "alternative"); alt != Null {
+			expr.Alternative = c.block(alt)
+		}
+		node = expr
+	case "FunctionLiteral":
+		async, _ := field(v, "async").(Bool)
+		variadic, _ := field(v, "variadic").(Bool)
+		expr := &syntax.FunctionLiteral{Async: bool(async), Params: c.idents(field(v, "params")), Variadic: bool(variadic), Body: c.block(field(v, "body"))}
+		if expr.Variadic && len(expr.Params) == 0 && c.err == nil {
+			c.err = fmt.Errorf("FunctionLiteral: variadic function needs at least one parameter")
+		}
+		if name := field(v, "name"); name != Null {
+			expr.Name = c.ident(name)
+		}
+		node = expr
+	case "FunctionStmt":
+		fn, _ := c.node(field(v, "fn")).(*syntax.FunctionLiteral)
+		if (fn == nil || fn.Name == nil) && c.err == nil {
+			c.err = fmt.Errorf("FunctionStmt: expected named FunctionLiteral")
+		}
+		node = &syntax.FunctionStmt{Fn: fn}
+	case "AwaitExpr":
+		node = &syntax.AwaitExpr{Pos: pos, Value: c.expr(field(v, "value"))}
+	case "SpreadExpr":
+		node = &syntax.SpreadExpr{Value: c.expr(field(v, "value"))}
+	case "MacroLiteral":
+		return nil, fmt.Errorf("macros cannot be defined by macros")
+	case "CallExpr":
+		node = &syntax.CallExpr{Function: c.expr(field(v, "function")), Args: c.exprs(field(v, "args"))}
+	case "IndexExpr":
+		node = &syntax.IndexExpr{Left: c.expr(field(v, "left")), Index: c.expr(field(v, "index"))}
+	case "SliceExpr":
+		expr := &syntax.SliceExpr{Left: c.expr(field(v, "left"))}
+		if start := field(v, "start"); start != Null {
+			expr.Start = c.expr(start)
+		}
+		if end := field(v, "end"); end != Null {
+			expr.End = c.expr(end)
+		}
+		node = expr
+	case "DotExpr":
+		node = &syntax.DotExpr{Left: c.expr(field(v, "left")), Name: c.ident(field(v, "name"))}
+	case "RecordStmt":
+		node = &syntax.RecordStmt{Pos: pos, Name: c.ident(field(v, "name")), Fields: c.idents(field(v, "fields"))}
+	case "ImportExpr":
+		node = &syntax.ImportExpr{Path: c.expr(field(v, "path"))}
+	case "ImportStmt":
+		imp, _ := c.node(field(v, "import")).(*syntax.ImportExpr)
+		if imp == nil && c.err == nil {
+			c.err = fmt.Errorf("ImportStmt: expected ImportExpr")
+		}
+		node = &syntax.ImportStmt{Import: imp, Alias: c.ident(field(v, "alias"))}
+	case "FromImportStmt":
+		node = &syntax.FromImportStmt{Path: c.expr(field(v, "path")), Names: c.idents(field(v, "names"))}
+	default:
+		return nil, fmt.Errorf("unknown syntax tree type %q", typ)
+	}
+	if c.err != nil {
+		if errors.Is(c.err, errSyntaxDepth) {
+			// 不为每一层都加上节点类型，否则错误信息会有上千层
+			return nil, c.err
+		}
+		return nil, fmt.Errorf("%s: %v", typ, c.err)
+	}
+	return node, nil
+}
+
+// converter 转换子节点，记录遇到的第一个错误
+type converter struct {
+	depth int // 子节点的深度
+	err   error
+}
+
+func (c *converter) node(v Value) syntax.Node {
+	if c.err != nil {
+		return nil
+	}
+	node, err := convertNode(v, c.depth)
+	if err != nil {
+		c.err = err
+	}
+	return node
+}
+
+func (c *converter) expr(v Value) syntax.Expr {
+	node := c.node(v)
+	if node == nil {
+		return nil
+	}
+	expr, ok := node.(syntax.Expr)
+	if !ok {
+		c.err = fmt.Errorf("expected expression, got %s", nodeType(v))
+	}
+	return expr
+}
+
+func (c *converter) stmt(v Value) syntax.Stmt {
+	node := c.node(v)
+	if node == nil {
+		return nil
+	}
+	if expr, ok := node.(syntax.Expr); ok {
+		return &syntax.ExprStmt{Expr: expr}
+	}
+	stmt, ok := node.(syntax.Stmt)
+	if !ok {
+		c.err = fmt.Errorf("expected statement, got %s", nodeType(v))
+	}
+	return stmt
+}
+
+func (c *converter) block(v Value) *syntax.BlockStmt {
+	if nodeType(v) != "BlockStmt" {
+		if c.err == nil {
+			c.err = fmt.Errorf("expected BlockStmt, got %s", v.Type())
+		}
+		return nil
+	}
+	return &syntax.BlockStmt{Stmts: c.stmts(field(v, "stmts"))}
+}
+
+func (c *converter) list(v Value) []Value {
+	arr, ok := v.(*Array)
+	if !ok {
+		if c.err == nil {
+			c.err = fmt.Errorf("expected array, got %s", v.Type())
+		}
+		return nil
+	}
+	return arr.items
+}
+
+func (c *converter) stmts(v Value) []syntax.Stmt {
+	var stmts []syntax.Stmt
+	for _, item := range c.list(v) {
+		stmts = append(stmts, c.stmt(item))
+	}
+	return stmts
+}
+
+func (c *converter) exprs(v Value) []syntax.Expr {
+	var exprs []syntax.Expr
+	for _, item := range c.list(v) {
+		exprs = append(exprs, c.expr(item))
+	}
+	return exprs
+}
+
+func (c *converter) ident(v Value) *syntax.Identifier {
+	name, ok := v.(String)
+	if !ok {
+		if c.err == nil {
+			c.err = fmt.Errorf("expected identifier name, got %s", v.Type())
+		}
+		return nil
+	}
+	return &syntax.Identifier{Value: string(name)}
+}
+
+func (c *converter) idents(v Value) []*syntax.Identifier {
+	var idents []*syntax.Identifier
+	for _, item := range c.list(v) {
+		idents = append(idents, c.ident(item))
+	}
+	return idents
+}
+
+func (c *converter) op(v Value) syntax.Token {
+	s, ok := v.(String)
+	if ok {
+		if tok, ok := syntax.LookupOperator(string(s)); ok {
+			return tok
+		}
+	}
+	if c.err == nil {
+		c.err = fmt.Errorf("unknown operator %s", v)
+	}
+	return syntax.ILLEGAL
+}
+
+var (
+	_ Value = (*Macro)(nil)
+)
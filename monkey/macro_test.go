@@ -0,0 +1,146 @@
+package monkey
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+func TestMacros(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(1 + x)["op"]`, "+"},
+		{`let x = 2; quote(unquote(x) + y)["left"]["value"]`, "2"},
+		{`quote(unquote(quote(a)))["name"]`, "a"},
+		{
+			`let unless = macro(cond, then, otherwise) {
+				quote(if (!(unquote(cond))) { unquote(then) } else { unquote(otherwise) })
+			};
+			unless(10 > 5, "not greater", "greater")`,
+			"greater",
+		},
+		// 参数不会被求值，因此可以只执行一个分支
+		{
+			`let unless = macro(cond, then, otherwise) {
+				quote(if (!(unquote(cond))) { unquote(then) } else { unquote(otherwise) })
+			};
+			unless(true, undefined, "ok")`,
+			"ok",
+		},
+		// 宏可以检查参数的语法树
+		{
+			`let swap = macro(e) {
+				let l = e["left"];
+				let r = e["right"];
+				quote(unquote(r) - unquote(l))
+			};
+			swap(1 - 10)`,
+			"9",
+		},
+		// 宏展开的结果中可以包含宏调用
+		{
+			`let double = macro(e) { quote(unquote(e) * 2) };
+			let quad = macro(e) { quote(double(double(unquote(e)))) };
+			quad(3)`,
+			"12",
+		},
+		{`let m = macro(a) { quote(unquote(a)) }; m(1, 2)`, "wrong number of arguments"},
+		{`let m = macro(a) { 1 }; m(1)`, "expected syntax tree, got int"},
+		{`let m = macro() { quote(m()) }; m()`, "expansion too deep"},
+		{`let m = macro(a) { a }; let f = m; f(1)`, "non-function (macro)"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalThread(t, tt.input)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestASTRoundTrip(t *testing.T) {
+	inputs := []string{
+		`let add = fn(a, b) { return a + b; };`,
+		`if (!x) { [1, "two", true] } else { {"k": -1}["k"] };`,
+		`export let f = fn() { import("lib.mky")["g"](1) };`,
+		`from "lib.mky" import (a, b);`,
+	}
+	for _, input := range inputs {
+		program, err := syntax.NewParser(input).Parse()
+		if err != nil {
+			t.Fatalf("parser error: %s", err)
+		}
+		node, err := astNode(astValue(program))
+		if err != nil {
+			t.Fatalf("%s: %s", input, err)
+		}
+		if node.String() != program.String() {
+			t.Errorf("round trip mismatch.\nwant=%s\ngot=%s", program, node)
+		}
+	}
+}
+
+func TestMacroSyntaxDepth(t *testing.T) {
+	// deep(n) 展开为嵌套 n 层的 -(-(...1))
+	deep := `let deep = macro(n) {
+		let build = fn(n) { if (n == 0) { quote(1) } else { quote(-(unquote(build(n - 1)))) } };
+		build(n["value"])
+	};
+	`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{deep + `deep(1000)`, "1"},
+		{deep + `deep(1001)`, "-1"},
+		{deep + `deep(2500)`, "macro deep: syntax tree too deeply nested (max depth 2000)"},
+		// 展开的结果本身没有超过限制，但与外层的表达式一起超过了
+		{deep + strings.Repeat("- ", 600) + "deep(1500)", "syntax tree too deeply nested (max depth 2000)"},
+		{deep + strings.Repeat("- ", 400) + "deep(1500)", "1"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalThread(t, tt.input)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.HasSuffix(got, tt.expected) {
+			if len(got) > 200 {
+				got = got[:200] + "..."
+			}
+			t.Errorf("%s: expected %q. got=%q", tt.input[len(deep):min(len(tt.input), len(deep)+40)], tt.expected, got)
+		}
+	}
+}
+
+func TestMacroExpanded(t *testing.T) {
+	tests := []struct {
+		input    string
+		expanded bool
+	}{
+		{`let add = fn(a, b) { a + b }; add(1, 2)`, false},
+		{`let id = macro(x) { x }; id(1)`, true},
+	}
+	for _, tt := range tests {
+		program, err := syntax.NewParser(tt.input).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Eval(program, NewEnv(nil)); err != nil {
+			t.Fatal(err)
+		}
+		if program.Expanded != tt.expanded {
+			t.Errorf("%q: Expanded = %v, want %v", tt.input, program.Expanded, tt.expanded)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package monkey
+
+import "fmt"
+
+// 与数组一样，map 对脚本来说是不可变的，delete 返回删除之后的新 map。
+// keys 和 values 按插入的顺序返回。
+func init() {
+	Universe["keys"] = NewBuiltinFunction("keys", keys)
+	Universe["values"] = NewBuiltinFunction("values", values)
+	Universe["has"] = NewBuiltinFunction("has", has)
+	Universe["delete"] = NewBuiltinFunction("delete", deleteBuiltin)
+}
+
+// 检查 fn 的第一个参数是否是 map
+func mapArg(fn string, args []Value, want int) (*Map, error) {
+	if len(args) != want {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=%d", len(args), want)
+	}
+	m, ok := args[0].(*Map)
+	if !ok {
+		return nil, fmt.Errorf("argument to `%s` must be map, got %s", fn, args[0].Type())
+	}
+	return m, nil
+}
+
+// keys(m) 返回 m 的所有键
+func keys(thread *Thread, args ...Value) (Value, error) {
+	m, err := mapArg("keys", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]Value, len(m.items))
+	for i, entry := range m.items {
+		items[i] = entry.Key
+	}
+	return NewArray(items), nil
+}
+
+// values(m) 返回 m 的所有值
+func values(thread *Thread, args ...Value) (Value, error) {
+	m, err := mapArg("values", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]Value, len(m.items))
+	for i, entry := range m.items {
+		items[i] = entry.Value
+	}
+	return NewArray(items), nil
+}
+
+// has(m, key) 判断 m 中是否有 key，与 m[key] != null 不同，值为 null 的键也算存在
+func has(thread *Thread, args ...Value) (Value, error) {
+	m, err := mapArg("has", args, 2)
+	if err != nil {
+		return nil, err
+	}
+	_, found, err := m.Get(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return Bool(found), nil
+}
+
+// delete(m, key) 返回去掉 key 之后的新 map，key 不存在时返回与 m 相同内容的新 map
+func deleteBuiltin(thread *Thread, args ...Value) (Value, error) {
+	m, err := mapArg("delete", args, 2)
+	if err != nil {
+		return nil, err
+	}
+	result := NewMap()
+	for _, entry := range m.items {
+		result.SetKey(entry.Key, entry.Value)
+	}
+	if _, err := result.Delete(args[1]); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,160 @@
+package monkey
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+func TestMapBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"b": 1, "a": 2, "c": 3}`, "{b: 1, a: 2, c: 3}"},
+		{"{\n  \"z\": 1,\n  \"y\": 2\n}", "{z: 1, y: 2}"},
+		{`keys({"b": 1, "a": 2, 3: 3})`, "[b, a, 3]"},
+		{`values({"b": 1, "a": 2})`, "[1, 2]"},
+		{`has({"a": 1}, "a")`, "true"},
+		{`has({"a": 1}, "b")`, "false"},
+		{`delete({"a": 1, "b": 2, "c": 3}, "b")`, "{a: 1, c: 3}"},
+		{`delete({"a": 1}, "x")`, "{a: 1}"},
+		{`let m = {"a": 1, "b": 2}; delete(m, "a"); m`, "{a: 1, b: 2}"},
+		{`keys(delete({"a": 1, "b": 2, "c": 3}, "a"))`, "[b, c]"},
+		{`delete({"a": 1, "b": 2, "c": 3}, "a")["c"]`, "3"},
+		{`keys({})`, "[]"},
+		{`has({}, [1])`, "unhashable type: array"},
+		{`keys([1])`, "argument to `keys` must be map, got array"},
+		{`delete({})`, "wrong number of arguments. got=1, want=2"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalThread(t, tt.input)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestMapDelete(t *testing.T) {
+	m := NewMap()
+	for i := 0; i < 5; i++ {
+		m.SetKey(Int(i), Int(i*10))
+	}
+	if ok, err := m.Delete(Int(1)); !ok || err != nil {
+		t.Fatalf("expected Delete to find the key. got=%v, %v", ok, err)
+	}
+	if ok, _ := m.Delete(Int(1)); ok {
+		t.Errorf("expected second Delete to report a missing key")
+	}
+	m.SetKey(Int(1), Int(100))
+	m.SetKey(Int(3), Int(300))
+	if got, want := m.String(), "{0: 0, 2: 20, 3: 300, 4: 40, 1: 100}"; got != want {
+		t.Errorf("wrong order after delete. expected=%q, got=%q", want, got)
+	}
+	for i := 0; i < 5; i++ {
+		if val, found, _ := m.Get(Int(i)); !found || val == Null {
+			t.Errorf("key %d lost after delete", i)
+		}
+	}
+}
+
+func TestMapHashCollisions(t *testing.T) {
+	// Int 的哈希值只取低 32 位，这两个键的哈希值相同
+	a, b := Int(1), Int(1+1<<32)
+	ha, _ := a.Hash()
+	hb, _ := b.Hash()
+	if ha != hb {
+		t.Fatalf("expected colliding hashes. got=%d and %d", ha, hb)
+	}
+
+	m := NewMap()
+	m.SetKey(a, String("a"))
+	m.SetKey(b, String("b"))
+	if m.Len() != 2 {
+		t.Fatalf("colliding keys overwrote each other. got=%s", m)
+	}
+	for _, tt := range []struct {
+		key      Value
+		expected Value
+	}{{a, String("a")}, {b, String("b")}, {Float(1), String("a")}} {
+		if val, found, _ := m.Get(tt.key); !found || val != tt.expected {
+			t.Errorf("m[%s]: expected %s. got=%s", tt.key, tt.expected, val)
+		}
+	}
+
+	m.Delete(a)
+	if val, found, _ := m.Get(b); !found || val != String("b") {
+		t.Errorf("deleting a colliding key removed the other one. got=%s", m)
+	}
+	if _, found, _ := m.Get(a); found {
+		t.Errorf("expected %s to be deleted. got=%s", a, m)
+	}
+}
+
+func TestMapGrowAndShrink(t *testing.T) {
+	// 跨过 smallMapSize 时在顺序查找与索引之间切换，两种表示的结果应当相同
+	m := NewMap()
+	n := 2*smallMapSize + 1
+	for i := 0; i < n; i++ {
+		m.SetKey(Int(i), Int(i))
+		if (m.index != nil) != (m.Len() > smallMapSize) {
+			t.Fatalf("len=%d: unexpected index state %v", m.Len(), m.index != nil)
+		}
+	}
+	check := func() {
+		t.Helper()
+		for i, entry := range m.Items() {
+			if val, found, _ := m.Get(entry.Key); !found || val != entry.Value {
+				t.Fatalf("m[%s]: expected %s at %d. got=%v", entry.Key, entry.Value, i, val)
+			}
+		}
+	}
+	check()
+	for i := 0; i < n-smallMapSize; i++ {
+		m.Delete(Int(i))
+		check()
+	}
+	if m.index != nil {
+		t.Errorf("expected the index to be dropped at len=%d", m.Len())
+	}
+	if got, want := m.Items()[0].Value, Int(n-smallMapSize); got != want {
+		t.Errorf("wrong first entry. expected=%s, got=%s", want, got)
+	}
+}
+
+func BenchmarkMapLiteral(b *testing.B) {
+	for _, size := range []int{2, smallMapSize, 4 * smallMapSize} {
+		pairs := make([]string, size)
+		for i := range pairs {
+			pairs[i] = fmt.Sprintf("%q: %d", fmt.Sprint("k", i), i)
+		}
+		input := fmt.Sprintf(`let m = {%s}; m["k1"]`, strings.Join(pairs, ", "))
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			benchmarkEval(b, input)
+		})
+	}
+}
+
+// 反复执行 input，统计每次执行的耗时和内存分配
+func benchmarkEval(b *testing.B, input string) {
+	program, err := syntax.NewParser(input).Parse()
+	if err != nil {
+		b.Fatalf("parser error: %s", err)
+	}
+	thread := &Thread{}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := EvalThread(thread, program, NewEnv(nil)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package monkey
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// math 模块提供常用的数学函数和常量，通过 import 使用：
+//
+//	let m = import("math");
+//	m.sqrt(2) * m.pi
+//	from "math" import (min, max);
+func init() {
+	BuiltinModules["math"] = NewBuiltinModule("math", map[string]Value{
+		"pi":     Float(math.Pi),
+		"e":      Float(math.E),
+		"abs":    NewBuiltinFunction("math.abs", mathAbs),
+		"min":    NewBuiltinFunction("math.min", mathMin),
+		"max":    NewBuiltinFunction("math.max", mathMax),
+		"pow":    NewBuiltinFunction("math.pow", mathPow),
+		"sqrt":   NewBuiltinFunction("math.sqrt", mathSqrt),
+		"floor":  NewBuiltinFunction("math.floor", mathFloor),
+		"ceil":   NewBuiltinFunction("math.ceil", mathCeil),
+		"random": NewBuiltinFunction("math.random", mathRandom),
+	})
+}
+
+// abs(x) 返回 x 的绝对值，类型与 x 相同
+func mathAbs(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	if _, err := mathArg("abs", args[0]); err != nil {
+		return nil, err
+	}
+	if neg, _ := Compare(syntax.LT, args[0], Int(0)); neg.Truth() {
+		return Unary(syntax.MINUS, args[0])
+	}
+	return args[0], nil
+}
+
+// min(x, y...) 返回参数中最小的数，类型与该参数相同
+func mathMin(thread *Thread, args ...Value) (Value, error) {
+	return mathExtreme("min", syntax.LT, args)
+}
+
+// max(x, y...) 返回参数中最大的数，类型与该参数相同
+func mathMax(thread *Thread, args ...Value) (Value, error) {
+	return mathExtreme("max", syntax.GT, args)
+}
+
+// 返回 args 中按 op 比较排在最前面的数，相等时取靠前的参数
+func mathExtreme(fn string, op syntax.Token, args []Value) (Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want at least 1", len(args))
+	}
+	var result Value
+	for _, arg := range args {
+		f, err := mathArg(fn, arg)
+		if err != nil {
+			return nil, err
+		}
+		if math.IsNaN(f) {
+			return arg, nil
+		}
+		if result == nil {
+			result = arg
+		} else if better, _ := Compare(op, arg, result); better.Truth() {
+			result = arg
+		}
+	}
+	return result, nil
+}
+
+// pow(x, y) 与 x ** y 相同：两个整数的结果是整数，y 为负数或有浮点数时结果是浮点数
+func mathPow(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	for _, arg := range args {
+		if _, err := mathArg("pow", arg); err != nil {
+			return nil, err
+		}
+	}
+	return Binary(syntax.STARSTAR, args[0], args[1])
+}
+
+// sqrt(x) 返回 x 的平方根，结果总是浮点数
+func mathSqrt(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	f, err := mathArg("sqrt", args[0])
+	if err != nil {
+		return nil, err
+	}
+	if f < 0 {
+		return nil, fmt.Errorf("math domain error: sqrt(%s)", args[0])
+	}
+	return Float(math.Sqrt(f)), nil
+}
+
+// floor(x) 返回不大于 x 的最大整数
+func mathFloor(thread *Thread, args ...Value) (Value, error) {
+	return mathRound("floor", math.Floor, args)
+}
+
+// ceil(x) 返回不小于 x 的最小整数
+func mathCeil(thread *Thread, args ...Value) (Value, error) {
+	return mathRound("ceil", math.Ceil, args)
+}
+
+// 使用 round 将浮点数取整并转换为 Int，整数原样返回
+func mathRound(fn string, round func(float64) float64, args []Value) (Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	if i, ok := args[0].(Int); ok {
+		return i, nil
+	}
+	f, err := mathArg(fn, args[0])
+	if err != nil {
+		return nil, err
+	}
+	f = round(f)
+	if math.IsNaN(f) || math.IsInf(f, 0) || f >= math.MaxInt64 || f < math.MinInt64 {
+		return nil, fmt.Errorf("cannot convert %s to int", args[0])
+	}
+	return Int(f), nil
+}
+
+// random() 返回 [0, 1) 范围内均匀分布的浮点数
+func mathRandom(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=0", len(args))
+	}
+	n, err := thread.provider().Random()
+	if err != nil {
+		return nil, err
+	}
+	// 取 53 位，正好是 float64 尾数的精度
+	return Float(n>>10) / (1 << 53), nil
+}
+
+// 检查数学函数 fn 的参数是整数或浮点数，并返回它的浮点数值
+func mathArg(fn string, v Value) (float64, error) {
+	switch v := v.(type) {
+	case Int:
+		return float64(v), nil
+	case Float:
+		return float64(v), nil
+	}
+	return 0, fmt.Errorf("argument to `math.%s` must be int or float, got %s", fn, v.Type())
+}
@@ -0,0 +1,45 @@
+package monkey
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMathModule(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let m = import("math"); m.pi`, "3.14159"},
+		{`import("math")["e"]`, "2.71828"},
+		{`from "math" import (abs); [abs(-3), abs(3), abs(-2.5), abs(0)]`, "[3, 3, 2.5, 0]"},
+		{`from "math" import (min, max); [min(3, 1, 2), max(3, 1, 2), min(2.5), max(1, 1.5)]`, "[1, 3, 2.5, 1.5]"},
+		{`from "math" import (min); min(1, 1.0)`, "1"},
+		{`from "math" import (max); max([1, 5, 2]...)`, "5"},
+		{`from "math" import (pow); [pow(2, 10), pow(2, -1), pow(4, 0.5)]`, "[1024, 0.5, 2.0]"},
+		{`from "math" import (sqrt); [sqrt(16), sqrt(2.25)]`, "[4.0, 1.5]"},
+		{`from "math" import (floor, ceil); [floor(2.7), ceil(2.1), floor(-2.5), ceil(-2.5), floor(3)]`, "[2, 3, -3, -2, 3]"},
+		{`from "math" import (random); let r = random(); if (r >= 0) { r < 1 } else { false }`, "true"},
+		{`import("math").abs`, "math.abs"},
+		{`from "math" import (abs); abs("x")`, "argument to `math.abs` must be int or float, got string"},
+		{`from "math" import (min); min()`, "wrong number of arguments. got=0, want at least 1"},
+		{`from "math" import (max); max(1, true)`, "argument to `math.max` must be int or float, got bool"},
+		{`from "math" import (pow); pow(2, 64)`, "integer overflow"},
+		{`from "math" import (sqrt); sqrt(-1)`, "math domain error: sqrt(-1)"},
+		{`from "math" import (floor); floor(1e300)`, "cannot convert 1e+300 to int"},
+		{`from "math" import (random); random(1)`, "wrong number of arguments. got=1, want=0"},
+		{`from "math" import (tau)`, "tau"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalThread(t, tt.input)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
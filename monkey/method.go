@@ -0,0 +1,96 @@
+package monkey
+
+import "fmt"
+
+// HasMethods 是有方法的值。x.name 在 x 没有名为 name 的字段时查找 x 的方法，
+// 因此 x.name(args...) 调用方法，x.name 本身得到绑定了 x 的方法：
+//
+//	"abc".len()          // 3
+//	[1, 2].push(3)       // [1, 2, 3]
+//	{"a": 1}.keys()      // ["a"]
+//	let add = [1].push;
+//	add(2)               // [1, 2]
+type HasMethods interface {
+	Value
+	// Method 返回绑定了接收者的方法 name，不存在时 ok 为 false
+	Method(name string) (_ Value, ok bool)
+}
+
+// 字符串、数组和 map 的方法就是以接收者为第一个参数调用的同名内置函数
+var (
+	stringMethods = methodSet("len")
+	arrayMethods  = methodSet("len", "push", "pop", "insert", "remove", "map", "filter", "reduce")
+	mapMethods    = methodSet("len", "keys", "values", "has", "delete")
+)
+
+func methodSet(names ...string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// 返回以 recv 为第一个参数调用内置函数 name 的方法，name 不在 methods 中时 ok 为 false
+func bindMethod(recv Value, methods map[string]bool, name string) (_ Value, ok bool) {
+	if !methods[name] {
+		return nil, false
+	}
+	builtin, ok := Universe[name]
+	if !ok {
+		return nil, false
+	}
+	return NewBuiltinFunction(name, func(thread *Thread, args ...Value) (Value, error) {
+		return builtin.CallInternal(thread, append([]Value{recv}, args...)...)
+	}), true
+}
+
+// Method implements HasMethods.
+func (s String) Method(name string) (Value, bool) {
+	return bindMethod(s, stringMethods, name)
+}
+
+// Method implements HasMethods.
+func (a *Array) Method(name string) (Value, bool) {
+	return bindMethod(a, arrayMethods, name)
+}
+
+// Method implements HasMethods.
+func (m *Map) Method(name string) (Value, bool) {
+	return bindMethod(m, mapMethods, name)
+}
+
+// 读取 left 中名为 name 的字段，没有该字段时查找方法；与 left["name"] 不同，两者都不存在时报错
+func evalDotExpr(left Value, name string) (Value, error) {
+	mapping, isMapping := left.(Mapping)
+	if isMapping {
+		val, found, err := mapping.Get(String(name))
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			return val, nil
+		}
+	}
+	methods, hasMethods := left.(HasMethods)
+	if hasMethods {
+		if method, ok := methods.Method(name); ok {
+			return method, nil
+		}
+	}
+	switch {
+	case isMapping && hasMethods:
+		return nil, fmt.Errorf("%s has no field or method %s", left.Type(), name)
+	case isMapping:
+		return nil, fmt.Errorf("%s has no field %s", left.Type(), name)
+	case hasMethods:
+		return nil, fmt.Errorf("%s has no method %s", left.Type(), name)
+	}
+	return nil, fmt.Errorf("%s has no fields, cannot access .%s", left.Type(), name)
+}
+
+var (
+	_ HasMethods = String("")
+	_ HasMethods = (*Array)(nil)
+	_ HasMethods = (*Map)(nil)
+)
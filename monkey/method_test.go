@@ -0,0 +1,46 @@
+package monkey
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMethods(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"abc".len()`, "3"},
+		{`"héllo".len()`, "5"},
+		{`[1, 2].push(3)`, "[1, 2, 3]"},
+		{`[1, 2, 3].pop().len()`, "2"},
+		{`[1, 2, 3].map(fn(x) { x * 2 }).filter(fn(x) { x > 2 })`, "[4, 6]"},
+		{`[1, 2, 3].reduce(0, fn(a, b) { a + b })`, "6"},
+		{`{"a": 1, "b": 2}.keys()`, "[a, b]"},
+		{`{"a": 1}.has("a")`, "true"},
+		{`{"a": 1, "b": 2}.delete("a")`, "{b: 2}"},
+		{`let m = {"len": fn() { 42 }}; m.len()`, "42"},
+		{`{"keys": 1}.keys`, "1"},
+		{`let push = [1].push; push(2, 3)`, "[1, 2, 3]"},
+		{`[1].push`, "<built-in function push>"},
+		{`let xs = [1]; xs.push(2); xs`, "[1]"},
+		{`"abc".push(1)`, "1:7 string has no method push"},
+		{`[1].keys()`, "array has no method keys"},
+		{`{"a": 1}.b`, "map has no field or method b"},
+		{`[1].insert("a", 2)`, "index argument to `insert` must be int, got string"},
+		{`[1].map(fn(x) { x / 0 })`, "division by zero"},
+		{`true.len()`, "bool has no fields, cannot access .len"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalThread(t, tt.input)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package monkey
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// Module 是 import 表达式返回的模块值，可以通过下标访问模块的全局绑定。
+// 模块中有 export 声明时，只有被导出的绑定可以访问。
+type Module struct {
+	Name    string // 模块的绝对路径，内置模块为模块的名称
+	env     *Env
+	exports map[string]bool // 为 nil 时公开所有全局绑定
+	loading bool            // 模块是否仍在执行顶层代码
+	cycle   string          // 模块所在的导入循环，用于错误信息
+}
+
+// Get implements Mapping.
+func (m *Module) Get(key Value) (_ Value, _ bool, err error) {
+	name, ok := key.(String)
+	if !ok {
+		return nil, false, fmt.Errorf("module attribute must be string, got %s", key.Type())
+	}
+	val, ok, err := m.lookup(string(name))
+	if err != nil || !ok {
+		return Null, false, err
+	}
+	return val, true, nil
+}
+
+// 查找模块对外公开的绑定。
+// 在循环导入中访问尚未初始化的绑定时返回包含导入链的错误。
+func (m *Module) lookup(name string) (Value, bool, error) {
+	if m.exports != nil && !m.exports[name] {
+		return nil, false, nil
+	}
+	val, ok := m.env.Get(name)
+	if !ok && m.loading {
+		return nil, false, fmt.Errorf("%q in %s is not initialized yet because of an import cycle: %s",
+			name, displayName(m.Name), m.cycle)
+	}
+	return val, ok, nil
+}
+
+// Exports 返回模块对外公开的绑定名称，按字母顺序排列
+func (m *Module) Exports() []string {
+	if m.exports == nil {
+		return m.env.Names()
+	}
+	names := make([]string, 0, len(m.exports))
+	for name := range m.exports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 收集程序顶层的 export 声明，没有任何 export 时返回 nil
+func exportedNames(program *syntax.Program) map[string]bool {
+	var exports map[string]bool
+	for _, stmt := range program.Stmts {
+		if stmt, ok := stmt.(*syntax.ExportStmt); ok {
+			if exports == nil {
+				exports = make(map[string]bool)
+			}
+			exports[stmt.Let.Name.Value] = true
+		}
+	}
+	return exports
+}
+
+// Hash implements Value.
+func (m *Module) Hash() (uint32, error) {
+	return String(m.Name).Hash()
+}
+
+// String implements Value.
+func (m *Module) String() string {
+	return fmt.Sprintf("<module %q>", m.Name)
+}
+
+// Truth implements Value.
+func (m *Module) Truth() bool {
+	return true
+}
+
+// Type implements Value.
+func (m *Module) Type() string {
+	return "module"
+}
+
+// BuiltinModules 是以名称为键的内置模块，例如 math。
+// import 的路径与内置模块的名称相同时直接返回内置模块，不会查找同名的文件。
+var BuiltinModules = map[string]*Module{}
+
+// NewBuiltinModule 返回名为 name、公开 members 中所有绑定的内置模块，
+// 扩展包可以将它加入 BuiltinModules：
+//
+//	monkey.BuiltinModules["kv"] = monkey.NewBuiltinModule("kv", map[string]monkey.Value{...})
+func NewBuiltinModule(name string, members map[string]Value) *Module {
+	env := NewEnv(nil)
+	for name, val := range members {
+		env.Set(name, val)
+	}
+	return &Module{Name: name, env: env}
+}
+
+// Importer 负责查找、加载和缓存模块，同一个模块只会被执行一次
+type Importer struct {
+	// CacheDir 是远程模块的缓存目录，为空时使用 DefaultCacheDir
+	CacheDir string
+
+	// Lockfile 是记录远程模块校验和的锁文件，为空时不校验也不记录
+	Lockfile string
+
+	mu      sync.Mutex
+	modules map[string]*Module // 以绝对路径为键
+}
+
+// NewImporter 返回一个使用当前目录所在项目的锁文件的 Importer，见 FindLockfile
+func NewImporter() *Importer {
+	return &Importer{Lockfile: FindLockfile(".")}
+}
+
+// Import 加载 path 对应的模块，相对路径相对于 from 所在的目录解析
+func (im *Importer) Import(thread *Thread, from, path string) (*Module, error) {
+	return im.load(thread, syntax.Position{}, from, path)
+}
+
+// 加载模块，pos 是 import 表达式在 from 中的位置。
+// 模块之间存在循环导入时，返回尚未执行完毕的模块，
+// 只要在模块初始化期间不访问尚未定义的绑定（例如只在函数体中使用），循环导入就是允许的。
+func (im *Importer) load(thread *Thread, pos syntax.Position, from, path string) (*Module, error) {
+	if module, ok := BuiltinModules[path]; ok {
+		return module, nil
+	}
+	if isRemote(path) {
+		if err := thread.Require(CapNet, "import"); err != nil {
+			return nil, err
+		}
+	}
+	filename, err := im.resolve(from, path)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, loading := range thread.imports {
+		if loading.module.Name == filename {
+			chain := append([]importFrame(nil), thread.imports[i+1:]...)
+			chain = append(chain, importFrame{module: loading.module, from: from, pos: pos})
+			loading.module.cycle = formatCycle(loading.module, chain)
+			return loading.module, nil
+		}
+	}
+
+	im.mu.Lock()
+	if im.modules == nil {
+		im.modules = make(map[string]*Module)
+	}
+	module, ok := im.modules[filename]
+	im.mu.Unlock()
+	if ok {
+		return module, nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	program, err := Parse(thread, filename, string(data))
+	if err != nil {
+		// 解析错误的位置中已经包含了文件名
+		return nil, err
+	}
+
+	module = &Module{Name: filename, env: NewEnv(nil), exports: exportedNames(program), loading: true}
+	file := thread.file
+	thread.file = filename
+	thread.imports = append(thread.imports, importFrame{module: module, from: from, pos: pos})
+	_, err = eval(thread, program, module.env)
+	thread.imports = thread.imports[:len(thread.imports)-1]
+	thread.file = file
+	module.loading = false
+	if err != nil {
+		return nil, err
+	}
+
+	im.mu.Lock()
+	im.modules[filename] = module
+	im.mu.Unlock()
+	return module, nil
+}
+
+// importFrame 是一个正在加载的模块，以及导入它的 import 表达式的位置
+type importFrame struct {
+	module *Module
+	from   string
+	pos    syntax.Position
+}
+
+// 将循环导入格式化为 "a.mky -> b.mky -> a.mky"，并逐行列出每次导入的位置
+func formatCycle(start *Module, chain []importFrame) string {
+	var names, lines []string
+	names = append(names, displayName(start.Name))
+	for _, frame := range chain {
+		names = append(names, displayName(frame.module.Name))
+		lines = append(lines, fmt.Sprintf("\t%s:%d:%d: imports %s",
+			displayName(frame.from), frame.pos.Line, frame.pos.Col, displayName(frame.module.Name)))
+	}
+	return strings.Join(names, " -> ") + "\n" + strings.Join(lines, "\n")
+}
+
+// 尽量使用相对于当前目录的路径显示模块文件
+func displayName(filename string) string {
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, filename); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return filename
+}
+
+// 标记项目根目录的文件
+var projectRootMarkers = []string{"monkey.toml", ".monkeyroot"}
+
+// FindProjectRoot 从 dir 开始向上查找包含 monkey.toml 或 .monkeyroot 的目录
+func FindProjectRoot(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		for _, marker := range projectRootMarkers {
+			if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+				return dir, true
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// 将 import 的参数解析为模块文件的绝对路径，按以下顺序查找：
+//
+//  1. 远程模块下载到缓存目录
+//  2. 绝对路径，以及以 ./ 或 ../ 开头的路径只相对于导入它的文件解析
+//  3. 相对于导入它的文件所在的目录
+//  4. 相对于项目根目录（包含 monkey.toml 或 .monkeyroot 的目录）
+//  5. 相对于环境变量 MONKEYPATH 中的每个目录
+func (im *Importer) resolve(from, path string) (string, error) {
+	if isRemote(path) {
+		return im.fetch(path)
+	}
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path), nil
+	}
+
+	dir := "."
+	if from != "" {
+		dir = filepath.Dir(from)
+	}
+	dirs := []string{dir}
+	if !strings.HasPrefix(path, "./") && !strings.HasPrefix(path, "../") {
+		if root, ok := FindProjectRoot(dir); ok {
+			dirs = append(dirs, root)
+		}
+		for _, dir := range filepath.SplitList(os.Getenv("MONKEYPATH")) {
+			if dir != "" {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+
+	var searched []string
+	for _, dir := range dirs {
+		filename, err := filepath.Abs(filepath.Join(dir, path))
+		if err != nil {
+			return "", err
+		}
+		if info, err := os.Stat(filename); err == nil && !info.IsDir() {
+			return filename, nil
+		}
+		searched = append(searched, filename)
+	}
+	return "", fmt.Errorf("module %q not found, searched:\n\t%s", path, strings.Join(searched, "\n\t"))
+}
+
+func (t *Thread) importer() *Importer {
+	if t.Importer == nil {
+		t.Importer = NewImporter()
+	}
+	return t.Importer
+}
+
+// 对 import 的路径求值并加载模块，相对路径相对于当前正在执行的模块解析
+func evalImport(thread *Thread, pos syntax.Position, expr syntax.Expr, env *Env) (*Module, error) {
+	arg, err := eval(thread, expr, env)
+	if err != nil {
+		return nil, err
+	}
+	path, ok := arg.(String)
+	if !ok {
+		return nil, fmt.Errorf("argument to `import` must be string, got %s", arg.Type())
+	}
+	from := thread.file
+	if from == "" {
+		from = thread.Name
+	}
+	return thread.importer().load(thread, pos, from, string(path))
+}
+
+var (
+	_ Value   = (*Module)(nil)
+	_ Mapping = (*Module)(nil)
+)
@@ -0,0 +1,388 @@
+package monkey
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+func TestImportRemote(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/example.com/lib/math.mky" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`let double = fn(x) { x * 2 };`))
+	}))
+	defer server.Close()
+	t.Setenv("MONKEYPROXY", server.URL)
+
+	dir := t.TempDir()
+	lockfile := filepath.Join(dir, LockfileName)
+	importer := &Importer{CacheDir: filepath.Join(dir, "cache"), Lockfile: lockfile}
+
+	program, err := syntax.NewParser(`import("pkg:example.com/lib/math.mky")["double"](21)`).Parse()
+	if err != nil {
+		t.Fatalf("parser error: %s", err)
+	}
+	// 导入远程模块需要 net 能力
+	if _, err := EvalThread(&Thread{Importer: importer}, program, NewEnv(nil)); err == nil || !strings.Contains(err.Error(), `"net" capability`) {
+		t.Fatalf("expected a policy error. got=%v", err)
+	}
+	if requests != 0 {
+		t.Fatalf("module fetched without the net capability")
+	}
+	val, err := EvalThread(&Thread{Importer: importer, Policy: NewPolicy(CapNet)}, program, NewEnv(nil))
+	if err != nil {
+		t.Fatalf("eval error: %s", err)
+	}
+	if val != Int(42) {
+		t.Errorf("wrong result. got=%s", val)
+	}
+
+	sums, err := ReadLockfile(lockfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sums["example.com/lib/math.mky"], "sha256:") {
+		t.Errorf("checksum not recorded. got=%v", sums)
+	}
+
+	// 第二次导入使用缓存，不再请求服务器
+	if _, err := (&Importer{CacheDir: importer.CacheDir, Lockfile: lockfile}).Fetch("pkg:example.com/lib/math.mky"); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 {
+		t.Errorf("module fetched %d times, want 1", requests)
+	}
+
+	// 校验和不一致时报错
+	sums["example.com/lib/math.mky"] = Checksum([]byte("tampered"))
+	if err := WriteLockfile(lockfile, sums); err != nil {
+		t.Fatal(err)
+	}
+	_, err = (&Importer{CacheDir: importer.CacheDir, Lockfile: lockfile}).Fetch("pkg:example.com/lib/math.mky")
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("expected checksum mismatch. got=%v", err)
+	}
+}
+
+func TestImportRemoteChecks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`let x = 1;`))
+	}))
+	defer server.Close()
+	t.Setenv("MONKEYPROXY", server.URL)
+
+	dir := t.TempDir()
+	cache := filepath.Join(dir, "cache")
+	lockfile := filepath.Join(dir, LockfileName)
+
+	// 名称中的 .. 和空的段不能让缓存文件落到缓存目录之外
+	for _, path := range []string{
+		"pkg:example.com/../../../x.mky",
+		"https://example.com/../../x.mky",
+		"pkg:example.com//x.mky",
+		"pkg:/x.mky",
+	} {
+		_, err := (&Importer{CacheDir: cache, Lockfile: lockfile}).Fetch(path)
+		if err == nil || !strings.Contains(err.Error(), "invalid remote module path") {
+			t.Errorf("%s: expected an invalid path error. got=%v", path, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "x.mky")); !os.IsNotExist(err) {
+		t.Errorf("file written outside the cache directory")
+	}
+
+	// 校验和不一致的下载不会写入缓存
+	if err := WriteLockfile(lockfile, map[string]string{"example.com/y.mky": Checksum([]byte("other"))}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := (&Importer{CacheDir: cache, Lockfile: lockfile}).Fetch("pkg:example.com/y.mky")
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("expected checksum mismatch. got=%v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cache, "example.com", "y.mky")); !os.IsNotExist(err) {
+		t.Errorf("module with a wrong checksum was cached")
+	}
+
+	// 不允许明文下载
+	_, err = (&Importer{CacheDir: cache, Lockfile: lockfile}).Fetch(server.URL + "/z.mky")
+	if err == nil || !strings.Contains(err.Error(), "insecure remote module") {
+		t.Errorf("expected an insecure module error. got=%v", err)
+	}
+
+	// 第一个段中有点号的本地路径不是远程模块
+	if isRemote("v1.2/util.mky") || isRemote("example.com/lib.mky") {
+		t.Errorf("local path treated as remote")
+	}
+}
+
+func TestImportRemoteLimits(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/example.com/slow.mky" {
+			<-release
+		}
+		w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	defer server.Close()
+	defer close(release)
+	t.Setenv("MONKEYPROXY", server.URL)
+
+	client, size := httpClient, maxModuleSize
+	defer func() { httpClient, maxModuleSize = client, size }()
+	httpClient = &http.Client{Timeout: 50 * time.Millisecond}
+	maxModuleSize = 10
+
+	cache := t.TempDir()
+	_, err := (&Importer{CacheDir: cache}).Fetch("pkg:example.com/big.mky")
+	if err == nil || !strings.Contains(err.Error(), "larger than 10 bytes") {
+		t.Errorf("expected a size error. got=%v", err)
+	}
+	_, err = (&Importer{CacheDir: cache}).Fetch("pkg:example.com/slow.mky")
+	if err == nil || !strings.Contains(err.Error(), "Timeout") {
+		t.Errorf("expected a timeout error. got=%v", err)
+	}
+}
+
+func TestImportCache(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib.mky")
+	if err := os.WriteFile(lib, []byte(`let n = random(1000000);`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	program, err := syntax.NewParser(`import("lib.mky")["n"] == import("./lib.mky")["n"]`).Parse()
+	if err != nil {
+		t.Fatalf("parser error: %s", err)
+	}
+	thread := &Thread{Name: filepath.Join(dir, "main.mky")}
+	val, err := EvalThread(thread, program, NewEnv(nil))
+	if err != nil {
+		t.Fatalf("eval error: %s", err)
+	}
+	if val != True {
+		t.Errorf("module evaluated more than once")
+	}
+}
+
+func TestImportAlias(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "lib"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "lib", "math.mky"), []byte(`let double = fn(x) { x * 2 };`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import("lib/math.mky") as m; m["double"](21)`, "42"},
+		{`from "lib/math.mky" import (double); double(4)`, "8"},
+		{`from "lib/math.mky" import (triple)`, `does not export "triple"`},
+		{`import(1)`, "must be string"},
+	}
+	for _, tt := range tests {
+		program, err := syntax.NewParser(tt.input).Parse()
+		if err != nil {
+			t.Fatalf("parser error: %s", err)
+		}
+		thread := &Thread{Name: filepath.Join(dir, "main.mky")}
+		val, err := EvalThread(thread, program, NewEnv(nil))
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestImportExports(t *testing.T) {
+	dir := t.TempDir()
+	src := `let helper = fn(x) { x + 1 }; export let inc = fn(x) { helper(x) }; export let zero = 0;`
+	if err := os.WriteFile(filepath.Join(dir, "lib.mky"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import("lib.mky")["inc"](1)`, "2"},
+		{`import("lib.mky")["helper"]`, "null"},
+		{`from "lib.mky" import (inc, zero); inc(zero)`, "1"},
+		{`from "lib.mky" import (helper)`, `does not export "helper"`},
+		{`let f = fn() { export let x = 1; }; f()`, "only allowed at the top level"},
+	}
+	for _, tt := range tests {
+		program, err := syntax.NewParser(tt.input).Parse()
+		if err != nil {
+			t.Fatalf("parser error: %s", err)
+		}
+		thread := &Thread{Name: filepath.Join(dir, "main.mky")}
+		val, err := EvalThread(thread, program, NewEnv(nil))
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	module, err := (&Importer{}).Import(&Thread{}, filepath.Join(dir, "main.mky"), "lib.mky")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(module.Exports(), ","); got != "inc,zero" {
+		t.Errorf("wrong exports. got=%s", got)
+	}
+}
+
+func TestImportCycle(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		// 只在函数体中使用对方的绑定，允许循环导入
+		"even.mky": `import("odd.mky") as odd; export let even = fn(n) { if (n == 0) { true } else { odd["odd"](n - 1) } };`,
+		"odd.mky":  `import("even.mky") as even; export let odd = fn(n) { if (n == 0) { false } else { even["even"](n - 1) } };`,
+		// 在初始化期间访问尚未定义的绑定
+		"a.mky": `from "b.mky" import (b); export let a = 1;`,
+		"b.mky": `from "a.mky" import (a); export let b = a + 1;`,
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import("even.mky")["even"](10)`, "true"},
+		{`import("odd.mky")["odd"](7)`, "true"},
+		{`import("a.mky")`, `"a" in`},
+	}
+	for _, tt := range tests {
+		program, err := syntax.NewParser(tt.input).Parse()
+		if err != nil {
+			t.Fatalf("parser error: %s", err)
+		}
+		thread := &Thread{Name: filepath.Join(dir, "main.mky")}
+		val, err := EvalThread(thread, program, NewEnv(nil))
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	_, err := (&Importer{}).Import(&Thread{}, filepath.Join(dir, "main.mky"), "a.mky")
+	if err == nil {
+		t.Fatal("expected import cycle error")
+	}
+	a, b := filepath.Join(dir, "a.mky"), filepath.Join(dir, "b.mky")
+	for _, want := range []string{a + " -> " + b + " -> " + a, a + ":1:1: imports", b + ":1:1: imports"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not contain %q. got=%q", want, err)
+		}
+	}
+}
+
+func TestImportResolve(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"project/monkey.toml":       ``,
+		"project/app/main.mky":      ``,
+		"project/app/local.mky":     `let origin = "local";`,
+		"project/lib/shared.mky":    `let origin = "root";`,
+		"project/app/lib/dup.mky":   `let origin = "relative";`,
+		"project/lib/dup.mky":       `let origin = "root";`,
+		"path1/vendor/pkg.mky":      `let origin = "path1";`,
+		"path2/vendor/pkg.mky":      `let origin = "path2";`,
+		"path2/vendor/only2.mky":    `let origin = "path2";`,
+		"project/app/sub/child.mky": `let origin = "child";`,
+	}
+	for name, src := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("MONKEYPATH", filepath.Join(dir, "path1")+string(filepath.ListSeparator)+filepath.Join(dir, "path2"))
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"local.mky", "local"},
+		{"./local.mky", "local"},
+		{"sub/child.mky", "child"},
+		{"lib/shared.mky", "root"},
+		{"lib/dup.mky", "relative"},
+		{"vendor/pkg.mky", "path1"},
+		{"vendor/only2.mky", "path2"},
+		{"./lib/shared.mky", "not found"},
+		{"missing.mky", "not found"},
+	}
+	from := filepath.Join(dir, "project", "app", "main.mky")
+	for _, tt := range tests {
+		var got string
+		module, err := (&Importer{}).Import(&Thread{}, from, tt.path)
+		if err != nil {
+			got = err.Error()
+		} else {
+			val, _, _ := module.Get(String("origin"))
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.path, tt.expected, got)
+		}
+	}
+
+	if root, ok := FindProjectRoot(filepath.Join(dir, "project", "app", "sub")); !ok || root != filepath.Join(dir, "project") {
+		t.Errorf("wrong project root. got=%q, %t", root, ok)
+	}
+}
+
+func TestFindLockfile(t *testing.T) {
+	dir := t.TempDir()
+	app := filepath.Join(dir, "project", "app")
+	if err := os.MkdirAll(app, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "project", "monkey.toml"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// 项目中的任何目录都使用项目根目录下的锁文件，不在项目中时使用目录本身下的锁文件
+	if got, expected := FindLockfile(app), filepath.Join(dir, "project", LockfileName); got != expected {
+		t.Errorf("expected %s. got=%s", expected, got)
+	}
+	if got, expected := FindLockfile(dir), filepath.Join(dir, LockfileName); got != expected {
+		t.Errorf("expected %s. got=%s", expected, got)
+	}
+}
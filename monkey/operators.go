@@ -0,0 +1,75 @@
+package monkey
+
+import (
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// OperatorSupport 表示运算符支持的一组操作数类型
+type OperatorSupport struct {
+	Op     syntax.Token
+	Left   string // 左操作数的类型，一元运算符时为空
+	Right  string // 右操作数的类型，一元运算符时为唯一的操作数
+	Result string // 运算结果的类型
+}
+
+var (
+	// 各种类型的操作数示例，数值不为零，以免除法因为除以零而失败
+	operandSamples = []Value{
+		Null,
+		True,
+		Int(3),
+		Float(2.5),
+		String("a"),
+		NewArray([]Value{Int(1)}),
+		NewMap(),
+		&Error{Msg: "e"},
+		&Function{},
+		Universe["len"],
+		&Record{typ: &RecordType{name: "record"}},
+	}
+
+	UnaryOperators   = []syntax.Token{syntax.MINUS, syntax.PLUS, syntax.BANG}
+	BinaryOperators  = []syntax.Token{syntax.PLUS, syntax.MINUS, syntax.STAR, syntax.SLASH, syntax.PERCENT, syntax.STARSTAR}
+	CompareOperators = []syntax.Token{syntax.EQ, syntax.NE, syntax.LT, syntax.LE, syntax.GT, syntax.GE}
+)
+
+// OperandTypes 返回 Operators 检查的所有操作数类型
+func OperandTypes() []string {
+	types := make([]string, len(operandSamples))
+	for i, v := range operandSamples {
+		types[i] = v.Type()
+	}
+	return types
+}
+
+// Operators 对每种类型的示例值尝试所有运算符，返回求值成功的组合。
+// 结果直接来自 Unary、Binary 和 Compare 的实现，因此总是与解释器的行为一致。
+func Operators() []OperatorSupport {
+	var ops []OperatorSupport
+	for _, op := range UnaryOperators {
+		for _, x := range operandSamples {
+			if v, err := Unary(op, x); err == nil {
+				ops = append(ops, OperatorSupport{Op: op, Right: x.Type(), Result: v.Type()})
+			}
+		}
+	}
+	for _, op := range BinaryOperators {
+		for _, x := range operandSamples {
+			for _, y := range operandSamples {
+				if v, err := Binary(op, x, y); err == nil {
+					ops = append(ops, OperatorSupport{Op: op, Left: x.Type(), Right: y.Type(), Result: v.Type()})
+				}
+			}
+		}
+	}
+	for _, op := range CompareOperators {
+		for _, x := range operandSamples {
+			for _, y := range operandSamples {
+				if v, err := Compare(op, x, y); err == nil {
+					ops = append(ops, OperatorSupport{Op: op, Left: x.Type(), Right: y.Type(), Result: v.Type()})
+				}
+			}
+		}
+	}
+	return ops
+}
@@ -0,0 +1,56 @@
+package monkey
+
+import (
+	"testing"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+func TestOperators(t *testing.T) {
+	type key struct {
+		op          syntax.Token
+		left, right string
+	}
+	results := make(map[key]string)
+	for _, op := range Operators() {
+		results[key{op.Op, op.Left, op.Right}] = op.Result
+	}
+
+	tests := []struct {
+		op          syntax.Token
+		left, right string
+		expected    string // 为空表示不支持
+	}{
+		{syntax.MINUS, "", "int", "int"},
+		{syntax.MINUS, "", "string", ""},
+		{syntax.BANG, "", "map", "bool"},
+		{syntax.PLUS, "int", "int", "int"},
+		{syntax.PLUS, "int", "float", "float"},
+		{syntax.PLUS, "float", "int", "float"},
+		{syntax.PLUS, "string", "string", "string"},
+		{syntax.PLUS, "string", "int", ""},
+		{syntax.PLUS, "null", "null", ""},
+		{syntax.STARSTAR, "int", "int", "int"},
+		{syntax.PERCENT, "float", "float", "float"},
+		{syntax.EQ, "bool", "bool", "bool"},
+		{syntax.EQ, "int", "float", "bool"},
+		{syntax.EQ, "record", "record", "bool"},
+		{syntax.LT, "bool", "bool", "bool"},
+		{syntax.LT, "record", "record", ""},
+		{syntax.LT, "int", "int", "bool"},
+		{syntax.EQ, "array", "array", ""},
+	}
+	for _, tt := range tests {
+		got := results[key{tt.op, tt.left, tt.right}]
+		if got != tt.expected {
+			t.Errorf("%s %s %s: expected %q. got=%q", tt.left, tt.op, tt.right, tt.expected, got)
+		}
+	}
+
+	// 每一项都必须记录操作数和结果的类型
+	for _, op := range Operators() {
+		if op.Left == "" && op.Right == "" || op.Result == "" {
+			t.Errorf("incomplete entry %+v", op)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package monkey
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Capability 是一项需要授权才能在脚本中使用的能力
+type Capability string
+
+const (
+	CapEval Capability = "eval" // eval() 和 parse()
+	CapNet  Capability = "net"  // 导入远程模块
+)
+
+// Capabilities 是所有已知的能力，提供内置函数的扩展包可以在 init 中注册新的能力
+var Capabilities = []Capability{CapEval, CapNet}
+
+// Policy 是允许脚本使用的能力的集合，nil 不允许任何能力
+type Policy struct {
+	allowed map[Capability]bool
+}
+
+// NewPolicy 返回允许 caps 的 Policy
+func NewPolicy(caps ...Capability) *Policy {
+	p := &Policy{allowed: make(map[Capability]bool)}
+	for _, c := range caps {
+		p.allowed[c] = true
+	}
+	return p
+}
+
+// ParsePolicy 解析以逗号分隔的能力列表，"all" 表示所有能力
+func ParsePolicy(s string) (*Policy, error) {
+	p := NewPolicy()
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		switch {
+		case name == "":
+		case name == "all":
+			for _, c := range Capabilities {
+				p.allowed[c] = true
+			}
+		case isCapability(Capability(name)):
+			p.allowed[Capability(name)] = true
+		default:
+			return nil, fmt.Errorf("unknown capability %q", name)
+		}
+	}
+	return p, nil
+}
+
+func isCapability(c Capability) bool {
+	for _, known := range Capabilities {
+		if c == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows 返回 p 是否允许能力 c
+func (p *Policy) Allows(c Capability) bool {
+	return p != nil && p.allowed[c]
+}
+
+// String 返回以逗号分隔的能力列表
+func (p *Policy) String() string {
+	if p == nil {
+		return ""
+	}
+	names := make([]string, 0, len(p.allowed))
+	for c := range p.allowed {
+		names = append(names, string(c))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// Require 检查线程的 Policy 是否允许内置函数 fn 使用能力 c
+func (t *Thread) Require(c Capability, fn string) error {
+	if !t.Policy.Allows(c) {
+		return fmt.Errorf("`%s` requires the %q capability, which is not allowed by the policy", fn, c)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package monkey
+
+import (
+	"fmt"
+	"strings"
+)
+
+func init() {
+	Universe["sprintf"] = NewBuiltinFunction("sprintf", sprintf)
+	Universe["printf"] = NewBuiltinFunction("printf", printf)
+}
+
+// sprintf(format, args...)
+//
+// 按 format 格式化参数并返回得到的字符串，支持以下动词：
+//
+//	%d  整数
+//	%f  浮点数，整数会被转换为浮点数
+//	%s  字符串原样输出，其他值与 print 的输出相同
+//	%q  带引号和转义的字符串
+//	%v  与 %s 相同
+//	%%  百分号
+//
+// 动词之前可以有与 Go 相同的标志、宽度和精度，例如 %-8s、%05d、%.2f。
+func sprintf(thread *Thread, args ...Value) (Value, error) {
+	s, err := format("sprintf", args)
+	if err != nil {
+		return nil, err
+	}
+	return String(s), nil
+}
+
+// printf(format, args...) 与 sprintf 相同，但输出格式化的结果而不是返回它，不会自动换行
+func printf(thread *Thread, args ...Value) (Value, error) {
+	s, err := format("printf", args)
+	if err != nil {
+		return nil, err
+	}
+	if thread.Print != nil {
+		thread.Print(thread, s)
+	} else {
+		fmt.Print(s)
+	}
+	return Null, nil
+}
+
+// 按 args[0] 格式化 args[1:]，fn 是用于错误信息的函数名
+func format(fn string, args []Value) (string, error) {
+	if len(args) < 1 {
+		return "", fmt.Errorf("wrong number of arguments. got=%d, want at least 1", len(args))
+	}
+	str, ok := args[0].(String)
+	if !ok {
+		return "", fmt.Errorf("first argument to `%s` must be string, got %s", fn, args[0].Type())
+	}
+	format, args := string(str), args[1:]
+
+	var out strings.Builder
+	n := 0 // 已经使用的参数个数
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			out.WriteByte(format[i])
+			continue
+		}
+		// 标志、宽度和精度原样交给 fmt
+		start := i
+		for i++; i < len(format) && strings.IndexByte("+-# 0123456789.", format[i]) >= 0; i++ {
+		}
+		if i == len(format) {
+			return "", fmt.Errorf("%s: incomplete verb %q at end of format", fn, format[start:])
+		}
+		verb := format[i]
+		if verb == '%' {
+			out.WriteByte('%')
+			continue
+		}
+		if n == len(args) {
+			return "", fmt.Errorf("%s: missing argument for %%%c", fn, verb)
+		}
+		arg, err := formatArg(fn, verb, args[n])
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&out, format[start:i+1], arg)
+		n++
+	}
+	if n < len(args) {
+		return "", fmt.Errorf("%s: %d unused arguments, format has %d verbs", fn, len(args)-n, n)
+	}
+	return out.String(), nil
+}
+
+// 将 val 转换为动词 verb 对应的 Go 值
+func formatArg(fn string, verb byte, val Value) (any, error) {
+	switch verb {
+	case 'd':
+		if i, ok := val.(Int); ok {
+			return int64(i), nil
+		}
+		return nil, fmt.Errorf("%s: %%d expects int, got %s", fn, val.Type())
+	case 'f':
+		switch x := val.(type) {
+		case Float:
+			return float64(x), nil
+		case Int:
+			return float64(x), nil
+		}
+		return nil, fmt.Errorf("%s: %%f expects float, got %s", fn, val.Type())
+	case 's', 'q', 'v':
+		if s, ok := val.(String); ok {
+			return string(s), nil
+		}
+		return val.String(), nil
+	}
+	return nil, fmt.Errorf("%s: unknown verb %%%c", fn, verb)
+}
@@ -0,0 +1,64 @@
+package monkey
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+func TestSprintf(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`sprintf("%d apples", 3)`, "3 apples"},
+		{`sprintf("%s=%v", "name", [1, "a"])`, "name=[1, a]"},
+		{`sprintf("%v %s", "raw", {"k": 1})`, "raw {k: 1}"},
+		{`sprintf("%q", "a\"b")`, `"a\"b"`},
+		{`sprintf("%.2f %f", 3.14159, 2)`, "3.14 2.000000"},
+		{`sprintf("[%5d|%-5s|%05d]", 42, "ab", 7)`, "[   42|ab   |00007]"},
+		{`sprintf("100%%")`, "100%"},
+		{`sprintf("no verbs")`, "no verbs"},
+		{`sprintf("%s %v", true, 1.5)`, "true 1.5"},
+		{`sprintf("%d", "x")`, "sprintf: %d expects int, got string"},
+		{`sprintf("%f", true)`, "sprintf: %f expects float, got bool"},
+		{`sprintf("%d %d", 1)`, "sprintf: missing argument for %d"},
+		{`sprintf("%d", 1, 2)`, "sprintf: 1 unused arguments, format has 1 verbs"},
+		{`sprintf("%x", 1)`, "sprintf: unknown verb %x"},
+		{`sprintf("50%")`, `sprintf: incomplete verb "%" at end of format`},
+		{`sprintf(1)`, "first argument to `sprintf` must be string, got int"},
+		{`sprintf()`, "wrong number of arguments. got=0, want at least 1"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalThread(t, tt.input)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestPrintf(t *testing.T) {
+	var out strings.Builder
+	thread := &Thread{Print: func(thread *Thread, msg string) { out.WriteString(msg) }}
+	program, err := syntax.NewParser(`printf("%s: %d\n", "count", 2); printf("done")`).Parse()
+	if err != nil {
+		t.Fatalf("parser error: %s", err)
+	}
+	val, err := EvalThread(thread, program, NewEnv(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != Null {
+		t.Errorf("printf should return null. got=%s", val)
+	}
+	if out.String() != "count: 2\ndone" {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+}
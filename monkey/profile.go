@@ -0,0 +1,318 @@
+package monkey
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultProfileInterval 是采样器默认的采样间隔
+const DefaultProfileInterval = time.Millisecond
+
+// Profiler 周期性地对 Thread 的调用栈进行采样。结果可以输出为火焰图工具（如 flamegraph.pl、speedscope）
+// 可以直接使用的 folded 格式、按函数统计的文本报告，或者 go tool pprof 可以读取的 profile.proto 格式。
+type Profiler struct {
+	thread   *Thread
+	interval time.Duration
+
+	mu      sync.Mutex
+	samples map[string]int
+	start   time.Time
+	elapsed time.Duration
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewProfiler 创建一个对 thread 进行采样的 Profiler，interval <= 0 时使用默认采样间隔
+func NewProfiler(thread *Thread, interval time.Duration) *Profiler {
+	if interval <= 0 {
+		interval = DefaultProfileInterval
+	}
+	return &Profiler{
+		thread:   thread,
+		interval: interval,
+		samples:  make(map[string]int),
+	}
+}
+
+// Start 启动后台采样
+func (p *Profiler) Start() {
+	p.start = time.Now()
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	go p.run()
+}
+
+// Stop 停止采样并等待采样 goroutine 退出
+func (p *Profiler) Stop() {
+	close(p.stop)
+	<-p.done
+	p.elapsed = time.Since(p.start)
+}
+
+func (p *Profiler) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.sample()
+		}
+	}
+}
+
+func (p *Profiler) sample() {
+	stack := p.thread.CallStack()
+	names := make([]string, 0, len(stack)+1)
+	names = append(names, "<toplevel>")
+	for _, frame := range stack {
+		names = append(names, foldedName(frame.Name))
+	}
+	key := strings.Join(names, ";")
+
+	p.mu.Lock()
+	p.samples[key]++
+	p.mu.Unlock()
+}
+
+// WriteFolded 以 folded 格式输出采样结果，每行为 "frame;frame;... count"，按调用栈排序
+func (p *Profiler) WriteFolded(w io.Writer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	keys := make([]string, 0, len(p.samples))
+	for key := range p.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if _, err := fmt.Fprintf(w, "%s %d\n", key, p.samples[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 把 folded 格式的调用栈拆分为帧名，最外层的帧在前
+func splitStack(key string) []string {
+	return strings.Split(key, ";")
+}
+
+// WriteReport 输出按函数统计的文本报告，格式与 go tool pprof -top 类似。
+// flat 是采样时函数位于栈顶的时间，cum 是函数位于调用栈中任意位置的时间，递归调用只计算一次；
+// 时间为采样次数乘以采样间隔。各行按 flat 从大到小排列
+func (p *Profiler) WriteReport(w io.Writer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	type entry struct {
+		name      string
+		flat, cum int
+	}
+	entries := make(map[string]*entry)
+	get := func(name string) *entry {
+		e, ok := entries[name]
+		if !ok {
+			e = &entry{name: name}
+			entries[name] = e
+		}
+		return e
+	}
+	total := 0
+	for key, n := range p.samples {
+		total += n
+		frames := splitStack(key)
+		get(frames[len(frames)-1]).flat += n
+		seen := make(map[string]bool)
+		for _, name := range frames {
+			if !seen[name] {
+				seen[name] = true
+				get(name).cum += n
+			}
+		}
+	}
+
+	list := make([]*entry, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.flat != b.flat {
+			return a.flat > b.flat
+		}
+		if a.cum != b.cum {
+			return a.cum > b.cum
+		}
+		return a.name < b.name
+	})
+
+	percent := func(n int) float64 {
+		if total == 0 {
+			return 0
+		}
+		return float64(n) * 100 / float64(total)
+	}
+	if _, err := fmt.Fprintf(w, "%d samples, %v total, sampled every %v\n", total, time.Duration(total)*p.interval, p.interval); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "%10s %7s %7s %10s %7s  %s\n", "flat", "flat%", "sum%", "cum", "cum%", "function"); err != nil {
+		return err
+	}
+	sum := 0
+	for _, e := range list {
+		sum += e.flat
+		_, err := fmt.Fprintf(w, "%10v %6.2f%% %6.2f%% %10v %6.2f%%  %s\n",
+			time.Duration(e.flat)*p.interval, percent(e.flat), percent(sum),
+			time.Duration(e.cum)*p.interval, percent(e.cum), e.name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WritePprof 以 gzip 压缩的 profile.proto 格式输出采样结果，可以用 go tool pprof 查看。
+// 每个 Monkey 函数对应一个 pprof 函数，每个样本包含采样次数和对应的 CPU 时间
+func (p *Profiler) WritePprof(w io.Writer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// 字符串表的第一项必须是空字符串
+	strs := map[string]int64{"": 0}
+	table := []string{""}
+	str := func(s string) int64 {
+		i, ok := strs[s]
+		if !ok {
+			i = int64(len(table))
+			strs[s] = i
+			table = append(table, s)
+		}
+		return i
+	}
+
+	keys := make([]string, 0, len(p.samples))
+	for key := range p.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b protobuf
+	valueType := func(field int, typ, unit string) {
+		var vt protobuf
+		vt.int(1, str(typ))
+		vt.int(2, str(unit))
+		b.bytes(field, vt)
+	}
+	valueType(1, "samples", "count")
+	valueType(1, "cpu", "nanoseconds")
+
+	// 每个函数只有一个 location，两者使用相同的 id
+	ids := make(map[string]uint64)
+	var names []string
+	for _, key := range keys {
+		frames := splitStack(key)
+		locs := make([]uint64, len(frames))
+		for i, name := range frames {
+			id, ok := ids[name]
+			if !ok {
+				id = uint64(len(names) + 1)
+				ids[name] = id
+				names = append(names, name)
+			}
+			// 样本中的 location 从栈顶开始排列
+			locs[len(frames)-1-i] = id
+		}
+		n := int64(p.samples[key])
+		var sample protobuf
+		sample.packed(1, locs...)
+		sample.packed(2, uint64(n), uint64(n*p.interval.Nanoseconds()))
+		b.bytes(2, sample)
+	}
+	for i := range names {
+		id := uint64(i + 1)
+		var loc, line protobuf
+		line.uint(1, id)
+		loc.uint(1, id)
+		loc.bytes(4, line)
+		b.bytes(4, loc)
+	}
+	for i, name := range names {
+		// pprof 会把尖括号当作 C++ 的模板参数去掉
+		if name == "<toplevel>" {
+			name = "toplevel"
+		}
+		var fn protobuf
+		fn.uint(1, uint64(i+1))
+		fn.int(2, str(name))
+		fn.int(3, str(name))
+		fn.int(4, str(p.thread.Name))
+		b.bytes(5, fn)
+	}
+	valueType(11, "cpu", "nanoseconds")
+	b.int(12, p.interval.Nanoseconds())
+	b.int(9, p.start.UnixNano())
+	b.int(10, p.elapsed.Nanoseconds())
+	// 字符串表最后写入，此后不能再加入新的字符串
+	for _, s := range table {
+		b.bytes(6, []byte(s))
+	}
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(b); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// protobuf 是 protocol buffers 编码的消息，只支持 WritePprof 用到的字段类型
+type protobuf []byte
+
+func (b *protobuf) varint(v uint64) {
+	for v >= 0x80 {
+		*b = append(*b, byte(v)|0x80)
+		v >>= 7
+	}
+	*b = append(*b, byte(v))
+}
+
+func (b *protobuf) uint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	b.varint(uint64(field) << 3)
+	b.varint(v)
+}
+
+func (b *protobuf) int(field int, v int64) {
+	b.uint(field, uint64(v))
+}
+
+func (b *protobuf) bytes(field int, data []byte) {
+	b.varint(uint64(field)<<3 | 2)
+	b.varint(uint64(len(data)))
+	*b = append(*b, data...)
+}
+
+func (b *protobuf) packed(field int, vs ...uint64) {
+	var data protobuf
+	for _, v := range vs {
+		data.varint(v)
+	}
+	b.bytes(field, data)
+}
+
+// folded 格式使用分号分隔帧、空格分隔计数，因此需要将帧名中的这些字符替换掉
+var foldedReplacer = strings.NewReplacer(";", ":", " ", "", "\n", "")
+
+func foldedName(name string) string {
+	return foldedReplacer.Replace(name)
+}
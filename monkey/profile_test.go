@@ -0,0 +1,84 @@
+package monkey
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestProfiler() *Profiler {
+	p := NewProfiler(&Thread{Name: "main.mky"}, 10*time.Millisecond)
+	p.samples = map[string]int{
+		"<toplevel>":                  1,
+		"<toplevel>;main;fib":         2,
+		"<toplevel>;main;fib;fib;fib": 4,
+		"<toplevel>;main;print":       3,
+	}
+	return p
+}
+
+func TestProfilerWriteReport(t *testing.T) {
+	var out strings.Builder
+	if err := newTestProfiler().WriteReport(&out); err != nil {
+		t.Fatal(err)
+	}
+	expected := `10 samples, 100ms total, sampled every 10ms
+      flat   flat%    sum%        cum    cum%  function
+      60ms  60.00%  60.00%       60ms  60.00%  fib
+      30ms  30.00%  90.00%       30ms  30.00%  print
+      10ms  10.00% 100.00%      100ms 100.00%  <toplevel>
+        0s   0.00% 100.00%       90ms  90.00%  main
+`
+	if out.String() != expected {
+		t.Errorf("wrong report.\nexpected=%s\ngot=%s", expected, out.String())
+	}
+}
+
+func TestProfilerWritePprof(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newTestProfiler().WritePprof(&buf); err != nil {
+		t.Fatal(err)
+	}
+	r, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 只解码顶层的字段，统计样本数并取出字符串表
+	samples := 0
+	var table []string
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		data = data[n:]
+		if tag&7 == 0 {
+			_, n = binary.Uvarint(data)
+			data = data[n:]
+			continue
+		}
+		size, n := binary.Uvarint(data)
+		value := data[n : n+int(size)]
+		data = data[n+int(size):]
+		switch tag >> 3 {
+		case 2:
+			samples++
+		case 6:
+			table = append(table, string(value))
+		}
+	}
+	if samples != 4 {
+		t.Errorf("expected 4 samples, got %d", samples)
+	}
+	expected := []string{"", "samples", "count", "cpu", "nanoseconds", "toplevel", "main.mky", "main", "fib", "print"}
+	if !reflect.DeepEqual(table, expected) {
+		t.Errorf("wrong string table.\nexpected=%q\ngot=%q", expected, table)
+	}
+}
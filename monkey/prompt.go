@@ -0,0 +1,119 @@
+package monkey
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Universe["confirm"] = NewBuiltinFunction("confirm", confirm)
+	Universe["select"] = NewBuiltinFunction("select", selectBuiltin)
+	Universe["password"] = NewBuiltinFunction("password", password)
+}
+
+// confirm(prompt) 或 confirm(prompt, default)
+//
+// 询问一个是非问题并返回 true 或 false。直接回车或输入结束时返回 default，默认为 false；
+// 无法识别的回答会重新询问。
+func confirm(thread *Thread, args ...Value) (Value, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+	prompt, ok := args[0].(String)
+	if !ok {
+		return nil, fmt.Errorf("first argument to `confirm` must be string, got %s", args[0].Type())
+	}
+	def := false
+	if len(args) == 2 {
+		b, ok := args[1].(Bool)
+		if !ok {
+			return nil, fmt.Errorf("second argument to `confirm` must be bool, got %s", args[1].Type())
+		}
+		def = bool(b)
+	}
+	hint := "[y/N]"
+	if def {
+		hint = "[Y/n]"
+	}
+
+	for {
+		fmt.Printf("%s %s ", prompt, hint)
+		line, err := thread.provider().ReadLine()
+		if err == io.EOF {
+			return Bool(def), nil
+		} else if err != nil {
+			return nil, err
+		}
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "":
+			return Bool(def), nil
+		case "y", "yes":
+			return True, nil
+		case "n", "no":
+			return False, nil
+		}
+		fmt.Println("Please answer y or n.")
+	}
+}
+
+// select(prompt, options)
+//
+// 列出带编号的选项，返回用户选择的那一项，输入结束时返回 null；无效的编号会重新询问。
+func selectBuiltin(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	prompt, ok := args[0].(String)
+	if !ok {
+		return nil, fmt.Errorf("first argument to `select` must be string, got %s", args[0].Type())
+	}
+	options, ok := args[1].(*Array)
+	if !ok {
+		return nil, fmt.Errorf("second argument to `select` must be array, got %s", args[1].Type())
+	}
+	if len(options.items) == 0 {
+		return nil, fmt.Errorf("`select` requires at least one option")
+	}
+
+	fmt.Println(prompt)
+	for i, option := range options.items {
+		fmt.Printf("  %d) %s\n", i+1, option)
+	}
+	for {
+		fmt.Printf("Enter a number (1-%d): ", len(options.items))
+		line, err := thread.provider().ReadLine()
+		if err == io.EOF {
+			return Null, nil
+		} else if err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(line))
+		if err == nil && n >= 1 && n <= len(options.items) {
+			return options.items[n-1], nil
+		}
+		fmt.Printf("Invalid choice %q.\n", line)
+	}
+}
+
+// password(prompt)
+//
+// 读取一行输入，在终端中输入的内容不会回显，输入结束时返回 null
+func password(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	prompt, ok := args[0].(String)
+	if !ok {
+		return nil, fmt.Errorf("argument to `password` must be string, got %s", args[0].Type())
+	}
+	fmt.Print(string(prompt))
+	line, err := thread.provider().ReadPassword()
+	if err == io.EOF {
+		return Null, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return String(line), nil
+}
@@ -0,0 +1,80 @@
+package monkey
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// 依次返回预先设定的输入行的 Provider
+type scriptedProvider struct {
+	SystemProvider
+	lines []string
+}
+
+func (p *scriptedProvider) ReadLine() (string, error) {
+	if len(p.lines) == 0 {
+		return "", io.EOF
+	}
+	line := p.lines[0]
+	p.lines = p.lines[1:]
+	return line, nil
+}
+
+func (p *scriptedProvider) ReadPassword() (string, error) {
+	return p.ReadLine()
+}
+
+func TestPromptBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		lines    []string
+		expected string
+	}{
+		{`confirm("Proceed?")`, []string{"y"}, "true"},
+		{`confirm("Proceed?")`, []string{"NO"}, "false"},
+		{`confirm("Proceed?")`, []string{""}, "false"},
+		{`confirm("Proceed?", true)`, []string{""}, "true"},
+		{`confirm("Proceed?", true)`, nil, "true"},
+		{`confirm("Proceed?")`, []string{"maybe", "yes"}, "true"},
+		{`confirm("Proceed?", 1)`, nil, "must be bool"},
+		{`select("Pick", ["a", "b", "c"])`, []string{"2"}, "b"},
+		{`select("Pick", ["a", "b", "c"])`, []string{"0", "x", "3"}, "c"},
+		{`select("Pick", ["a"])`, nil, "null"},
+		{`select("Pick", [])`, nil, "at least one option"},
+		{`select("Pick", "a")`, nil, "must be array"},
+		{`password("Token: ")`, []string{"s3cret"}, "s3cret"},
+		{`password("Token: ")`, nil, "null"},
+	}
+	for _, tt := range tests {
+		program, err := syntax.NewParser(tt.input).Parse()
+		if err != nil {
+			t.Fatalf("parser error: %s", err)
+		}
+		thread := &Thread{Provider: &scriptedProvider{lines: tt.lines}}
+		val, err := EvalThread(thread, program, NewEnv(nil))
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s %q: expected %q. got=%q", tt.input, tt.lines, tt.expected, got)
+		}
+	}
+}
+
+func TestRecordReplayPassword(t *testing.T) {
+	program, err := syntax.NewParser(`password("Token: ")`).Parse()
+	if err != nil {
+		t.Fatalf("parser error: %s", err)
+	}
+	log := `{"op":"password","value":"s3cret"}` + "\n"
+	val, err := EvalThread(&Thread{Provider: NewReplayer(strings.NewReader(log))}, program, NewEnv(nil))
+	if err != nil || val.String() != "s3cret" {
+		t.Errorf("replay password: got=%v, %v", val, err)
+	}
+}
@@ -0,0 +1,266 @@
+package monkey
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chzyer/readline"
+)
+
+// Provider 为内置函数提供所有非确定性的输入（时间、随机数、环境变量、标准输入）。
+// 所有此类内置函数都必须通过 Provider 获取数据，这样才能录制和回放一次执行。
+type Provider interface {
+	Now() (time.Time, error)
+	Random() (int64, error)
+	Getenv(name string) (_ string, found bool, err error)
+	ReadLine() (string, error)
+	ReadPassword() (string, error) // 与 ReadLine 相同，但在终端中不回显输入
+}
+
+// SystemProvider 直接使用操作系统提供的数据，可以被多个线程同时使用
+type SystemProvider struct {
+	once   sync.Once
+	mu     sync.Mutex
+	stdin  *bufio.Reader
+	random *rand.Rand
+}
+
+// Now implements Provider.
+func (s *SystemProvider) Now() (time.Time, error) {
+	return time.Now(), nil
+}
+
+// Random implements Provider.
+func (s *SystemProvider) Random() (int64, error) {
+	s.init()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.random.Int63(), nil
+}
+
+// Getenv implements Provider.
+func (s *SystemProvider) Getenv(name string) (string, bool, error) {
+	val, found := os.LookupEnv(name)
+	return val, found, nil
+}
+
+// ReadLine implements Provider.
+func (s *SystemProvider) ReadLine() (string, error) {
+	s.init()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	line, err := s.stdin.ReadString('\n')
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// ReadPassword implements Provider.
+func (s *SystemProvider) ReadPassword() (string, error) {
+	s.init()
+	fd := int(os.Stdin.Fd())
+	if !readline.IsTerminal(fd) {
+		return s.ReadLine()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	line, err := readline.ReadPassword(fd)
+	// 输入的换行符没有回显
+	fmt.Println()
+	return string(line), err
+}
+
+func (s *SystemProvider) init() {
+	s.once.Do(func() {
+		s.stdin = bufio.NewReader(os.Stdin)
+		s.random = rand.New(rand.NewSource(time.Now().UnixNano()))
+	})
+}
+
+var defaultProvider Provider = new(SystemProvider)
+
+// 录制文件中的一条记录，每行一个 JSON 对象
+type event struct {
+	Op    string `json:"op"`
+	Arg   string `json:"arg,omitempty"`
+	Value string `json:"value"`
+	Found bool   `json:"found,omitempty"`
+	Err   string `json:"err,omitempty"`
+}
+
+// Recorder 将底层 Provider 返回的所有数据写入 w，以便之后通过 NewReplayer 回放。
+// 录制的数据包括 password() 读取的密码，录制文件应当妥善保管。
+type Recorder struct {
+	p   Provider
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewRecorder 返回一个包装了 p 的 Recorder
+func NewRecorder(p Provider, w io.Writer) *Recorder {
+	return &Recorder{p: p, enc: json.NewEncoder(w)}
+}
+
+func (r *Recorder) record(ev event, err error) error {
+	if err != nil {
+		ev.Err = err.Error()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(ev); err != nil {
+		return fmt.Errorf("record: %v", err)
+	}
+	return nil
+}
+
+// Now implements Provider.
+func (r *Recorder) Now() (time.Time, error) {
+	t, err := r.p.Now()
+	if rerr := r.record(event{Op: "now", Value: t.Format(time.RFC3339Nano)}, err); rerr != nil {
+		return t, rerr
+	}
+	return t, err
+}
+
+// Random implements Provider.
+func (r *Recorder) Random() (int64, error) {
+	n, err := r.p.Random()
+	if rerr := r.record(event{Op: "random", Value: strconv.FormatInt(n, 10)}, err); rerr != nil {
+		return n, rerr
+	}
+	return n, err
+}
+
+// Getenv implements Provider.
+func (r *Recorder) Getenv(name string) (string, bool, error) {
+	val, found, err := r.p.Getenv(name)
+	if rerr := r.record(event{Op: "getenv", Arg: name, Value: val, Found: found}, err); rerr != nil {
+		return val, found, rerr
+	}
+	return val, found, err
+}
+
+// ReadLine implements Provider.
+func (r *Recorder) ReadLine() (string, error) {
+	line, err := r.p.ReadLine()
+	if rerr := r.record(event{Op: "readline", Value: line}, err); rerr != nil {
+		return line, rerr
+	}
+	return line, err
+}
+
+// ReadPassword implements Provider.
+func (r *Recorder) ReadPassword() (string, error) {
+	line, err := r.p.ReadPassword()
+	if rerr := r.record(event{Op: "password", Value: line}, err); rerr != nil {
+		return line, rerr
+	}
+	return line, err
+}
+
+// Replayer 按顺序回放 Recorder 录制的数据。
+// 如果脚本请求数据的顺序与录制时不一致，则返回错误。
+type Replayer struct {
+	mu  sync.Mutex
+	dec *json.Decoder
+}
+
+// NewReplayer 返回一个从 r 中读取录制数据的 Replayer
+func NewReplayer(r io.Reader) *Replayer {
+	return &Replayer{dec: json.NewDecoder(r)}
+}
+
+func (r *Replayer) next(op, arg string) (ev event, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.dec.Decode(&ev); err != nil {
+		if err == io.EOF {
+			return ev, fmt.Errorf("replay: unexpected %s call, recording exhausted", op)
+		}
+		return ev, fmt.Errorf("replay: %v", err)
+	}
+	if ev.Op != op || ev.Arg != arg {
+		return ev, fmt.Errorf("replay: expected %s(%q) call, got %s(%q)", ev.Op, ev.Arg, op, arg)
+	}
+	return ev, nil
+}
+
+func replayErr(ev event) error {
+	switch ev.Err {
+	case "":
+		return nil
+	case io.EOF.Error():
+		return io.EOF
+	default:
+		return errors.New(ev.Err)
+	}
+}
+
+// Now implements Provider.
+func (r *Replayer) Now() (time.Time, error) {
+	ev, err := r.next("now", "")
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.Parse(time.RFC3339Nano, ev.Value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("replay: %v", err)
+	}
+	return t, replayErr(ev)
+}
+
+// Random implements Provider.
+func (r *Replayer) Random() (int64, error) {
+	ev, err := r.next("random", "")
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(ev.Value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("replay: %v", err)
+	}
+	return n, replayErr(ev)
+}
+
+// Getenv implements Provider.
+func (r *Replayer) Getenv(name string) (string, bool, error) {
+	ev, err := r.next("getenv", name)
+	if err != nil {
+		return "", false, err
+	}
+	return ev.Value, ev.Found, replayErr(ev)
+}
+
+// ReadLine implements Provider.
+func (r *Replayer) ReadLine() (string, error) {
+	ev, err := r.next("readline", "")
+	if err != nil {
+		return "", err
+	}
+	return ev.Value, replayErr(ev)
+}
+
+// ReadPassword implements Provider.
+func (r *Replayer) ReadPassword() (string, error) {
+	ev, err := r.next("password", "")
+	if err != nil {
+		return "", err
+	}
+	return ev.Value, replayErr(ev)
+}
+
+var (
+	_ Provider = (*SystemProvider)(nil)
+	_ Provider = (*Recorder)(nil)
+	_ Provider = (*Replayer)(nil)
+)
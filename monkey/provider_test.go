@@ -0,0 +1,80 @@
+package monkey
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+func TestRecordReplay(t *testing.T) {
+	input := `[random(1000), time(), getenv("MONKEY_TEST_VAR")]`
+
+	program, err := syntax.NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("parser error: %s", err)
+	}
+
+	t.Setenv("MONKEY_TEST_VAR", "banana")
+
+	var log bytes.Buffer
+	recorded, err := EvalThread(
+		&Thread{Provider: NewRecorder(new(SystemProvider), &log)},
+		program, NewEnv(nil),
+	)
+	if err != nil {
+		t.Fatalf("record error: %s", err)
+	}
+
+	t.Setenv("MONKEY_TEST_VAR", "apple")
+
+	replayed, err := EvalThread(
+		&Thread{Provider: NewReplayer(strings.NewReader(log.String()))},
+		program, NewEnv(nil),
+	)
+	if err != nil {
+		t.Fatalf("replay error: %s", err)
+	}
+
+	if recorded.String() != replayed.String() {
+		t.Errorf("replay mismatch. recorded=%s, replayed=%s", recorded, replayed)
+	}
+}
+
+func TestReplayMismatch(t *testing.T) {
+	program, err := syntax.NewParser(`time()`).Parse()
+	if err != nil {
+		t.Fatalf("parser error: %s", err)
+	}
+
+	log := `{"op":"random","value":"42"}` + "\n"
+	_, err = EvalThread(&Thread{Provider: NewReplayer(strings.NewReader(log))}, program, NewEnv(nil))
+	if err == nil {
+		t.Fatalf("expected replay error, got nil")
+	}
+	if !strings.Contains(err.Error(), "replay") {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+// async 函数和 with_group 的任务共享调用者的 Provider，go test -race 可以发现其中的数据竞争
+func TestProviderConcurrent(t *testing.T) {
+	// 只调用 random()，回放时各个调用的先后顺序不影响结果的类型
+	input := `let r = async fn() { [random(), random(), random()] }; await [r(), r(), r(), r(), r(), r(), r(), r()]`
+	program, err := syntax.NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("parser error: %s", err)
+	}
+
+	var log bytes.Buffer
+	if _, err := EvalThread(&Thread{Provider: NewRecorder(new(SystemProvider), &log)}, program, NewEnv(nil)); err != nil {
+		t.Fatalf("record error: %s", err)
+	}
+	if n := strings.Count(log.String(), "\n"); n != 24 {
+		t.Errorf("expected 24 records, got %d", n)
+	}
+	if _, err := EvalThread(&Thread{Provider: NewReplayer(strings.NewReader(log.String()))}, program, NewEnv(nil)); err != nil {
+		t.Fatalf("replay error: %s", err)
+	}
+}
@@ -0,0 +1,350 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+)
+
+// quickcheck 默认运行的次数，以及收缩反例时最多尝试的次数
+const (
+	quickcheckTrials = 100
+	quickcheckShrink = 1000
+)
+
+// Generator 是 quickcheck 使用的随机值生成器
+type Generator struct {
+	name   string
+	gen    func(r *rand.Rand, size int) Value
+	shrink func(v Value) []Value // 返回比 v 更“小”的候选值
+}
+
+// Hash implements Value.
+func (g *Generator) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: generator")
+}
+
+// String implements Value.
+func (g *Generator) String() string {
+	return fmt.Sprintf("<generator %s>", g.name)
+}
+
+// Truth implements Value.
+func (g *Generator) Truth() bool {
+	return true
+}
+
+// Type implements Value.
+func (g *Generator) Type() string {
+	return "generator"
+}
+
+func init() {
+	Universe["quickcheck"] = NewBuiltinFunction("quickcheck", quickcheck)
+	Universe["gen_int"] = NewBuiltinFunction("gen_int", genInt)
+	Universe["gen_string"] = NewBuiltinFunction("gen_string", genString)
+	Universe["gen_array"] = NewBuiltinFunction("gen_array", genArray)
+	Universe["gen_map"] = NewBuiltinFunction("gen_map", genMap)
+}
+
+// quickcheck(property, generators...)
+//
+// 使用生成器生成的随机参数多次调用 property，property 返回假值或出错即视为失败。
+// 失败时会收缩参数，并返回一个包含最小反例的错误；全部通过时返回 null。
+func quickcheck(thread *Thread, args ...Value) (Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want at least 1", len(args))
+	}
+	property := args[0]
+	gens := make([]*Generator, len(args)-1)
+	for i, arg := range args[1:] {
+		gen, ok := arg.(*Generator)
+		if !ok {
+			return nil, fmt.Errorf("argument %d to `quickcheck` must be a generator, got %s", i+2, arg.Type())
+		}
+		gens[i] = gen
+	}
+
+	// 随机种子也通过 Provider 获取，以便录制和回放
+	seed, err := thread.provider().Random()
+	if err != nil {
+		return nil, err
+	}
+	r := rand.New(rand.NewSource(seed))
+
+	for trial := 0; trial < quickcheckTrials; trial++ {
+		values := make([]Value, len(gens))
+		for i, gen := range gens {
+			values[i] = gen.gen(r, trial+1)
+		}
+		if err := check(thread, property, values); err != nil {
+			if values, err = shrink(thread, property, gens, values, err); aborted(err) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("quickcheck: property failed after %d trials with arguments (%s): %w",
+				trial+1, joinValues(values), err)
+		}
+	}
+	return Null, nil
+}
+
+// 调用 property，返回失败的原因；通过时返回 nil
+func check(thread *Thread, property Value, values []Value) error {
+	result, err := Call(thread, property, values...)
+	if err != nil {
+		return err
+	}
+	if !result.Truth() {
+		return fmt.Errorf("returned %s", result)
+	}
+	return nil
+}
+
+// 求值被取消或者超出资源限制时，错误与 property 本身无关，应当立即停止而不是继续收缩
+func aborted(err error) bool {
+	return errors.Is(err, ErrCancelled) || errors.Is(err, ErrResourceExhausted)
+}
+
+// 贪心地将失败的参数逐个替换为更小的候选值，直到无法继续收缩；reason 是 values 失败的原因
+func shrink(thread *Thread, property Value, gens []*Generator, values []Value, reason error) ([]Value, error) {
+	if aborted(reason) {
+		return values, reason
+	}
+	steps := 0
+	for shrunk := true; shrunk && steps < quickcheckShrink; {
+		shrunk = false
+		for i, gen := range gens {
+			for _, candidate := range gen.shrink(values[i]) {
+				if steps++; steps >= quickcheckShrink {
+					return values, reason
+				}
+				next := append([]Value(nil), values...)
+				next[i] = candidate
+				if err := check(thread, property, next); aborted(err) {
+					return values, err
+				} else if err != nil {
+					values, reason, shrunk = next, err, true
+					break
+				}
+			}
+		}
+	}
+	return values, reason
+}
+
+func joinValues(values []Value) string {
+	strs := make([]string, len(values))
+	for i, v := range values {
+		if s, ok := v.(String); ok {
+			strs[i] = fmt.Sprintf("%q", string(s))
+		} else {
+			strs[i] = v.String()
+		}
+	}
+	return strings.Join(strs, ", ")
+}
+
+// gen_int() 或 gen_int(min, max) 生成 [min, max] 范围内的整数，默认范围随运行次数增长
+func genInt(thread *Thread, args ...Value) (Value, error) {
+	var bounded bool
+	var min, max Int
+	switch len(args) {
+	case 0:
+	case 2:
+		var ok1, ok2 bool
+		min, ok1 = args[0].(Int)
+		max, ok2 = args[1].(Int)
+		if !ok1 || !ok2 || min > max {
+			return nil, fmt.Errorf("arguments to `gen_int` must be ints with min <= max")
+		}
+		bounded = true
+	default:
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=0 or 2", len(args))
+	}
+
+	return &Generator{
+		name: "int",
+		gen: func(r *rand.Rand, size int) Value {
+			if bounded {
+				return randInt(r, min, max)
+			}
+			return Int(r.Intn(2*size+1) - size)
+		},
+		shrink: func(v Value) []Value {
+			var candidates []Value
+			for _, c := range shrinkInt(v.(Int)) {
+				if !bounded || (c >= min && c <= max) {
+					candidates = append(candidates, c)
+				}
+			}
+			return candidates
+		},
+	}, nil
+}
+
+// 返回 [min, max] 范围内均匀分布的整数，max - min 可能超出 int64 的范围
+func randInt(r *rand.Rand, min, max Int) Int {
+	span := uint64(max) - uint64(min)
+	var n uint64
+	switch {
+	case span < math.MaxInt64:
+		n = uint64(r.Int63n(int64(span) + 1))
+	case span == math.MaxUint64:
+		n = r.Uint64()
+	default:
+		// 拒绝采样，每次被拒绝的概率小于 1/2
+		for n = r.Uint64(); n > span; n = r.Uint64() {
+		}
+	}
+	return Int(uint64(min) + n)
+}
+
+func shrinkInt(x Int) []Int {
+	if x == 0 {
+		return nil
+	}
+	candidates := []Int{0}
+	if x < 0 && x != math.MinInt64 {
+		candidates = append(candidates, -x)
+	}
+	if half := x / 2; half != 0 {
+		candidates = append(candidates, half)
+	}
+	if x > 0 {
+		candidates = append(candidates, x-1)
+	} else {
+		candidates = append(candidates, x+1)
+	}
+	return candidates
+}
+
+// gen_string() 生成由可打印 ASCII 字符组成的字符串
+func genString(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=0", len(args))
+	}
+	return &Generator{
+		name: "string",
+		gen: func(r *rand.Rand, size int) Value {
+			buf := make([]byte, r.Intn(size+1))
+			for i := range buf {
+				buf[i] = byte(' ' + r.Intn('~'-' '+1))
+			}
+			return String(buf)
+		},
+		shrink: func(v Value) []Value {
+			s := string(v.(String))
+			var candidates []Value
+			if len(s) > 0 {
+				candidates = append(candidates, String(""), String(s[:len(s)/2]))
+			}
+			for i := 0; i < len(s); i++ {
+				candidates = append(candidates, String(s[:i]+s[i+1:]))
+			}
+			// 将字符简化为 'a'
+			for i := 0; i < len(s); i++ {
+				if s[i] != 'a' {
+					candidates = append(candidates, String(s[:i]+"a"+s[i+1:]))
+				}
+			}
+			return candidates
+		},
+	}, nil
+}
+
+// gen_array(gen) 生成元素由 gen 生成的数组
+func genArray(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	elem, ok := args[0].(*Generator)
+	if !ok {
+		return nil, fmt.Errorf("argument to `gen_array` must be a generator, got %s", args[0].Type())
+	}
+	return &Generator{
+		name: "array",
+		gen: func(r *rand.Rand, size int) Value {
+			items := make([]Value, r.Intn(size+1))
+			for i := range items {
+				items[i] = elem.gen(r, size)
+			}
+			return NewArray(items)
+		},
+		shrink: func(v Value) []Value {
+			items := v.(*Array).items
+			var candidates []Value
+			if len(items) > 0 {
+				candidates = append(candidates, NewArray(nil), NewArray(append([]Value(nil), items[:len(items)/2]...)))
+			}
+			// 删除一个元素
+			for i := range items {
+				rest := append(append([]Value(nil), items[:i]...), items[i+1:]...)
+				candidates = append(candidates, NewArray(rest))
+			}
+			// 收缩一个元素
+			for i, item := range items {
+				for _, c := range elem.shrink(item) {
+					next := append([]Value(nil), items...)
+					next[i] = c
+					candidates = append(candidates, NewArray(next))
+				}
+			}
+			return candidates
+		},
+	}, nil
+}
+
+// gen_map(keyGen, valueGen) 生成键值分别由 keyGen 和 valueGen 生成的 map
+func genMap(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	keyGen, ok1 := args[0].(*Generator)
+	valGen, ok2 := args[1].(*Generator)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("arguments to `gen_map` must be generators")
+	}
+	build := func(entries []MapEntry) Value {
+		m := NewMap()
+		for _, entry := range entries {
+			m.SetKey(entry.Key, entry.Value)
+		}
+		return m
+	}
+	return &Generator{
+		name: "map",
+		gen: func(r *rand.Rand, size int) Value {
+			m := NewMap()
+			for n := r.Intn(size + 1); n > 0; n-- {
+				// 不可哈希的键直接跳过
+				m.SetKey(keyGen.gen(r, size), valGen.gen(r, size))
+			}
+			return m
+		},
+		shrink: func(v Value) []Value {
+			entries := v.(*Map).Items()
+			var candidates []Value
+			if len(entries) > 0 {
+				candidates = append(candidates, NewMap())
+			}
+			for i := range entries {
+				rest := append(append([]MapEntry(nil), entries[:i]...), entries[i+1:]...)
+				candidates = append(candidates, build(rest))
+			}
+			for i, entry := range entries {
+				for _, c := range valGen.shrink(entry.Value) {
+					next := append([]MapEntry(nil), entries...)
+					next[i] = MapEntry{Key: entry.Key, Value: c}
+					candidates = append(candidates, build(next))
+				}
+			}
+			return candidates
+		},
+	}, nil
+}
+
+var (
+	_ Value = (*Generator)(nil)
+)
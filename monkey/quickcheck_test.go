@@ -0,0 +1,103 @@
+package monkey
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// 返回固定随机数的 Provider，使测试结果可重复
+type fixedProvider struct {
+	SystemProvider
+	seed int64
+}
+
+func (p *fixedProvider) Random() (int64, error) {
+	return p.seed, nil
+}
+
+func (p *fixedProvider) Now() (time.Time, error) {
+	return time.Unix(0, 0), nil
+}
+
+func testEvalThread(t *testing.T, input string) (Value, error) {
+	t.Helper()
+	program, err := syntax.NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("parser error: %s", err)
+	}
+	thread := &Thread{Provider: &fixedProvider{seed: 42}}
+	return EvalThread(thread, program, NewEnv(nil))
+}
+
+func TestQuickcheckPass(t *testing.T) {
+	tests := []string{
+		`quickcheck(fn(x, y) { x + y == y + x }, gen_int(), gen_int())`,
+		`quickcheck(fn(s) { len(s + s) == 2 * len(s) }, gen_string())`,
+		`quickcheck(fn(a) { len(a) > -1 }, gen_array(gen_int(0, 9)))`,
+		`quickcheck(fn(m) { true }, gen_map(gen_string(), gen_int()))`,
+		// 范围超过 int64 的一半时不能溢出
+		`quickcheck(fn(x) { true }, gen_int(-9223372036854775807 - 1, 9223372036854775807))`,
+		`quickcheck(fn(x) { x >= -1 }, gen_int(-1, 9223372036854775807))`,
+		`quickcheck(fn(x) { x == 7 }, gen_int(7, 7))`,
+	}
+	for _, input := range tests {
+		val, err := testEvalThread(t, input)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", input, err)
+			continue
+		}
+		if val != Null {
+			t.Errorf("%s: expected null. got=%s", input, val)
+		}
+	}
+}
+
+func TestQuickcheckShrink(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quickcheck(fn(x) { x < 10 }, gen_int())`, "with arguments (10)"},
+		{`quickcheck(fn(x) { x > -5 }, gen_int(-100, 100))`, "with arguments (-5)"},
+		{`quickcheck(fn(s) { len(s) < 3 }, gen_string())`, `with arguments ("aaa")`},
+		{`quickcheck(fn(a) { len(a) < 2 }, gen_array(gen_int()))`, "with arguments ([0, 0])"},
+	}
+	for _, tt := range tests {
+		_, err := testEvalThread(t, tt.input)
+		if err == nil {
+			t.Errorf("%s: expected error", tt.input)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: error does not contain %q. got=%q", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestQuickcheckAbort(t *testing.T) {
+	program, err := syntax.NewParser(`quickcheck(fn(x) { let f = fn(n) { if (n > 0) { f(n - 1) } }; f(1000); true }, gen_int())`).Parse()
+	if err != nil {
+		t.Fatalf("parser error: %s", err)
+	}
+	steps := 0
+	thread := &Thread{Provider: &fixedProvider{seed: 42}, MaxSteps: 500, Hooks: &Hooks{OnStmt: func(*Thread, syntax.Stmt, error) { steps++ }}}
+	_, err = EvalThread(thread, program, NewEnv(nil))
+	if !errors.Is(err, ErrResourceExhausted) {
+		t.Fatalf("expected a resource exhausted error. got=%v", err)
+	}
+	// 超出限制之后不再收缩，property 不会被继续调用
+	if steps > 1000 {
+		t.Errorf("property kept running after the step limit, %d statements executed", steps)
+	}
+
+	// 其他错误被包装起来，仍然可以通过 errors.As 取出
+	_, err = testEvalThread(t, `quickcheck(fn(x) { 1 / 0 }, gen_int())`)
+	var evalErr *EvalError
+	if !errors.As(err, &evalErr) || !strings.Contains(err.Error(), "quickcheck: property failed") {
+		t.Errorf("expected a wrapped runtime error. got=%v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package monkey
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// RecordType 是 record 语句声明的记录类型，调用它按字段的顺序创建记录：
+//
+//	record Point {x, y}
+//	let p = Point(1, 2);
+//	p.x + p["y"]  // 3
+type RecordType struct {
+	name   string
+	fields []string
+}
+
+// CallInternal implements Callable.
+func (r *RecordType) CallInternal(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != len(r.fields) {
+		return nil, fmt.Errorf("wrong number of arguments to %s. got=%d, want=%d", r.name, len(args), len(r.fields))
+	}
+	values := make([]Value, len(args))
+	copy(values, args)
+	return &Record{typ: r, values: values}, nil
+}
+
+// Name implements Callable.
+func (r *RecordType) Name() string {
+	return r.name
+}
+
+// Hash implements Value.
+func (r *RecordType) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: record_type")
+}
+
+// String implements Value.
+func (r *RecordType) String() string {
+	return fmt.Sprintf("<record %s>", r.name)
+}
+
+// Truth implements Value.
+func (r *RecordType) Truth() bool {
+	return true
+}
+
+// Type implements Value.
+func (r *RecordType) Type() string {
+	return "record_type"
+}
+
+// Record 是记录类型的实例。记录创建后不能修改，字段可以通过 r.x 或 r["x"] 读取。
+// 同一个记录类型的两个记录在所有字段都相等时相等，字段都可以哈希时记录可以作为 map 的键。
+type Record struct {
+	typ    *RecordType
+	values []Value
+}
+
+// Get implements Mapping.
+func (r *Record) Get(key Value) (_ Value, _ bool, err error) {
+	name, ok := key.(String)
+	if !ok {
+		return Null, false, nil
+	}
+	for i, field := range r.typ.fields {
+		if field == string(name) {
+			return r.values[i], true, nil
+		}
+	}
+	return Null, false, nil
+}
+
+// Compare implements Comparable.
+func (r *Record) Compare(op syntax.Token, y Value) (_ Value, err error) {
+	other, ok := y.(*Record)
+	if !ok {
+		return nil, fmt.Errorf("invalid cmp operator: %s %s %s", r, op, y)
+	}
+	equal := r.typ == other.typ
+	for i := 0; equal && i < len(r.values); i++ {
+		equal = equalValues(r.values[i], other.values[i])
+	}
+	switch op {
+	case syntax.EQ:
+		return Bool(equal), nil
+	case syntax.NE:
+		return Bool(!equal), nil
+	}
+	return nil, fmt.Errorf("invalid cmp operator: %s %s %s", r, op, y)
+}
+
+// Hash implements Value.
+func (r *Record) Hash() (uint32, error) {
+	h := softHashString(r.typ.name)
+	for _, val := range r.values {
+		vh, err := val.Hash()
+		if err != nil {
+			return 0, err
+		}
+		h = h*31 + vh
+	}
+	return h, nil
+}
+
+// String implements Value.
+func (r *Record) String() string {
+	var out bytes.Buffer
+	out.WriteString(r.typ.name)
+	out.WriteString("{")
+	for i, field := range r.typ.fields {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(field)
+		out.WriteString(": ")
+		out.WriteString(r.values[i].String())
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
+// Truth implements Value.
+func (r *Record) Truth() bool {
+	return true
+}
+
+// Type implements Value.
+func (r *Record) Type() string {
+	return r.typ.name
+}
+
+// 执行 record 语句，创建记录类型
+func evalRecordStmt(node *syntax.RecordStmt) *RecordType {
+	fields := make([]string, len(node.Fields))
+	for i, field := range node.Fields {
+		fields[i] = field.Value
+	}
+	return &RecordType{name: node.Name.Value, fields: fields}
+}
+
+var (
+	_ Callable   = (*RecordType)(nil)
+	_ Mapping    = (*Record)(nil)
+	_ Comparable = (*Record)(nil)
+)
@@ -0,0 +1,45 @@
+package monkey
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRecords(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"record Point {x, y}; Point(1, 2)", "Point{x: 1, y: 2}"},
+		{"record Point {x, y}; Point", "<record Point>"},
+		{"record Point {x, y}; let p = Point(1, 2); p.x + p[\"y\"]", "3"},
+		{"record Point {x, y}; Point(1, 2) == Point(1, 2)", "true"},
+		{"record Point {x, y}; Point(1, 2) != Point(1, 3)", "true"},
+		{"record A {x}; A(1) == A(2)", "false"},
+		{"record A {x}; record B {x}; A(1) == B(1)", "invalid cmp operator: A{x: 1} == B{x: 1}"},
+		{"record Point {x, y}; let m = {Point(1, 2): \"a\"}; m[Point(1, 2)]", "a"},
+		{"record Point {x, y}; Point(1, 2)[\"z\"]", "null"},
+		{"record Line {start, end}; record Point {x, y}; Line(Point(0, 0), Point(3, 4)).end.y", "4"},
+		{"record Empty {}; Empty()", "Empty{}"},
+		{"let f = fn() { record P {v}; P(1) }; f().v", "1"},
+		{"{\"a\": {\"b\": 1}}.a.b", "1"},
+		{"record Point {x, y}; Point(1)", "wrong number of arguments to Point. got=1, want=2"},
+		{"record Point {x, y}; Point(1, 2).z", "1:34 Point has no field z"},
+		{"1.x", "int has no fields, cannot access .x"},
+		{"record Box {v}; {Box([1]): 1}", "unhashable type: array"},
+		{"record Point {x, y}; Point(1, 2) < Point(1, 2)", "invalid cmp operator"},
+	}
+
+	for _, tt := range tests {
+		val, err := testEvalThread(t, tt.input)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package monkey
+
+import (
+	"fmt"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+func init() {
+	Universe["eval"] = NewBuiltinFunction("eval", evalBuiltin)
+	Universe["parse"] = NewBuiltinFunction("parse", parseBuiltin)
+}
+
+// eval(code) 或 eval(code, globals)
+//
+// 在新的全局作用域中执行 code 并返回最后一个表达式的值，
+// globals 是一个以字符串为键的 map，其中的键值对会预先绑定到该作用域中。
+func evalBuiltin(thread *Thread, args ...Value) (Value, error) {
+	if err := thread.Require(CapEval, "eval"); err != nil {
+		return nil, err
+	}
+	if len(args) < 1 || len(args) > 2 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+	code, ok := args[0].(String)
+	if !ok {
+		return nil, fmt.Errorf("first argument to `eval` must be string, got %s", args[0].Type())
+	}
+
+	env := NewEnv(nil)
+	if len(args) == 2 {
+		globals, ok := args[1].(*Map)
+		if !ok {
+			return nil, fmt.Errorf("second argument to `eval` must be map, got %s", args[1].Type())
+		}
+		for _, entry := range globals.Items() {
+			name, ok := entry.Key.(String)
+			if !ok {
+				return nil, fmt.Errorf("keys of `eval` globals must be strings, got %s", entry.Key.Type())
+			}
+			env.Set(string(name), entry.Value)
+		}
+	}
+
+	program, err := Parse(thread, "<eval>", string(code))
+	if err != nil {
+		return nil, err
+	}
+	return eval(thread, program, env)
+}
+
+// parse(code)
+//
+// 解析 code 并以 map 的形式返回语法树，每个节点都有表示节点类型的 "type" 键，
+// 除 Program 外还有表示位置的 "line" 和 "col" 键，其余的键对应节点的各个部分。
+func parseBuiltin(thread *Thread, args ...Value) (Value, error) {
+	if err := thread.Require(CapEval, "parse"); err != nil {
+		return nil, err
+	}
+	if len(args) != 1 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	code, ok := args[0].(String)
+	if !ok {
+		return nil, fmt.Errorf("argument to `parse` must be string, got %s", args[0].Type())
+	}
+	program, err := Parse(thread, "<parse>", string(code))
+	if err != nil {
+		return nil, err
+	}
+	return astValue(program), nil
+}
+
+// 将语法树节点转换为 map
+func astValue(node syntax.Node) Value {
+	m := NewMap()
+	set := func(key string, value Value) { m.SetKey(String(key), value) }
+
+	var typ string
+	switch node := node.(type) {
+	case *syntax.Program:
+		set("stmts", stmtsValue(node.Stmts))
+		set("type", String("Program"))
+		return m
+
+	case *syntax.LetStmt:
+		typ = "LetStmt"
+		set("name", String(node.Name.Value))
+		set("value", astValue(node.Value))
+	case *syntax.ExportStmt:
+		typ = "ExportStmt"
+		set("let", astValue(node.Let))
+	case *syntax.ReturnStmt:
+		typ = "ReturnStmt"
+		set("value", astValue(node.Value))
+	case *syntax.ExprStmt:
+		typ = "ExprStmt"
+		set("expr", astValue(node.Expr))
+	case *syntax.BlockStmt:
+		typ = "BlockStmt"
+		set("stmts", stmtsValue(node.Stmts))
+	case *syntax.ImportStmt:
+		typ = "ImportStmt"
+		set("import", astValue(node.Import))
+		set("alias", String(node.Alias.Value))
+	case *syntax.FromImportStmt:
+		typ = "FromImportStmt"
+		set("path", astValue(node.Path))
+		set("names", namesValue(node.Names))
+
+	case *syntax.Identifier:
+		typ = "Identifier"
+		set("name", String(node.Value))
+	case *syntax.IntegerLiteral:
+		typ = "IntegerLiteral"
+		set("value", Int(node.Value))
+	case *syntax.FloatLiteral:
+		typ = "FloatLiteral"
+		set("value", Float(node.Value))
+	case *syntax.StringLiteral:
+		typ = "StringLiteral"
+		set("value", String(node.Value))
+	case *syntax.Boolean:
+		typ = "Boolean"
+		set("value", Bool(node.Value))
+	case *syntax.ArrayLiteral:
+		typ = "ArrayLiteral"
+		set("items", exprsValue(node.Items))
+	case *syntax.MapLiteral:
+		typ = "MapLiteral"
+		pairs := make([]Value, len(node.Pairs))
+		for i, pair := range node.Pairs {
+			pairs[i] = NewArray([]Value{astValue(pair.Key), astValue(pair.Value)})
+		}
+		set("pairs", NewArray(pairs))
+	case *syntax.PrefixExpr:
+		typ = "PrefixExpr"
+		set("op", String(node.Op.String()))
+		set("right", astValue(node.Right))
+	case *syntax.InfixExpr:
+		typ = "InfixExpr"
+		set("op", String(node.Op.String()))
+		set("left", astValue(node.Left))
+		set("right", astValue(node.Right))
+	case *syntax.IfExpr:
+		typ = "IfExpr"
+		set("cond", astValue(node.Cond))
+		set("consequence", astValue(node.Consequence))
+		if node.Alternative != nil {
+			set("alternative", astValue(node.Alternative))
+		} else {
+			set("alternative", Null)
+		}
+	case *syntax.FunctionLiteral:
+		typ = "FunctionLiteral"
+		set("name", Null)
+		if node.Name != nil {
+			set("name", String(node.Name.Value))
+		}
+		set("async", Bool(node.Async))
+		set("params", namesValue(node.Params))
+		set("variadic", Bool(node.Variadic))
+		set("body", astValue(node.Body))
+	case *syntax.MacroLiteral:
+		typ = "MacroLiteral"
+		set("params", namesValue(node.Params))
+		set("body", astValue(node.Body))
+	case *syntax.AwaitExpr:
+		typ = "AwaitExpr"
+		set("value", astValue(node.Value))
+	case *syntax.SpreadExpr:
+		typ = "SpreadExpr"
+		set("value", astValue(node.Value))
+	case *syntax.CallExpr:
+		typ = "CallExpr"
+		set("function", astValue(node.Function))
+		set("args", exprsValue(node.Args))
+	case *syntax.IndexExpr:
+		typ = "IndexExpr"
+		set("left", astValue(node.Left))
+		set("index", astValue(node.Index))
+	case *syntax.SliceExpr:
+		typ = "SliceExpr"
+		set("left", astValue(node.Left))
+		set("start", Null)
+		if node.Start != nil {
+			set("start", astValue(node.Start))
+		}
+		set("end", Null)
+		if node.End != nil {
+			set("end", astValue(node.End))
+		}
+	case *syntax.DotExpr:
+		typ = "DotExpr"
+		set("left", astValue(node.Left))
+		set("name", String(node.Name.Value))
+	case *syntax.FunctionStmt:
+		typ = "FunctionStmt"
+		set("fn", astValue(node.Fn))
+	case *syntax.RecordStmt:
+		typ = "RecordStmt"
+		set("name", String(node.Name.Value))
+		set("fields", namesValue(node.Fields))
+	case *syntax.ImportExpr:
+		typ = "ImportExpr"
+		set("path", astValue(node.Path))
+	default:
+		typ = fmt.Sprintf("%T", node)
+	}
+
+	start, _ := node.Span()
+	set("type", String(typ))
+	set("line", Int(start.Line))
+	set("col", Int(start.Col))
+	return m
+}
+
+func stmtsValue(stmts []syntax.Stmt) Value {
+	items := make([]Value, len(stmts))
+	for i, stmt := range stmts {
+		items[i] = astValue(stmt)
+	}
+	return NewArray(items)
+}
+
+func exprsValue(exprs []syntax.Expr) Value {
+	items := make([]Value, len(exprs))
+	for i, expr := range exprs {
+		items[i] = astValue(expr)
+	}
+	return NewArray(items)
+}
+
+func namesValue(names []*syntax.Identifier) Value {
+	items := make([]Value, len(names))
+	for i, name := range names {
+		items[i] = String(name.Value)
+	}
+	return NewArray(items)
+}
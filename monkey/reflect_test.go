@@ -0,0 +1,66 @@
+package monkey
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+func testEvalPolicy(t *testing.T, input string, policy *Policy) (Value, error) {
+	t.Helper()
+	program, err := syntax.NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("parser error: %s", err)
+	}
+	return EvalThread(&Thread{Policy: policy}, program, NewEnv(nil))
+}
+
+func TestEvalBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`eval("1 + 2")`, "3"},
+		{`eval("let x = 2; x * y", {"y": 21})`, "42"},
+		{`let f = eval("fn(x) { x + 1 }"); f(1)`, "2"},
+		{`eval("let x = ")`, "no prefix parse function"},
+		{`eval("undefined")`, "identifier not found: undefined"},
+		{`eval(1)`, "must be string"},
+		{`parse("let x = 1;")["stmts"][0]["type"]`, "LetStmt"},
+		{`parse("let x = 1;")["stmts"][0]["value"]["value"]`, "1"},
+		{`parse("f(a, 2)")["stmts"][0]["expr"]["args"][0]["name"]`, "a"},
+		{`parse("1 + 2")["stmts"][0]["expr"]["op"]`, "+"},
+		{"parse(\"\n  x\")[\"stmts\"][0][\"col\"]", "3"},
+		{`parse("{1: 2, 3: 4}")["stmts"][0]["expr"]["pairs"][1][0]["value"]`, "3"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalPolicy(t, tt.input, NewPolicy(CapEval))
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestEvalPolicy(t *testing.T) {
+	for _, input := range []string{`eval("1")`, `parse("1")`} {
+		_, err := testEvalPolicy(t, input, nil)
+		if err == nil || !strings.Contains(err.Error(), `requires the "eval" capability`) {
+			t.Errorf("%s: expected policy error. got=%v", input, err)
+		}
+	}
+
+	policy, err := ParsePolicy("all")
+	if err != nil || !policy.Allows(CapEval) {
+		t.Errorf("all does not allow eval. got=%v, %v", policy, err)
+	}
+	if _, err := ParsePolicy("eval,network"); err == nil {
+		t.Errorf("expected unknown capability error")
+	}
+}
@@ -0,0 +1,128 @@
+package monkey
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 正则表达式使用 Go 的 regexp 语法（RE2），不支持反向引用。
+// 匹配的结果是一个 map：
+//
+//	{"match": 整个匹配, "groups": [各个分组], "named": {分组名: 分组}}
+//
+// 没有参与匹配的分组为 null。
+func init() {
+	Universe["re_match"] = NewBuiltinFunction("re_match", reMatch)
+	Universe["re_find_all"] = NewBuiltinFunction("re_find_all", reFindAll)
+	Universe["re_replace"] = NewBuiltinFunction("re_replace", reReplace)
+}
+
+// re_match(pattern, s) 返回 s 中 pattern 的第一个匹配，没有匹配时返回 null：
+//
+//	re_match("(\\w+)@(?P<host>\\w+)", "me@example")["named"]["host"]  // "example"
+func reMatch(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	re, s, err := regexpArgs("re_match", args[0], args[1])
+	if err != nil {
+		return nil, err
+	}
+	loc := re.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return Null, nil
+	}
+	return matchValue(re, s, loc), nil
+}
+
+// re_find_all(pattern, s) 按出现的顺序返回 s 中 pattern 的所有不重叠的匹配
+func reFindAll(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	re, s, err := regexpArgs("re_find_all", args[0], args[1])
+	if err != nil {
+		return nil, err
+	}
+	locs := re.FindAllStringSubmatchIndex(s, -1)
+	matches := make([]Value, len(locs))
+	for i, loc := range locs {
+		matches[i] = matchValue(re, s, loc)
+	}
+	return NewArray(matches), nil
+}
+
+// re_replace(pattern, s, repl) 返回将 s 中 pattern 的所有匹配替换为 repl 之后的字符串。
+// repl 是字符串时其中的 $1 和 ${name} 替换为对应的分组；
+// repl 是函数时以匹配的结果调用它，它返回的字符串作为替换的文本：
+//
+//	re_replace("(\\w+)=(\\w+)", "a=b", "$2=$1")                     // "b=a"
+//	re_replace("\\d+", "1 2", fn(m) { sprintf("<%s>", m["match"]) })  // "<1> <2>"
+func reReplace(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 3 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=3", len(args))
+	}
+	re, s, err := regexpArgs("re_replace", args[0], args[1])
+	if err != nil {
+		return nil, err
+	}
+	switch repl := args[2].(type) {
+	case String:
+		return String(re.ReplaceAllString(s, string(repl))), nil
+	case *Function, Callable:
+		var out strings.Builder
+		last := 0
+		for _, loc := range re.FindAllStringSubmatchIndex(s, -1) {
+			val, err := Call(thread, repl, matchValue(re, s, loc))
+			if err != nil {
+				return nil, fmt.Errorf("re_replace: %w", err)
+			}
+			str, ok := val.(String)
+			if !ok {
+				return nil, fmt.Errorf("re_replace: replacement function must return string, got %s", val.Type())
+			}
+			out.WriteString(s[last:loc[0]])
+			out.WriteString(string(str))
+			last = loc[1]
+		}
+		out.WriteString(s[last:])
+		return String(out.String()), nil
+	}
+	return nil, fmt.Errorf("replacement argument to `re_replace` must be string or function, got %s", args[2].Type())
+}
+
+// 检查正则函数 fn 的模式和字符串参数，并编译模式
+func regexpArgs(fn string, pattern, s Value) (*regexp.Regexp, string, error) {
+	p, ok1 := pattern.(String)
+	str, ok2 := s.(String)
+	if !ok1 || !ok2 {
+		return nil, "", fmt.Errorf("arguments to `%s` must be strings, got %s and %s", fn, pattern.Type(), s.Type())
+	}
+	re, err := regexp.Compile(string(p))
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", fn, err)
+	}
+	return re, string(str), nil
+}
+
+// 将 re 在 s 中位于 loc 的匹配转换为 map
+func matchValue(re *regexp.Regexp, s string, loc []int) *Map {
+	names := re.SubexpNames()
+	groups := make([]Value, re.NumSubexp())
+	named := NewMap()
+	for i := range groups {
+		groups[i] = Null
+		if start, end := loc[2*i+2], loc[2*i+3]; start >= 0 {
+			groups[i] = String(s[start:end])
+		}
+		if names[i+1] != "" {
+			named.SetKey(String(names[i+1]), groups[i])
+		}
+	}
+	result := NewMap()
+	result.SetKey(String("match"), String(s[loc[0]:loc[1]]))
+	result.SetKey(String("groups"), NewArray(groups))
+	result.SetKey(String("named"), named)
+	return result
+}
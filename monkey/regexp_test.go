@@ -0,0 +1,52 @@
+package monkey
+
+import (
+	"errors"
+	resyntax "regexp/syntax"
+	"strings"
+	"testing"
+)
+
+func TestRegexp(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`re_match("\\d+", "abc 123 456")["match"]`, "123"},
+		{`re_match("(\\w+)@(\\w+)", "me@example")["groups"]`, "[me, example]"},
+		{`re_match("(\\w+)@(?P<host>\\w+)", "me@example")["named"]["host"]`, "example"},
+		{`re_match("a(x)?b", "ab")["groups"][0]`, "null"},
+		{`re_match("\\d", "abc")`, "null"},
+		{`len(re_find_all("\\d+", "1 22 333"))`, "3"},
+		{`map(re_find_all("(\\w)=(\\d)", "a=1 b=2"), fn(m) { m["groups"][1] })`, "[1, 2]"},
+		{`re_find_all("x", "abc")`, "[]"},
+		{`re_replace("(\\w+)=(\\w+)", "a=b c=d", "$2=$1")`, "b=a d=c"},
+		{`re_replace("(?P<n>\\d+)", "x1", "<${n}>")`, "x<1>"},
+		{`re_replace("\\d+", "1 22", fn(m) { sprintf("<%d>", len(m["match"])) })`, "<1> <2>"},
+		{`re_replace("\\d", "1", fn(m) { 1 })`, "must return string"},
+		{`re_replace("\\d", "1", 1)`, "must be string or function"},
+		{`re_match("(", "x")`, "re_match: error parsing regexp"},
+		{`re_match(1, "x")`, "must be strings"},
+		{`re_find_all("x")`, "wrong number of arguments"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalThread(t, tt.input)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestRegexpError(t *testing.T) {
+	_, err := testEvalThread(t, `re_match("(", "x")`)
+	var syntaxErr *resyntax.Error
+	if !errors.As(err, &syntaxErr) || syntaxErr.Code != resyntax.ErrMissingParen {
+		t.Errorf("expected a wrapped *syntax.Error. got=%v", err)
+	}
+}
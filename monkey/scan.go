@@ -0,0 +1,114 @@
+package monkey
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Universe["scan"] = NewBuiltinFunction("scan", scan)
+}
+
+// scan(input, pattern)
+//
+// 按 pattern 匹配整个 input，返回以字段名为键的 map，不匹配时返回 null：
+//
+//	scan("key=value", "{key}={value}")  // {"key": "key", "value": "value"}
+//
+// 字段写作 {name} 或 {name:int}，后者匹配整数并转换为 int；
+// {} 匹配任意文本但不记录，{{ 和 }} 表示字面的花括号。
+// 字段匹配尽可能少的文本，最后一个字段匹配到输入的末尾。
+func scan(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	input, ok1 := args[0].(String)
+	pattern, ok2 := args[1].(String)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("arguments to `scan` must be strings, got %s and %s", args[0].Type(), args[1].Type())
+	}
+
+	re, fields, err := compileScanPattern(string(pattern))
+	if err != nil {
+		return nil, fmt.Errorf("scan: %w", err)
+	}
+	match := re.FindStringSubmatch(string(input))
+	if match == nil {
+		return Null, nil
+	}
+
+	result := NewMap()
+	for i, field := range fields {
+		var val Value = String(match[i+1])
+		if field.kind == "int" {
+			n, err := strconv.ParseInt(match[i+1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("scan: field %s: %w", field.name, err)
+			}
+			val = Int(n)
+		}
+		result.SetKey(String(field.name), val)
+	}
+	return result, nil
+}
+
+type scanField struct {
+	name string
+	kind string // "" 或 "int"
+}
+
+// 将 scan 的模式编译为锚定整个输入的正则表达式，每个具名字段对应一个分组
+func compileScanPattern(pattern string) (*regexp.Regexp, []scanField, error) {
+	var re strings.Builder
+	var fields []scanField
+	seen := make(map[string]bool)
+
+	re.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "{{"), strings.HasPrefix(pattern[i:], "}}"):
+			re.WriteString(regexp.QuoteMeta(string(c)))
+			i++
+		case c == '{':
+			end := strings.IndexByte(pattern[i:], '}')
+			if end < 0 {
+				return nil, nil, fmt.Errorf("unclosed { in pattern %q", pattern)
+			}
+			name, kind, _ := strings.Cut(pattern[i+1:i+end], ":")
+			i += end
+
+			var group string
+			switch kind {
+			case "":
+				group = "(?s:.*?)"
+			case "int":
+				group = `[+-]?\d+`
+			default:
+				return nil, nil, fmt.Errorf("unknown field type %q in pattern %q", kind, pattern)
+			}
+			if name == "" {
+				re.WriteString("(?:" + group + ")")
+				continue
+			}
+			if seen[name] {
+				return nil, nil, fmt.Errorf("duplicate field %q in pattern %q", name, pattern)
+			}
+			seen[name] = true
+			fields = append(fields, scanField{name: name, kind: kind})
+			re.WriteString("(" + group + ")")
+		case c == '}':
+			return nil, nil, fmt.Errorf("unmatched } in pattern %q", pattern)
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	re.WriteString("$")
+
+	compiled, err := regexp.Compile(re.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	return compiled, fields, nil
+}
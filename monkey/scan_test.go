@@ -0,0 +1,49 @@
+package monkey
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`scan("key=value", "{key}={value}")["value"]`, "value"},
+		{`scan("a=b=c", "{key}={value}")["value"]`, "b=c"},
+		{`scan("GET /index.html 200", "{method} {path} {status:int}")["status"] + 1`, "201"},
+		{`scan("x: -12", "x: {n:int}")["n"]`, "-12"},
+		{`scan("[INFO] started", "[{level}] {}")["level"]`, "INFO"},
+		{`scan("{a}", "{{{x}}}")["x"]`, "a"},
+		{`scan("x=1", "{k}:{v}")`, "null"},
+		{`scan("n=abc", "n={n:int}")`, "null"},
+		{`scan("", "")`, "{}"},
+		{`scan("a", "{x")`, "unclosed {"},
+		{`scan("a", "x}")`, "unmatched }"},
+		{`scan("a b", "{x} {x}")`, "duplicate field"},
+		{`scan("a", "{x:float}")`, "unknown field type"},
+		{`scan(1, "x")`, "must be strings"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalThread(t, tt.input)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestScanError(t *testing.T) {
+	_, err := testEvalThread(t, `scan("n=99999999999999999999", "n={n:int}")`)
+	if !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("expected a wrapped strconv.ErrRange. got=%v", err)
+	}
+}
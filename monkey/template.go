@@ -0,0 +1,228 @@
+package monkey
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+func init() {
+	Universe["template"] = NewBuiltinFunction("template", template)
+}
+
+// template(str, data)
+//
+// 渲染模板 str，data 是以字符串为键的 map，其中的键值对在模板中作为变量使用：
+//
+//	{{expr}}                     插入表达式的值，字符串原样插入
+//	{{#if expr}}...{{else}}...{{/if}}
+//	{{#each expr}}...{{/each}}   对数组的每个元素渲染一次，元素绑定到 it，下标绑定到 index
+//	{{#each expr as x}}...{{/each}}
+func template(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	src, ok := args[0].(String)
+	if !ok {
+		return nil, fmt.Errorf("first argument to `template` must be string, got %s", args[0].Type())
+	}
+	data, ok := args[1].(*Map)
+	if !ok {
+		return nil, fmt.Errorf("second argument to `template` must be map, got %s", args[1].Type())
+	}
+
+	start := time.Now()
+	nodes, err := parseTemplate(string(src))
+	thread.parsed("<template>", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("template: %w", err)
+	}
+
+	env := NewEnv(nil)
+	for _, entry := range data.Items() {
+		name, ok := entry.Key.(String)
+		if !ok {
+			return nil, fmt.Errorf("keys of `template` data must be strings, got %s", entry.Key.Type())
+		}
+		env.Set(string(name), entry.Value)
+	}
+
+	var out strings.Builder
+	if err := renderTemplate(thread, &out, nodes, env); err != nil {
+		return nil, fmt.Errorf("template: %w", err)
+	}
+	return String(out.String()), nil
+}
+
+// 模板的语法树节点
+type templateNode struct {
+	text string      // 文本节点的内容
+	line int         // 指令所在的行号
+	expr syntax.Expr // {{expr}}、#if 和 #each 的表达式，文本节点为 nil
+	kind string      // "", "if" 或 "each"
+	name string      // #each 中元素绑定的变量名
+	body []*templateNode
+	alt  []*templateNode // #if 的 else 分支
+}
+
+// 将模板解析为节点列表
+func parseTemplate(src string) ([]*templateNode, error) {
+	p := &templateParser{src: src, line: 1}
+	nodes, end, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if end != "" {
+		return nil, fmt.Errorf("line %d: unexpected {{%s}}", p.line, end)
+	}
+	return nodes, nil
+}
+
+type templateParser struct {
+	src  string
+	line int
+}
+
+// 解析节点直到输入结束或遇到 {{else}}/{{/...}}，返回遇到的结束指令
+func (p *templateParser) parse() (nodes []*templateNode, end string, err error) {
+	for p.src != "" {
+		i := strings.Index(p.src, "{{")
+		if i < 0 {
+			nodes = append(nodes, &templateNode{text: p.src})
+			p.src = ""
+			break
+		}
+		if i > 0 {
+			nodes = append(nodes, &templateNode{text: p.src[:i]})
+			p.advance(i)
+		}
+
+		j := strings.Index(p.src, "}}")
+		if j < 0 {
+			return nil, "", fmt.Errorf("line %d: unclosed {{", p.line)
+		}
+		tag := strings.TrimSpace(p.src[2:j])
+		line := p.line
+		p.advance(j + 2)
+
+		switch {
+		case tag == "else" || strings.HasPrefix(tag, "/"):
+			return nodes, tag, nil
+
+		case strings.HasPrefix(tag, "#if "):
+			node := &templateNode{kind: "if", line: line}
+			if node.expr, err = parseTemplateExpr(tag[len("#if "):], line); err != nil {
+				return nil, "", err
+			}
+			var end string
+			if node.body, end, err = p.parse(); err != nil {
+				return nil, "", err
+			}
+			if end == "else" {
+				if node.alt, end, err = p.parse(); err != nil {
+					return nil, "", err
+				}
+			}
+			if end != "/if" {
+				return nil, "", fmt.Errorf("line %d: {{#if}} is not closed by {{/if}}", line)
+			}
+			nodes = append(nodes, node)
+
+		case strings.HasPrefix(tag, "#each "):
+			node := &templateNode{kind: "each", line: line, name: "it"}
+			expr := tag[len("#each "):]
+			if i := strings.LastIndex(expr, " as "); i >= 0 {
+				node.name = strings.TrimSpace(expr[i+len(" as "):])
+				expr = expr[:i]
+			}
+			if node.expr, err = parseTemplateExpr(expr, line); err != nil {
+				return nil, "", err
+			}
+			var end string
+			if node.body, end, err = p.parse(); err != nil {
+				return nil, "", err
+			}
+			if end != "/each" {
+				return nil, "", fmt.Errorf("line %d: {{#each}} is not closed by {{/each}}", line)
+			}
+			nodes = append(nodes, node)
+
+		case strings.HasPrefix(tag, "#"):
+			return nil, "", fmt.Errorf("line %d: unknown block {{%s}}", line, tag)
+
+		default:
+			node := &templateNode{line: line}
+			if node.expr, err = parseTemplateExpr(tag, line); err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes, "", nil
+}
+
+func (p *templateParser) advance(n int) {
+	p.line += strings.Count(p.src[:n], "\n")
+	p.src = p.src[n:]
+}
+
+func parseTemplateExpr(src string, line int) (syntax.Expr, error) {
+	program, err := syntax.NewParser(src).Parse()
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", line, err)
+	}
+	if len(program.Stmts) != 1 {
+		return nil, fmt.Errorf("line %d: expected an expression, got %q", line, src)
+	}
+	stmt, ok := program.Stmts[0].(*syntax.ExprStmt)
+	if !ok {
+		return nil, fmt.Errorf("line %d: expected an expression, got %q", line, src)
+	}
+	return stmt.Expr, nil
+}
+
+func renderTemplate(thread *Thread, out *strings.Builder, nodes []*templateNode, env *Env) error {
+	for _, node := range nodes {
+		if node.expr == nil {
+			out.WriteString(node.text)
+			continue
+		}
+		val, err := eval(thread, node.expr, env)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.line, err)
+		}
+
+		switch node.kind {
+		case "if":
+			body := node.alt
+			if val.Truth() {
+				body = node.body
+			}
+			if err := renderTemplate(thread, out, body, env); err != nil {
+				return err
+			}
+		case "each":
+			items, ok := val.(*Array)
+			if !ok {
+				return fmt.Errorf("line %d: {{#each}} requires an array, got %s", node.line, val.Type())
+			}
+			for i, item := range items.items {
+				scope := NewEnv(env)
+				scope.Set(node.name, item)
+				scope.Set("index", Int(i))
+				if err := renderTemplate(thread, out, node.body, scope); err != nil {
+					return err
+				}
+			}
+		default:
+			if s, ok := val.(String); ok {
+				out.WriteString(string(s))
+			} else {
+				out.WriteString(val.String())
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package monkey
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTemplate(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`template("hello, {{name}}!", {"name": "world"})`, "hello, world!"},
+		{`template("{{a + b}} {{[a, b]}}", {"a": 1, "b": 2})`, "3 [1, 2]"},
+		{`template("{{#if ok}}yes{{else}}no{{/if}}", {"ok": false})`, "no"},
+		{`template("{{#if ok}}yes{{/if}}", {"ok": true})`, "yes"},
+		{`template("{{#each xs}}{{index}}:{{it}} {{/each}}", {"xs": ["a", "b"]})`, "0:a 1:b "},
+		{
+			`template("{{#each rows as row}}{{#each row as cell}}{{cell}}{{/each}};{{/each}}", {"rows": [[1, 2], [3]]})`,
+			"12;3;",
+		},
+		{`template("{{#each xs}}{{#if it == 2}}two{{/if}}{{/each}}", {"xs": [1, 2, 3]})`, "two"},
+		{`template("{{missing}}", {})`, "template: line 1: <invalid>:1:1 identifier not found: missing"},
+		{`template("{{#if x}}", {"x": 1})`, "not closed by {{/if}}"},
+		{`template("{{/each}}", {})`, "unexpected {{/each}}"},
+		{`template("{{#each x}}{{/each}}", {"x": 1})`, "requires an array"},
+		{`template("{{#with x}}{{/with}}", {})`, "unknown block"},
+		{`template("{{x", {})`, "unclosed {{"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalThread(t, tt.input)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = string(val.(String))
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestTemplateParseError(t *testing.T) {
+	_, err := testEvalThread(t, `template("{{1 +}}", {})`)
+	if !errors.Is(err, ErrParse) || !errors.Is(err, ErrRuntime) {
+		t.Errorf("expected a runtime error wrapping a syntax error. got=%v", err)
+	}
+}
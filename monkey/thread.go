@@ -0,0 +1,183 @@
+package monkey
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// Thread 表示一次求值的执行上下文，记录了调用栈等运行时状态。
+// 一个 Thread 同一时刻只能被一个 goroutine 用于求值。
+type Thread struct {
+	Name string
+
+	// Provider 为内置函数提供时间、随机数等非确定性输入，为 nil 时使用操作系统
+	Provider Provider
+
+	// Print 是内置函数 print 的输出方式，为 nil 时输出到标准输出
+	Print func(thread *Thread, msg string)
+
+	// Debugger 不为 nil 时，在每条语句执行前被调用
+	Debugger Debugger
+
+	// Policy 是允许脚本使用的能力，为 nil 时不允许任何需要授权的能力
+	Policy *Policy
+
+	// Importer 用于加载 import() 的模块，为 nil 时在第一次 import 时创建
+	Importer *Importer
+
+	// Hooks 是宿主程序的遥测回调，为 nil 时不调用任何回调
+	Hooks *Hooks
+
+	// MaxSteps 大于 0 时限制一次 EvalThread 或 ExecReader 最多执行的语句数，
+	// 其中 async 函数和 with_group 的任务执行的语句也计算在内。超出时求值终止，
+	// 返回包装了 ErrResourceExhausted 的 step limit exceeded 错误，用于防止不可信的脚本无限运行。
+	MaxSteps int64
+
+	// MaxAlloc 大于 0 时限制一次 EvalThread 或 ExecReader 累计创建的字符串、数组、map 和 record 的近似字节数，
+	// 计算方式与 MaxSteps 相同。只计算新创建的值本身的大小（字符串的字节数、数组每个元素 16 字节等），
+	// 不跟踪值何时被回收，因此它限制的是分配的总量而不是同时占用的内存。
+	// 超出时返回包装了 ErrResourceExhausted 的 memory limit exceeded 错误。
+	MaxAlloc int64
+
+	// Language 是解析源码时启用的可选语言特性，用于 Parse、ExecReader、import 的模块和 eval 的代码
+	Language syntax.LanguageOptions
+
+	mu    sync.Mutex // 保护 stack，采样器会在其他 goroutine 中读取调用栈
+	stack []Frame
+
+	cancel    *cancelScope  // 不为 nil 时，作用域被取消后在下一条语句之前终止求值
+	budget    *budget       // 不为 nil 时，执行的语句数或分配的内存超过上限后终止求值
+	failedEnv *Env          // 最内层发生错误时的 Env
+	file      string        // 正在执行的模块文件，用于解析相对路径的 import
+	imports   []importFrame // 正在加载的模块，用于检测循环导入
+}
+
+// Frame 是调用栈中的一帧
+type Frame struct {
+	Name string          // 被调用函数的名称
+	Pos  syntax.Position // 调用发生的位置
+	Env  *Env            // 函数的局部作用域，内置函数为 nil
+}
+
+// Debugger 是求值器提供给调试器的钩子
+type Debugger interface {
+	// BeforeStmt 在 stmt 执行之前被调用，env 为 stmt 所在的作用域。
+	// 调试器可以在此阻塞以实现断点和单步执行，返回错误则终止求值。
+	BeforeStmt(thread *Thread, stmt syntax.Stmt, env *Env) error
+}
+
+// CallStack 返回当前调用栈的拷贝，最外层的调用在前
+func (t *Thread) CallStack() []Frame {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stack := make([]Frame, len(t.stack))
+	copy(stack, t.stack)
+	return stack
+}
+
+// FailedEnv 返回最近一次运行时错误发生时所在的 Env，用于事后调试；没有发生错误时返回 nil
+func (t *Thread) FailedEnv() *Env {
+	return t.failedEnv
+}
+
+// 记录错误发生时的 Env，错误向外传播时只保留最内层的
+func (t *Thread) fail(env *Env) {
+	if t.failedEnv == nil {
+		t.failedEnv = env
+	}
+}
+
+// 调用栈的最大深度，防止无限递归耗尽 Go 的栈空间
+const maxCallDepth = 10000
+
+func (t *Thread) push(frame Frame) {
+	t.mu.Lock()
+	t.stack = append(t.stack, frame)
+	t.mu.Unlock()
+}
+
+func (t *Thread) pop() {
+	t.mu.Lock()
+	t.stack = t.stack[:len(t.stack)-1]
+	t.mu.Unlock()
+}
+
+// 在最外层的求值开始时按 MaxSteps 和 MaxAlloc 创建预算，返回求值结束时调用的清理函数。
+// 嵌套的求值（例如 eval 内置函数）使用同一个预算。
+func (t *Thread) startBudget() func() {
+	if (t.MaxSteps <= 0 && t.MaxAlloc <= 0) || t.budget != nil {
+		return func() {}
+	}
+	t.budget = &budget{maxSteps: t.MaxSteps, maxAlloc: t.MaxAlloc}
+	return func() { t.budget = nil }
+}
+
+// budget 是一次求值中所有任务共享的资源上限
+type budget struct {
+	maxSteps int64
+	steps    atomic.Int64
+	maxAlloc int64
+	alloc    atomic.Int64
+}
+
+// 记录新创建的值 v，分配的总量超过 MaxAlloc 时返回错误
+func (t *Thread) allocate(v Value) error {
+	if t.budget == nil || t.budget.maxAlloc <= 0 {
+		return nil
+	}
+	if t.budget.alloc.Add(allocSize(v)) > t.budget.maxAlloc {
+		return exhausted("memory limit (%d bytes) exceeded", t.budget.maxAlloc)
+	}
+	return nil
+}
+
+// allocated 返回一个函数，它把内置函数的结果计入 thread 的分配，用于包装 CallInternal 的返回值。
+// 内置函数返回的可能是参数本身，这时会被重复计算，MaxAlloc 只是近似的上限。
+func allocated(thread *Thread) func(Value, error) (Value, error) {
+	return func(v Value, err error) (Value, error) {
+		if err != nil {
+			return nil, err
+		}
+		if err := thread.allocate(v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+}
+
+// 返回新创建的值 v 本身的近似字节数，不包括其中的元素
+func allocSize(v Value) int64 {
+	switch v := v.(type) {
+	case String:
+		return int64(len(v))
+	case *Array:
+		return 16 * int64(len(v.items))
+	case *Map:
+		return 48 * int64(len(v.items))
+	case *Record:
+		return 16 * int64(len(v.values))
+	}
+	return 0
+}
+
+func (t *Thread) provider() Provider {
+	if t.Provider != nil {
+		return t.Provider
+	}
+	return defaultProvider
+}
+
+func (t *Thread) beforeStmt(stmt syntax.Stmt, env *Env) error {
+	if t.cancel.cancelled() {
+		return errCancelled
+	}
+	if b := t.budget; b != nil && b.maxSteps > 0 && b.steps.Add(1) > b.maxSteps {
+		return exhausted("step limit (%d) exceeded", b.maxSteps)
+	}
+	if t.Debugger == nil {
+		return nil
+	}
+	return t.Debugger.BeforeStmt(t, stmt, env)
+}
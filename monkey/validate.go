@@ -0,0 +1,131 @@
+package monkey
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func init() {
+	Universe["validate"] = NewBuiltinFunction("validate", validate)
+}
+
+// validate(value, schema)
+//
+// 按 schema 检查 value 的结构，返回描述所有不符合之处的数组，每个元素是
+// {"path": 路径, "message": 说明}，value 符合 schema 时返回空数组：
+//
+//	let errs = validate(config, {"name": "string", "port": "int", "tags": ["string"], "tls": {"cert": "string?"}});
+//	if (len(errs) > 0) { print(errs[0]["path"] + ": " + errs[0]["message"]) }
+//
+// schema 可以是：
+//   - 类型名字符串，例如 "int"、"string"、"array"，另外 "number" 匹配 int 和 float，
+//     "function" 匹配所有可调用的值，"any" 匹配任何值；以 ? 结尾表示值可以为 null，
+//     作为 map 的字段时还表示该键可以不存在
+//   - 只包含一个元素的数组，表示值必须是数组，并且每个元素都符合该元素描述的 schema
+//   - map，表示值必须是 map，并且包含 schema 中的每个键（可选的除外），
+//     对应的值符合该键描述的 schema；schema 中没有的键不做检查
+//
+// 路径的写法类似 servers[0].host，根节点的路径为空字符串。
+func validate(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	var errs []Value
+	report := func(path, format string, a ...any) {
+		e := NewMap()
+		e.SetKey(String("path"), String(path))
+		e.SetKey(String("message"), String(fmt.Sprintf(format, a...)))
+		errs = append(errs, e)
+	}
+	if err := validateValue(args[0], args[1], "", report); err != nil {
+		return nil, fmt.Errorf("validate: %w", err)
+	}
+	return NewArray(errs), nil
+}
+
+// 按 schema 检查 path 处的 val，不符合之处通过 report 报告，schema 本身无效时返回错误
+func validateValue(val, schema Value, path string, report func(path, format string, a ...any)) error {
+	switch schema := schema.(type) {
+	case String:
+		typ, optional := strings.CutSuffix(string(schema), "?")
+		if val == Null && optional {
+			return nil
+		}
+		ok, err := matchesType(val, typ)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			report(path, "expected %s, got %s", typ, val.Type())
+		}
+		return nil
+
+	case *Array:
+		if schema.Len() != 1 {
+			return fmt.Errorf("array schema must have exactly one element, got %d", schema.Len())
+		}
+		arr, ok := val.(*Array)
+		if !ok {
+			report(path, "expected array, got %s", val.Type())
+			return nil
+		}
+		for i, item := range arr.items {
+			if err := validateValue(item, schema.items[0], fmt.Sprintf("%s[%d]", path, i), report); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case *Map:
+		m, ok := val.(*Map)
+		if !ok {
+			report(path, "expected map, got %s", val.Type())
+			return nil
+		}
+		// 按键排序，使错误的顺序与 map 的遍历顺序无关
+		fields := schema.Items()
+		sort.Slice(fields, func(i, j int) bool {
+			return fields[i].Key.String() < fields[j].Key.String()
+		})
+		for _, field := range fields {
+			fieldPath := field.Key.String()
+			if path != "" {
+				fieldPath = path + "." + fieldPath
+			}
+			item, found, err := m.Get(field.Key)
+			if err != nil {
+				return err
+			}
+			if !found {
+				if s, ok := field.Value.(String); !ok || !strings.HasSuffix(string(s), "?") {
+					report(fieldPath, "missing required key")
+				}
+				continue
+			}
+			if err := validateValue(item, field.Value, fieldPath, report); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("invalid schema %s, expected string, array or map", schema.Type())
+	}
+}
+
+// 判断 val 是否是名为 typ 的类型
+func matchesType(val Value, typ string) (bool, error) {
+	switch typ {
+	case "any":
+		return true, nil
+	case "number":
+		return val.Type() == "int" || val.Type() == "float", nil
+	case "function":
+		_, ok := val.(Callable)
+		return ok, nil
+	case "null", "int", "float", "bool", "string", "array", "map", "error", "future":
+		return val.Type() == typ, nil
+	}
+	return false, fmt.Errorf("unknown type %q in schema", typ)
+}
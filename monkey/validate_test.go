@@ -0,0 +1,51 @@
+package monkey
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`validate({"name": "a", "port": 80}, {"name": "string", "port": "int"})`, "[]"},
+		{`validate({"name": "a"}, {"name": "string", "port": "int"})`, "path: port"},
+		{`validate({}, {"port": "int"})[0]["message"]`, "missing required key"},
+		{`validate({"name": 1}, {"name": "string"})`, "message: expected string, got int"},
+		{`validate({"name": "a"}, {"name": "string", "port": "int?"})`, "[]"},
+		{`validate(1.5, "number")`, "[]"},
+		{`validate(len, "function")`, "[]"},
+		{`validate("x", "any")`, "[]"},
+		{`validate([1, "a", 3], ["int"])`, "path: [1]"},
+		{`validate({"servers": [{"host": "a"}, {"host": 1}]}, {"servers": [{"host": "string"}]})`, "path: servers[1].host"},
+		{`validate({"tls": 1}, {"tls": {"cert": "string"}})`, "message: expected map, got int"},
+		{`len(validate({}, {"a": "int", "b": "string"}))`, "2"},
+		{`validate(1, "integer")`, `validate: unknown type "integer" in schema`},
+		{`validate([1], ["int", "string"])`, "validate: array schema must have exactly one element, got 2"},
+		{`validate(1, 1)`, "validate: invalid schema int"},
+		{`validate(1)`, "wrong number of arguments. got=1, want=2"},
+	}
+
+	for _, tt := range tests {
+		val, err := testEvalThread(t, tt.input)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestValidateSchemaError(t *testing.T) {
+	_, err := validate(&Thread{}, Int(1), NewArray(nil))
+	if inner := errors.Unwrap(err); inner == nil || inner.Error() != "array schema must have exactly one element, got 0" {
+		t.Errorf("expected the schema error to be wrapped. got=%v", err)
+	}
+}
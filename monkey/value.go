@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"hash/maphash"
+	"math"
 	"math/big"
+	"strconv"
 	"strings"
 	"unicode/utf8"
 
@@ -45,6 +47,12 @@ type Indexable interface {
 	Index(i int) Value
 }
 
+// Sliceable 是支持 a[start:end] 切片的值
+type Sliceable interface {
+	Indexable
+	Slice(start, end int) Value
+}
+
 type HasUnary interface {
 	Value
 	Unary(op syntax.Token) (_ Value, err error)
@@ -69,7 +77,7 @@ type TotallyOrdered interface {
 type Callable interface {
 	Value
 	Name() string
-	CallInternal(args ...Value) (_ Value, err error)
+	CallInternal(thread *Thread, args ...Value) (_ Value, err error)
 }
 
 type NullType int
@@ -127,24 +135,94 @@ func (i Int) Binary(op syntax.Token, y Value, side Side) (_ Value, err error) {
 		yv = y
 	}
 
+	var result Int
+	var ok bool
 	switch op {
 	case syntax.PLUS:
-		return i + yv, nil
+		result, ok = addInt(i, yv)
 	case syntax.MINUS:
-		return i - yv, nil
+		result, ok = subInt(i, yv)
 	case syntax.STAR:
-		return i * yv, nil
+		result, ok = mulInt(i, yv)
 	case syntax.SLASH:
-		return i / yv, nil
+		if yv == 0 {
+			return nil, fmt.Errorf("division by zero: %d / 0", i)
+		}
+		// 只有 MinInt64 / -1 会溢出
+		result, ok = i/yv, i != math.MinInt64 || yv != -1
+	case syntax.PERCENT:
+		// 与 Go 相同，结果的符号与被除数相同
+		if yv == 0 {
+			return nil, fmt.Errorf("modulo by zero: %d %% 0", i)
+		}
+		return i % yv, nil
+	case syntax.STARSTAR:
+		return intPow(i, yv)
 	default:
 		return nil, nil
 	}
+	if !ok {
+		return nil, fmt.Errorf("integer overflow: %d %s %d", i, op, yv)
+	}
+	return result, nil
+}
+
+// 计算 x ** y，y 为负数时结果为浮点数，结果超出 int 的范围时返回错误
+func intPow(x, y Int) (Value, error) {
+	if y < 0 {
+		return Float(math.Pow(float64(x), float64(y))), nil
+	}
+	result, base := Int(1), x
+	for e := y; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			r, ok := mulInt(result, base)
+			if !ok {
+				return nil, fmt.Errorf("integer overflow: %d ** %d", x, y)
+			}
+			result = r
+		}
+		if e > 1 {
+			b, ok := mulInt(base, base)
+			if !ok {
+				return nil, fmt.Errorf("integer overflow: %d ** %d", x, y)
+			}
+			base = b
+		}
+	}
+	return result, nil
+}
+
+// 返回 x + y，溢出时 ok 为 false
+func addInt(x, y Int) (_ Int, ok bool) {
+	r := x + y
+	return r, (r > x) == (y > 0)
+}
+
+// 返回 x - y，溢出时 ok 为 false
+func subInt(x, y Int) (_ Int, ok bool) {
+	r := x - y
+	return r, (r < x) == (y > 0)
+}
+
+// 返回 x * y，溢出时 ok 为 false
+func mulInt(x, y Int) (_ Int, ok bool) {
+	if x == 0 || y == 0 {
+		return 0, true
+	}
+	r := x * y
+	if r/y != x || (x == -1 && y == math.MinInt64) || (y == -1 && x == math.MinInt64) {
+		return 0, false
+	}
+	return r, true
 }
 
 // Unary implements HasUnary.
 func (i Int) Unary(op syntax.Token) (_ Value, err error) {
 	switch op {
 	case syntax.MINUS:
+		if i == math.MinInt64 {
+			return nil, fmt.Errorf("integer overflow: -(%d)", i)
+		}
 		return -i, nil
 	case syntax.PLUS:
 		return i, nil
@@ -168,6 +246,113 @@ func (i Int) Type() string {
 	return "int"
 }
 
+type Float float64
+
+// Hash implements Value.
+func (f Float) Hash() (uint32, error) {
+	// 与整数相等的浮点数和该整数的哈希值相同，使 1 和 1.0 作为 map 的键时等价
+	if i := Int(f); Float(i) == f {
+		return i.Hash()
+	}
+	return uint32(math.Float64bits(float64(f))>>32) ^ uint32(math.Float64bits(float64(f))), nil
+}
+
+// Compare implements Comparable.
+func (f Float) Compare(op syntax.Token, y Value) (_ Value, err error) {
+	var yv Float
+	switch y := y.(type) {
+	case Float:
+		yv = y
+	case Int:
+		yv = Float(y)
+	default:
+		return nil, fmt.Errorf("invalid cmp operator: %s %s %s", f, op, y)
+	}
+	// 直接使用 Go 的比较运算，NaN 与任何值都不相等
+	switch op {
+	case syntax.EQ:
+		return Bool(f == yv), nil
+	case syntax.NE:
+		return Bool(f != yv), nil
+	case syntax.LT:
+		return Bool(f < yv), nil
+	case syntax.LE:
+		return Bool(f <= yv), nil
+	case syntax.GT:
+		return Bool(f > yv), nil
+	case syntax.GE:
+		return Bool(f >= yv), nil
+	}
+	return nil, fmt.Errorf("invalid cmp operator: %s %s %s", f, op, y)
+}
+
+// Binary implements HasBinary.
+// 整数与浮点数运算时，整数先被转换为浮点数。
+func (f Float) Binary(op syntax.Token, y Value, side Side) (_ Value, err error) {
+	var yv Float
+	switch y := y.(type) {
+	case Float:
+		yv = y
+	case Int:
+		yv = Float(y)
+	default:
+		return nil, nil
+	}
+
+	x := f
+	if side == Right {
+		x, yv = yv, x
+	}
+	switch op {
+	case syntax.PLUS:
+		return x + yv, nil
+	case syntax.MINUS:
+		return x - yv, nil
+	case syntax.STAR:
+		return x * yv, nil
+	case syntax.SLASH:
+		return x / yv, nil
+	case syntax.PERCENT:
+		return Float(math.Mod(float64(x), float64(yv))), nil
+	case syntax.STARSTAR:
+		return Float(math.Pow(float64(x), float64(yv))), nil
+	default:
+		return nil, nil
+	}
+}
+
+// Unary implements HasUnary.
+func (f Float) Unary(op syntax.Token) (_ Value, err error) {
+	switch op {
+	case syntax.MINUS:
+		return -f, nil
+	case syntax.PLUS:
+		return f, nil
+	default:
+		return nil, nil
+	}
+}
+
+// String implements Value.
+// 整数值的浮点数也带有小数点（1.0），以便与整数区分。
+func (f Float) String() string {
+	s := strconv.FormatFloat(float64(f), 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
+// Truth implements Value.
+func (f Float) Truth() bool {
+	return f != 0
+}
+
+// Type implements Value.
+func (f Float) Type() string {
+	return "float"
+}
+
 type Bool bool
 
 // Hash implements Value.
@@ -211,7 +396,7 @@ var seed = maphash.MakeSeed()
 
 // Hash implements Value.
 func (s String) Hash() (uint32, error) {
-	if len(s) >= 12 {
+	if len(s) >= 12 && !fixedHashing.Load() {
 		// Call the Go runtime's optimized hash implementation,
 		// which uses the AES instructions on amd64 and arm64 machines.
 		h := maphash.String(seed, string(s))
@@ -230,9 +415,46 @@ func softHashString(s string) uint32 {
 	return h
 }
 
-// Index implements Indexable.
+// Cmp implements TotallyOrdered. 字符串按字节的字典序比较。
+func (s String) Cmp(y Value) (_ int, err error) {
+	yv, ok := y.(String)
+	if !ok {
+		return 0, fmt.Errorf("invalid cmp operator: %s %s %s", s, syntax.EQ, y)
+	}
+	return strings.Compare(string(s), string(yv)), nil
+}
+
+// Index implements Indexable. 字符串按字符（rune）而不是字节索引。
 func (s String) Index(i int) Value {
-	return s[i : i+1]
+	off := runeOffset(string(s), 0, i)
+	_, size := utf8.DecodeRuneInString(string(s[off:]))
+	// 复制单个字符，避免它引用整个字符串
+	return String(strings.Clone(string(s[off : off+size])))
+}
+
+// 子串的长度不到原字符串的 1/sliceCopyRatio 时复制子串，
+// 以免很小的子串使很大的原字符串无法被回收
+const sliceCopyRatio = 8
+
+// Slice implements Sliceable. 较长的子串直接引用原字符串的内存而不复制。
+func (s String) Slice(start, end int) Value {
+	lo := runeOffset(string(s), 0, start)
+	hi := runeOffset(string(s), lo, end-start)
+	sub := s[lo:hi]
+	if len(sub) < len(s)/sliceCopyRatio {
+		return String(strings.Clone(string(sub)))
+	}
+	return sub
+}
+
+// 返回 s 中从字节偏移 from 开始第 n 个字符的字节偏移
+func runeOffset(s string, from, n int) int {
+	off := from
+	for ; n > 0 && off < len(s); n-- {
+		_, size := utf8.DecodeRuneInString(s[off:])
+		off += size
+	}
+	return off
 }
 
 // Len implements Indexable.
@@ -271,6 +493,34 @@ type Array struct {
 	items []Value
 }
 
+// 不超过 smallArraySize 个元素的数组与元素存放在同一次分配的内存中
+const smallArraySize = 4
+
+// 带有内联存储的数组，items 指向 storage
+type smallArray struct {
+	Array
+	storage [smallArraySize]Value
+}
+
+// NewArray 返回一个包含 items 的数组，数组会持有 items 而不是复制它
+func NewArray(items []Value) *Array {
+	return &Array{items: items}
+}
+
+// 返回一个长度为 n 的数组，由调用方填充元素。元素较少时只需要一次内存分配
+func makeArray(n int) *Array {
+	switch {
+	case n == 0:
+		return &Array{}
+	case n <= smallArraySize:
+		a := &smallArray{}
+		// 容量限制为 n，append 时会复制到新的内存而不是写入 storage 的剩余部分
+		a.items = a.storage[:n:n]
+		return &a.Array
+	}
+	return &Array{items: make([]Value, n)}
+}
+
 // Hash implements Value.
 func (a *Array) Hash() (uint32, error) {
 	return 0, fmt.Errorf("unhashable type: array")
@@ -281,6 +531,13 @@ func (a *Array) Index(i int) Value {
 	return a.items[i]
 }
 
+// Slice implements Sliceable. 返回包含这些元素的新数组。
+func (a *Array) Slice(start, end int) Value {
+	arr := makeArray(end - start)
+	copy(arr.items, a.items[start:end])
+	return arr
+}
+
 // Len implements Indexable.
 func (a *Array) Len() int {
 	return len(a.items)
@@ -315,13 +572,110 @@ type MapEntry struct {
 	Value Value
 }
 
+// 不超过 smallMapSize 个键值对的 map 不建立索引，查找时直接顺序比较哈希值，
+// 脚本中大量出现的小 map 字面量因此不需要为索引分配内存
+const smallMapSize = 8
+
+// Map 按插入的顺序保存键值对，遍历和输出的顺序与插入的顺序相同
 type Map struct {
-	entries map[uint32]MapEntry
+	// 键的哈希值到 items 下标的映射，键值对超过 smallMapSize 个时才建立。
+	// 哈希值相同的键放在同一个桶中，通过 keyEqual 区分
+	index  map[uint32][]int
+	hashes []uint32 // hashes[i] 是 items[i].Key 的哈希值
+	items  []MapEntry
+}
+
+// NewMap 返回一个空的 map
+func NewMap() *Map {
+	return &Map{}
+}
+
+// 返回一个空的 map，并为 n 个键值对预留空间
+func newMap(n int) *Map {
+	return &Map{hashes: make([]uint32, 0, n), items: make([]MapEntry, 0, n)}
+}
+
+// 查找 key 在 items 中的下标，hash 是 key 的哈希值
+func (m *Map) find(key Value, hash uint32) (int, bool) {
+	if m.index == nil {
+		for i, h := range m.hashes {
+			if h == hash && keyEqual(m.items[i].Key, key) {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+	for _, i := range m.index[hash] {
+		if keyEqual(m.items[i].Key, key) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// 根据 hashes 重建索引，键值对不超过 smallMapSize 个时丢弃索引
+func (m *Map) reindex() {
+	if len(m.items) <= smallMapSize {
+		m.index = nil
+		return
+	}
+	m.index = make(map[uint32][]int, len(m.hashes))
+	for i, h := range m.hashes {
+		m.index[h] = append(m.index[h], i)
+	}
+}
+
+// SetKey 设置 key 对应的值，key 必须是可哈希的。
+// 已经存在的键保持原来的位置，新的键排在最后。
+func (m *Map) SetKey(key, value Value) error {
+	hash, err := key.Hash()
+	if err != nil {
+		return err
+	}
+	if i, ok := m.find(key, hash); ok {
+		m.items[i] = MapEntry{Key: key, Value: value}
+		return nil
+	}
+	m.hashes = append(m.hashes, hash)
+	m.items = append(m.items, MapEntry{Key: key, Value: value})
+	switch {
+	case m.index != nil:
+		m.index[hash] = append(m.index[hash], len(m.items)-1)
+	case len(m.items) > smallMapSize:
+		m.reindex()
+	}
+	return nil
+}
+
+// Delete 删除 key 对应的键值对，返回 key 是否存在
+func (m *Map) Delete(key Value) (bool, error) {
+	hash, err := key.Hash()
+	if err != nil {
+		return false, err
+	}
+	i, ok := m.find(key, hash)
+	if !ok {
+		return false, nil
+	}
+	m.hashes = append(m.hashes[:i], m.hashes[i+1:]...)
+	m.items = append(m.items[:i], m.items[i+1:]...)
+	if m.index != nil {
+		// 后面的键值对的下标都发生了变化，重建索引
+		m.reindex()
+	}
+	return true, nil
+}
+
+// Items 按插入的顺序返回 map 中的所有键值对
+func (m *Map) Items() []MapEntry {
+	items := make([]MapEntry, len(m.items))
+	copy(items, m.items)
+	return items
 }
 
 // Len implements Sequence.
 func (m *Map) Len() int {
-	return len(m.entries)
+	return len(m.items)
 }
 
 // Get implements Mapping.
@@ -330,13 +684,21 @@ func (m *Map) Get(v Value) (_ Value, _ bool, err error) {
 	if err != nil {
 		return nil, false, err
 	}
-	val, ok := m.entries[hash]
-	if ok {
-		return val.Value, true, nil
+	if i, ok := m.find(v, hash); ok {
+		return m.items[i].Value, true, nil
 	}
 	return Null, false, nil
 }
 
+// 判断两个可哈希的值作为 map 的键时是否相同
+func keyEqual(x, y Value) bool {
+	if x == y {
+		return true
+	}
+	eq, err := Compare(syntax.EQ, x, y)
+	return err == nil && eq.Truth()
+}
+
 // Hash implements Value.
 func (m *Map) Hash() (uint32, error) {
 	return 0, fmt.Errorf("unhashable type: map")
@@ -345,7 +707,7 @@ func (m *Map) Hash() (uint32, error) {
 // String implements Value.
 func (m *Map) String() string {
 	var entries = make([]string, 0)
-	for _, item := range m.entries {
+	for _, item := range m.Items() {
 		entries = append(entries, fmt.Sprintf("%s: %s", item.Key.String(), item.Value.String()))
 	}
 
@@ -391,14 +753,19 @@ func (r *returnValue) Type() string {
 }
 
 type Function struct {
-	Params []*syntax.Identifier
-	Body   *syntax.BlockStmt
-	Env    *Env
+	Name     string // 函数声明或具名函数表达式的名字，匿名函数为空字符串
+	Async    bool   // async fn，调用时返回 *Future
+	Params   []*syntax.Identifier
+	Variadic bool // 最后一个参数以数组的形式接收其余的实参
+	Body     *syntax.BlockStmt
+	Env      *Env
+
+	noClosures bool // 函数体中不创建闭包，调用结束后可以回收调用时的作用域
 }
 
 // Hash implements Value.
 func (f *Function) Hash() (uint32, error) {
-	panic("unimplemented")
+	return 0, fmt.Errorf("unhashable type: function")
 }
 
 // String implements Value.
@@ -408,13 +775,74 @@ func (f *Function) String() string {
 	for _, p := range f.Params {
 		params = append(params, p.String())
 	}
-	out.WriteString("fn(")
+	if f.Variadic {
+		params[len(params)-1] += "..."
+	}
+	if f.Async {
+		out.WriteString("async ")
+	}
+	out.WriteString("fn")
+	if f.Name != "" {
+		out.WriteString(" " + f.Name)
+	}
+	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
 	out.WriteString(f.Body.String())
 	return out.String()
 }
 
+// 创建 node 描述的函数，env 为函数定义时的作用域
+func newFunction(node *syntax.FunctionLiteral, env *Env) *Function {
+	fn := &Function{Async: node.Async, Params: node.Params, Variadic: node.Variadic, Body: node.Body, Env: env, noClosures: node.NoClosures}
+	if node.Name != nil {
+		fn.Name = node.Name.Value
+	}
+	return fn
+}
+
+// 检查调用 f 时的实参个数，可变参数函数至少需要除剩余参数以外的所有参数
+func (f *Function) checkArgs(n int) error {
+	want := len(f.Params)
+	if f.Variadic {
+		if want--; n >= want {
+			return nil
+		}
+	} else if n == want {
+		return nil
+	}
+	var to string
+	if f.Name != "" {
+		to = " to " + f.Name
+	}
+	if f.Variadic {
+		return fmt.Errorf("wrong number of arguments%s: want at least %d, got=%d", to, want, n)
+	}
+	return fmt.Errorf("wrong number of arguments%s: want=%d, got=%d", to, want, n)
+}
+
+// 将实参绑定到 env 中的参数上，剩余参数绑定为包含其余实参的数组
+func (f *Function) bindArgs(env *Env, args []Value) {
+	params := f.Params
+	if f.Variadic {
+		params = params[:len(params)-1]
+		rest := makeArray(len(args) - len(params))
+		copy(rest.items, args[len(params):])
+		env.Set(f.Params[len(params)].Value, rest)
+	}
+	for i, param := range params {
+		env.Set(param.Value, args[i])
+	}
+}
+
+// 创建 node 描述的函数，函数体中的 name 总是指向函数自身
+func newRecursiveFunction(node *syntax.FunctionLiteral, name string, env *Env) *Function {
+	scope := NewEnv(env)
+	fn := newFunction(node, scope)
+	scope.Set(name, fn)
+	return fn
+}
+
 // Truth implements Value.
 func (f *Function) Truth() bool {
 	return true
@@ -427,17 +855,17 @@ func (f *Function) Type() string {
 
 type BuiltinFunction struct {
 	name string
-	fn   func(args ...Value) (Value, error)
+	fn   func(thread *Thread, args ...Value) (Value, error)
 }
 
 // Hash implements Value.
 func (b *BuiltinFunction) Hash() (uint32, error) {
-	panic("unimplemented")
+	return 0, fmt.Errorf("unhashable type: builtin_function")
 }
 
 // CallInternal implements Callable.
-func (b *BuiltinFunction) CallInternal(args ...Value) (_ Value, err error) {
-	return b.fn(args...)
+func (b *BuiltinFunction) CallInternal(thread *Thread, args ...Value) (_ Value, err error) {
+	return b.fn(thread, args...)
 }
 
 // Name implements Callable.
@@ -460,7 +888,7 @@ func (b *BuiltinFunction) Type() string {
 	return "builtin_function"
 }
 
-func NewBuiltinFunction(name string, fn func(args ...Value) (Value, error)) *BuiltinFunction {
+func NewBuiltinFunction(name string, fn func(thread *Thread, args ...Value) (Value, error)) *BuiltinFunction {
 	return &BuiltinFunction{name, fn}
 }
 
@@ -474,9 +902,12 @@ var (
 	_ Comparable     = Bool(false)
 	_ Value          = String("")
 	_ Indexable      = String("")
+	_ Sliceable      = String("")
+	_ TotallyOrdered = String("")
 	_ HasBinary      = String("")
 	_ Value          = (*Array)(nil)
 	_ Indexable      = (*Array)(nil)
+	_ Sliceable      = (*Array)(nil)
 	_ Value          = (*Map)(nil)
 	_ Mapping        = (*Map)(nil)
 	_ Sequence       = (*Map)(nil)
@@ -0,0 +1,234 @@
+package monkey
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestFloat(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`3.14`, "3.14"},
+		{`1e-9`, "1e-09"},
+		{`2.5e3`, "2500.0"},
+		{`1.0`, "1.0"},
+		{`0.1 + 0.2`, "0.30000000000000004"},
+		{`1 + 0.5`, "1.5"},
+		{`0.5 + 1`, "1.5"},
+		{`1 - 0.25`, "0.75"},
+		{`0.25 - 1`, "-0.75"},
+		{`1 / 4.0`, "0.25"},
+		{`4.0 / 1 / 2`, "2.0"},
+		{`-1.5 * 2`, "-3.0"},
+		{`1 / 0.0`, "+Inf"},
+		{`1 == 1.0`, "true"},
+		{`1.5 > 1`, "true"},
+		{`2 < 1.5`, "false"},
+		{`1 <= 1.0`, "true"},
+		{`0.0 / 0.0 == 0.0 / 0.0`, "false"},
+		{`{1: "one"}[1.0]`, "one"},
+		{`int(3.9)`, "3"},
+		{`int(-3.9)`, "-3"},
+		{`int("42")`, "42"},
+		{`int(1e100)`, "cannot convert"},
+		{`float(3)`, "3.0"},
+		{`float("2.5")`, "2.5"},
+		{`float("x")`, "cannot convert"},
+		{`if (0.0) { 1 } else { 2 }`, "2"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalThread(t, tt.input)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestModuloAndPower(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`7 % 3`, "1"},
+		{`-7 % 3`, "-1"},
+		{`7 % 0`, "modulo by zero"},
+		{`7.5 % 2`, "1.5"},
+		{`2 ** 10`, "1024"},
+		{`2 ** 3 ** 2`, "512"},
+		{`-2 ** 2`, "-4"},
+		{`(-2) ** 3`, "-8"},
+		{`2 ** 0`, "1"},
+		{`2 ** -1`, "0.5"},
+		{`2.0 ** 0.5`, "1.4142135623730951"},
+		{`2 ** 63`, "integer overflow: 2 ** 63"},
+		{`(-2) ** 63`, "-9223372036854775808"},
+		{`3 ** 40`, "integer overflow"},
+		{`1 ** 100000000000`, "1"},
+		{`2 * 3 % 4`, "2"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalThread(t, tt.input)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestIntOverflow(t *testing.T) {
+	const max, min = "9223372036854775807", "(-9223372036854775807 - 1)"
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{max + ` + 1`, "integer overflow: 9223372036854775807 + 1"},
+		{`1 + ` + max, "integer overflow: 1 + 9223372036854775807"},
+		{max + ` + 0`, max},
+		{min + ` + -1`, "integer overflow: -9223372036854775808 + -1"},
+		{min + ` + ` + max, "-1"},
+		{min + ` - 1`, "integer overflow: -9223372036854775808 - 1"},
+		{`0 - ` + min, "integer overflow: 0 - -9223372036854775808"},
+		{`-1 - ` + min, max},
+		{max + ` - -1`, "integer overflow: 9223372036854775807 - -1"},
+		{max + ` * 2`, "integer overflow: 9223372036854775807 * 2"},
+		{min + ` * -1`, "integer overflow: -9223372036854775808 * -1"},
+		{`-1 * ` + min, "integer overflow"},
+		{max + ` * -1`, "-9223372036854775807"},
+		{`4611686018427387904 * 2`, "integer overflow"},
+		{`-4611686018427387904 * 2`, "-9223372036854775808"},
+		{min + ` / -1`, "integer overflow: -9223372036854775808 / -1"},
+		{min + ` / 1`, "-9223372036854775808"},
+		{min + ` % -1`, "0"},
+		{`-` + min, "integer overflow: -(-9223372036854775808)"},
+		{`let x = ` + max + `; x += 1; x`, "integer overflow"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalThread(t, tt.input)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestDivisionByZero(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`1 / 0`, ":1:3 division by zero: 1 / 0"},
+		{`let x = 0;
+		  10 / x`, ":2:8 division by zero: 10 / 0"},
+		{`5 % 0`, ":1:3 modulo by zero"},
+		{`try(fn() { 1 / 0 })["error"]["message"]`, "division by zero"},
+		{`1 / 0.0`, "+Inf"},
+		{`1 + "a"`, ":1:3 unknown binary operator"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalThread(t, tt.input)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestSliceExpr(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`[1, 2, 3, 4][1:3]`, "[2, 3]"},
+		{`[1, 2, 3, 4][:2]`, "[1, 2]"},
+		{`[1, 2, 3, 4][2:]`, "[3, 4]"},
+		{`[1, 2, 3, 4][:]`, "[1, 2, 3, 4]"},
+		{`[1, 2, 3, 4][-2:]`, "[3, 4]"},
+		{`[1, 2, 3, 4][:-1]`, "[1, 2, 3]"},
+		{`[1, 2, 3, 4][3:1]`, "[]"},
+		{`[1, 2, 3, 4][-10:10]`, "[1, 2, 3, 4]"},
+		{`"hello"[1:3]`, "el"},
+		{`"héllo"[1:2]`, "é"},
+		{`"héllo"[1]`, "é"},
+		{`"héllo"[-1]`, "o"},
+		{`"日本語"[1:]`, "本語"},
+		{`len("日本語"[:2])`, "2"},
+		{`[1, 2][5]`, "index out of range: 5 with length 2"},
+		{`"ab"[-3]`, "index out of range: -3 with length 2"},
+		{`[1, 2]["a":]`, "invalid slice index type: string"},
+		{`{"a": 1}[0:1]`, "1:1 slice operator not supported: map"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalThread(t, tt.input)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestStringSliceCopy(t *testing.T) {
+	s := String(strings.Repeat("héllo", 1000))
+	half := s.Slice(0, 2500).(String)
+	if unsafe.StringData(string(half)) != unsafe.StringData(string(s)) {
+		t.Errorf("expected a long substring to share memory with its parent")
+	}
+	small := s.Slice(5, 10).(String)
+	if small != "héllo" {
+		t.Errorf("wrong substring. got=%q", small)
+	}
+	if unsafe.StringData(string(small)) == unsafe.StringData(string(s[6:])) {
+		t.Errorf("expected a short substring to be copied")
+	}
+}
+
+func BenchmarkStringSlice(b *testing.B) {
+	s := String(strings.Repeat("héllo wörld ", 1<<16))
+	n := s.Len()
+	b.Run("half", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s.Slice(0, n/2)
+		}
+	})
+	b.Run("small", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s.Slice(n/2, n/2+16)
+		}
+	})
+	b.Run("index", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s.Index(n / 2)
+		}
+	})
+}
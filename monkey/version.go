@@ -0,0 +1,119 @@
+package monkey
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// 发布时通过链接参数设置版本和提交：
+//
+//	go build -ldflags "-X github.com/hungtcs/monkey-lang/monkey.Version=v0.3.0 -X github.com/hungtcs/monkey-lang/monkey.Commit=$(git rev-parse HEAD)"
+var (
+	Version = "devel" // 解释器的版本
+	Commit  = ""      // 构建时的 git 提交，为空时使用 go build 记录的版本控制信息
+)
+
+// LanguageVersion 是当前解释器实现的语言版本
+const LanguageVersion = 1
+
+// Engine 是执行程序的引擎，目前只有遍历语法树求值的 "eval"
+const Engine = "eval"
+
+// Features 是当前解释器支持的语言特性，脚本可以通过 version()["features"] 检查
+var Features = []string{"async", "macros", "modules", "records", "methods", "variadic"}
+
+func init() {
+	Universe["version"] = NewBuiltinFunction("version", versionBuiltin)
+	Docs["version"] = "version()\n\n返回解释器的版本、git 提交、语言版本、引擎和支持的语言特性"
+}
+
+// BuildInfo 描述解释器的构建信息
+type BuildInfo struct {
+	Version   string
+	Commit    string // 未知时为空字符串，工作区有未提交的修改时以 -dirty 结尾
+	Language  int
+	Engine    string
+	Features  []string
+	GoVersion string
+}
+
+// ReadBuildInfo 返回当前解释器的构建信息
+func ReadBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		Language:  LanguageVersion,
+		Engine:    Engine,
+		Features:  Features,
+		GoVersion: runtime.Version(),
+	}
+	if info.Commit == "" {
+		info.Commit = vcsRevision()
+	}
+	return info
+}
+
+// 返回 go build 记录的提交
+func vcsRevision() string {
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	var revision, modified string
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value
+		}
+	}
+	if revision != "" && modified == "true" {
+		revision += "-dirty"
+	}
+	return revision
+}
+
+// String 以 "monkey v0.3.0 (commit 1a2b3c4, language 1, engine eval, go1.22.2)" 的形式描述构建信息
+func (b BuildInfo) String() string {
+	commit := b.Commit
+	if commit == "" {
+		commit = "unknown"
+	} else if len(commit) > 12 {
+		commit = commit[:12]
+	}
+	return fmt.Sprintf("monkey %s (commit %s, language %d, engine %s, %s)", b.Version, commit, b.Language, b.Engine, b.GoVersion)
+}
+
+// version()
+//
+// 返回描述解释器的 map，可以写入错误报告：
+//
+//	version()["version"]   // "v0.3.0"，开发版本为 "devel"
+//	version()["commit"]    // 构建时的 git 提交，未知时为 null
+//	version()["language"]  // 语言版本，与 monkey.toml 中的 monkey 对应
+//	version()["engine"]    // "eval"
+//	version()["features"]  // ["async", "macros", ...]
+func versionBuiltin(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=0", len(args))
+	}
+	info := ReadBuildInfo()
+	m := NewMap()
+	m.SetKey(String("version"), String(info.Version))
+	if info.Commit != "" {
+		m.SetKey(String("commit"), String(info.Commit))
+	} else {
+		m.SetKey(String("commit"), Null)
+	}
+	m.SetKey(String("language"), Int(info.Language))
+	m.SetKey(String("engine"), String(info.Engine))
+	features := make([]Value, len(info.Features))
+	for i, feature := range info.Features {
+		features[i] = String(feature)
+	}
+	m.SetKey(String("features"), NewArray(features))
+	m.SetKey(String("go"), String(info.GoVersion))
+	return m, nil
+}
@@ -0,0 +1,44 @@
+package monkey
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	defer func(version, commit string) { Version, Commit = version, commit }(Version, Commit)
+	Version, Commit = "v1.2.3", "0123456789abcdef"
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`version()["version"]`, "v1.2.3"},
+		{`version()["commit"]`, "0123456789abcdef"},
+		{`version()["language"]`, "1"},
+		{`version()["engine"]`, "eval"},
+		{`len(filter(version()["features"], fn(f) { f == "records" }))`, "1"},
+		{`version(1)`, "wrong number of arguments. got=1, want=0"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalThread(t, tt.input)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	info := BuildInfo{Version: "v1.2.3", Commit: "0123456789abcdef", Language: 1, Engine: "eval", GoVersion: "go1.22.2"}
+	if got, want := info.String(), "monkey v1.2.3 (commit 0123456789ab, language 1, engine eval, go1.22.2)"; got != want {
+		t.Errorf("wrong String. expected=%q, got=%q", want, got)
+	}
+	info.Commit = ""
+	if got := info.String(); !strings.Contains(got, "commit unknown") {
+		t.Errorf("expected unknown commit. got=%q", got)
+	}
+}
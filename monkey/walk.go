@@ -0,0 +1,41 @@
+package monkey
+
+// WalkValue 以深度优先的顺序访问 v 以及它包含的所有值：数组的元素、map 的键和值。
+// fn 返回 false 时不再访问该值包含的值。同一个数组或 map 只会被访问一次，
+// 因此即使 Go 代码构造了包含自身的容器，WalkValue 也会结束。
+//
+// 宿主程序可以用它检查、清理或脱敏脚本产生的数据，例如查找所有的字符串：
+//
+//	monkey.WalkValue(v, func(v monkey.Value) bool {
+//		if s, ok := v.(monkey.String); ok {
+//			check(string(s))
+//		}
+//		return true
+//	})
+func WalkValue(v Value, fn func(Value) bool) {
+	walkValue(v, fn, make(map[Value]bool))
+}
+
+func walkValue(v Value, fn func(Value) bool, seen map[Value]bool) {
+	switch v.(type) {
+	case *Array, *Map:
+		if seen[v] {
+			return
+		}
+		seen[v] = true
+	}
+	if !fn(v) {
+		return
+	}
+	switch v := v.(type) {
+	case *Array:
+		for _, item := range v.items {
+			walkValue(item, fn, seen)
+		}
+	case *Map:
+		for _, entry := range v.Items() {
+			walkValue(entry.Key, fn, seen)
+			walkValue(entry.Value, fn, seen)
+		}
+	}
+}
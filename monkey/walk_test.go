@@ -0,0 +1,57 @@
+package monkey
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestWalkValue(t *testing.T) {
+	inner := NewArray([]Value{String("b"), Int(2)})
+	m := NewMap()
+	m.SetKey(String("k"), inner)
+	root := NewArray([]Value{String("a"), m, inner})
+
+	var visited []string
+	WalkValue(root, func(v Value) bool {
+		visited = append(visited, v.Type()+" "+v.String())
+		return true
+	})
+	sort.Strings(visited)
+	expected := []string{
+		"array [a, {k: [b, 2]}, [b, 2]]",
+		"array [b, 2]", // 同一个数组只访问一次
+		"int 2",
+		"map {k: [b, 2]}",
+		"string a",
+		"string b",
+		"string k",
+	}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("wrong values visited.\nexpected=%q\ngot=%q", expected, visited)
+	}
+
+	// 返回 false 时跳过容器的内容
+	var count int
+	WalkValue(root, func(v Value) bool {
+		count++
+		_, isMap := v.(*Map)
+		return !isMap
+	})
+	if count != 6 { // root、"a"、m、inner、"b" 和 2，不包括 m 的键 "k"
+		t.Errorf("expected 6 values when skipping maps. got=%d", count)
+	}
+
+	// 包含自身的数组
+	items := []Value{Int(1), nil}
+	cyclic := NewArray(items)
+	items[1] = cyclic
+	count = 0
+	WalkValue(cyclic, func(v Value) bool {
+		count++
+		return true
+	})
+	if count != 2 {
+		t.Errorf("expected 2 values in cyclic array. got=%d", count)
+	}
+}
@@ -0,0 +1,289 @@
+package monkey
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+func init() {
+	Universe["worker"] = NewBuiltinFunction("worker", workerBuiltin)
+}
+
+// worker(code) 或 worker(code, capabilities)
+//
+// 在新的 goroutine 中启动一个隔离的解释器执行 code，code 以 .mky 结尾时视为文件路径，
+// 相对路径相对于当前模块所在的目录解析。worker 有自己的全局作用域和模块缓存，
+// 默认不允许任何能力，capabilities 是授予它的能力名称数组，只能是当前 Policy 允许的能力的子集。
+//
+// worker 和创建它的脚本之间只能通过消息通信，消息只能是 null、布尔值、数字、字符串、错误值，
+// 以及由它们组成的数组和 map，发送时会被深拷贝，因此双方不会共享任何可变状态：
+//
+//	let w = worker("send(receive() * 2)");
+//	w["send"](21);
+//	w["receive"]();  // 42
+//	w["join"]();     // 等待 worker 结束，返回它最后一个表达式的值
+//
+// 在 worker 中，send(v) 向创建者发送消息，receive() 接收创建者发送的消息。
+// 对方关闭（创建者调用 close，或 worker 执行完毕）且没有剩余消息时，receive 返回 null。
+// 两边的 receive 都可以传入 context() 返回的 Context，Context 被取消时停止等待并返回错误。
+func workerBuiltin(thread *Thread, args ...Value) (Value, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+	code, ok := args[0].(String)
+	if !ok {
+		return nil, fmt.Errorf("first argument to `worker` must be string, got %s", args[0].Type())
+	}
+
+	policy := NewPolicy()
+	if len(args) == 2 {
+		caps, ok := args[1].(*Array)
+		if !ok {
+			return nil, fmt.Errorf("second argument to `worker` must be array, got %s", args[1].Type())
+		}
+		for _, item := range caps.items {
+			name, ok := item.(String)
+			if !ok {
+				return nil, fmt.Errorf("capabilities of `worker` must be strings, got %s", item.Type())
+			}
+			if !thread.Policy.Allows(Capability(name)) {
+				return nil, fmt.Errorf("cannot grant the %q capability to a worker, it is not allowed by the policy", string(name))
+			}
+			policy.allowed[Capability(name)] = true
+		}
+	}
+
+	from := thread.file
+	if from == "" {
+		from = thread.Name
+	}
+	name := "worker"
+	if from != "" {
+		name = from + " (worker)"
+	}
+	src := string(code)
+	if !strings.Contains(src, "\n") && strings.HasSuffix(src, ".mky") {
+		name = src
+		if !filepath.IsAbs(name) && from != "" {
+			name = filepath.Join(filepath.Dir(from), name)
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		src = string(data)
+	}
+	program, err := Parse(thread, name, src)
+	if err != nil {
+		return nil, err
+	}
+
+	importer := thread.importer()
+	w := &Worker{
+		name:   name,
+		inbox:  newMailbox(),
+		outbox: newMailbox(),
+		done:   make(chan struct{}),
+	}
+	// worker 与创建者共享 Provider，录制、回放和确定性模式同样作用于 worker；
+	// 创建者所在的求值被取消时 worker 也被取消
+	child := &Thread{
+		Name:     name,
+		Provider: thread.Provider,
+		Print:    thread.Print,
+		Policy:   policy,
+		Importer: &Importer{CacheDir: importer.CacheDir, Lockfile: importer.Lockfile},
+		Hooks:    thread.Hooks,
+		MaxSteps: thread.MaxSteps, // worker 有自己的预算
+		MaxAlloc: thread.MaxAlloc,
+		Language: thread.Language,
+		cancel:   thread.cancel,
+	}
+	env := NewEnv(nil)
+	env.Set("send", NewBuiltinFunction("send", w.outbox.send))
+	env.Set("receive", NewBuiltinFunction("receive", w.inbox.receive))
+
+	go func() {
+		defer close(w.done)
+		defer w.outbox.close()
+		result, err := EvalThread(child, program, env)
+		if err != nil {
+			w.err = err
+			return
+		}
+		if w.result, err = copyValue(result); err != nil {
+			w.err = fmt.Errorf("result of worker: %w", err)
+		}
+	}()
+	return w, nil
+}
+
+// Worker 是 worker() 返回的句柄
+type Worker struct {
+	name   string
+	inbox  *mailbox // 发送给 worker 的消息
+	outbox *mailbox // worker 发出的消息
+	done   chan struct{}
+	result Value // worker 结束后有效
+	err    error
+}
+
+// Get implements Mapping.
+func (w *Worker) Get(key Value) (_ Value, _ bool, err error) {
+	name, _ := key.(String)
+	var fn func(thread *Thread, args ...Value) (Value, error)
+	switch name {
+	case "send":
+		fn = w.inbox.send
+	case "receive":
+		fn = w.outbox.receive
+	case "close":
+		fn = func(thread *Thread, args ...Value) (Value, error) {
+			if len(args) != 0 {
+				return nil, fmt.Errorf("wrong number of arguments. got=%d, want=0", len(args))
+			}
+			w.inbox.close()
+			return Null, nil
+		}
+	case "join":
+		fn = func(thread *Thread, args ...Value) (Value, error) {
+			if len(args) != 0 {
+				return nil, fmt.Errorf("wrong number of arguments. got=%d, want=0", len(args))
+			}
+			<-w.done
+			if w.err != nil {
+				return nil, fmt.Errorf("%s: %w", w.name, w.err)
+			}
+			return w.result, nil
+		}
+	default:
+		return Null, false, nil
+	}
+	return NewBuiltinFunction(string(name), fn), true, nil
+}
+
+// Hash implements Value.
+func (w *Worker) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: worker")
+}
+
+// String implements Value.
+func (w *Worker) String() string {
+	return fmt.Sprintf("<worker %s>", w.name)
+}
+
+// Truth implements Value.
+func (w *Worker) Truth() bool {
+	return true
+}
+
+// Type implements Value.
+func (w *Worker) Type() string {
+	return "worker"
+}
+
+// mailbox 是一个无界的消息队列，发送永远不会阻塞
+type mailbox struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	items  []Value
+	closed bool
+}
+
+func newMailbox() *mailbox {
+	m := new(mailbox)
+	m.cond = sync.NewCond(&m.mu)
+	return m
+}
+
+func (m *mailbox) send(thread *Thread, args ...Value) (Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	msg, err := copyValue(args[0])
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, fmt.Errorf("send on closed worker channel")
+	}
+	m.items = append(m.items, msg)
+	m.cond.Signal()
+	return Null, nil
+}
+
+// receive([ctx]) 接收一条消息，ctx 被取消时返回它的错误
+func (m *mailbox) receive(thread *Thread, args ...Value) (Value, error) {
+	ctx, args := SplitContext(args)
+	if len(args) != 0 {
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=0", len(args))
+	}
+	// ctx 被取消时唤醒等待中的 receive
+	stop := context.AfterFunc(ctx, func() {
+		m.mu.Lock()
+		m.cond.Broadcast()
+		m.mu.Unlock()
+	})
+	defer stop()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for len(m.items) == 0 && !m.closed {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		m.cond.Wait()
+	}
+	if len(m.items) == 0 {
+		return Null, nil
+	}
+	msg := m.items[0]
+	m.items = m.items[1:]
+	return msg, nil
+}
+
+func (m *mailbox) close() {
+	m.mu.Lock()
+	m.closed = true
+	m.cond.Broadcast()
+	m.mu.Unlock()
+}
+
+// 深拷贝可以在 worker 之间传递的值，其他类型的值返回错误
+func copyValue(v Value) (Value, error) {
+	switch v := v.(type) {
+	case NullType, Bool, Int, Float, String, *Error:
+		return v, nil
+	case *Array:
+		items := make([]Value, len(v.items))
+		for i, item := range v.items {
+			var err error
+			if items[i], err = copyValue(item); err != nil {
+				return nil, err
+			}
+		}
+		return NewArray(items), nil
+	case *Map:
+		m := NewMap()
+		for _, entry := range v.items {
+			value, err := copyValue(entry.Value)
+			if err != nil {
+				return nil, err
+			}
+			m.SetKey(entry.Key, value)
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("cannot pass %s between workers", v.Type())
+}
+
+var (
+	_ Value   = (*Worker)(nil)
+	_ Mapping = (*Worker)(nil)
+)
@@ -0,0 +1,105 @@
+package monkey
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+func TestWorker(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "double.mky"), []byte(`send(receive() * 2)`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	script := filepath.ToSlash(filepath.Join(dir, "double.mky"))
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let w = worker("send(receive() * 2)"); w["send"](21); w["receive"]()`, "42"},
+		{`let w = worker("` + script + `"); w["send"](4); w["receive"]()`, "8"},
+		{`let w = worker("1 + 2"); w["join"]()`, "3"},
+		{`let w = worker("send(1); send(2)"); [w["receive"](), w["receive"](), w["receive"]()]`, "[1, 2, null]"},
+		{`let w = worker("let f = fn(n) { let m = receive(); if (m) { f(n + m) } else { n } }; f(0)");
+		  w["send"](1); w["send"](2); w["send"](3); w["close"](); w["join"]()`, "6"},
+		{`let w = worker("receive()[1][1]"); w["send"]({1: [1, 2]}); w["join"]()`, "2"},
+		{`let w = worker("x"); w["join"]()`, "worker: worker:1:1 identifier not found: x"},
+		{`let w = worker("1"); w["send"](fn() { 1 })`, "cannot pass function between workers"},
+		{`let w = worker("fn() { 1 }"); w["join"]()`, "cannot pass function between workers"},
+		{`let w = worker("eval(receive())"); w["send"]("1"); w["join"]()`, `requires the "eval" capability`},
+		{`let w = worker("eval(receive())", ["eval"]); w["send"]("1 + 1"); w["join"]()`, "2"},
+		{`worker("missing.mky")`, "no such file"},
+	}
+	for _, tt := range tests {
+		val, err := testEvalPolicy(t, tt.input, NewPolicy(CapEval))
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.String()
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	_, err := testEvalPolicy(t, `worker("1", ["eval"])`, nil)
+	if err == nil || !strings.Contains(err.Error(), "cannot grant") {
+		t.Errorf("expected policy error. got=%v", err)
+	}
+}
+
+func TestWorkerDeterministic(t *testing.T) {
+	program, err := syntax.NewParser(`worker("[random(), time()]")["join"]()`).Parse()
+	if err != nil {
+		t.Fatalf("parser error: %s", err)
+	}
+	run := func() string {
+		thread := &Thread{}
+		(&Deterministic{Seed: 1}).Apply(thread)
+		val, err := EvalThread(thread, program, NewEnv(nil))
+		if err != nil {
+			t.Fatalf("eval error: %s", err)
+		}
+		return val.String()
+	}
+	if a, b := run(), run(); a != b {
+		t.Errorf("worker is not deterministic. got %s and %s", a, b)
+	}
+}
+
+func TestWorkerCancel(t *testing.T) {
+	// 创建者超时之后 worker 同样被取消，join 不会一直等待下去
+	slow := `worker("let f = fn(n) { if (n > 0) { f(n - 1); f(n - 1) } }; f(40)")["join"]()`
+	done := make(chan error, 1)
+	go func() {
+		_, err := Run(slow, WithLimits(Limits{Timeout: 20 * time.Millisecond}))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrResourceExhausted) && !errors.Is(err, ErrCancelled) {
+			t.Errorf("expected the worker to be cancelled, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("worker kept running after the evaluation was cancelled")
+	}
+}
+
+func TestWorkerResultError(t *testing.T) {
+	_, err := testEvalPolicy(t, `worker("context()")["join"]()`, nil)
+	// join 的错误包装了 worker 的错误，其中又包装了结果无法传递的原因
+	var found bool
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		found = found || e.Error() == "cannot pass context between workers"
+	}
+	if !found {
+		t.Errorf("expected the copy error to be wrapped. got=%v", err)
+	}
+}
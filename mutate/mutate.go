@@ -0,0 +1,108 @@
+// Package mutate 对 AST 做简单的变异（翻转运算符、否定条件等），
+// 用于检验测试能否发现这些人为引入的缺陷。
+package mutate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// Mutant 是对 AST 的一处变异，Apply 和 Revert 原地修改 AST
+type Mutant struct {
+	Pos    syntax.Position
+	Desc   string
+	apply  func()
+	revert func()
+}
+
+// Apply 将变异应用到 AST 上
+func (m *Mutant) Apply() { m.apply() }
+
+// Revert 撤销变异
+func (m *Mutant) Revert() { m.revert() }
+
+func (m *Mutant) String() string {
+	return fmt.Sprintf("%v: %s", m.Pos, m.Desc)
+}
+
+// 运算符的变异规则
+var flips = map[syntax.Token]syntax.Token{
+	syntax.PLUS:    syntax.MINUS,
+	syntax.MINUS:   syntax.PLUS,
+	syntax.STAR:    syntax.SLASH,
+	syntax.SLASH:   syntax.STAR,
+	syntax.PERCENT: syntax.SLASH,
+	syntax.EQ:      syntax.NE,
+	syntax.NE:      syntax.EQ,
+	syntax.LT:      syntax.LE,
+	syntax.LE:      syntax.LT,
+	syntax.GT:      syntax.GE,
+	syntax.GE:      syntax.GT,
+	syntax.AND:     syntax.OR,
+	syntax.OR:      syntax.AND,
+}
+
+// Mutants 返回 node 中所有可以变异的位置，按源码顺序排列
+func Mutants(node syntax.Node) []*Mutant {
+	var mutants []*Mutant
+	syntax.Inspect(node, func(node syntax.Node) bool {
+		switch node := node.(type) {
+		case *syntax.MacroLiteral:
+			// 宏的函数体是生成代码的模板，不做变异
+			return false
+
+		case *syntax.InfixExpr:
+			start, _ := node.Span()
+			op, ok := flips[node.Op]
+			if !ok {
+				return true
+			}
+			orig := node.Op
+			mutants = append(mutants, &Mutant{
+				Pos:    start,
+				Desc:   fmt.Sprintf("%s -> %s", orig, op),
+				apply:  func() { node.Op = op },
+				revert: func() { node.Op = orig },
+			})
+
+		case *syntax.IfExpr:
+			start, _ := node.Span()
+			cond := node.Cond
+			mutants = append(mutants, &Mutant{
+				Pos:    start,
+				Desc:   fmt.Sprintf("negate condition %s", cond),
+				apply:  func() { node.Cond = &syntax.PrefixExpr{Op: syntax.BANG, Pos: start, Right: cond} },
+				revert: func() { node.Cond = cond },
+			})
+
+		case *syntax.Boolean:
+			start, _ := node.Span()
+			orig := node.Value
+			mutants = append(mutants, &Mutant{
+				Pos:    start,
+				Desc:   fmt.Sprintf("%t -> %t", orig, !orig),
+				apply:  func() { node.Value = !orig },
+				revert: func() { node.Value = orig },
+			})
+
+		case *syntax.IntegerLiteral:
+			start, _ := node.Span()
+			orig, raw := node.Value, node.Raw
+			mutants = append(mutants, &Mutant{
+				Pos:    start,
+				Desc:   fmt.Sprintf("%d -> %d", orig, orig+1),
+				apply:  func() { node.Value, node.Raw = orig+1, strconv.FormatInt(orig+1, 10) },
+				revert: func() { node.Value, node.Raw = orig, raw },
+			})
+		}
+		return true
+	})
+	sort.SliceStable(mutants, func(i, j int) bool {
+		a, b := mutants[i].Pos, mutants[j].Pos
+		return a.Line < b.Line || a.Line == b.Line && a.Col < b.Col
+	})
+	return mutants
+}
@@ -0,0 +1,44 @@
+package mutate
+
+import (
+	"testing"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+func TestMutants(t *testing.T) {
+	input := `let f = fn(x) { if (x < 10) { x + 1 } else { true } };`
+
+	program, err := syntax.NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("parser error: %s", err)
+	}
+	original := program.String()
+
+	expected := []string{
+		"negate condition (x < 10)",
+		"< -> <=",
+		"10 -> 11",
+		"+ -> -",
+		"1 -> 2",
+		"true -> false",
+	}
+	mutants := Mutants(program)
+	if len(mutants) != len(expected) {
+		t.Fatalf("wrong number of mutants. want=%d, got=%d: %v", len(expected), len(mutants), mutants)
+	}
+	for i, mutant := range mutants {
+		if mutant.Desc != expected[i] {
+			t.Errorf("mutants[%d] wrong. want=%q, got=%q", i, expected[i], mutant.Desc)
+		}
+
+		mutant.Apply()
+		if mutant.Desc != expected[5] && program.String() == original {
+			t.Errorf("mutants[%d] did not change the program", i)
+		}
+		mutant.Revert()
+		if program.String() != original {
+			t.Errorf("mutants[%d] not reverted. got=%s", i, program.String())
+		}
+	}
+}
@@ -0,0 +1,251 @@
+// Package project 读写 Monkey 项目的清单文件 monkey.toml：
+//
+//	[project]
+//	name = "hello"
+//	monkey = "1"
+//	entry = "main.mky"
+//	scope = "module"
+//	dependencies = ["pkg:example.com/lib/math.mky"]
+//
+//	[test]
+//	paths = ["."]
+//
+//	[fmt]
+//	indent = 2
+//
+//	[lint]
+//	disable = []
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hungtcs/monkey-lang/lint"
+	"github.com/hungtcs/monkey-lang/monkey"
+)
+
+// ManifestName 是项目清单文件的名称
+const ManifestName = "monkey.toml"
+
+// LanguageVersion 是当前解释器实现的语言版本
+const LanguageVersion = monkey.LanguageVersion
+
+// Manifest 是 monkey.toml 的内容
+type Manifest struct {
+	Name         string   // 项目名称
+	Monkey       int      // 项目要求的语言版本
+	Entry        string   // monkey run 不指定文件时执行的入口文件
+	Scope        string   // monkey run 目录时的执行方式，见 Files
+	Dependencies []string // 远程模块，monkey get 不指定模块时下载它们
+
+	Test struct {
+		Paths []string // monkey test 不指定路径时测试的文件或目录
+	}
+	Fmt struct {
+		Indent int // monkey fmt 每级缩进的空格数
+	}
+	Lint struct {
+		Disable []string // monkey check 关闭的检查，见 lint.Checks
+	}
+}
+
+// New 返回一个使用默认配置的清单
+func New(name string) *Manifest {
+	m := &Manifest{Name: name, Monkey: LanguageVersion, Entry: "main.mky", Scope: ScopeModule}
+	m.Test.Paths = []string{"."}
+	m.Fmt.Indent = 2
+	return m
+}
+
+// Parse 解析 monkey.toml 的内容，未设置的字段使用默认值
+func Parse(src string) (*Manifest, error) {
+	tables, err := parseTOML(src)
+	if err != nil {
+		return nil, err
+	}
+	for key := range tables[""] {
+		return nil, fmt.Errorf("key %q must be in a table", key)
+	}
+
+	m := New("")
+	fields := map[string]map[string]any{
+		"project": {
+			"name":         &m.Name,
+			"monkey":       &m.Monkey,
+			"entry":        &m.Entry,
+			"scope":        &m.Scope,
+			"dependencies": &m.Dependencies,
+		},
+		"test": {"paths": &m.Test.Paths},
+		"fmt":  {"indent": &m.Fmt.Indent},
+		"lint": {"disable": &m.Lint.Disable},
+	}
+	for name, table := range tables {
+		if name == "" {
+			continue
+		}
+		known, ok := fields[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown table [%s]", name)
+		}
+		for key, value := range table {
+			field, ok := known[key]
+			if !ok {
+				return nil, fmt.Errorf("unknown key %q in [%s]", key, name)
+			}
+			if err := assign(field, value); err != nil {
+				return nil, fmt.Errorf("%s.%s: %v", name, key, err)
+			}
+		}
+	}
+
+	if m.Scope != ScopeModule && m.Scope != ScopeShared {
+		return nil, fmt.Errorf("project.scope: want %q or %q, got %q", ScopeModule, ScopeShared, m.Scope)
+	}
+	if m.Fmt.Indent < 1 || m.Fmt.Indent > 8 {
+		return nil, fmt.Errorf("fmt.indent: want 1 to 8, got %d", m.Fmt.Indent)
+	}
+	for _, check := range m.Lint.Disable {
+		if !slices.Contains(lint.Checks, check) {
+			return nil, fmt.Errorf("lint.disable: unknown check %q, want one of %s", check, strings.Join(lint.Checks, ", "))
+		}
+	}
+	if m.Monkey > LanguageVersion {
+		return nil, fmt.Errorf("project requires monkey language version %d, this interpreter supports version %d", m.Monkey, LanguageVersion)
+	}
+	return m, nil
+}
+
+func assign(field any, value any) error {
+	switch field := field.(type) {
+	case *string:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("want string, got %T", value)
+		}
+		*field = s
+	case *int:
+		switch v := value.(type) {
+		case int64:
+			*field = int(v)
+		case string:
+			// 版本号也可以写成字符串
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("want integer, got %q", v)
+			}
+			*field = n
+		default:
+			return fmt.Errorf("want integer, got %T", value)
+		}
+	case *[]string:
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("want array of strings, got %T", value)
+		}
+		strs := make([]string, len(items))
+		for i, item := range items {
+			s, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("want array of strings, got %T element", item)
+			}
+			strs[i] = s
+		}
+		*field = strs
+	}
+	return nil
+}
+
+// String 将清单编码为 monkey.toml 的格式
+func (m *Manifest) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[project]\n")
+	fmt.Fprintf(&b, "name = %s\n", quote(m.Name))
+	fmt.Fprintf(&b, "monkey = %s\n", quote(strconv.Itoa(m.Monkey)))
+	fmt.Fprintf(&b, "entry = %s\n", quote(m.Entry))
+	fmt.Fprintf(&b, "scope = %s\n", quote(m.Scope))
+	fmt.Fprintf(&b, "dependencies = %s\n", quoteList(m.Dependencies))
+	fmt.Fprintf(&b, "\n[test]\n")
+	fmt.Fprintf(&b, "paths = %s\n", quoteList(m.Test.Paths))
+	fmt.Fprintf(&b, "\n[fmt]\n")
+	fmt.Fprintf(&b, "indent = %d\n", m.Fmt.Indent)
+	fmt.Fprintf(&b, "\n[lint]\n")
+	fmt.Fprintf(&b, "disable = %s\n", quoteList(m.Lint.Disable))
+	return b.String()
+}
+
+// monkey run 目录时的执行方式
+const (
+	ScopeModule = "module" // 只执行入口文件，其他文件作为模块导入
+	ScopeShared = "shared" // 目录中的所有文件组成一个程序，共享顶层作用域
+)
+
+// Files 按执行的顺序返回 monkey run dir 要执行的文件。
+// Scope 为 "module" 时只有入口文件；为 "shared" 时是入口文件所在目录中所有的 .mky 文件（不包括子目录），
+// 按文件名排序，入口文件总是排在最后，因此它可以使用其他文件中的定义。
+func (m *Manifest) Files(dir string) ([]string, error) {
+	entry := filepath.Join(dir, m.Entry)
+	_, err := os.Stat(entry)
+	hasEntry := err == nil
+	if m.Scope != ScopeShared {
+		if !hasEntry {
+			return nil, fmt.Errorf("no entry file %s in %s", m.Entry, dir)
+		}
+		return []string{entry}, nil
+	}
+
+	// os.ReadDir 按文件名排序，因此顺序是确定的
+	pkg := filepath.Dir(entry)
+	entries, err := os.ReadDir(pkg)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		name := filepath.Join(pkg, e.Name())
+		if !e.IsDir() && strings.HasSuffix(name, ".mky") && name != entry {
+			files = append(files, name)
+		}
+	}
+	if hasEntry {
+		files = append(files, entry)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .mky files in %s", pkg)
+	}
+	return files, nil
+}
+
+func quoteList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = quote(item)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// Load 从 dir 开始向上查找项目根目录并读取其中的 monkey.toml。
+// 不在项目中（或项目根目录只有 .monkeyroot）时返回 nil 清单和空的根目录。
+func Load(dir string) (m *Manifest, root string, err error) {
+	root, ok := monkey.FindProjectRoot(dir)
+	if !ok {
+		return nil, "", nil
+	}
+	filename := filepath.Join(root, ManifestName)
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, "", nil
+	} else if err != nil {
+		return nil, "", err
+	}
+	m, err = Parse(string(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %v", filename, err)
+	}
+	return m, root, nil
+}
@@ -0,0 +1,149 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	src := `
+# 项目配置
+[project]
+name = "demo"   # 名称
+monkey = 1
+entry = "src/main.mky"
+dependencies = [
+	"pkg:example.com/lib/math.mky",   # 数学库
+	"pkg:example.com/lib/#str.mky",
+]
+
+[lint]
+disable = ["unused"]
+`
+	m, err := Parse(src)
+	if err != nil {
+		t.Fatalf("parse error: %s", err)
+	}
+	if m.Name != "demo" || m.Monkey != 1 || m.Entry != "src/main.mky" {
+		t.Errorf("wrong project table. got=%+v", m)
+	}
+	if want := []string{"pkg:example.com/lib/math.mky", "pkg:example.com/lib/#str.mky"}; !reflect.DeepEqual(m.Dependencies, want) {
+		t.Errorf("wrong dependencies. got=%q", m.Dependencies)
+	}
+	if !reflect.DeepEqual(m.Lint.Disable, []string{"unused"}) {
+		t.Errorf("wrong lint.disable. got=%q", m.Lint.Disable)
+	}
+	// 未设置的字段使用默认值
+	if !reflect.DeepEqual(m.Test.Paths, []string{"."}) || m.Fmt.Indent != 2 {
+		t.Errorf("defaults not applied. got=%+v", m)
+	}
+
+	// String 的输出可以被重新解析
+	again, err := Parse(m.String())
+	if err != nil {
+		t.Fatalf("parse error: %s", err)
+	}
+	if !reflect.DeepEqual(m, again) {
+		t.Errorf("round trip mismatch.\nwant=%+v\ngot=%+v", m, again)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`name = "x"`, "must be in a table"},
+		{"[project]\nname = \"x", "line 2: unterminated string"},
+		{"[project]\nname = 1", "project.name: want string"},
+		{"[project]\nversion = 1", `unknown key "version"`},
+		{"[build]", "unknown table [build]"},
+		{"[project]\n[project]", "line 2: duplicate table"},
+		{"[test]\npaths = [1]", "want array of strings"},
+		{"[project]\nmonkey = 99", "requires monkey language version 99"},
+		{"[project]\nscope = \"global\"", `project.scope: want "module" or "shared", got "global"`},
+		{"[fmt]\nindent = 0", "fmt.indent: want 1 to 8, got 0"},
+		{"[lint]\ndisable = [\"unusd\"]", `lint.disable: unknown check "unusd"`},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q. got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ManifestName), []byte(New("demo").String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(dir, "a", "b")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	m, root, err := Load(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m == nil || m.Name != "demo" || root != dir {
+		t.Errorf("wrong manifest. got=%+v, root=%q", m, root)
+	}
+
+	if m, _, err := Load(t.TempDir()); m != nil || err != nil {
+		t.Errorf("expected no manifest. got=%+v, %v", m, err)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"main.mky", "b.mky", "a.mky", "notes.txt", "lib/c.mky"} {
+		filename := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := New("demo")
+	tests := []struct {
+		scope, entry string
+		expected     []string
+	}{
+		{ScopeModule, "main.mky", []string{"main.mky"}},
+		{ScopeShared, "main.mky", []string{"a.mky", "b.mky", "main.mky"}},
+		{ScopeShared, "b.mky", []string{"a.mky", "main.mky", "b.mky"}},
+		{ScopeShared, "lib/c.mky", []string{"lib/c.mky"}},
+		// 共享作用域时入口文件可以不存在
+		{ScopeShared, "start.mky", []string{"a.mky", "b.mky", "main.mky"}},
+	}
+	for _, tt := range tests {
+		m.Scope, m.Entry = tt.scope, tt.entry
+		files, err := m.Files(dir)
+		if err != nil {
+			t.Errorf("%s %s: %s", tt.scope, tt.entry, err)
+			continue
+		}
+		for i, file := range files {
+			files[i], _ = filepath.Rel(dir, file)
+			files[i] = filepath.ToSlash(files[i])
+		}
+		if !reflect.DeepEqual(files, tt.expected) {
+			t.Errorf("%s %s: expected %q. got=%q", tt.scope, tt.entry, tt.expected, files)
+		}
+	}
+
+	m.Scope, m.Entry = ScopeModule, "start.mky"
+	if _, err := m.Files(dir); err == nil || !strings.Contains(err.Error(), "no entry file start.mky") {
+		t.Errorf("expected missing entry error. got=%v", err)
+	}
+	m.Scope = ScopeShared
+	if _, err := m.Files(t.TempDir()); err == nil || !strings.Contains(err.Error(), "no .mky files") {
+		t.Errorf("expected empty directory error. got=%v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package project
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 解析 monkey.toml 使用的 TOML 子集：
+// [table] 表头、key = value 键值对和 # 注释，
+// 值可以是字符串、整数、布尔值或由它们组成的（可跨行的）数组。
+// 返回以表名为键的键值表，表头之前的键位于名为 "" 的表中。
+func parseTOML(src string) (map[string]map[string]any, error) {
+	tables := map[string]map[string]any{"": {}}
+	table := ""
+
+	lines := strings.Split(src, "\n")
+	for i := 0; i < len(lines); i++ {
+		lineno := i + 1
+		line := strings.TrimSpace(stripComment(lines[i]))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: malformed table header %q", lineno, line)
+			}
+			table = strings.TrimSpace(line[1 : len(line)-1])
+			if !isBareKey(table) {
+				return nil, fmt.Errorf("line %d: invalid table name %q", lineno, table)
+			}
+			if _, ok := tables[table]; ok {
+				return nil, fmt.Errorf("line %d: duplicate table [%s]", lineno, table)
+			}
+			tables[table] = map[string]any{}
+			continue
+		}
+
+		key, raw, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected key = value, got %q", lineno, line)
+		}
+		key, raw = strings.TrimSpace(key), strings.TrimSpace(raw)
+		if !isBareKey(key) {
+			return nil, fmt.Errorf("line %d: invalid key %q", lineno, key)
+		}
+		if _, ok := tables[table][key]; ok {
+			return nil, fmt.Errorf("line %d: duplicate key %q", lineno, key)
+		}
+
+		// 数组可以跨越多行，读到方括号配对为止
+		for strings.HasPrefix(raw, "[") && !balanced(raw) && i+1 < len(lines) {
+			i++
+			raw += " " + strings.TrimSpace(stripComment(lines[i]))
+		}
+
+		value, rest, err := parseValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineno, err)
+		}
+		if strings.TrimSpace(rest) != "" {
+			return nil, fmt.Errorf("line %d: unexpected %q after value", lineno, rest)
+		}
+		tables[table][key] = value
+	}
+	return tables, nil
+}
+
+func parseValue(s string) (value any, rest string, err error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "":
+		return nil, "", fmt.Errorf("missing value")
+
+	case s[0] == '"':
+		end := 1
+		for ; end < len(s) && s[end] != '"'; end++ {
+			if s[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(s) {
+			return nil, "", fmt.Errorf("unterminated string")
+		}
+		str, err := strconv.Unquote(s[:end+1])
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid string %s", s[:end+1])
+		}
+		return str, s[end+1:], nil
+
+	case s[0] == '[':
+		items := []any{}
+		rest := strings.TrimSpace(s[1:])
+		for !strings.HasPrefix(rest, "]") {
+			item, r, err := parseValue(rest)
+			if err != nil {
+				return nil, "", err
+			}
+			items = append(items, item)
+			rest = strings.TrimSpace(r)
+			if strings.HasPrefix(rest, ",") {
+				rest = strings.TrimSpace(rest[1:])
+			} else if !strings.HasPrefix(rest, "]") {
+				return nil, "", fmt.Errorf("expected , or ] in array")
+			}
+		}
+		return items, rest[1:], nil
+	}
+
+	end := strings.IndexAny(s, ",] \t")
+	if end < 0 {
+		end = len(s)
+	}
+	word := s[:end]
+	switch word {
+	case "true":
+		return true, s[end:], nil
+	case "false":
+		return false, s[end:], nil
+	}
+	n, err := strconv.ParseInt(strings.ReplaceAll(word, "_", ""), 10, 64)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid value %q", word)
+	}
+	return n, s[end:], nil
+}
+
+// 去掉不在字符串中的 # 注释
+func stripComment(line string) string {
+	inString := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			if inString {
+				i++
+			}
+		case '"':
+			inString = !inString
+		case '#':
+			if !inString {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
+
+// 方括号是否配对（忽略字符串中的方括号）
+func balanced(s string) bool {
+	depth, inString := 0, false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if inString {
+				i++
+			}
+		case '"':
+			inString = !inString
+		case '[':
+			if !inString {
+				depth++
+			}
+		case ']':
+			if !inString {
+				depth--
+			}
+		}
+	}
+	return depth <= 0
+}
+
+func isBareKey(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// 将字符串编码为 TOML 字符串
+func quote(s string) string {
+	return strconv.Quote(s)
+}
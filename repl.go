@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/hungtcs/monkey-lang/monkey"
+	"github.com/hungtcs/monkey-lang/repl"
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// monkey repl [flags] [file]
+//
+// 不带参数时启动交互式 REPL。使用 -listen 时在 Unix socket 上提供远程 REPL，
+// 如果指定了 file，则同时在同一个全局作用域中执行它，以便在另一个终端中检查
+// 正在运行的脚本；使用 -connect 连接到这样的 socket：
+//
+//	monkey repl -listen /tmp/monkey.sock daemon.mky
+//	monkey repl -connect /tmp/monkey.sock
+func replCmd(args []string) int {
+	flags := flag.NewFlagSet("repl", flag.ExitOnError)
+	listen := flags.String("listen", "", "serve a remote REPL on the Unix socket `path`")
+	connect := flags.String("connect", "", "connect to a remote REPL on the Unix socket `path`")
+	token := flags.String("token", os.Getenv("MONKEY_REPL_TOKEN"), "`secret` clients must send before evaluating anything (default $MONKEY_REPL_TOKEN)")
+	readOnly := flags.Bool("readonly", false, "with -listen, evaluate remote input in a scratch scope without capabilities")
+	allow := flags.String("allow", "", "comma-separated `capabilities` the file and remote input may use ("+capabilityNames()+", or all)")
+	wordOperators := flags.Bool("word-operators", false, "accept and, or and not as aliases for &&, || and !")
+	maxSteps := flags.Int64("max-steps", 0, fmt.Sprintf("with -listen, abort remote input after executing `n` statements (0 means %d, negative means no limit)", repl.DefaultMaxSteps))
+	history := flags.String("history", repl.HistoryFile, "save input history to `file`, empty to disable (also set by $MONKEY_HISTORY)")
+	noColor := flags.Bool("no-color", false, "do not highlight input and errors (also set by $NO_COLOR or TERM=dumb)")
+	flags.Parse(args)
+	repl.HistoryFile = *history
+	repl.Color = repl.Color && !*noColor
+	language := syntax.LanguageOptions{WordOperators: *wordOperators}
+
+	switch {
+	case *listen != "" && *connect != "":
+		fmt.Fprintln(os.Stderr, "-listen and -connect are mutually exclusive")
+		return 2
+	case *connect != "":
+		if flags.NArg() != 0 {
+			usage()
+			return 2
+		}
+		if err := repl.Connect(*connect, *token, os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	case *listen == "":
+		if flags.NArg() != 0 {
+			usage()
+			return 2
+		}
+		startRepl(language)
+		return 0
+	}
+	if flags.NArg() > 1 {
+		usage()
+		return 2
+	}
+
+	policy, err := monkey.ParsePolicy(*allow)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	var program *syntax.Program
+	if flags.NArg() == 1 {
+		data, err := os.ReadFile(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		parser := syntax.NewFileParser(flags.Arg(0), string(data))
+		parser.Options = language
+		if program, err = parser.Parse(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	l, err := repl.Listen(*listen)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer l.Close()
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupted)
+
+	env := monkey.NewEnv(nil)
+	go repl.Serve(l, env, repl.RemoteOptions{Token: *token, ReadOnly: *readOnly, Policy: policy, MaxSteps: *maxSteps, Language: language})
+	fmt.Fprintf(os.Stderr, "remote REPL listening on %s (press Ctrl+C to stop)\n", *listen)
+
+	if program != nil {
+		thread := &monkey.Thread{Name: flags.Arg(0), Policy: policy, Language: language}
+		value, err := monkey.EvalThread(thread, program, env)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		} else {
+			fmt.Println(value)
+		}
+	}
+	// 脚本结束后继续提供服务，以便检查它的全局作用域
+	<-interrupted
+	return 0
+}
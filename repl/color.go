@@ -0,0 +1,98 @@
+package repl
+
+import (
+	"os"
+	"strings"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// Color 控制 REPL 是否使用颜色高亮输入、回显和错误。默认在设置了环境变量 NO_COLOR 或 TERM=dumb 时关闭
+var Color = os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+
+const (
+	colorReset   = "\033[0m"
+	colorKeyword = "\033[35m"
+	colorString  = "\033[32m"
+	colorNumber  = "\033[36m"
+	colorComment = "\033[90m"
+	colorError   = "\033[31m"
+)
+
+// 返回 prompt 的提示符，关闭颜色时去掉其中的颜色
+func prompt(p string) string {
+	if Color {
+		return p
+	}
+	return strings.NewReplacer(colorComment, "", colorReset, "").Replace(p)
+}
+
+// highlight 使用词法分析器为源码 src 添加颜色：关键字、字符串、数字、注释和非法字符。
+// 空白和其他字符原样保留，因此去掉颜色之后与 src 相同
+func highlight(src string) string {
+	runes := []rune(src)
+	// lines[i] 是第 i+1 行第一个字符的下标，用于把词法单元的位置转换为下标
+	lines := []int{0}
+	for i, r := range runes {
+		if r == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	offset := func(pos syntax.Position) int {
+		i := lines[pos.Line-1] + int(pos.Col) - 1
+		return min(i, len(runes))
+	}
+
+	l := syntax.NewLexer(src)
+	l.KeepTrivia = true
+	tokens, _ := l.Tokenize() // 词法错误对应的字符是 ILLEGAL
+	var out strings.Builder
+	for _, tok := range tokens {
+		for _, t := range tok.Leading {
+			if t.Comment {
+				paint(&out, colorComment, t.Text)
+			} else {
+				out.WriteString(t.Text)
+			}
+		}
+		text := string(runes[offset(tok.Pos()):offset(tok.End())])
+		switch {
+		case tok.Type.IsKeyword():
+			paint(&out, colorKeyword, text)
+		case tok.Type == syntax.STRING:
+			paint(&out, colorString, text)
+		case tok.Type == syntax.INT || tok.Type == syntax.FLOAT:
+			paint(&out, colorNumber, text)
+		case tok.Type == syntax.ILLEGAL:
+			paint(&out, colorError, text)
+		default:
+			out.WriteString(text)
+		}
+	}
+	return out.String()
+}
+
+func paint(out *strings.Builder, color, text string) {
+	out.WriteString(color)
+	out.WriteString(text)
+	out.WriteString(colorReset)
+}
+
+// painter 在输入时高亮 readline 的当前行
+type painter struct{}
+
+// Paint implements readline.Painter.
+func (painter) Paint(line []rune, pos int) []rune {
+	if !Color {
+		return line
+	}
+	return []rune(highlight(string(line)))
+}
+
+// 返回错误信息，开启颜色时为红色
+func errorText(err error) string {
+	if !Color {
+		return err.Error()
+	}
+	return colorError + err.Error() + colorReset
+}
@@ -0,0 +1,36 @@
+package repl
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`let x = 1.5; // note`,
+			"\033[35mlet\033[0m x = \033[36m1.5\033[0m; \033[90m// note\033[0m",
+		},
+		{
+			`if (true) { "a\"b" } else { @ }`,
+			"\033[35mif\033[0m (\033[35mtrue\033[0m) { \033[32m\"a\\\"b\"\033[0m } \033[35melse\033[0m { \033[31m@\033[0m }",
+		},
+		{"fn(x) {\n  x * 2 /* twice */\n}", "\033[35mfn\033[0m(x) {\n  x * \033[36m2\033[0m \033[90m/* twice */\033[0m\n}"},
+		{`"unterminated 世界`, "\033[32m\"unterminated 世界\033[0m"},
+		{"", ""},
+	}
+	colors := regexp.MustCompile("\033\\[[0-9]+m")
+	for _, tt := range tests {
+		got := highlight(tt.input)
+		if got != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.input, tt.expected, got)
+		}
+		// 去掉颜色之后与输入相同
+		if plain := colors.ReplaceAllString(got, ""); plain != tt.input {
+			t.Errorf("%q: lost text, got %q", tt.input, plain)
+		}
+	}
+}
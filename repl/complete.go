@@ -0,0 +1,100 @@
+package repl
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/hungtcs/monkey-lang/monkey"
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// completer 在按下 Tab 时补全光标前的标识符，候选项来自 env 及其外层作用域中的变量、
+// 内置函数和关键字。光标前是 m[ 或 m["ab 并且 m 是 env 中的 map 时，补全 m 的字符串键。
+type completer struct {
+	env *monkey.Env
+}
+
+// Do implements readline.AutoCompleter.
+func (c *completer) Do(line []rune, pos int) (newLine [][]rune, length int) {
+	before := string(line[:pos])
+	var prefix string
+	var candidates []string
+	if name, partial, ok := indexPrefix(before); ok {
+		prefix = partial
+		candidates = c.keys(name)
+	} else {
+		prefix = identPrefix(before)
+		candidates = c.names()
+	}
+
+	seen := make(map[string]bool)
+	for _, cand := range candidates {
+		if strings.HasPrefix(cand, prefix) && !seen[cand] {
+			seen[cand] = true
+			newLine = append(newLine, []rune(cand[len(prefix):]))
+		}
+	}
+	sort.Slice(newLine, func(i, j int) bool { return string(newLine[i]) < string(newLine[j]) })
+	return newLine, len([]rune(prefix))
+}
+
+// 返回可以补全的所有名称
+func (c *completer) names() []string {
+	var names []string
+	for env := c.env; env != nil; env = env.Outer() {
+		names = append(names, env.Names()...)
+	}
+	for name := range monkey.Universe {
+		names = append(names, name)
+	}
+	return append(names, syntax.Keywords()...)
+}
+
+// 返回 env 中名为 name 的 map 的字符串键的补全，每一项都带有引号和右方括号，
+// 例如 "name"]。name 不是 map 时返回 nil
+func (c *completer) keys(name string) []string {
+	val, ok := c.env.Get(name)
+	if !ok {
+		return nil
+	}
+	m, ok := val.(*monkey.Map)
+	if !ok {
+		return nil
+	}
+	var keys []string
+	for _, entry := range m.Items() {
+		if key, ok := entry.Key.(monkey.String); ok {
+			keys = append(keys, strconv.Quote(string(key))+"]")
+		}
+	}
+	return keys
+}
+
+// 返回 s 末尾的标识符
+func identPrefix(s string) string {
+	i := strings.LastIndexFunc(s, func(r rune) bool { return !isIdentRune(r) })
+	return s[i+1:]
+}
+
+// 如果 s 以 name[ 或 name["partial 结尾，返回 name 和已经输入的键（包括引号）
+func indexPrefix(s string) (name, partial string, ok bool) {
+	i := strings.LastIndexByte(s, '[')
+	if i < 0 {
+		return "", "", false
+	}
+	partial = s[i+1:]
+	if partial != "" && (partial[0] != '"' || strings.ContainsAny(partial[1:], "\"\\")) {
+		return "", "", false
+	}
+	name = identPrefix(s[:i])
+	if name == "" || unicode.IsDigit([]rune(name)[0]) {
+		return "", "", false
+	}
+	return name, partial, true
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,41 @@
+package repl
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hungtcs/monkey-lang/monkey"
+)
+
+func TestCompleter(t *testing.T) {
+	env := monkey.NewEnv(nil)
+	env.Set("person", monkey.MustFromGo(map[string]any{"name": "a", "nick": "b", "age": 1}))
+	env.Set("people", monkey.Int(2))
+	c := &completer{env: monkey.NewEnv(env)}
+
+	tests := []struct {
+		line     string
+		expected []string
+		length   int
+	}{
+		{"peo", []string{"ple"}, 3},
+		{"pe", []string{"ople", "rson"}, 2},
+		{"1 + le", []string{"n", "t"}, 2},
+		{"red", []string{"uce"}, 3},
+		{"retu", []string{"rn"}, 4},
+		{`person[`, []string{`"age"]`, `"name"]`, `"nick"]`}, 0},
+		{`person["n`, []string{`ame"]`, `ick"]`}, 2},
+		{`people["`, nil, 1},
+		{`f(person["name"], x`, nil, 1},
+	}
+	for _, tt := range tests {
+		got, length := c.Do([]rune(tt.line), len([]rune(tt.line)))
+		var suffixes []string
+		for _, s := range got {
+			suffixes = append(suffixes, string(s))
+		}
+		if !reflect.DeepEqual(suffixes, tt.expected) || length != tt.length {
+			t.Errorf("%q: got %q, %d, want %q, %d", tt.line, suffixes, length, tt.expected, tt.length)
+		}
+	}
+}
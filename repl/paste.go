@@ -0,0 +1,121 @@
+package repl
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+)
+
+// 终端的括号粘贴模式：开启后终端用 pasteStart 和 pasteEnd 包围粘贴的文本，
+// 使程序可以区分粘贴的换行和用户按下的回车
+const (
+	enableBracketedPaste  = "\033[?2004h"
+	disableBracketedPaste = "\033[?2004l"
+	pasteStart            = "\033[200~"
+	pasteEnd              = "\033[201~"
+)
+
+// pasteReader 包装终端的输入，取出括号粘贴模式下粘贴的文本。
+// readline 不支持括号粘贴，粘贴的文本中的每个换行都会提交一行输入，
+// 因此粘贴的文本不交给 readline，而是在粘贴结束时只交给它一个回车，
+// 读到这一行之后再通过 take 取出粘贴的文本。
+type pasteReader struct {
+	r       io.Reader
+	buf     []byte // 已经读取但还没有处理的输入
+	pasting bool
+
+	mu     sync.Mutex
+	pasted strings.Builder // 正在粘贴或等待 take 的文本
+	ready  bool            // 有一次完整的粘贴等待 take
+}
+
+func newPasteReader(r io.Reader) *pasteReader {
+	return &pasteReader{r: r}
+}
+
+// Read implements io.Reader.
+func (p *pasteReader) Read(b []byte) (int, error) {
+	for {
+		if n := p.process(b); n > 0 {
+			return n, nil
+		}
+		chunk := make([]byte, 1024)
+		n, err := p.r.Read(chunk)
+		p.buf = append(p.buf, chunk[:n]...)
+		if err != nil {
+			// 输入结束时，不完整的标记按普通输入处理
+			if n := copy(b, p.buf); n > 0 {
+				p.buf = p.buf[n:]
+				return n, nil
+			}
+			return 0, err
+		}
+	}
+}
+
+// 处理 p.buf 中的输入，返回交给 readline 的字节数；需要读取更多的输入时返回 0
+func (p *pasteReader) process(b []byte) int {
+	if !p.pasting {
+		i := bytes.Index(p.buf, []byte(pasteStart))
+		if i == 0 {
+			p.pasting = true
+			p.buf = p.buf[len(pasteStart):]
+			return p.process(b)
+		}
+		if i < 0 {
+			// 末尾可能是被拆开的 pasteStart，留到下次读取之后再判断
+			i = len(p.buf) - partialSuffix(p.buf, pasteStart)
+		}
+		n := copy(b, p.buf[:i])
+		p.buf = p.buf[n:]
+		return n
+	}
+
+	i := bytes.Index(p.buf, []byte(pasteEnd))
+	end := i
+	if i < 0 {
+		end = len(p.buf) - partialSuffix(p.buf, pasteEnd)
+	}
+	p.mu.Lock()
+	p.pasted.Write(p.buf[:end])
+	if i >= 0 {
+		p.ready = true
+	}
+	p.mu.Unlock()
+	if i < 0 {
+		p.buf = p.buf[end:]
+		return 0
+	}
+	p.pasting = false
+	p.buf = p.buf[i+len(pasteEnd):]
+	if len(b) == 0 {
+		return 0
+	}
+	b[0] = '\r'
+	return 1
+}
+
+// 返回 b 的末尾与 marker 的前缀相同的最大长度（小于 len(marker)）
+func partialSuffix(b []byte, marker string) int {
+	for n := min(len(b), len(marker)-1); n > 0; n-- {
+		if bytes.HasSuffix(b, []byte(marker[:n])) {
+			return n
+		}
+	}
+	return 0
+}
+
+// take 返回最近一次完整粘贴的文本，换行统一为 \n；没有粘贴时返回 false
+func (p *pasteReader) take() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.ready {
+		return "", false
+	}
+	text := p.pasted.String()
+	p.pasted.Reset()
+	p.ready = false
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.ReplaceAll(text, "\r", "\n"), true
+}
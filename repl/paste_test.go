@@ -0,0 +1,84 @@
+package repl
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/hungtcs/monkey-lang/monkey"
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+func TestPasteReader(t *testing.T) {
+	tests := []struct {
+		input  string
+		typed  string // readline 收到的输入
+		pasted []string
+	}{
+		{"1 + 1\r", "1 + 1\r", nil},
+		{"\033[200~let x = 1;\rx\r\033[201~", "\r", []string{"let x = 1;\nx\n"}},
+		{"a\033[200~b\r\nc\033[201~d\r", "a\rd\r", []string{"b\nc"}},
+		{"\033[200~1\033[201~\033[200~2\033[201~", "\r\r", []string{"1", "2"}},
+		{"\033[A\033[2", "\033[A\033[2", nil}, // 不完整的标记按普通输入处理
+	}
+	for _, tt := range tests {
+		// 每次只读一个字节，标记会被拆开
+		r := newPasteReader(iotest.OneByteReader(strings.NewReader(tt.input)))
+		var typed bytes.Buffer
+		var pasted []string
+		b := make([]byte, 1)
+		for {
+			n, err := r.Read(b)
+			typed.Write(b[:n])
+			if n > 0 && b[0] == '\r' {
+				if text, ok := r.take(); ok {
+					pasted = append(pasted, text)
+				}
+			}
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if typed.String() != tt.typed {
+			t.Errorf("%q: typed %q, want %q", tt.input, typed.String(), tt.typed)
+		}
+		if strings.Join(pasted, "|") != strings.Join(tt.pasted, "|") {
+			t.Errorf("%q: pasted %q, want %q", tt.input, pasted, tt.pasted)
+		}
+	}
+}
+
+func TestEvalStmts(t *testing.T) {
+	tests := []struct {
+		input  string
+		stdout string
+		stderr string
+	}{
+		{"let x = 1;\nx + 1\nlet y = x * 10;\ny", "2\n10\n", ""},
+		{"let m = macro(a) { quote(unquote(a) * 2) };\nm(3)\nm(4)", "6\n8\n", ""},
+		{"1\nz\n2", "1\n", "identifier not found: z"},
+		{"1\nlet = 2", "", "expected next token"},
+	}
+	for _, tt := range tests {
+		// 同样的文本执行两次，第二次使用缓存的语法树
+		for i := 0; i < 2; i++ {
+			var stdout, stderr bytes.Buffer
+			evalStmts(new(monkey.Thread), tt.input, monkey.NewEnv(nil), &stdout, &stderr)
+			if stdout.String() != tt.stdout || !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("%q: got stdout %q and stderr %q", tt.input, stdout.String(), stderr.String())
+			}
+		}
+	}
+
+	// 粘贴的文本按 thread.Language 解析
+	var stdout, stderr bytes.Buffer
+	thread := &monkey.Thread{Language: syntax.LanguageOptions{WordOperators: true}}
+	evalStmts(thread, "let t = true;\nt and not false", monkey.NewEnv(nil), &stdout, &stderr)
+	if stdout.String() != "true\n" || stderr.Len() != 0 {
+		t.Errorf("got stdout %q and stderr %q", stdout.String(), stderr.String())
+	}
+}
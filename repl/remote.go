@@ -0,0 +1,200 @@
+package repl
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hungtcs/monkey-lang/monkey"
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// 远程 REPL 的提示符，不使用颜色以便 nc 等工具直接连接
+const REMOTE_PROMPT = ">> "
+
+// RemoteOptions 是远程 REPL 的选项
+type RemoteOptions struct {
+	// Token 不为空时，客户端连接后发送的第一行必须与它相同，否则连接被关闭
+	Token string
+
+	// ReadOnly 为 true 时，每次输入都在 env 之上的临时作用域中求值，
+	// let 不会修改共享的 env，并且不允许使用任何需要授权的能力
+	ReadOnly bool
+
+	// Policy 是非只读模式下远程输入允许使用的能力
+	Policy *monkey.Policy
+
+	// Language 是解析远程输入时启用的可选语言特性
+	Language syntax.LanguageOptions
+
+	// MaxSteps 和 MaxAlloc 限制每次远程输入执行的语句数和分配的内存，见 monkey.Thread。
+	// 远程输入在宿主程序的进程中求值，因此为 0 时使用 DefaultMaxSteps 和 DefaultMaxAlloc，小于 0 时不限制
+	MaxSteps int64
+	MaxAlloc int64
+}
+
+// 远程输入默认的资源限制
+const (
+	DefaultMaxSteps = 1_000_000
+	DefaultMaxAlloc = 64 << 20
+)
+
+// 返回 RemoteOptions 中的限制对应的 monkey.Thread 中的限制
+func remoteLimit(limit, def int64) int64 {
+	switch {
+	case limit == 0:
+		return def
+	case limit < 0:
+		return 0
+	}
+	return limit
+}
+
+// Listen 在 path 上创建一个只有当前用户可以访问的 Unix socket，关闭返回的 Listener 时删除它。
+// socket 先在一个只有当前用户可以访问的临时目录中创建，设置好权限之后再链接到 path，
+// 其他用户不能在设置权限之前连接。path 已经存在时返回错误
+func Listen(path string) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".monkey-repl-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	tmp := filepath.Join(dir, "sock")
+	l, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, err
+	}
+	ul := l.(*net.UnixListener)
+	ul.SetUnlinkOnClose(false)
+	if err := os.Chmod(tmp, 0o600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	if err := os.Link(tmp, path); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return &unixListener{UnixListener: ul, path: path}, nil
+}
+
+// unixListener 在关闭时删除 socket 文件
+type unixListener struct {
+	*net.UnixListener
+	path string
+	once sync.Once
+}
+
+func (l *unixListener) Close() error {
+	err := l.UnixListener.Close()
+	l.once.Do(func() { os.Remove(l.path) })
+	return err
+}
+
+// Serve 接受 l 上的连接，在 env 中求值每个连接发来的输入，直到 l 被关闭。
+// 每行输入是一段独立的程序，print 的输出、求值结果和错误都写回连接。
+// env 可以同时被其他 goroutine 中运行的脚本使用，以便检查一个正在运行的程序。
+func Serve(l net.Listener, env *monkey.Env, opts RemoteOptions) error {
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		go serveConn(conn, env, opts)
+	}
+}
+
+func serveConn(conn net.Conn, env *monkey.Env, opts RemoteOptions) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	if opts.Token != "" {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		token := strings.TrimRight(line, "\r\n")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(opts.Token)) != 1 {
+			fmt.Fprintln(conn, "authentication failed")
+			return
+		}
+	}
+
+	thread := &monkey.Thread{
+		Name:     "remote",
+		Print:    func(_ *monkey.Thread, msg string) { io.WriteString(conn, msg) },
+		MaxSteps: remoteLimit(opts.MaxSteps, DefaultMaxSteps),
+		MaxAlloc: remoteLimit(opts.MaxAlloc, DefaultMaxAlloc),
+		Language: opts.Language,
+	}
+	if !opts.ReadOnly {
+		thread.Policy = opts.Policy
+	}
+	for {
+		if _, err := io.WriteString(conn, REMOTE_PROMPT); err != nil {
+			return
+		}
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		program, err := monkey.Parse(thread, "", line)
+		if err != nil {
+			fmt.Fprintln(conn, err)
+			continue
+		}
+		scope := env
+		if opts.ReadOnly {
+			scope = monkey.NewEnv(env)
+		}
+		val, err := monkey.EvalThread(thread, program, scope)
+		if err != nil {
+			fmt.Fprintln(conn, err)
+			continue
+		}
+		if val != monkey.Null {
+			fmt.Fprintln(conn, val.String())
+		}
+	}
+}
+
+// Connect 连接到 path 上的远程 REPL，把 stdin 的内容发送给它并把回应写入 stdout，
+// 直到 stdin 结束或服务端关闭连接。token 不为空时首先发送 token 进行认证。
+func Connect(path, token string, stdin io.Reader, stdout io.Writer) error {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if token != "" {
+		if _, err := fmt.Fprintln(conn, token); err != nil {
+			return err
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(stdout, conn)
+		done <- err
+	}()
+	go func() {
+		io.Copy(conn, stdin)
+		// 输入结束后只关闭写入方向，继续接收最后一次求值的输出
+		if c, ok := conn.(*net.UnixConn); ok {
+			c.CloseWrite()
+		}
+	}()
+	return <-done
+}
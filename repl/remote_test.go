@@ -0,0 +1,109 @@
+package repl
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hungtcs/monkey-lang/monkey"
+)
+
+func TestRemote(t *testing.T) {
+	tests := []struct {
+		opts     RemoteOptions
+		token    string
+		input    string
+		expected []string
+	}{
+		{
+			input:    "x + 1\nlet y = 2;\ny\nprint(3)\n",
+			expected: []string{">> 42\n", ">> >> 2\n", ">> 3\n"},
+		},
+		{
+			input:    "let = 1\nz\n",
+			expected: []string{"expected next token", "identifier not found: z"},
+		},
+		{
+			opts:     RemoteOptions{ReadOnly: true},
+			input:    "let x = 1;\nx\n",
+			expected: []string{">> >> 41\n"},
+		},
+		{
+			// 远程输入默认限制执行的语句数，不能占满宿主进程
+			opts:     RemoteOptions{ReadOnly: true},
+			input:    "let p = fn(d) { if (d == 0) { 1 } else { p(d - 1) + p(d - 1) } }; p(40)\nx\n",
+			expected: []string{"step limit (1000000) exceeded", ">> 41\n"},
+		},
+		{
+			opts:     RemoteOptions{MaxSteps: 10},
+			input:    "let p = fn(d) { if (d == 0) { 1 } else { p(d - 1) + p(d - 1) } }; p(5)\n",
+			expected: []string{"step limit (10) exceeded"},
+		},
+		{
+			opts:     RemoteOptions{Token: "secret"},
+			token:    "secret",
+			input:    "x\n",
+			expected: []string{">> 41\n"},
+		},
+		{
+			opts:     RemoteOptions{Token: "secret"},
+			token:    "wrong",
+			input:    "x\n",
+			expected: []string{"authentication failed"},
+		},
+	}
+	for i, tt := range tests {
+		path := filepath.Join(t.TempDir(), "monkey.sock")
+		l, err := Listen(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		env := monkey.NewEnv(nil)
+		env.Set("x", monkey.Int(41))
+		go Serve(l, env, tt.opts)
+
+		var out bytes.Buffer
+		if err := Connect(path, tt.token, strings.NewReader(tt.input), &out); err != nil {
+			t.Fatalf("tests[%d]: %s", i, err)
+		}
+		l.Close()
+		for _, want := range tt.expected {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("tests[%d]: expected output to contain %q. got=%q", i, want, out.String())
+			}
+		}
+		if x, _ := env.Get("x"); x != monkey.Int(41) {
+			t.Errorf("tests[%d]: x changed to %v", i, x)
+		}
+	}
+}
+
+func TestListen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "monkey.sock")
+	l, err := Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSocket == 0 || info.Mode().Perm() != 0o600 {
+		t.Errorf("wrong mode. got=%v", info.Mode())
+	}
+	// 临时目录已经删除，path 已经存在时不能再次监听
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected only the socket in %s. got=%v", dir, entries)
+	}
+	if _, err := Listen(path); err == nil {
+		t.Errorf("expected an error listening on an existing path")
+	}
+
+	l.Close()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket not removed after Close. got=%v", err)
+	}
+}
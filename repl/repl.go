@@ -2,10 +2,13 @@ package repl
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 
 	"github.com/chzyer/readline"
 	"github.com/hungtcs/monkey-lang/monkey"
@@ -17,20 +20,61 @@ const CONTINUE_PROMPT = "\033[90m..\033[0m "
 
 var interrupted = make(chan os.Signal, 1)
 
+// HistoryFile 是保存输入历史的文件，为空时不保存。默认为环境变量 MONKEY_HISTORY 的值，
+// 没有设置时为 ~/.monkey_history。历史在下次启动时载入，可以用上下方向键或 Ctrl+R 搜索
+var HistoryFile = defaultHistoryFile()
+
+func defaultHistoryFile() string {
+	if file, ok := os.LookupEnv("MONKEY_HISTORY"); ok {
+		return file
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".monkey_history")
+}
+
+// 反复输入（例如粘贴）相同的代码时复用解析的结果
+var parseCache = syntax.NewParseCache(256)
+
 func Start() (err error) {
+	return StartEnv(monkey.NewEnv(nil))
+}
+
+// StartEnv 启动一个在 env 中求值的 REPL，可用于在脚本出错后检查现场
+func StartEnv(env *monkey.Env) (err error) {
+	return StartThread(new(monkey.Thread), env)
+}
+
+// StartThread 启动一个在 thread 和 env 中求值的 REPL，输入按 thread.Language 解析
+func StartThread(thread *monkey.Thread, env *monkey.Env) (err error) {
 	signal.Notify(interrupted, os.Interrupt)
 	defer signal.Stop(interrupted)
 
-	rl, err := readline.New(PROMPT)
+	paste := newPasteReader(os.Stdin)
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:       prompt(PROMPT),
+		Stdin:        readline.NewCancelableStdin(paste),
+		AutoComplete: &completer{env: env},
+		Painter:      painter{},
+
+		HistoryFile:            HistoryFile,
+		HistorySearchFold:      true,
+		DisableAutoSaveHistory: true, // 由 history 决定保存哪些行
+	})
 	if err != nil {
 		return err
 	}
 	defer rl.Close()
-
-	env := monkey.NewEnv(nil)
+	hist := &history{save: rl.SaveHistory}
+	if readline.DefaultIsTerminal() {
+		fmt.Print(enableBracketedPaste)
+		defer fmt.Print(disableBracketedPaste)
+	}
 
 	for {
-		if err := repl(rl, env); err != nil {
+		if err := repl(rl, paste, hist, thread, env); err != nil {
 			if err == readline.ErrInterrupt {
 				fmt.Println("(To exit, press Ctrl+D)")
 				continue
@@ -41,7 +85,7 @@ func Start() (err error) {
 	return nil
 }
 
-func repl(rl *readline.Instance, env *monkey.Env) error {
+func repl(rl *readline.Instance, paste *pasteReader, hist *history, thread *monkey.Thread, env *monkey.Env) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	go func() {
@@ -53,16 +97,17 @@ func repl(rl *readline.Instance, env *monkey.Env) error {
 	}()
 
 	var eof = false
-	rl.SetPrompt(PROMPT)
+	rl.SetPrompt(prompt(PROMPT))
 	readline := func() (string, error) {
 		line, err := rl.Readline()
-		rl.SetPrompt(CONTINUE_PROMPT)
+		rl.SetPrompt(prompt(CONTINUE_PROMPT))
 		if err != nil {
 			if err == io.EOF {
 				eof = true
 			}
 			return "", err
 		}
+		hist.add(line)
 		return line + "\n", nil
 	}
 
@@ -70,17 +115,34 @@ func repl(rl *readline.Instance, env *monkey.Env) error {
 	if err != nil {
 		return err
 	}
+	if text, ok := paste.take(); ok {
+		// 粘贴的文本没有经过 readline，需要自己回显
+		for _, l := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+			if Color {
+				l = highlight(l)
+			}
+			fmt.Fprintln(os.Stdout, prompt(CONTINUE_PROMPT)+l)
+			hist.add(l)
+		}
+		evalStmts(thread, line+text, env, os.Stdout, os.Stderr)
+		return nil
+	}
 
-	parser := syntax.NewParser(line)
-	program, err := parser.Parse()
+	program, err := readStmt(thread.Language, line, readline)
 	if err != nil {
 		if eof {
+			if syntax.IsIncomplete(err) {
+				printError(err)
+			}
 			return io.EOF
 		}
+		if !errors.Is(err, syntax.ErrParse) {
+			return err // 继续读取时按下了 Ctrl+C
+		}
 		printError(err)
 		return nil
 	}
-	val, err := monkey.Eval(program, env)
+	val, err := monkey.EvalThread(thread, program, env)
 	if err != nil {
 		printError(err)
 		return nil
@@ -92,6 +154,65 @@ func repl(rl *readline.Instance, env *monkey.Env) error {
 	return nil
 }
 
+// 按 opts 解析以 line 开头的输入。输入不完整（例如括号或字符串没有闭合）时，
+// 继续通过 more 读取下一行，直到输入完整、出现其他语法错误或者 more 返回错误
+func readStmt(opts syntax.LanguageOptions, line string, more func() (string, error)) (*syntax.Program, error) {
+	src := line
+	for {
+		program, err := parseCache.ParseWith("", src, opts)
+		if err == nil || !syntax.IsIncomplete(err) {
+			return program, err
+		}
+		next, readErr := more()
+		if readErr != nil {
+			if readErr == io.EOF {
+				return nil, err
+			}
+			return nil, readErr
+		}
+		src += next
+	}
+}
+
+// 依次执行 src 中的每条语句，并输出每条语句不为 null 的值，遇到错误时停止。
+// 用于一次粘贴的多条语句，整段文本只解析一次。
+func evalStmts(thread *monkey.Thread, src string, env *monkey.Env, stdout, stderr io.Writer) {
+	program, err := parseCache.ParseWith("", src, thread.Language)
+	if err != nil {
+		fmt.Fprintln(stderr, errorText(err))
+		return
+	}
+	for _, stmt := range program.Stmts {
+		single := &syntax.Program{Stmts: []syntax.Stmt{stmt}}
+		val, err := monkey.EvalThread(thread, single, env)
+		if single.Expanded {
+			// 语句属于缓存的程序，宏展开修改了它
+			program.Expanded = true
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, errorText(err))
+			return
+		}
+		if val != monkey.Null {
+			fmt.Fprintln(stdout, val.String())
+		}
+	}
+}
+
+// history 把输入的行添加到 readline 的历史中，跳过空行和与上一行相同的行
+type history struct {
+	save func(line string) error
+	last string
+}
+
+func (h *history) add(line string) {
+	if strings.TrimSpace(line) == "" || line == h.last {
+		return
+	}
+	h.last = line
+	h.save(line) // 写入历史文件失败不影响使用
+}
+
 func printError(err error) {
-	fmt.Fprintln(os.Stderr, err.Error())
+	fmt.Fprintln(os.Stderr, errorText(err))
 }
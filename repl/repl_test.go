@@ -0,0 +1,79 @@
+package repl
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+func TestReadStmt(t *testing.T) {
+	tests := []struct {
+		lines    []string
+		expected string // 解析得到的程序，出错时是错误信息
+		read     int    // 读取的行数
+	}{
+		{[]string{"1 + 2\n"}, "(1 + 2)", 1},
+		{[]string{"let f = fn(x) {\n", "x * 2\n", "};\n", "unused\n"}, "let f = fn(x) {(x * 2)};", 3},
+		{[]string{"max(1,\n", "2)\n"}, "max(1, 2)", 2},
+		{[]string{"\"a\n", "b\"\n"}, "a\nb", 2},
+		{[]string{"/* comment\n", "*/ 1\n"}, "1", 2},
+		// 不是因为输入不完整的错误不会继续读取
+		{[]string{"let = 1 + (\n", "2)\n"}, "expected next token", 1},
+		{[]string{"fn(x) {\n"}, `expected next token to be "}"`, 1},
+	}
+	for _, tt := range tests {
+		read := 1
+		more := func() (string, error) {
+			if read == len(tt.lines) {
+				return "", io.EOF
+			}
+			read++
+			return tt.lines[read-1], nil
+		}
+		program, err := readStmt(syntax.LanguageOptions{}, tt.lines[0], more)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = program.String()
+		}
+		if !strings.Contains(got, tt.expected) || read != tt.read {
+			t.Errorf("%q: got %q after reading %d lines, want %q after %d", tt.lines, got, read, tt.expected, tt.read)
+		}
+	}
+
+	// 读取下一行的错误（例如 Ctrl+C）被原样返回
+	interrupt := errors.New("interrupt")
+	_, err := readStmt(syntax.LanguageOptions{}, "if (true) {\n", func() (string, error) { return "", interrupt })
+	if err != interrupt {
+		t.Errorf("expected interrupt, got %v", err)
+	}
+	if _, err := readStmt(syntax.LanguageOptions{}, "1 +\n", func() (string, error) { return "", io.EOF }); !syntax.IsIncomplete(err) {
+		t.Errorf("expected an incomplete input error, got %v", err)
+	}
+
+	// 启用的语言特性对读取的输入生效
+	program, err := readStmt(syntax.LanguageOptions{WordOperators: true}, "not true or\n", func() (string, error) { return "false\n", nil })
+	if err != nil || program.String() != "((!true) || false)" {
+		t.Errorf("expected word operators to be parsed, got %v, %v", program, err)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	var saved []string
+	h := &history{save: func(line string) error {
+		saved = append(saved, line)
+		return nil
+	}}
+	for _, line := range []string{"1 + 1", "1 + 1", "", "  ", "let x = 1;", "1 + 1", "x", "x"} {
+		h.add(line)
+	}
+	expected := []string{"1 + 1", "let x = 1;", "1 + 1", "x"}
+	if !reflect.DeepEqual(saved, expected) {
+		t.Errorf("expected %q, got %q", expected, saved)
+	}
+}
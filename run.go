@@ -0,0 +1,244 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chzyer/readline"
+	"github.com/hungtcs/monkey-lang/monkey"
+	"github.com/hungtcs/monkey-lang/project"
+	"github.com/hungtcs/monkey-lang/repl"
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// monkey run [flags] [file | dir | -]
+//
+// 不指定文件时执行当前项目 monkey.toml 中的入口文件，文件为 - 时从标准输入逐条读取并执行语句。
+// 指定目录时执行其中的入口文件，目录是 scope = "shared" 的项目时，其中所有的文件组成一个程序，见 project.Manifest.Files。
+// 指定 -stream 时同样逐条执行文件中的语句，不需要先把整个文件读入内存。
+func runCmd(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	profile := flags.String("profile", "", "sample the call stack while running and write a CPU profile to `file`")
+	profileFormat := flags.String("profile-format", "folded", "`format` of the -profile output: folded (for flame graphs), top (flat and cumulative time per function) or pprof (for go tool pprof)")
+	record := flags.String("record", "", "record all nondeterministic inputs to `file`")
+	replay := flags.String("replay", "", "replay nondeterministic inputs from `file` written by -record")
+	allow := flags.String("allow", "", "comma-separated `capabilities` the script may use ("+capabilityNames()+", or all)")
+	deterministic := flags.Bool("deterministic", false, "fix map order, the random seed and the clock so that runs produce identical output")
+	seed := flags.Int64("seed", 0, "random `seed` for -deterministic")
+	postMortem := flags.Bool("post-mortem", false, "on an uncaught runtime error, start a REPL in the failing scope (stdin must be a terminal)")
+	stream := flags.Bool("stream", false, "parse and execute one statement at a time instead of parsing the whole file first")
+	maxSteps := flags.Int64("max-steps", 0, "abort after executing `n` statements (0 means no limit)")
+	maxAlloc := flags.Int64("max-alloc", 0, "abort after allocating about `bytes` of strings, arrays and maps (0 means no limit)")
+	wordOperators := flags.Bool("word-operators", false, "accept and, or and not as aliases for &&, || and !")
+	flags.Parse(args)
+	language := syntax.LanguageOptions{WordOperators: *wordOperators}
+	switch *profileFormat {
+	case "folded", "top", "pprof":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown profile format %q, want folded, top or pprof\n", *profileFormat)
+		return 2
+	}
+
+	// 要执行的文件，有多个文件时它们组成一个共享顶层作用域的程序，最后一个是入口文件
+	var files []string
+	switch flags.NArg() {
+	case 0:
+		m, root, err := project.Load(".")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if m == nil {
+			fmt.Fprintf(os.Stderr, "no file given and no %s found\n", project.ManifestName)
+			return 2
+		}
+		if files, err = m.Files(root); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	case 1:
+		files = []string{flags.Arg(0)}
+		if info, err := os.Stat(flags.Arg(0)); err == nil && info.IsDir() {
+			if files, err = dirFiles(flags.Arg(0)); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+		}
+	default:
+		usage()
+		return 2
+	}
+	filename := files[len(files)-1]
+	if *record != "" && *replay != "" {
+		fmt.Fprintln(os.Stderr, "-record and -replay are mutually exclusive")
+		return 2
+	}
+	if *deterministic && *replay != "" {
+		fmt.Fprintln(os.Stderr, "-deterministic and -replay are mutually exclusive")
+		return 2
+	}
+	if *stream && len(files) > 1 {
+		fmt.Fprintln(os.Stderr, "-stream cannot run a directory with shared scope")
+		return 2
+	}
+
+	// 逐条执行时 input 是源码的来源，否则先解析整个文件得到 program
+	var input io.Reader
+	var program *syntax.Program
+	switch {
+	case filename == "-":
+		filename, input = "<stdin>", os.Stdin
+	case *stream:
+		f, err := os.Open(filename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		input = f
+	default:
+		program = &syntax.Program{}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			parser := syntax.NewFileParser(file, string(data))
+			parser.Options = language
+			parsed, err := parser.Parse()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			program.Stmts = append(program.Stmts, parsed.Stmts...)
+		}
+	}
+
+	policy, err := monkey.ParsePolicy(*allow)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	thread := &monkey.Thread{Name: filename, Policy: policy, MaxSteps: *maxSteps, MaxAlloc: *maxAlloc, Language: language}
+	if *deterministic {
+		d := &monkey.Deterministic{Seed: *seed, Stdin: os.Stdin}
+		d.Apply(thread)
+	}
+
+	switch {
+	case *record != "":
+		f, err := os.Create(*record)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		provider := thread.Provider
+		if provider == nil {
+			provider = new(monkey.SystemProvider)
+		}
+		thread.Provider = monkey.NewRecorder(provider, f)
+	case *replay != "":
+		f, err := os.Open(*replay)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		thread.Provider = monkey.NewReplayer(f)
+	}
+
+	if *profile != "" {
+		profiler := monkey.NewProfiler(thread, 0)
+		profiler.Start()
+		defer func() {
+			profiler.Stop()
+			if err := writeProfile(profiler, *profile, *profileFormat); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}()
+	}
+
+	var value monkey.Value
+	if input != nil {
+		value, err = monkey.ExecReader(thread, filename, input, monkey.NewEnv(nil))
+	} else {
+		value, err = monkey.EvalThread(thread, program, monkey.NewEnv(nil))
+	}
+	if err != nil {
+		if evalErr, ok := err.(*monkey.EvalError); ok {
+			// 运行时错误同时输出调用栈
+			fmt.Fprintln(os.Stderr, evalErr.Backtrace())
+		} else {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		if *postMortem && thread.FailedEnv() != nil {
+			startPostMortem(thread)
+		}
+		return 1
+	}
+	fmt.Println(value)
+	return 0
+}
+
+// 返回 monkey run dir 要执行的文件。dir 是项目根目录时使用 monkey.toml 中的 entry 和 scope，
+// 否则执行其中的 main.mky
+func dirFiles(dir string) ([]string, error) {
+	m, root, err := project.Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	if abs, _ := filepath.Abs(dir); m == nil || root != abs {
+		m = project.New("")
+	}
+	return m.Files(dir)
+}
+
+// 以 format 指定的格式把采样结果写入 filename，filename 为 - 时写入标准错误
+func writeProfile(profiler *monkey.Profiler, filename, format string) error {
+	write := profiler.WriteFolded
+	switch format {
+	case "top":
+		write = profiler.WriteReport
+	case "pprof":
+		write = profiler.WritePprof
+	}
+	if filename == "-" {
+		return write(os.Stderr)
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func startPostMortem(failed *monkey.Thread) {
+	if !readline.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Fprintln(os.Stderr, "post-mortem: stdin is not a terminal")
+		return
+	}
+	fmt.Fprintln(os.Stderr, "entering post-mortem REPL in the failing scope (press Ctrl+D to exit)")
+	thread := &monkey.Thread{Language: failed.Language}
+	if err := repl.StartThread(thread, failed.FailedEnv()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// 以逗号分隔的所有已知能力，包括通过构建标签启用的扩展包注册的能力
+func capabilityNames() string {
+	names := make([]string, len(monkey.Capabilities))
+	for i, c := range monkey.Capabilities {
+		names[i] = string(c)
+	}
+	return strings.Join(names, ", ")
+}
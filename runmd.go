@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hungtcs/monkey-lang/literate"
+	"github.com/hungtcs/monkey-lang/monkey"
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// monkey run-md [flags] file.md
+//
+// 按顺序在同一个 Env 中执行文档中的所有 ```monkey 代码块，
+// 输出在每个代码块之后插入了 ```output 结果块的文档；
+// 使用 -check 时则核对文档中已有的 ```output 块与实际结果是否一致。
+func runMarkdownCmd(args []string) int {
+	flags := flag.NewFlagSet("run-md", flag.ExitOnError)
+	check := flags.Bool("check", false, "verify existing ```output blocks instead of printing the document")
+	allow := flags.String("allow", "", "comma-separated `capabilities` the code blocks may use ("+capabilityNames()+", or all)")
+	wordOperators := flags.Bool("word-operators", false, "accept and, or and not as aliases for &&, || and !")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		usage()
+		return 2
+	}
+
+	var filename = flags.Arg(0)
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	src := string(data)
+	policy, err := monkey.ParsePolicy(*allow)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	language := syntax.LanguageOptions{WordOperators: *wordOperators}
+
+	var env = monkey.NewEnv(nil)
+	var doc strings.Builder
+	var prev = 0
+	var failed = 0
+	for _, block := range literate.Parse(src) {
+		thread := &monkey.Thread{Name: filename, Policy: policy, Language: language}
+		result := runBlock(thread, block, env)
+
+		if *check {
+			if block.Expected != nil && *block.Expected != result {
+				fmt.Fprintf(os.Stderr, "%s:%d: output mismatch\n--- want:\n%s--- got:\n%s", filename, block.Line, *block.Expected, result)
+				failed++
+			}
+			continue
+		}
+
+		doc.WriteString(src[prev:block.End])
+		prev = block.End
+		if block.Expected != nil {
+			prev = block.OutEnd
+		}
+		if result != "" {
+			doc.WriteString("\n```output\n")
+			doc.WriteString(result)
+			doc.WriteString("```\n")
+		}
+	}
+
+	if *check {
+		if failed > 0 {
+			fmt.Fprintf(os.Stderr, "%d block(s) failed\n", failed)
+			return 1
+		}
+		return 0
+	}
+	doc.WriteString(src[prev:])
+	fmt.Print(doc.String())
+	return 0
+}
+
+// 在 thread 中执行一个代码块，返回其打印的内容和最后一个表达式的值（null 除外）。
+// 错误的位置是在整个文档中的行号
+func runBlock(thread *monkey.Thread, block *literate.Block, env *monkey.Env) string {
+	var out strings.Builder
+	thread.Print = func(_ *monkey.Thread, msg string) { out.WriteString(msg) }
+
+	parser := syntax.NewFileParserAt(thread.Name, int32(block.Line), block.Code)
+	parser.Options = thread.Language
+	program, err := parser.Parse()
+	if err == nil {
+		var value monkey.Value
+		value, err = monkey.EvalThread(thread, program, env)
+		if err == nil && value != monkey.Null {
+			out.WriteString(value.String() + "\n")
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(&out, "error: %s\n", err)
+	}
+	return out.String()
+}
@@ -3,6 +3,7 @@ package syntax
 import (
 	"bytes"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -23,12 +24,22 @@ type Expr interface {
 }
 
 type Program struct {
-	Stmts []Stmt
+	start    Position // 源码的开始位置
+	end      Position // 源码的结束位置
+	Stmts    []Stmt
+	Comments []Comment // 源码中的所有注释，按出现的顺序排列
+	Expanded bool      // 语法树已经被宏展开修改过，不能再作为源码的解析结果共享
+}
+
+// Comment 是源码中的一条注释，它不参与求值，保留下来供格式化等工具使用
+type Comment struct {
+	Pos  Position
+	Text string // 注释的原文，包括 // 或 /* */
 }
 
 // Span implements Node.
 func (p *Program) Span() (start Position, end Position) {
-	panic("unimplemented")
+	return p.start, p.end
 }
 
 // String implements Node.
@@ -83,7 +94,8 @@ type LetStmt struct {
 
 // Span implements Stmt.
 func (l *LetStmt) Span() (start Position, end Position) {
-	return l.Pos, l.Pos.add("let")
+	_, end = l.Value.Span()
+	return l.Pos, end
 }
 
 // String implements Stmt.
@@ -197,14 +209,41 @@ func (i *IntegerLiteral) expr() {
 	panic("unimplemented")
 }
 
+type FloatLiteral struct {
+	Raw   string
+	Pos   Position
+	Value float64
+}
+
+// Span implements Expr.
+func (f *FloatLiteral) Span() (start Position, end Position) {
+	return f.Pos, f.Pos.add(f.Raw)
+}
+
+// Literal implements Expr.
+func (f *FloatLiteral) Literal() string {
+	return f.Raw
+}
+
+// String implements Expr.
+func (f *FloatLiteral) String() string {
+	return f.Raw
+}
+
+// expr implements Expr.
+func (f *FloatLiteral) expr() {
+	panic("unimplemented")
+}
+
 type StringLiteral struct {
 	pos   Position
+	end   Position // 结束的引号之后的位置，Value 是转义之后的值，长度与源码不同
 	Value string
 }
 
 // Span implements Expr.
 func (s *StringLiteral) Span() (start Position, end Position) {
-	return s.pos, s.pos.add(s.Value)
+	return s.pos, s.end
 }
 
 // Literal implements Expr.
@@ -258,6 +297,7 @@ func (p *PrefixExpr) expr() {
 type InfixExpr struct {
 	Left  Expr
 	Op    Token
+	OpPos Position // 运算符的位置
 	Right Expr
 }
 
@@ -291,23 +331,22 @@ func (i *InfixExpr) expr() {
 
 type Boolean struct {
 	pos   Position
-	raw   string
 	Value bool
 }
 
 // Span implements Expr.
 func (b *Boolean) Span() (start Position, end Position) {
-	return b.pos, b.pos.add(b.raw)
+	return b.pos, b.pos.add(b.String())
 }
 
 // Literal implements Expr.
 func (b *Boolean) Literal() string {
-	return b.raw
+	return b.String()
 }
 
 // String implements Expr.
 func (b *Boolean) String() string {
-	return b.raw
+	return strconv.FormatBool(b.Value)
 }
 
 // expr implements Expr.
@@ -323,7 +362,7 @@ type BlockStmt struct {
 
 // Span implements Stmt.
 func (b *BlockStmt) Span() (start Position, end Position) {
-	return start, end
+	return b.start, b.end
 }
 
 // Literal implements Stmt.
@@ -361,7 +400,7 @@ func (i *IfExpr) Span() (start Position, end Position) {
 	if body == nil {
 		body = i.Consequence
 	}
-	_, end = body.Stmts[len(body.Stmts)-1].Span()
+	_, end = body.Span()
 	return i.pos, end
 }
 
@@ -390,14 +429,22 @@ func (i *IfExpr) expr() {
 }
 
 type FunctionLiteral struct {
-	pos    Position
-	Params []*Identifier
-	Body   *BlockStmt
+	pos      Position    // async 函数为 async 关键字的位置
+	Async    bool        // async fn，调用时在新的 goroutine 中执行并立即返回 future
+	Name     *Identifier // fn add(x, y) { ... } 中的函数名，匿名函数为 nil
+	Params   []*Identifier
+	Variadic bool // 最后一个参数写作 xs...，以数组的形式接收其余的实参
+	Body     *BlockStmt
+
+	// 函数体中没有函数或宏字面量，因此调用结束后不会再有值引用调用时的作用域。
+	// 由解析器设置，其他方式构造的语法树以及被宏展开修改过的函数体为 false
+	NoClosures bool
 }
 
 // Span implements Expr.
 func (f *FunctionLiteral) Span() (start Position, end Position) {
-	panic("unimplemented")
+	_, end = f.Body.Span()
+	return f.pos, end
 }
 
 // Literal implements Expr.
@@ -412,7 +459,17 @@ func (f *FunctionLiteral) String() string {
 	for _, p := range f.Params {
 		params = append(params, p.String())
 	}
-	out.WriteString("fn(")
+	if f.Variadic {
+		params[len(params)-1] += "..."
+	}
+	if f.Async {
+		out.WriteString("async ")
+	}
+	out.WriteString("fn")
+	if f.Name != nil {
+		out.WriteString(" " + f.Name.Value)
+	}
+	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
 	out.WriteString(f.Body.String())
@@ -424,6 +481,90 @@ func (f *FunctionLiteral) expr() {
 	panic("unimplemented")
 }
 
+// fn add(x, y) { ... } 语句，在当前作用域中声明名为 add 的函数
+type FunctionStmt struct {
+	Fn *FunctionLiteral
+}
+
+// Span implements Stmt.
+func (f *FunctionStmt) Span() (start Position, end Position) {
+	return f.Fn.Span()
+}
+
+// Literal implements Stmt.
+func (f *FunctionStmt) Literal() string {
+	return "fn"
+}
+
+// String implements Stmt.
+func (f *FunctionStmt) String() string {
+	return f.Fn.String()
+}
+
+// stmt implements Stmt.
+func (f *FunctionStmt) stmt() {
+	panic("unimplemented")
+}
+
+// 宏字面量，宏在求值前被展开：调用宏时参数是未求值的语法树，返回值是替换调用的语法树
+type MacroLiteral struct {
+	pos    Position
+	Params []*Identifier
+	Body   *BlockStmt
+}
+
+// Span implements Expr.
+func (m *MacroLiteral) Span() (start Position, end Position) {
+	_, end = m.Body.Span()
+	return m.pos, end
+}
+
+// Literal implements Expr.
+func (m *MacroLiteral) Literal() string {
+	return "macro"
+}
+
+// String implements Expr.
+func (m *MacroLiteral) String() string {
+	params := make([]string, len(m.Params))
+	for i, p := range m.Params {
+		params[i] = p.String()
+	}
+	return "macro(" + strings.Join(params, ", ") + ") " + m.Body.String()
+}
+
+// expr implements Expr.
+func (m *MacroLiteral) expr() {
+	panic("unimplemented")
+}
+
+// await 表达式，等待 async 函数返回的 future 完成
+type AwaitExpr struct {
+	Pos   Position
+	Value Expr
+}
+
+// Span implements Expr.
+func (a *AwaitExpr) Span() (start Position, end Position) {
+	_, end = a.Value.Span()
+	return a.Pos, end
+}
+
+// Literal implements Expr.
+func (a *AwaitExpr) Literal() string {
+	return "await"
+}
+
+// String implements Expr.
+func (a *AwaitExpr) String() string {
+	return "(await " + a.Value.String() + ")"
+}
+
+// expr implements Expr.
+func (a *AwaitExpr) expr() {
+	panic("unimplemented")
+}
+
 type CallExpr struct {
 	start    Position
 	end      Position
@@ -433,7 +574,8 @@ type CallExpr struct {
 
 // Span implements Expr.
 func (c *CallExpr) Span() (start Position, end Position) {
-	return start, end
+	start, _ = c.Function.Span()
+	return start, c.end
 }
 
 // Literal implements Expr.
@@ -460,6 +602,33 @@ func (c *CallExpr) expr() {
 	panic("unimplemented")
 }
 
+// f(xs...) 中的 xs...，将数组展开为调用的多个实参，只能出现在调用的实参列表中
+type SpreadExpr struct {
+	end   Position // ... 之后的位置
+	Value Expr
+}
+
+// Span implements Expr.
+func (s *SpreadExpr) Span() (start Position, end Position) {
+	start, _ = s.Value.Span()
+	return start, s.end
+}
+
+// Literal implements Expr.
+func (s *SpreadExpr) Literal() string {
+	return "..."
+}
+
+// String implements Expr.
+func (s *SpreadExpr) String() string {
+	return s.Value.String() + "..."
+}
+
+// expr implements Expr.
+func (s *SpreadExpr) expr() {
+	panic("unimplemented")
+}
+
 type ArrayLiteral struct {
 	start Position
 	end   Position
@@ -468,7 +637,7 @@ type ArrayLiteral struct {
 
 // Span implements Expr.
 func (a *ArrayLiteral) Span() (start Position, end Position) {
-	return start, end
+	return a.start, a.end
 }
 
 // Literal implements Expr.
@@ -495,15 +664,21 @@ func (a *ArrayLiteral) expr() {
 	panic("unimplemented")
 }
 
+// MapPair 是 map 字面量中的一个键值对
+type MapPair struct {
+	Key   Expr
+	Value Expr
+}
+
 type MapLiteral struct {
 	start Position
 	end   Position
-	Pairs map[Expr]Expr
+	Pairs []MapPair // 按源码中的顺序排列
 }
 
 // Span implements Expr.
 func (m *MapLiteral) Span() (start Position, end Position) {
-	return start, end
+	return m.start, m.end
 }
 
 // Literal implements Expr.
@@ -515,10 +690,10 @@ func (m *MapLiteral) Literal() string {
 func (m *MapLiteral) String() string {
 	var out bytes.Buffer
 	out.WriteString("{")
-	for k, v := range m.Pairs {
-		out.WriteString(k.String())
+	for _, pair := range m.Pairs {
+		out.WriteString(pair.Key.String())
 		out.WriteString(":")
-		out.WriteString(v.String())
+		out.WriteString(pair.Value.String())
 		out.WriteString(",")
 	}
 	out.WriteString("}")
@@ -562,6 +737,227 @@ func (i *IndexExpr) expr() {
 	panic("unimplemented")
 }
 
+// a[start:end] 切片表达式，Start 和 End 省略时为 nil
+type SliceExpr struct {
+	end   Position
+	Left  Expr
+	Start Expr
+	End   Expr
+}
+
+// Span implements Expr.
+func (s *SliceExpr) Span() (start Position, end Position) {
+	start, _ = s.Left.Span()
+	return start, s.end
+}
+
+// Literal implements Expr.
+func (s *SliceExpr) Literal() string {
+	return ""
+}
+
+// String implements Expr.
+func (s *SliceExpr) String() string {
+	var out bytes.Buffer
+	out.WriteString(s.Left.String())
+	out.WriteString("[")
+	if s.Start != nil {
+		out.WriteString(s.Start.String())
+	}
+	out.WriteString(":")
+	if s.End != nil {
+		out.WriteString(s.End.String())
+	}
+	out.WriteString("]")
+	return out.String()
+}
+
+// expr implements Expr.
+func (s *SliceExpr) expr() {
+	panic("unimplemented")
+}
+
+// x.name 表达式，访问 x 中名为 name 的字段
+type DotExpr struct {
+	Left Expr
+	Name *Identifier
+}
+
+// Span implements Expr.
+func (d *DotExpr) Span() (start Position, end Position) {
+	start, _ = d.Left.Span()
+	_, end = d.Name.Span()
+	return start, end
+}
+
+// Literal implements Expr.
+func (d *DotExpr) Literal() string {
+	return "."
+}
+
+// String implements Expr.
+func (d *DotExpr) String() string {
+	return d.Left.String() + "." + d.Name.Value
+}
+
+// expr implements Expr.
+func (d *DotExpr) expr() {
+	panic("unimplemented")
+}
+
+// record Point {x, y} 语句，声明一个记录类型
+type RecordStmt struct {
+	Pos    Position
+	end    Position
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+// Span implements Stmt.
+func (r *RecordStmt) Span() (start Position, end Position) {
+	return r.Pos, r.end
+}
+
+// Literal implements Stmt.
+func (r *RecordStmt) Literal() string {
+	return "record"
+}
+
+// String implements Stmt.
+func (r *RecordStmt) String() string {
+	var out bytes.Buffer
+	out.WriteString("record ")
+	out.WriteString(r.Name.Value)
+	out.WriteString(" {")
+	for i, field := range r.Fields {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(field.Value)
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
+// stmt implements Stmt.
+func (r *RecordStmt) stmt() {
+	panic("unimplemented")
+}
+
+// export let 语句，声明模块对外公开的绑定
+type ExportStmt struct {
+	Pos Position
+	Let *LetStmt
+}
+
+// Span implements Stmt.
+func (e *ExportStmt) Span() (start Position, end Position) {
+	_, end = e.Let.Span()
+	return e.Pos, end
+}
+
+// Literal implements Stmt.
+func (e *ExportStmt) Literal() string {
+	return "export"
+}
+
+// String implements Stmt.
+func (e *ExportStmt) String() string {
+	return "export " + e.Let.String()
+}
+
+// stmt implements Stmt.
+func (e *ExportStmt) stmt() {
+	panic("unimplemented")
+}
+
+// import("path") 表达式，返回模块值
+type ImportExpr struct {
+	pos  Position
+	end  Position
+	Path Expr
+}
+
+// Span implements Expr.
+func (i *ImportExpr) Span() (start Position, end Position) {
+	return i.pos, i.end
+}
+
+// Literal implements Expr.
+func (i *ImportExpr) Literal() string {
+	return "import"
+}
+
+// String implements Expr.
+func (i *ImportExpr) String() string {
+	return "import(" + i.Path.String() + ")"
+}
+
+// expr implements Expr.
+func (i *ImportExpr) expr() {
+	panic("unimplemented")
+}
+
+// import("path") as name 语句，将模块绑定到 Alias
+type ImportStmt struct {
+	Import *ImportExpr
+	Alias  *Identifier
+}
+
+// Span implements Stmt.
+func (i *ImportStmt) Span() (start Position, end Position) {
+	start, _ = i.Import.Span()
+	_, end = i.Alias.Span()
+	return start, end
+}
+
+// Literal implements Stmt.
+func (i *ImportStmt) Literal() string {
+	return "import"
+}
+
+// String implements Stmt.
+func (i *ImportStmt) String() string {
+	return i.Import.String() + " as " + i.Alias.String() + ";"
+}
+
+// stmt implements Stmt.
+func (i *ImportStmt) stmt() {
+	panic("unimplemented")
+}
+
+// from "path" import (a, b) 语句，将模块中的绑定导入到当前作用域
+type FromImportStmt struct {
+	pos   Position
+	end   Position
+	Path  Expr
+	Names []*Identifier
+}
+
+// Span implements Stmt.
+func (f *FromImportStmt) Span() (start Position, end Position) {
+	return f.pos, f.end
+}
+
+// Literal implements Stmt.
+func (f *FromImportStmt) Literal() string {
+	return "from"
+}
+
+// String implements Stmt.
+func (f *FromImportStmt) String() string {
+	names := make([]string, len(f.Names))
+	for i, name := range f.Names {
+		names[i] = name.String()
+	}
+	return fmt.Sprintf("from %q import (%s);", f.Path.String(), strings.Join(names, ", "))
+}
+
+// stmt implements Stmt.
+func (f *FromImportStmt) stmt() {
+	panic("unimplemented")
+}
+
 var (
 	_ Node = (*Program)(nil)
 	_ Expr = (*Identifier)(nil)
@@ -580,4 +976,15 @@ var (
 	_ Expr = (*ArrayLiteral)(nil)
 	_ Expr = (*MapLiteral)(nil)
 	_ Expr = (*IndexExpr)(nil)
+	_ Expr = (*FloatLiteral)(nil)
+	_ Expr = (*MacroLiteral)(nil)
+	_ Expr = (*ImportExpr)(nil)
+	_ Stmt = (*ExportStmt)(nil)
+	_ Stmt = (*ImportStmt)(nil)
+	_ Stmt = (*FromImportStmt)(nil)
+	_ Expr = (*DotExpr)(nil)
+	_ Stmt = (*FunctionStmt)(nil)
+	_ Expr = (*SpreadExpr)(nil)
+	_ Expr = (*SliceExpr)(nil)
+	_ Stmt = (*RecordStmt)(nil)
 )
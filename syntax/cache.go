@@ -0,0 +1,79 @@
+package syntax
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"fmt"
+)
+
+// ParseCache 以源码的哈希为键缓存解析的结果，用于 REPL 中反复粘贴相同的函数定义等重复解析相同源码的场景。
+// 缓存最多保存 size 个程序，超出时丢弃最久没有使用的程序；解析出错的源码不会被缓存。
+//
+// 缓存的语法树由所有调用者共享。宏展开会直接修改语法树并设置 Program.Expanded，
+// 这样的语法树不会再被返回，而是重新解析源码。ParseCache 不能在多个 goroutine 中同时使用。
+type ParseCache struct {
+	size    int
+	entries map[[sha256.Size]byte]*list.Element
+	lru     *list.List // 最近使用的在前
+}
+
+type cacheEntry struct {
+	key     [sha256.Size]byte
+	program *Program
+}
+
+// NewParseCache 返回最多保存 size 个程序的缓存
+func NewParseCache(size int) *ParseCache {
+	return &ParseCache{
+		size:    size,
+		entries: make(map[[sha256.Size]byte]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Parse 返回 src 解析得到的程序，filename 与 NewFileParser 相同，为空时与 NewParser 相同
+func (c *ParseCache) Parse(filename, src string) (*Program, error) {
+	return c.ParseWith(filename, src, LanguageOptions{})
+}
+
+// ParseWith 与 Parse 相同，但启用 opts 中的语言特性，不同选项解析的结果分别缓存
+func (c *ParseCache) ParseWith(filename, src string, opts LanguageOptions) (*Program, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%+v\x00", filename, opts)
+	h.Write([]byte(src))
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		if !entry.program.Expanded {
+			c.lru.MoveToFront(elem)
+			return entry.program, nil
+		}
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+	}
+
+	parser := NewParser(src)
+	if filename != "" {
+		parser = NewFileParser(filename, src)
+	}
+	parser.Options = opts
+	program, err := parser.Parse()
+	if err != nil {
+		return nil, err
+	}
+	if c.size > 0 {
+		c.entries[key] = c.lru.PushFront(&cacheEntry{key, program})
+		if c.lru.Len() > c.size {
+			oldest := c.lru.Remove(c.lru.Back()).(*cacheEntry)
+			delete(c.entries, oldest.key)
+		}
+	}
+	return program, nil
+}
+
+// Len 返回缓存中程序的个数
+func (c *ParseCache) Len() int {
+	return c.lru.Len()
+}
@@ -0,0 +1,40 @@
+package syntax
+
+import "testing"
+
+func TestParseCache(t *testing.T) {
+	c := NewParseCache(2)
+
+	p1, err := c.Parse("", "let add = fn(a, b) { a + b };")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, _ := c.Parse("", "let add = fn(a, b) { a + b };")
+	if p1 != p2 {
+		t.Errorf("same source parsed twice")
+	}
+	if p3, _ := c.Parse("a.mky", "let add = fn(a, b) { a + b };"); p3 == p1 {
+		t.Errorf("different filenames share a program")
+	}
+	// 不同语言选项的解析结果分别缓存
+	plain, _ := c.Parse("", "true and false")
+	words, err := c.ParseWith("", "true and false", LanguageOptions{WordOperators: true})
+	if err != nil || words == plain || words.String() != "(true && false)" {
+		t.Errorf("word operators parsed as %v, %v", words, err)
+	}
+
+	// 被宏展开修改过的语法树需要重新解析
+	p1.Expanded = true
+	if p4, _ := c.Parse("", "let add = fn(a, b) { a + b };"); p4 == p1 || p4.Expanded {
+		t.Errorf("expanded program returned from cache")
+	}
+
+	if _, err := c.Parse("", "let = 1;"); err == nil {
+		t.Errorf("expected syntax error")
+	}
+	c.Parse("", "1")
+	c.Parse("", "2")
+	if c.Len() != 2 {
+		t.Errorf("cache holds %d programs, want 2", c.Len())
+	}
+}
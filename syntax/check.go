@@ -0,0 +1,63 @@
+package syntax
+
+import (
+	"errors"
+	"sort"
+)
+
+// Diagnostic 是 Check 报告的一个问题
+type Diagnostic struct {
+	Start Position // 出错的词法单元的开始位置
+	End   Position // 出错的词法单元的结束位置，错误位于输入的末尾时与 Start 相同
+	Msg   string
+}
+
+// String 返回与 Error 相同格式的描述
+func (d Diagnostic) String() string {
+	return d.Start.String() + " " + d.Msg
+}
+
+// Check 对名为 filename 的源码 src 做词法和语法分析，但不求值，按位置的顺序返回所有的问题。
+// 语法错误之后解析会跳到下一条语句继续，因此一次调用可以得到整个文件的诊断，
+// 适合编辑器、CI 和 pre-commit 钩子使用。返回的 error 只表示检查本身失败（例如解析器的缺陷），
+// 源码中的问题都在 Diagnostic 中。
+func Check(src, filename string) ([]Diagnostic, error) {
+	parser := NewParser(src)
+	if filename != "" {
+		parser = NewFileParser(filename, src)
+	}
+	_, err := parser.Parse()
+	var list ErrorList
+	if err != nil && !errors.As(err, &list) {
+		return nil, err
+	}
+
+	ends := tokenEnds(parser.l.pos.file, src)
+	diags := make([]Diagnostic, len(list))
+	for i, e := range list {
+		end, ok := ends[e.Position]
+		if !ok {
+			end = e.Position
+		}
+		diags[i] = Diagnostic{Start: e.Position, End: end, Msg: e.Msg}
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i].Start, diags[j].Start
+		return a.Line < b.Line || a.Line == b.Line && a.Col < b.Col
+	})
+	return diags, nil
+}
+
+// 返回 src 中每个词法单元的开始位置到结束位置的映射
+func tokenEnds(file *string, src string) map[Position]Position {
+	l := NewLexer(src)
+	l.pos = MakePosition(file, 1, 1)
+	ends := make(map[Position]Position)
+	for {
+		tok := l.NextToken()
+		if tok.Type == EOF {
+			return ends
+		}
+		ends[tok.pos] = l.pos
+	}
+}
@@ -0,0 +1,33 @@
+package syntax
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	diags, err := Check("let x = 1;\nlet = 2;\nlet y = foo(1,;\nlet s = \"abc", "main.mky")
+	if err != nil {
+		t.Fatal(err)
+	}
+	type span struct {
+		start, end string
+	}
+	var got []span
+	for _, d := range diags {
+		got = append(got, span{d.Start.String(), d.End.String()})
+	}
+	expected := []span{
+		{"main.mky:2:5", "main.mky:2:6"},   // =
+		{"main.mky:3:15", "main.mky:3:16"}, // ;
+		{"main.mky:4:9", "main.mky:4:13"},  // 没有结束的字符串
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("wrong spans.\nexpected=%v\ngot=%v\n%v", expected, got, diags)
+	}
+
+	diags, err = Check("let x = fn(a) { a * 2 };", "")
+	if err != nil || len(diags) != 0 {
+		t.Errorf("expected no diagnostics, got %v %v", diags, err)
+	}
+}
@@ -1,10 +1,21 @@
 package syntax
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrParse 被所有的语法错误包装，可以用 errors.Is(err, ErrParse) 判断是否是语法错误
+var ErrParse = errors.New("syntax error")
 
 type Error struct {
 	Msg      string
 	Position Position
+
+	// Incomplete 表示错误是因为输入在语法结构完成之前就结束了，
+	// 例如没有闭合的括号或字符串，追加更多的输入可能使源码合法
+	Incomplete bool
 }
 
 // Error implements error.
@@ -12,10 +23,54 @@ func (e *Error) Error() string {
 	return fmt.Sprintf("%v %s", e.Position, e.Msg)
 }
 
+// Is 使 errors.Is(e, ErrParse) 成立
+func (e *Error) Is(target error) bool {
+	return target == ErrParse
+}
+
 func NewError(pos Position, msg string) *Error {
 	return &Error{Msg: msg, Position: pos}
 }
 
+// 返回位于输入末尾的语法错误
+func newIncompleteError(pos Position, msg string) *Error {
+	return &Error{Msg: msg, Position: pos, Incomplete: true}
+}
+
+// IsIncomplete 判断 err 是否是只因为输入不完整而产生的语法错误，
+// REPL 据此判断是否需要继续读取下一行
+func IsIncomplete(err error) bool {
+	var list ErrorList
+	if errors.As(err, &list) && len(list) > 0 {
+		for _, e := range list {
+			if !e.Incomplete {
+				return false
+			}
+		}
+		return true
+	}
+	var e *Error
+	return errors.As(err, &e) && e.Incomplete
+}
+
+// ErrorList 是 Parser 在一次解析中遇到的所有语法错误，按出现的顺序排列
+type ErrorList []*Error
+
+// Error implements error.
+func (l ErrorList) Error() string {
+	msgs := make([]string, len(l))
+	for i, e := range l {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Is 使 errors.Is(l, ErrParse) 成立
+func (l ErrorList) Is(target error) bool {
+	return target == ErrParse
+}
+
 var (
 	_ error = (*Error)(nil)
+	_ error = ErrorList(nil)
 )
@@ -0,0 +1,355 @@
+// Package format 按统一的风格格式化 Monkey 源码：默认两个空格缩进，每条语句一行，
+// 二元运算符两侧各一个空格，只在改变含义时才加括号和分号。
+// 注释保留在它们原来所在的语句附近，语句之间的空行被保留，连续的多个空行合并为一个。
+package format
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// Options 是格式化的选项，零值表示默认的风格
+type Options struct {
+	Indent int // 每级缩进的空格数，<= 0 时为 2
+}
+
+// Source 以默认的风格格式化名为 filename 的源码 src，src 有语法错误时返回该错误
+func Source(filename string, src []byte) ([]byte, error) {
+	return Options{}.Source(filename, src)
+}
+
+// Program 以默认的风格格式化 program，见 Options.Program
+func Program(program *syntax.Program) []byte {
+	return Options{}.Program(program)
+}
+
+// Source 格式化名为 filename 的源码 src，src 有语法错误时返回该错误
+func (o Options) Source(filename string, src []byte) ([]byte, error) {
+	program, err := syntax.NewFileParser(filename, string(src)).Parse()
+	if err != nil {
+		return nil, err
+	}
+	return o.Program(program), nil
+}
+
+// Program 返回 program 格式化之后的源码，program.Comments 中的注释被放在它们所在的语句附近
+func (o Options) Program(program *syntax.Program) []byte {
+	unit := "  "
+	if o.Indent > 0 {
+		unit = strings.Repeat(" ", o.Indent)
+	}
+	p := &printer{comments: program.Comments, unit: unit}
+	p.stmts(program.Stmts, nil)
+	return p.buf
+}
+
+// 比所有运算符都结合得更紧的表达式（字面量、标识符、调用、索引等）的优先级
+const primary = syntax.INDEX + 1
+
+type printer struct {
+	buf      []byte
+	unit     string // 一级缩进
+	indent   int
+	comments []syntax.Comment // 还没有输出的注释，按出现的顺序排列
+	line     int32            // 当前的块中上一个输出的语句或注释在源码中的结束行，还没有输出时为 0
+}
+
+// 输出一个块中的语句，每条语句一行。end 是块的结束位置，
+// 在它之前的剩余注释在最后一条语句之后输出；end 为 nil 时输出所有剩余的注释
+func (p *printer) stmts(stmts []syntax.Stmt, end *syntax.Position) {
+	p.line = 0
+	semi := -1 // 上一条语句在 buf 中结束的位置
+	for i, stmt := range stmts {
+		start, _ := stmt.Span()
+		p.commentsBefore(&start)
+		p.startLine(start.Line)
+		at := len(p.buf)
+		p.stmt(stmt)
+		// 下一条语句以这些字符开头时，会被解析为上一条语句中表达式的延续
+		if semi >= 0 && strings.IndexByte("([+-", p.buf[at]) >= 0 {
+			p.buf = append(p.buf[:semi+1], p.buf[semi:]...)
+			p.buf[semi] = ';'
+		}
+		semi = len(p.buf)
+
+		// 与语句的最后一行在同一行的注释放在行尾，语句内部其他行的注释放在语句之后
+		_, stmtEnd := stmt.Span()
+		last := stmtEnd.Line
+		limit := end
+		if i+1 < len(stmts) {
+			next, _ := stmts[i+1].Span()
+			limit = &next
+		}
+		var inner []syntax.Comment
+		for len(p.comments) > 0 && p.comments[0].Pos.Line <= last && (limit == nil || before(p.comments[0].Pos, *limit)) {
+			c := p.comments[0]
+			p.comments = p.comments[1:]
+			if c.Pos.Line < last {
+				inner = append(inner, c)
+				continue
+			}
+			p.buf = append(p.buf, ' ')
+			p.buf = append(p.buf, c.Text...)
+			last += int32(strings.Count(c.Text, "\n"))
+		}
+		p.buf = append(p.buf, '\n')
+		for _, c := range inner {
+			p.writeIndent()
+			p.buf = append(p.buf, c.Text...)
+			p.buf = append(p.buf, '\n')
+		}
+		p.line = last
+	}
+	p.commentsBefore(end)
+}
+
+// 各自占一行输出 pos 之前的注释，pos 为 nil 时输出所有剩余的注释
+func (p *printer) commentsBefore(pos *syntax.Position) {
+	for len(p.comments) > 0 && (pos == nil || before(p.comments[0].Pos, *pos)) {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+		p.startLine(c.Pos.Line)
+		p.buf = append(p.buf, c.Text...)
+		p.buf = append(p.buf, '\n')
+		p.line = c.Pos.Line + int32(strings.Count(c.Text, "\n"))
+	}
+}
+
+// 开始输出源码中第 line 行的内容，与上一个输出的内容之间有空行时保留一个空行
+func (p *printer) startLine(line int32) {
+	if p.line > 0 && line > p.line+1 {
+		p.buf = append(p.buf, '\n')
+	}
+	p.writeIndent()
+}
+
+func (p *printer) writeIndent() {
+	for i := 0; i < p.indent; i++ {
+		p.buf = append(p.buf, p.unit...)
+	}
+}
+
+func (p *printer) print(s string) {
+	p.buf = append(p.buf, s...)
+}
+
+func (p *printer) stmt(stmt syntax.Stmt) {
+	switch stmt := stmt.(type) {
+	case *syntax.LetStmt:
+		p.print("let " + stmt.Name.Value + " = ")
+		p.expr(stmt.Value, syntax.LOWEST)
+	case *syntax.ExportStmt:
+		p.print("export ")
+		p.stmt(stmt.Let)
+	case *syntax.ReturnStmt:
+		p.print("return ")
+		p.expr(stmt.Value, syntax.LOWEST)
+	case *syntax.ExprStmt:
+		p.expr(stmt.Expr, syntax.LOWEST)
+	case *syntax.FunctionStmt:
+		p.expr(stmt.Fn, syntax.LOWEST)
+	case *syntax.ImportStmt:
+		p.expr(stmt.Import, syntax.LOWEST)
+		p.print(" as " + stmt.Alias.Value)
+	case *syntax.FromImportStmt:
+		p.print("from ")
+		p.expr(stmt.Path, syntax.LOWEST)
+		p.print(" import (" + identList(stmt.Names) + ")")
+	case *syntax.RecordStmt:
+		p.print("record " + stmt.Name.Value + " {" + identList(stmt.Fields) + "}")
+	case *syntax.BlockStmt:
+		p.block(stmt)
+	default:
+		panic(fmt.Sprintf("format: unexpected statement %T", stmt))
+	}
+}
+
+// 输出 { 和 } 包围的代码块，块中的语句缩进一层
+func (p *printer) block(block *syntax.BlockStmt) {
+	_, end := block.Span()
+	if len(block.Stmts) == 0 && (len(p.comments) == 0 || !before(p.comments[0].Pos, end)) {
+		p.print("{}")
+		return
+	}
+	p.print("{\n")
+	line := p.line
+	p.indent++
+	p.stmts(block.Stmts, &end)
+	p.indent--
+	p.line = line
+	p.writeIndent()
+	p.print("}")
+}
+
+// 输出表达式 e，e 的优先级低于 min 时加上括号
+func (p *printer) expr(e syntax.Expr, min int) {
+	if precedence(e) < min {
+		p.print("(")
+		defer p.print(")")
+	}
+	switch e := e.(type) {
+	case *syntax.Identifier:
+		p.print(e.Value)
+	case *syntax.IntegerLiteral:
+		p.print(e.Raw)
+	case *syntax.FloatLiteral:
+		p.print(e.Raw)
+	case *syntax.StringLiteral:
+		p.print(quote(e.Value))
+	case *syntax.Boolean:
+		p.print(e.String())
+	case *syntax.PrefixExpr:
+		p.print(e.Op.String())
+		p.expr(e.Right, syntax.PREFIX)
+	case *syntax.AwaitExpr:
+		p.print("await ")
+		p.expr(e.Value, syntax.PREFIX)
+	case *syntax.InfixExpr:
+		// 左结合的运算符的右操作数与 ** 的左操作数在优先级相同时也需要括号
+		prec := syntax.Precedence(e.Op)
+		left, right := prec, prec+1
+		if e.Op == syntax.STARSTAR {
+			left, right = prec+1, prec
+		}
+		p.expr(e.Left, left)
+		p.print(" " + e.Op.String() + " ")
+		p.expr(e.Right, right)
+	case *syntax.CallExpr:
+		p.expr(e.Function, syntax.CALL)
+		p.print("(")
+		p.exprList(e.Args)
+		p.print(")")
+	case *syntax.SpreadExpr:
+		p.expr(e.Value, syntax.LOWEST)
+		p.print("...")
+	case *syntax.IndexExpr:
+		p.expr(e.Left, syntax.INDEX)
+		p.print("[")
+		p.expr(e.Index, syntax.LOWEST)
+		p.print("]")
+	case *syntax.SliceExpr:
+		p.expr(e.Left, syntax.INDEX)
+		p.print("[")
+		if e.Start != nil {
+			p.expr(e.Start, syntax.LOWEST)
+		}
+		p.print(":")
+		if e.End != nil {
+			p.expr(e.End, syntax.LOWEST)
+		}
+		p.print("]")
+	case *syntax.DotExpr:
+		p.expr(e.Left, syntax.INDEX)
+		p.print("." + e.Name.Value)
+	case *syntax.ArrayLiteral:
+		p.print("[")
+		p.exprList(e.Items)
+		p.print("]")
+	case *syntax.MapLiteral:
+		p.print("{")
+		for i, pair := range e.Pairs {
+			if i > 0 {
+				p.print(", ")
+			}
+			p.expr(pair.Key, syntax.LOWEST)
+			p.print(": ")
+			p.expr(pair.Value, syntax.LOWEST)
+		}
+		p.print("}")
+	case *syntax.IfExpr:
+		p.print("if (")
+		p.expr(e.Cond, syntax.LOWEST)
+		p.print(") ")
+		p.block(e.Consequence)
+		if e.Alternative != nil {
+			p.print(" else ")
+			p.block(e.Alternative)
+		}
+	case *syntax.FunctionLiteral:
+		if e.Async {
+			p.print("async ")
+		}
+		p.print("fn")
+		if e.Name != nil {
+			p.print(" " + e.Name.Value)
+		}
+		params := identList(e.Params)
+		if e.Variadic {
+			params += "..."
+		}
+		p.print("(" + params + ") ")
+		p.block(e.Body)
+	case *syntax.MacroLiteral:
+		p.print("macro(" + identList(e.Params) + ") ")
+		p.block(e.Body)
+	case *syntax.ImportExpr:
+		p.print("import(")
+		p.expr(e.Path, syntax.LOWEST)
+		p.print(")")
+	default:
+		panic(fmt.Sprintf("format: unexpected expression %T", e))
+	}
+}
+
+func (p *printer) exprList(list []syntax.Expr) {
+	for i, e := range list {
+		if i > 0 {
+			p.print(", ")
+		}
+		p.expr(e, syntax.LOWEST)
+	}
+}
+
+// 返回表达式的优先级，与解析器使用的优先级相同
+func precedence(e syntax.Expr) int {
+	switch e := e.(type) {
+	case *syntax.InfixExpr:
+		return syntax.Precedence(e.Op)
+	case *syntax.PrefixExpr, *syntax.AwaitExpr:
+		return syntax.PREFIX
+	}
+	return primary
+}
+
+func identList(idents []*syntax.Identifier) string {
+	names := make([]string, len(idents))
+	for i, ident := range idents {
+		names[i] = ident.Value
+	}
+	return strings.Join(names, ", ")
+}
+
+// 返回字符串 s 的字面量，只使用词法分析器支持的转义序列
+func quote(s string) string {
+	var out strings.Builder
+	out.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '"' || r == '\\':
+			out.WriteByte('\\')
+			out.WriteRune(r)
+		case r == '\n':
+			out.WriteString(`\n`)
+		case r == '\t':
+			out.WriteString(`\t`)
+		case r == '\r':
+			out.WriteString(`\r`)
+		case r < ' ' || r == 0x7f:
+			fmt.Fprintf(&out, `\u%04x`, r)
+		default:
+			out.WriteString(s[i : i+size]) // 包括无效的 UTF-8 字节
+		}
+		i += size
+	}
+	out.WriteByte('"')
+	return out.String()
+}
+
+// 判断 a 是否在 b 之前
+func before(a, b syntax.Position) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Col < b.Col
+}
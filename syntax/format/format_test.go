@@ -0,0 +1,110 @@
+package format
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let   x=1+2*3;", "let x = 1 + 2 * 3\n"},
+		{"let x = (1 + 2) * 3; let y = 1 - (2 - 3); let z = (1 - 2) - 3", "let x = (1 + 2) * 3\nlet y = 1 - (2 - 3)\nlet z = 1 - 2 - 3\n"},
+		{"(2 ** 3) ** 2; 2 ** (3 ** 2); -(a ** b); (-a) ** b; - -a", "(2 ** 3) ** 2\n2 ** 3 ** 2;\n-a ** b;\n(-a) ** b;\n--a\n"},
+		{"(a + b)(c); (-a)[0]; f(x)[1:].y; g(xs...)", "(a + b)(c);\n(-a)[0]\nf(x)[1:].y\ng(xs...)\n"},
+		{"a || b && c; (a || b) && !c", "a || b && c;\n(a || b) && !c\n"},
+		{
+			"let f=fn(a,b){if(a>b){return a}else{return b}};",
+			"let f = fn(a, b) {\n  if (a > b) {\n    return a\n  } else {\n    return b\n  }\n}\n",
+		},
+		{"fn   add(x, ys...) {}", "fn add(x, ys...) {}\n"},
+		{"async fn f() { await g() }", "async fn f() {\n  await g()\n}\n"},
+		{`let m = {"a":[1,2], "b\n\"c": true}`, "let m = {\"a\": [1, 2], \"b\\n\\\"c\": true}\n"},
+		{`import("x.mky") as x; from "y.mky" import (a,b); export let z = 1; record P {x,y,}`, "import(\"x.mky\") as x\nfrom \"y.mky\" import (a, b)\nexport let z = 1\nrecord P {x, y}\n"},
+		{"let m = macro(a) { quote(unquote(a)) }", "let m = macro(a) {\n  quote(unquote(a))\n}\n"},
+		// 下一条语句以 ( [ + - 开头时保留分号
+		{"let x = 1; (x)(2); [1]; -1; y", "let x = 1\nx(2);\n[1];\n-1\ny\n"},
+		{"let f = fn() {}\n(1)", "let f = fn() {}(1)\n"},
+		// 空行和注释
+		{
+			"// header\n\nlet x = 1 // one\n\n\n/* two */\nlet y = [\n  1, // first\n  2\n]\nfn f() {\n  // inside\n\n  x\n  // end\n}\n// trailer",
+			"// header\n\nlet x = 1 // one\n\n/* two */\nlet y = [1, 2]\n// first\nfn f() {\n  // inside\n\n  x\n  // end\n}\n// trailer\n",
+		},
+		{"if (x) {\n  // todo\n}", "if (x) {\n  // todo\n}\n"},
+		{"a; // c\n-b", "a; // c\n-b\n"},
+		{"a; // c\n(b)", "a // c\nb\n"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got, err := Source("test.mky", []byte(tt.input))
+		if err != nil {
+			t.Errorf("%q: %v", tt.input, err)
+			continue
+		}
+		if string(got) != tt.expected {
+			t.Errorf("%q:\nexpected=%q\ngot=     %q", tt.input, tt.expected, got)
+		}
+	}
+
+	if _, err := Source("test.mky", []byte("let = 1")); err == nil {
+		t.Errorf("expected a syntax error")
+	}
+}
+
+func TestOptionsIndent(t *testing.T) {
+	src := "fn f(x) { if (x) { // c\n x } }"
+	got, err := Options{Indent: 4}.Source("test.mky", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "fn f(x) {\n    if (x) {\n        // c\n        x\n    }\n}\n"
+	if string(got) != expected {
+		t.Errorf("expected=%q\ngot=     %q", expected, got)
+	}
+}
+
+var (
+	separator  = regexp.MustCompile(`(?m)^---$`)
+	annotation = regexp.MustCompile(`(?m)###.*$`)
+)
+
+// 格式化不改变语法树，不丢失注释，并且格式化的结果再次格式化时不变
+func TestSourceStable(t *testing.T) {
+	files, _ := filepath.Glob("../../monkey/testdata/*.mky")
+	files = append(files, "../../example.mky")
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 一致性测试的语料由 --- 分成独立的段，其中包括故意写错的段；### 断言不是合法的源码
+		for _, chunk := range separator.Split(string(data), -1) {
+			src := annotation.ReplaceAllString(chunk, "")
+			before, err := syntax.NewParser(src).Parse()
+			if err != nil {
+				continue
+			}
+			formatted := Program(before)
+			after, err := syntax.NewParser(string(formatted)).Parse()
+			if err != nil {
+				t.Errorf("%s: formatted source does not parse: %v\n%s", file, err, formatted)
+				continue
+			}
+			if before.String() != after.String() {
+				t.Errorf("%s: formatting changed the program\nbefore=%s\nafter= %s", file, before, after)
+			}
+			if len(before.Comments) != len(after.Comments) {
+				t.Errorf("%s: formatting lost comments: %d -> %d", file, len(before.Comments), len(after.Comments))
+			}
+			if again := Program(after); string(again) != string(formatted) {
+				t.Errorf("%s: formatting is not idempotent\nfirst=\n%s\nsecond=\n%s", file, formatted, again)
+			}
+		}
+	}
+}
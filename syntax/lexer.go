@@ -2,6 +2,7 @@ package syntax
 
 import (
 	"fmt"
+	"runtime"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -10,14 +11,36 @@ import (
 type ReadLineFunc func() (string, error)
 
 type Lexer struct {
+	Options LanguageOptions
+
+	// KeepTrivia 为 true 时，NextToken 把词法单元之前的空白和注释记录在 TokenValue.Leading 中，
+	// 拼接所有词法单元的 Leading 和原文可以得到完整的源码，供语法高亮和格式化等工具使用
+	KeepTrivia bool
+
 	pos      Position // 当前读取的位置
 	rest     string
 	readline ReadLineFunc
+	comments []Comment // 已经跳过的注释
+	errors   ErrorList // 词法错误，由 Parser 取出
+	trivia   []Trivia  // KeepTrivia 时已经跳过的空白和注释
+}
+
+// 记录一个词法错误，词法分析不会因此中断
+func (l *Lexer) error(pos Position, msg string) {
+	l.errors = append(l.errors, NewError(pos, msg))
+}
+
+// 记录一个因为输入结束而产生的词法错误
+func (l *Lexer) incomplete(pos Position, msg string) {
+	l.errors = append(l.errors, newIncompleteError(pos, msg))
 }
 
 func (p *Lexer) recover(err *error) {
 	switch e := recover().(type) {
 	case nil:
+	case runtime.Error:
+		// 解析器自身的缺陷，不是语法错误
+		*err = fmt.Errorf("parser panic: %v", e)
 	case error:
 		*err = e
 	default:
@@ -30,11 +53,21 @@ func (p *Lexer) recover(err *error) {
 // 最后，名为newToken的小型函数可以帮助初始化这些词法单元。
 func (l *Lexer) NextToken() TokenValue {
 	l.skipWhitespace() // 跳过空白字符
+	leading := l.trivia
+	l.trivia = nil
 	c := l.peekRune()
 	start := l.pos
 	var tok TokenValue
 	switch c {
-	case '=', '!', '+', '-', '*', '/', '>', '<':
+	case '*':
+		if strings.HasPrefix(l.rest, "**") {
+			l.nextRune()
+			l.nextRune()
+			tok = TokenValue{pos: start, Type: STARSTAR, Literal: "**"}
+			break
+		}
+		fallthrough
+	case '=', '!', '+', '-', '/', '>', '<':
 		l.nextRune()
 		switch l.peekRune() {
 		case '=':
@@ -77,6 +110,21 @@ func (l *Lexer) NextToken() TokenValue {
 				tok = createToken(LT, c, start)
 			}
 		}
+	case '&', '|':
+		l.nextRune()
+		switch {
+		case c == '&' && l.peekRune() == '&':
+			l.nextRune()
+			tok = TokenValue{pos: start, Type: AND, Literal: "&&"}
+		case c == '|' && l.peekRune() == '|':
+			l.nextRune()
+			tok = TokenValue{pos: start, Type: OR, Literal: "||"}
+		default:
+			tok = createToken(ILLEGAL, c, start)
+		}
+	case '%':
+		l.nextRune()
+		tok = createToken(PERCENT, c, start)
 	case ',':
 		l.nextRune()
 		tok = createToken(COMMA, c, start)
@@ -86,6 +134,16 @@ func (l *Lexer) NextToken() TokenValue {
 	case ';':
 		l.nextRune()
 		tok = createToken(SEMICOLON, c, start)
+	case '.':
+		if strings.HasPrefix(l.rest, "...") {
+			l.nextRune()
+			l.nextRune()
+			l.nextRune()
+			tok = TokenValue{pos: start, Type: ELLIPSIS, Literal: "..."}
+			break
+		}
+		l.nextRune()
+		tok = createToken(DOT, c, start)
 	case '(':
 		l.nextRune()
 		tok = createToken(LPAREN, c, start)
@@ -115,18 +173,49 @@ func (l *Lexer) NextToken() TokenValue {
 			tok.pos = start
 			tok.Literal = l.readIdentifier()
 			tok.Type = LookupIdent(tok.Literal)
+			if op, ok := wordOperators[tok.Literal]; ok && l.Options.WordOperators {
+				tok.Type = op
+			}
 		} else if isDigit(c) {
 			tok.pos = start
-			tok.Literal = l.readNumber()
-			tok.Type = INT
+			tok.Literal, tok.Type = l.readNumber()
 		} else {
+			l.nextRune()
 			tok = createToken(ILLEGAL, c, start)
 		}
 	}
 	tok.pos = start
+	tok.end = l.pos
+	tok.Leading = leading
 	return tok
 }
 
+// Tokenize 读取剩余的所有词法单元，最后一个是 EOF。词法错误不会中断读取，
+// 返回的错误是包含所有词法错误的 ErrorList，此时仍然返回所有的词法单元，出错的字符是 ILLEGAL
+func (l *Lexer) Tokenize() (_ []TokenValue, err error) {
+	defer l.recover(&err)
+
+	var tokens []TokenValue
+	for {
+		tok := l.NextToken()
+		tokens = append(tokens, tok)
+		if tok.Type == EOF {
+			break
+		}
+	}
+	if len(l.errors) > 0 {
+		errs := l.errors
+		l.errors = nil
+		return tokens, errs
+	}
+	return tokens, nil
+}
+
+// Pos 返回当前读取的位置，即上一个词法单元的结束位置
+func (l *Lexer) Pos() Position {
+	return l.pos
+}
+
 func (l *Lexer) eof() bool {
 	return len(l.rest) == 0 && !l.readLine()
 }
@@ -192,40 +281,217 @@ func (l *Lexer) nextRune() rune {
 	return r
 }
 
-func (l *Lexer) readNumber() string {
+// 读取整数或浮点数，浮点数包含小数部分（3.14）或指数部分（1e-9）
+func (l *Lexer) readNumber() (string, Token) {
 	raw := new(strings.Builder)
-	for c := l.peekRune(); isDigit(c); c = l.peekRune() {
-		raw.WriteRune(c)
-		l.nextRune()
+	digits := func() {
+		for c := l.peekRune(); isDigit(c); c = l.peekRune() {
+			raw.WriteRune(c)
+			l.nextRune()
+		}
+	}
+
+	typ := INT
+	digits()
+	// 小数点后必须是数字，以免与其他使用 . 的语法混淆
+	if len(l.rest) > 1 && l.rest[0] == '.' && isDigit(rune(l.rest[1])) {
+		raw.WriteRune(l.nextRune())
+		digits()
+		typ = FLOAT
 	}
-	return raw.String()
+	if len(l.rest) > 1 && (l.rest[0] == 'e' || l.rest[0] == 'E') {
+		exp := l.rest[1:]
+		if exp[0] == '+' || exp[0] == '-' {
+			exp = exp[1:]
+		}
+		if len(exp) > 0 && isDigit(rune(exp[0])) {
+			raw.WriteRune(l.nextRune()) // e
+			if c := l.peekRune(); c == '+' || c == '-' {
+				raw.WriteRune(l.nextRune())
+			}
+			digits()
+			typ = FLOAT
+		}
+	}
+	return raw.String(), typ
 }
 
+// 读取字符串字面量，支持转义序列 \n \t \r \" \\ 和 \uXXXX
 func (l *Lexer) readString() string {
+	start := l.pos
 	l.nextRune() // 消耗引号
 	raw := new(strings.Builder)
-	for c := l.peekRune(); c != '"' && c != 0; c = l.peekRune() {
+	for {
+		c := l.peekRune()
+		switch c {
+		case 0:
+			l.incomplete(start, "unterminated string literal")
+			return raw.String()
+		case '"':
+			l.nextRune() // 消耗引号
+			return raw.String()
+		case '\\':
+			l.readEscape(raw)
+		default:
+			raw.WriteRune(c)
+			l.nextRune()
+		}
+	}
+}
+
+// 读取以反斜杠开头的转义序列，将它表示的字符写入 raw
+func (l *Lexer) readEscape(raw *strings.Builder) {
+	start := l.pos
+	l.nextRune() // 消耗反斜杠
+	c := l.peekRune()
+	if c == 0 {
+		return // 由 readString 报告字符串没有结束
+	}
+	l.nextRune()
+	switch c {
+	case 'n':
+		raw.WriteRune('\n')
+	case 't':
+		raw.WriteRune('\t')
+	case 'r':
+		raw.WriteRune('\r')
+	case '"', '\\':
 		raw.WriteRune(c)
-		l.nextRune()
+	case 'u':
+		var r rune
+		for i := 0; i < 4; i++ {
+			v, ok := hexValue(l.peekRune())
+			if !ok {
+				l.error(start, "invalid \\u escape: expected 4 hex digits")
+				return
+			}
+			l.nextRune()
+			r = r<<4 | v
+		}
+		raw.WriteRune(r)
+	default:
+		l.error(start, fmt.Sprintf("unknown escape sequence \\%c", c))
 	}
-	l.nextRune() // 消耗引号
-	return raw.String()
 }
 
 // readIdentifier()函数顾名思义，就是读入一个标识符并前移词法分析器的扫描位置，直到遇见非字母字符。
+// 标识符不会跨越换行，因此直接在 l.rest 中找到它的结尾并截取子串，不需要复制；
+// 关键字和常见的标识符返回 identTable 中的字符串，不会引用整个源码。
 func (l *Lexer) readIdentifier() string {
+	n, runes := 0, 0
+	for n < len(l.rest) {
+		if b := l.rest[n]; b < utf8.RuneSelf {
+			if !isIdentifierByte(b) {
+				break
+			}
+			n++
+		} else {
+			r, size := utf8.DecodeRuneInString(l.rest[n:])
+			if !isIdentifier(r) {
+				break
+			}
+			n += size
+		}
+		runes++
+	}
+	ident := l.rest[:n]
+	l.rest = l.rest[n:]
+	l.pos.Col += int32(runes)
+
+	if interned, ok := identTable[ident]; ok {
+		return interned
+	}
+	return ident
+}
+
+// identTable 是关键字和常见标识符的驻留表，以名称本身为值
+var identTable = map[string]string{}
+
+func init() {
+	for keyword := range keywords {
+		identTable[keyword] = keyword
+	}
+	for keyword := range wordOperators {
+		identTable[keyword] = keyword
+	}
+	for _, name := range []string{
+		"a", "b", "c", "e", "f", "i", "j", "k", "n", "s", "x", "y", "acc", "args", "err", "fn", "key", "value",
+		"len", "print", "push", "map", "filter", "reduce", "keys", "values", "error", "try",
+	} {
+		identTable[name] = name
+	}
+}
+
+// 跳过空白字符和注释，注释被记录在 l.comments 中，KeepTrivia 时空白和注释还被记录在 l.trivia 中
+func (l *Lexer) skipWhitespace() {
+	start := l.pos
+	space := new(strings.Builder)
+	// 把连续的空白记录为一个 Trivia
+	flush := func() {
+		if space.Len() > 0 {
+			l.trivia = append(l.trivia, Trivia{Pos: start, Text: space.String()})
+			space.Reset()
+		}
+	}
+	for {
+		switch c := l.peekRune(); {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if l.KeepTrivia {
+				if space.Len() == 0 {
+					start = l.pos
+				}
+				space.WriteRune(c)
+			}
+			l.nextRune()
+		case strings.HasPrefix(l.rest, "//"):
+			flush()
+			l.readLineComment()
+		case strings.HasPrefix(l.rest, "/*"):
+			flush()
+			l.readBlockComment()
+		default:
+			flush()
+			return
+		}
+	}
+}
+
+// 记录一条注释
+func (l *Lexer) comment(c Comment) {
+	l.comments = append(l.comments, c)
+	if l.KeepTrivia {
+		l.trivia = append(l.trivia, Trivia{Pos: c.Pos, Text: c.Text, Comment: true})
+	}
+}
+
+// 读取 // 开头直到行尾的注释，不包括换行符
+func (l *Lexer) readLineComment() {
+	start := l.pos
 	raw := new(strings.Builder)
-	for c := l.peekRune(); isIdentifier(c); c = l.peekRune() {
+	for c := l.peekRune(); c != '\n' && c != 0; c = l.peekRune() {
 		raw.WriteRune(c)
 		l.nextRune()
 	}
-	return raw.String()
+	l.comment(Comment{Pos: start, Text: raw.String()})
 }
 
-func (l *Lexer) skipWhitespace() {
-	for c := l.peekRune(); c == ' ' || c == '\t' || c == '\n' || c == '\r'; c = l.peekRune() {
-		l.nextRune()
+// 读取 /* ... */ 注释，块注释不能嵌套
+func (l *Lexer) readBlockComment() {
+	start := l.pos
+	raw := new(strings.Builder)
+	raw.WriteRune(l.nextRune()) // /
+	raw.WriteRune(l.nextRune()) // *
+	for !strings.HasPrefix(l.rest, "*/") {
+		if l.peekRune() == 0 {
+			l.incomplete(start, "unterminated comment")
+			l.comment(Comment{Pos: start, Text: raw.String()})
+			return
+		}
+		raw.WriteRune(l.nextRune())
 	}
+	raw.WriteRune(l.nextRune()) // *
+	raw.WriteRune(l.nextRune()) // /
+	l.comment(Comment{Pos: start, Text: raw.String()})
 }
 
 func NewLexer(input string) *Lexer {
@@ -248,10 +514,27 @@ func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
+func hexValue(c rune) (rune, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
 func isIdentifier(c rune) bool {
 	return isIdentifierStart(c) || isDigit(c)
 }
 
+// 判断 ASCII 字符 b 是否可以出现在标识符中
+func isIdentifierByte(b byte) bool {
+	return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || b == '_' || '0' <= b && b <= '9'
+}
+
 // 判断 c 是否为一个合法标识符的开始
 func isIdentifierStart(c rune) bool {
 	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_' || unicode.IsLetter(c)
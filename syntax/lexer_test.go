@@ -2,7 +2,10 @@ package syntax
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
+	"unsafe"
 )
 
 func TestNextToken(t *testing.T) {
@@ -14,7 +17,7 @@ let add = fn(x, y) {
 };
 
 let result = add(five, ten);
-!-/*5;
+!-/ *5;
 5 < 10 > 5;
 
 if (5 < 10) {
@@ -147,3 +150,196 @@ if (5 < 10) {
 		// }
 	}
 }
+
+func TestStringEscapes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a\nb"`, "a\nb"},
+		{`"a\tb\r"`, "a\tb\r"},
+		{`"say \"hi\""`, `say "hi"`},
+		{`"C:\\dir"`, `C:\dir`},
+		{`"\u4f60\u597D"`, "你好"},
+	}
+	for _, tt := range tests {
+		tok := NewLexer(tt.input).NextToken()
+		if tok.Type != STRING || tok.Literal != tt.expected {
+			t.Errorf("%s: expected STRING %q. got=%s %q", tt.input, tt.expected, tok.Type, tok.Literal)
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{`let s = "abc`, ":1:9 unterminated string literal"},
+		{`"abc\`, ":1:1 unterminated string literal"},
+		{`"a\qb"`, ":1:3 unknown escape sequence \\q"},
+		{`"\u12g4"`, ":1:2 invalid \\u escape"},
+	}
+	for _, tt := range errors {
+		_, err := NewParser(tt.input).Parse()
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected error %q. got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	input := "foo_1 名字x\nlet _a9 len"
+	expected := []struct {
+		typ     Token
+		literal string
+		pos     string
+	}{
+		{IDENT, "foo_1", "1:1"},
+		{IDENT, "名字x", "1:7"},
+		{LET, "let", "2:1"},
+		{IDENT, "_a9", "2:5"},
+		{IDENT, "len", "2:9"},
+		{EOF, "", "2:12"},
+	}
+	l := NewLexer(input)
+	for i, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt.typ || tok.Literal != tt.literal {
+			t.Fatalf("tokens[%d]: expected %s %q. got=%s %q", i, tt.typ, tt.literal, tok.Type, tok.Literal)
+		}
+		if pos := fmt.Sprintf("%d:%d", tok.pos.Line, tok.pos.Col); pos != tt.pos {
+			t.Errorf("tokens[%d]: expected position %s. got=%s", i, tt.pos, pos)
+		}
+	}
+
+	// 关键字和常见的标识符使用驻留的字符串，不引用源码
+	l = NewLexer(strings.Repeat(" ", 10) + "len")
+	if tok := l.NextToken(); unsafe.StringData(tok.Literal) != unsafe.StringData(identTable["len"]) {
+		t.Errorf("expected len to be interned")
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// header
+let a = 1; // trailing
+/* block
+   comment */ let b = a / 2;
+/**/`
+	program, err := NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("parser error: %s", err)
+	}
+	if got := program.String(); got != "let a = 1;let b = (a / 2);" {
+		t.Errorf("program wrong. got=%q", got)
+	}
+
+	expected := []struct {
+		text string
+		pos  string
+	}{
+		{"// header", "1:1"},
+		{"// trailing", "2:12"},
+		{"/* block\n   comment */", "3:1"},
+		{"/**/", "5:1"},
+	}
+	if len(program.Comments) != len(expected) {
+		t.Fatalf("expected %d comments. got=%d", len(expected), len(program.Comments))
+	}
+	for i, tt := range expected {
+		c := program.Comments[i]
+		if c.Text != tt.text {
+			t.Errorf("comments[%d] text wrong. expected=%q, got=%q", i, tt.text, c.Text)
+		}
+		if pos := fmt.Sprintf("%d:%d", c.Pos.Line, c.Pos.Col); pos != tt.pos {
+			t.Errorf("comments[%d] position wrong. expected=%s, got=%s", i, tt.pos, pos)
+		}
+	}
+
+	_, err = NewParser("let a = 1; /* open").Parse()
+	if err == nil || !strings.Contains(err.Error(), ":1:12 unterminated comment") {
+		t.Errorf("expected unterminated comment error. got=%v", err)
+	}
+}
+
+// 约 1MB 的源码，包含常见的关键字、标识符、数字、字符串和注释
+var benchmarkSource = strings.Repeat(`// 计算斐波那契数列
+let fibonacci = fn(n) {
+	if (n < 2) { return n; }
+	fibonacci(n - 1) + fibonacci(n - 2)
+};
+let result = map([1, 2, 3, 4.5], fn(x) { x * 2 });
+let config = {"name": "monkey", "version": 1, "enabled": true};
+export let 名字 = async fn(path, options...) { await load(path, options...) };
+`, 3000)
+
+func BenchmarkLexer(b *testing.B) {
+	b.SetBytes(int64(len(benchmarkSource)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l := NewLexer(benchmarkSource)
+		for tok := l.NextToken(); tok.Type != EOF; tok = l.NextToken() {
+		}
+	}
+}
+
+func BenchmarkParser(b *testing.B) {
+	b.SetBytes(int64(len(benchmarkSource)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewParser(benchmarkSource).Parse(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	input := "// header\nlet s = \"a\\tb\"; /* x */\n\n  s  // end\n"
+	l := NewLexer(input)
+	l.KeepTrivia = true
+	tokens, err := l.Tokenize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type token struct {
+		typ      Token
+		pos, end string
+		leading  []Trivia
+	}
+	at := func(line, col int32) Position { return MakePosition(nil, line, col) }
+	expected := []token{
+		{LET, "2:1", "2:4", []Trivia{{at(1, 1), "// header", true}, {at(1, 10), "\n", false}}},
+		{IDENT, "2:5", "2:6", []Trivia{{at(2, 4), " ", false}}},
+		{ASSIGN, "2:7", "2:8", []Trivia{{at(2, 6), " ", false}}},
+		{STRING, "2:9", "2:15", []Trivia{{at(2, 8), " ", false}}},
+		{SEMICOLON, "2:15", "2:16", nil},
+		{IDENT, "4:3", "4:4", []Trivia{{at(2, 16), " ", false}, {at(2, 17), "/* x */", true}, {at(2, 24), "\n\n  ", false}}},
+		{EOF, "5:1", "5:1", []Trivia{{at(4, 4), "  ", false}, {at(4, 6), "// end", true}, {at(4, 12), "\n", false}}},
+	}
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens, got %d: %v", len(expected), len(tokens), tokens)
+	}
+	for i, tt := range expected {
+		tok := tokens[i]
+		pos := fmt.Sprintf("%d:%d", tok.Pos().Line, tok.Pos().Col)
+		end := fmt.Sprintf("%d:%d", tok.End().Line, tok.End().Col)
+		if tok.Type != tt.typ || pos != tt.pos || end != tt.end {
+			t.Errorf("tokens[%d]: expected %s %s-%s, got %s %s-%s", i, tt.typ, tt.pos, tt.end, tok.Type, pos, end)
+		}
+		if !reflect.DeepEqual(tok.Leading, tt.leading) {
+			t.Errorf("tokens[%d]: expected leading %v, got %v", i, tt.leading, tok.Leading)
+		}
+	}
+
+	// 默认不记录空白和注释，非法字符是 ILLEGAL 词法单元
+	tokens, err = NewLexer("a @ b").Tokenize()
+	if len(tokens) != 4 || tokens[1].Type != ILLEGAL || tokens[0].Leading != nil || tokens[2].Leading != nil {
+		t.Errorf("unexpected tokens %v", tokens)
+	}
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	_, err = NewLexer(`"abc`).Tokenize()
+	if !IsIncomplete(err) {
+		t.Errorf("expected an incomplete error, got %v", err)
+	}
+}
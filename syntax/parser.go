@@ -1,7 +1,11 @@
+// Package syntax 实现 Monkey 的词法分析、语法分析和语法树，不依赖解释器，
+// 编辑器、格式化和静态检查等只需要源码的工具可以单独使用它。执行脚本见 interp 包。
 package syntax
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"strconv"
 )
 
@@ -9,27 +13,45 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	LOGICAL_OR   // ||
+	LOGICAL_AND  // &&
 	EQUALS       // ==
 	LESS_GREATER // > or <
 	SUM          // +
 	PRODUCT      // *
 	PREFIX       // -X or !X
+	POWER        // **
 	CALL         // myFunction(X)
 	INDEX        // a[i]
 )
 
 // 运算符对应的优先级
 var precedence = map[Token]int{
+	OR:       LOGICAL_OR,
+	AND:      LOGICAL_AND,
 	EQ:       EQUALS,
 	NE:       EQUALS,
 	LT:       LESS_GREATER,
+	LE:       LESS_GREATER,
 	GT:       LESS_GREATER,
+	GE:       LESS_GREATER,
 	PLUS:     SUM,
 	MINUS:    SUM,
 	SLASH:    PRODUCT,
 	STAR:     PRODUCT,
+	PERCENT:  PRODUCT,
+	STARSTAR: POWER,
 	LPAREN:   CALL,
 	LBRACKET: INDEX,
+	DOT:      INDEX,
+}
+
+// Precedence 返回二元运算符 op 的优先级，数值越大结合得越紧，op 不是二元运算符时返回 LOWEST
+func Precedence(op Token) int {
+	if val, ok := precedence[op]; ok {
+		return val
+	}
+	return LOWEST
 }
 
 // 用于实现普拉特语法分析器
@@ -38,11 +60,27 @@ type (
 	infixParseFn  func(Expr) Expr
 )
 
+// 表达式默认的最大嵌套深度
+const DefaultMaxDepth = 1000
+
 type Parser struct {
 	l *Lexer
 
+	// MaxDepth 是表达式的最大嵌套深度，超过时报告语法错误而不是耗尽 Go 的栈，
+	// 为 0 时使用 DefaultMaxDepth。每一层括号、数组、map、前缀运算符或函数体都算一层，
+	// a + b + c 这样的链中每一个运算符也算一层，因此生成的语法树的深度不超过 MaxDepth 的两倍
+	MaxDepth int
+	depth    int // 当前的嵌套深度
+
+	// Options 是启用的可选语言特性，需要在开始解析之前设置
+	Options LanguageOptions
+
 	// pos    Position
-	curTok TokenValue
+	curTok   TokenValue
+	end      Position  // 上一个被消耗的词法单元的结束位置，用于节点的 Span
+	errors   ErrorList // 已经遇到的语法错误
+	started  bool      // Next 是否已经读取了第一个词法单元
+	closures int       // 已经解析的函数和宏字面量的个数，用于判断函数体中是否创建闭包
 
 	prefixParseFns map[Token]prefixParseFn
 	infixParseFns  map[Token]infixParseFn
@@ -57,15 +95,26 @@ func (p *Parser) registerInfixFn(tokenType Token, fn infixParseFn) {
 }
 
 func (p *Parser) noPrefixParseFnError(t Token) {
-	panic(fmt.Errorf(
-		`no prefix parse function for "%s" found`,
-		t,
-	))
+	panic(p.unexpected(fmt.Sprintf(`no prefix parse function for "%s" found`, t)))
+}
+
+// 返回当前词法单元处的语法错误，当前词法单元是 EOF 时错误是 Incomplete 的
+func (p *Parser) unexpected(msg string) *Error {
+	if p.curTok.Type == EOF {
+		return newIncompleteError(p.curTok.pos, msg)
+	}
+	return NewError(p.curTok.pos, msg)
 }
 
 func (p *Parser) nextToken() Position {
 	pos := p.curTok.pos
+	p.end = p.l.pos // 词法分析器停在当前词法单元之后，之后的空白和注释在读取下一个词法单元时才跳过
+	p.l.Options = p.Options
 	p.curTok = p.l.NextToken()
+	if len(p.l.errors) > 0 {
+		p.errors = append(p.errors, p.l.errors...)
+		p.l.errors = nil
+	}
 	return pos
 }
 
@@ -76,15 +125,7 @@ func (p *Parser) curTokenIs(t Token) bool {
 // 断言当前 token 是否为 t
 func (p *Parser) expect(t Token) {
 	if !p.curTokenIs(t) {
-		panic(
-			NewError(
-				p.curTok.pos,
-				fmt.Sprintf(
-					`expected next token to be "%s", got "%s" instead`,
-					t, p.curTok,
-				),
-			),
-		)
+		panic(p.unexpected(fmt.Sprintf(`expected next token to be "%s", got "%s" instead`, t, p.curTok)))
 	}
 }
 
@@ -93,15 +134,7 @@ func (p *Parser) consume(t Token) Position {
 	if p.curTok.Type == t {
 		return p.nextToken()
 	}
-	panic(
-		NewError(
-			p.curTok.pos,
-			fmt.Sprintf(
-				`expected next token to be "%s", got "%s" instead`,
-				t, p.curTok,
-			),
-		),
-	)
+	panic(p.unexpected(fmt.Sprintf(`expected next token to be "%s", got "%s" instead`, t, p.curTok)))
 }
 
 // func (p *Parser) peekPrecedence() int {
@@ -118,26 +151,122 @@ func (p *Parser) curPrecedence() int {
 	return LOWEST
 }
 
+// Parse 解析整个程序。遇到语法错误时不会立即停止，而是跳到下一条语句继续解析，
+// 返回的错误是包含所有语法错误的 ErrorList，此时 Program 只包含解析成功的语句。
 func (p *Parser) Parse() (_ *Program, err error) {
 	defer p.l.recover(&err)
 
-	program := &Program{}
+	program := &Program{start: p.l.pos}
 	program.Stmts = make([]Stmt, 0)
 
+	// 读取第一个词法单元，放在这里是为了让读取输入的错误也能被 recover 捕获
+	p.nextToken()
+
 	for p.curTok.Type != EOF {
-		stmt := p.parseStmt()
-		program.Stmts = append(program.Stmts, stmt)
+		if stmt := p.parseStmtRecover(); stmt != nil {
+			program.Stmts = append(program.Stmts, stmt)
+		}
 	}
+	program.end = p.l.pos
+	program.Comments = p.l.comments
 
+	if len(p.errors) > 0 {
+		return program, p.errors
+	}
 	return program, nil
 }
 
+// Next 解析并返回下一条语句，没有更多语句时返回 io.EOF，出现语法错误时返回该语句的 ErrorList。
+// 与 Parse 不同，Next 不保留已经解析的语句和注释，因此可以逐条处理任意大的输入。
+// 解析器需要读到下一条语句的第一个词法单元才能确定当前语句已经结束。
+func (p *Parser) Next() (stmt Stmt, err error) {
+	defer p.l.recover(&err)
+
+	if !p.started {
+		p.started = true
+		p.nextToken()
+	}
+	for p.curTok.Type != EOF && stmt == nil && len(p.errors) == 0 {
+		stmt = p.parseStmtRecover()
+		p.l.comments = nil
+	}
+	if len(p.errors) > 0 {
+		errs := p.errors
+		p.errors = nil
+		return nil, errs
+	}
+	if stmt == nil {
+		return nil, io.EOF
+	}
+	return stmt, nil
+}
+
+// 解析一条语句，出现语法错误时记录错误并跳到下一条语句的开始，返回 nil
+func (p *Parser) parseStmtRecover() (stmt Stmt) {
+	start := p.curTok.pos
+	if p.recoverStmt(func() { stmt = p.parseStmt() }) {
+		return stmt
+	}
+	// 至少前进一个词法单元，避免在同一个位置反复出错
+	if p.curTok.pos == start && !p.curTokenIs(EOF) {
+		p.nextToken()
+	}
+	p.synchronize()
+	return nil
+}
+
+// 执行 fn，如果 fn 因为语法错误而 panic，记录该错误并返回 false。其他 panic 继续向上传递。
+func (p *Parser) recoverStmt(fn func()) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, isSyntaxError := r.(*Error)
+			if !isSyntaxError {
+				panic(r)
+			}
+			p.errors = append(p.errors, e)
+		}
+	}()
+	fn()
+	return true
+}
+
+// 跳过词法单元，直到分号之后，或下一个语句关键字、右花括号、EOF 之前
+func (p *Parser) synchronize() {
+	for {
+		switch p.curTok.Type {
+		case EOF, RBRACE, LET, RETURN, EXPORT, IMPORT, FROM, RECORD:
+			return
+		case SEMICOLON:
+			p.nextToken()
+			return
+		}
+		p.nextToken()
+	}
+}
+
 func (p *Parser) parseStmt() Stmt {
 	switch p.curTok.Type {
 	case LET:
 		return p.parseLetStmt()
 	case RETURN:
 		return p.parseReturnStmt()
+	case EXPORT:
+		pos := p.nextToken() // 消耗 export
+		p.expect(LET)
+		return &ExportStmt{Pos: pos, Let: p.parseLetStmt()}
+	case IMPORT:
+		return p.parseImportStmt()
+	case FROM:
+		return p.parseFromImportStmt()
+	case RECORD:
+		return p.parseRecordStmt()
+	case FUNCTION, ASYNC:
+		// 整个语句是一个具名函数时，它是函数声明
+		stmt := p.parseExprStmt()
+		if fn, ok := stmt.Expr.(*FunctionLiteral); ok && fn.Name != nil {
+			return &FunctionStmt{Fn: fn}
+		}
+		return stmt
 	default:
 		return p.parseExprStmt()
 	}
@@ -161,6 +290,37 @@ func (p *Parser) parseLetStmt() *LetStmt {
 	return stmt
 }
 
+// record Point {x, y}
+func (p *Parser) parseRecordStmt() *RecordStmt {
+	pos := p.nextToken() // 消耗 record
+	stmt := &RecordStmt{Pos: pos}
+
+	stmt.Name = &Identifier{Value: p.curTok.Literal}
+	stmt.Name.Pos = p.consume(IDENT)
+
+	p.consume(LBRACE)
+	seen := make(map[string]bool)
+	for !p.curTokenIs(RBRACE) {
+		field := &Identifier{Value: p.curTok.Literal}
+		field.Pos = p.consume(IDENT)
+		if seen[field.Value] {
+			panic(NewError(field.Pos, fmt.Sprintf("duplicate field %s in record %s", field.Value, stmt.Name.Value)))
+		}
+		seen[field.Value] = true
+		stmt.Fields = append(stmt.Fields, field)
+		// 允许在最后一个字段后面加逗号
+		if !p.curTokenIs(RBRACE) {
+			p.consume(COMMA)
+		}
+	}
+	p.consume(RBRACE)
+	stmt.end = p.end
+	if p.curTokenIs(SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
 func (p *Parser) parseReturnStmt() *ReturnStmt {
 	pos := p.nextToken()
 	stmt := &ReturnStmt{
@@ -188,7 +348,55 @@ func (p *Parser) parseExprStmt() *ExprStmt {
 	return stmt
 }
 
+// import("path") [as name]
+func (p *Parser) parseImportStmt() Stmt {
+	stmt := p.parseExprStmt()
+	imp, ok := stmt.Expr.(*ImportExpr)
+	if !ok || !p.curTokenIs(AS) {
+		return stmt
+	}
+	p.nextToken() // 消耗 as
+	alias := &Identifier{Value: p.curTok.Literal}
+	alias.Pos = p.consume(IDENT)
+	if p.curTokenIs(SEMICOLON) {
+		p.nextToken()
+	}
+	return &ImportStmt{Import: imp, Alias: alias}
+}
+
+// from "path" import (a, b)
+func (p *Parser) parseFromImportStmt() Stmt {
+	pos := p.nextToken() // 消耗 from
+	stmt := &FromImportStmt{pos: pos}
+	p.expect(STRING)
+	stmt.Path = p.parseStringLiteral()
+	p.consume(IMPORT)
+	p.consume(LPAREN)
+	for !p.curTokenIs(RPAREN) {
+		name := &Identifier{Value: p.curTok.Literal}
+		name.Pos = p.consume(IDENT)
+		stmt.Names = append(stmt.Names, name)
+		if !p.curTokenIs(RPAREN) {
+			p.consume(COMMA)
+		}
+	}
+	rparen := p.consume(RPAREN)
+	stmt.end = p.end
+	if len(stmt.Names) == 0 {
+		panic(NewError(rparen, "empty import list"))
+	}
+	if p.curTokenIs(SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
 func (p *Parser) parseExpr(precedence int) Expr {
+	// 出错时 panic 会经过这里，defer 保证深度在恢复之后仍然正确
+	depth := p.depth
+	defer func() { p.depth = depth }()
+	p.enter()
+
 	prefix := p.prefixParseFns[p.curTok.Type]
 	if prefix == nil {
 		p.noPrefixParseFnError(p.curTok.Type)
@@ -202,12 +410,26 @@ func (p *Parser) parseExpr(precedence int) Expr {
 		if infix == nil {
 			return leftExp
 		}
+		// a + b + c 和 f()()() 这样的链会生成向左加深的语法树，链中的每一环也算一层
+		p.enter()
 		leftExp = infix(leftExp)
 	}
 
 	return leftExp
 }
 
+// 进入一层嵌套，超过最大深度时报告语法错误
+func (p *Parser) enter() {
+	maxDepth := p.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = DefaultMaxDepth
+	}
+	if p.depth >= maxDepth {
+		panic(NewError(p.curTok.pos, fmt.Sprintf("expression too deeply nested (max depth %d)", maxDepth)))
+	}
+	p.depth++
+}
+
 func (p *Parser) parseIdentifier() Expr {
 	value := p.curTok.Literal
 	pos := p.nextToken()
@@ -220,23 +442,32 @@ func (p *Parser) parseIntegerLiteral() Expr {
 	expr := &IntegerLiteral{Raw: raw, Pos: pos}
 	value, err := strconv.ParseInt(raw, 0, 64)
 	if err != nil {
-		panic(fmt.Errorf("could not parse %q as integer", p.curTok.Literal))
+		panic(NewError(pos, fmt.Sprintf("could not parse %q as integer", raw)))
 	}
 	expr.Value = value
 	return expr
 }
 
+func (p *Parser) parseFloatLiteral() Expr {
+	raw := p.curTok.Literal
+	pos := p.nextToken()
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		panic(NewError(pos, fmt.Sprintf("could not parse %q as float", raw)))
+	}
+	return &FloatLiteral{Raw: raw, Pos: pos, Value: value}
+}
+
 func (p *Parser) parseStringLiteral() Expr {
 	val := p.curTok.Literal
 	pos := p.nextToken()
-	return &StringLiteral{pos: pos, Value: val}
+	return &StringLiteral{pos: pos, end: p.end, Value: val}
 }
 
 func (p *Parser) parseBoolean() Expr {
-	raw := p.curTok.Literal
 	val := p.curTokenIs(TRUE)
 	pos := p.nextToken()
-	return &Boolean{pos: pos, raw: raw, Value: val}
+	return &Boolean{pos: pos, Value: val}
 }
 
 func (p *Parser) parsePrefixExpr() Expr {
@@ -256,7 +487,10 @@ func (p *Parser) parseInfixExpr(left Expr) Expr {
 		Left: left,
 	}
 	precedence := p.curPrecedence()
-	p.nextToken() // 消耗运算符
+	if expr.Op == STARSTAR {
+		precedence-- // ** 是右结合的：2 ** 3 ** 2 == 2 ** (3 ** 2)
+	}
+	expr.OpPos = p.nextToken() // 消耗运算符
 	expr.Right = p.parseExpr(precedence)
 
 	return expr
@@ -273,22 +507,29 @@ func (p *Parser) parseArrayLiteral() Expr {
 	start := p.nextToken()
 	expr := &ArrayLiteral{start: start}
 	expr.Items = p.parseExprList(RBRACKET)
-	end := p.consume(RBRACKET)
-	expr.end = end
+	p.consume(RBRACKET)
+	expr.end = p.end
 	return expr
 }
 
 func (p *Parser) parseMapLiteral() Expr {
 	start := p.nextToken()
 	expr := &MapLiteral{start: start}
-	expr.Pairs = make(map[Expr]Expr)
+	seen := make(map[string]bool) // 已经出现的常量键
 
 	// 检测到右括号，结束循环
 	for !p.curTokenIs(RBRACE) {
 		key := p.parseExpr(LOWEST) // 解析 Key
+		if k, ok := constantKey(key); ok {
+			if seen[k] {
+				pos, _ := key.Span()
+				panic(NewError(pos, fmt.Sprintf("duplicate key %s in map literal", k)))
+			}
+			seen[k] = true
+		}
 		p.consume(COLON)           // 解析冒号
 		val := p.parseExpr(LOWEST) // 解析 Value
-		expr.Pairs[key] = val
+		expr.Pairs = append(expr.Pairs, MapPair{Key: key, Value: val})
 
 		// 如果下一个字符不是右括号，并且不是逗号，则结束循环
 		if !p.curTokenIs(RBRACE) {
@@ -296,12 +537,31 @@ func (p *Parser) parseMapLiteral() Expr {
 		}
 	}
 
-	end := p.consume(RBRACE)
-	expr.end = end
+	p.consume(RBRACE)
+	expr.end = p.end
 
 	return expr
 }
 
+// 如果 map 的键 expr 是字面量，返回它作为键时的表示，值相同的键表示也相同
+func constantKey(expr Expr) (string, bool) {
+	switch expr := expr.(type) {
+	case *StringLiteral:
+		return strconv.Quote(expr.Value), true
+	case *IntegerLiteral:
+		return strconv.FormatInt(expr.Value, 10), true
+	case *FloatLiteral:
+		// 与整数相等的浮点数和该整数是同一个键
+		if i := int64(expr.Value); float64(i) == expr.Value {
+			return strconv.FormatInt(i, 10), true
+		}
+		return strconv.FormatFloat(expr.Value, 'g', -1, 64), true
+	case *Boolean:
+		return strconv.FormatBool(expr.Value), true
+	}
+	return "", false
+}
+
 // 读取参数列表，知道遇到 end token，但是不消耗 end token
 func (p *Parser) parseExprList(end Token) []Expr {
 	exprs := make([]Expr, 0)
@@ -321,11 +581,12 @@ func (p *Parser) parseBlockStmt() *BlockStmt {
 	block := &BlockStmt{start: start}
 	block.Stmts = make([]Stmt, 0)
 	for !p.curTokenIs(RBRACE) && !p.curTokenIs(EOF) {
-		stmt := p.parseStmt()
-		block.Stmts = append(block.Stmts, stmt)
+		if stmt := p.parseStmtRecover(); stmt != nil {
+			block.Stmts = append(block.Stmts, stmt)
+		}
 	}
-	end := p.consume(RBRACE)
-	block.end = end
+	p.consume(RBRACE)
+	block.end = p.end
 	return block
 }
 
@@ -349,57 +610,188 @@ func (p *Parser) parseIfExpr() Expr {
 	return expr
 }
 
-func (p *Parser) parseFunctionParams() []*Identifier {
+// 解析参数列表，最后一个参数后面有 ... 时 variadic 为 true
+func (p *Parser) parseFunctionParams() (_ []*Identifier, variadic bool) {
 	identifiers := make([]*Identifier, 0)
 	p.nextToken() // 消耗左括号
 	// 如果是右括号，直接返回
 	if p.curTokenIs(RPAREN) {
 		p.consume(RPAREN)
-		return identifiers
+		return identifiers, false
 	}
+	p.expect(IDENT)
 	val := p.curTok.Literal
 	pos := p.nextToken()
 	identifier := &Identifier{Pos: pos, Value: val}
 	identifiers = append(identifiers, identifier)
 	for p.curTokenIs(COMMA) {
 		p.consume(COMMA)
+		p.expect(IDENT)
 		val := p.curTok.Literal
 		pos := p.nextToken()
 		identifier := &Identifier{Pos: pos, Value: val}
 		identifiers = append(identifiers, identifier)
 	}
+	// 只有最后一个参数可以是剩余参数，... 后面必须是右括号
+	if p.curTokenIs(ELLIPSIS) {
+		p.consume(ELLIPSIS)
+		variadic = true
+	}
 	p.consume(RPAREN)
-	return identifiers
+	return identifiers, variadic
 }
 
 func (p *Parser) parseFunctionLiteral() Expr {
 	pos := p.nextToken()
 	expr := &FunctionLiteral{pos: pos}
+	if p.curTokenIs(IDENT) {
+		expr.Name = &Identifier{Value: p.curTok.Literal}
+		expr.Name.Pos = p.nextToken()
+	}
+	p.expect(LPAREN)
+	expr.Params, expr.Variadic = p.parseFunctionParams()
+	p.expect(LBRACE)
+	closures := p.closures
+	expr.Body = p.parseBlockStmt()
+	expr.NoClosures = p.closures == closures
+	p.closures++
+	return expr
+}
+
+func (p *Parser) parseAsyncFunctionLiteral() Expr {
+	pos := p.nextToken() // 消耗 async
+	p.expect(FUNCTION)
+	expr := p.parseFunctionLiteral().(*FunctionLiteral)
+	expr.pos = pos
+	expr.Async = true
+	return expr
+}
+
+func (p *Parser) parseAwaitExpr() Expr {
+	pos := p.nextToken() // 消耗 await
+	return &AwaitExpr{Pos: pos, Value: p.parseExpr(PREFIX)}
+}
+
+func (p *Parser) parseMacroLiteral() Expr {
+	pos := p.nextToken()
+	expr := &MacroLiteral{pos: pos}
 	p.expect(LPAREN)
-	expr.Params = p.parseFunctionParams()
+	var variadic bool
+	if expr.Params, variadic = p.parseFunctionParams(); variadic {
+		panic(NewError(pos, "macro parameters cannot be variadic"))
+	}
 	p.expect(LBRACE)
 	expr.Body = p.parseBlockStmt()
+	p.closures++
 	return expr
 }
 
 func (p *Parser) parseCallExpr(function Expr) Expr {
 	start := p.consume(LPAREN)
 	expr := &CallExpr{start: start, Function: function}
-	expr.Args = p.parseExprList(RPAREN)
-	end := p.consume(RPAREN)
-	expr.end = end
+	expr.Args = make([]Expr, 0)
+	if !p.curTokenIs(RPAREN) {
+		expr.Args = append(expr.Args, p.parseCallArg())
+		for p.curTokenIs(COMMA) {
+			p.consume(COMMA)
+			expr.Args = append(expr.Args, p.parseCallArg())
+		}
+	}
+	p.consume(RPAREN)
+	expr.end = p.end
 	return expr
 }
 
+// 解析调用的一个实参，后面有 ... 时展开为多个实参
+func (p *Parser) parseCallArg() Expr {
+	arg := p.parseExpr(LOWEST)
+	if p.curTokenIs(ELLIPSIS) {
+		p.consume(ELLIPSIS)
+		return &SpreadExpr{Value: arg, end: p.end}
+	}
+	return arg
+}
+
+func (p *Parser) parseImportExpr() Expr {
+	pos := p.nextToken() // 消耗 import
+	expr := &ImportExpr{pos: pos}
+	p.consume(LPAREN)
+	expr.Path = p.parseExpr(LOWEST)
+	p.consume(RPAREN)
+	expr.end = p.end
+	return expr
+}
+
+// x.name
+func (p *Parser) parseDotExpr(left Expr) Expr {
+	p.consume(DOT)
+	name := &Identifier{Value: p.curTok.Literal}
+	name.Pos = p.consume(IDENT)
+	return &DotExpr{Left: left, Name: name}
+}
+
+// a[i]，或者切片 a[i:j]、a[:j]、a[i:]、a[:]
 func (p *Parser) parseIndexExpr(left Expr) Expr {
 	p.consume(LBRACKET)
-	indexExpr := &IndexExpr{Left: left}
-	indexExpr.Index = p.parseExpr(LOWEST)
-	end := p.consume(RBRACKET)
-	indexExpr.end = end
+	var index Expr
+	if !p.curTokenIs(COLON) {
+		index = p.parseExpr(LOWEST)
+	}
+	if p.curTokenIs(COLON) {
+		p.consume(COLON)
+		sliceExpr := &SliceExpr{Left: left, Start: index}
+		if !p.curTokenIs(RBRACKET) {
+			sliceExpr.End = p.parseExpr(LOWEST)
+		}
+		p.consume(RBRACKET)
+		sliceExpr.end = p.end
+		return sliceExpr
+	}
+	indexExpr := &IndexExpr{Left: left, Index: index}
+	p.consume(RBRACKET)
+	indexExpr.end = p.end
 	return indexExpr
 }
 
+// NewFileParser 返回一个解析 input 的 Parser，节点的位置带有文件名 filename
+func NewFileParser(filename, input string) *Parser {
+	p := NewParser(input)
+	p.l.pos = MakePosition(&filename, 1, 1)
+	return p
+}
+
+// NewFileParserAt 与 NewFileParser 相同，但 input 从文件的第 line 行开始，
+// 用于解析嵌在其他文件中的源码（例如 Markdown 中的代码块），位置中的行号是在整个文件中的行号
+func NewFileParserAt(filename string, line int32, input string) *Parser {
+	p := NewParser(input)
+	p.l.pos = MakePosition(&filename, line, 1)
+	return p
+}
+
+// NewParserReader 返回从 r 中逐行读取源码的解析器，filename 用于错误的位置。
+// 配合 Next 使用时，任何时候只有当前的一行在内存中。读取 r 的错误由 Parse 或 Next 返回。
+func NewParserReader(filename string, r io.Reader) *Parser {
+	p := NewFileParser(filename, "")
+	br := bufio.NewReader(r)
+	var readErr error // 与最后一行一起读到的错误，在这一行被处理之后再返回
+	p.l.readline = func() (string, error) {
+		if readErr != nil {
+			return "", readErr
+		}
+		line, err := br.ReadString('\n')
+		switch {
+		case err == io.EOF:
+			// 返回空字符串表示输入结束
+			return line, nil
+		case err != nil && line != "":
+			readErr = err
+			return line, nil
+		}
+		return line, err
+	}
+	return p
+}
+
 func NewParser(input string) *Parser {
 	p := &Parser{
 		l:              NewLexer(input),
@@ -409,6 +801,7 @@ func NewParser(input string) *Parser {
 	// 注册前缀解析函数
 	p.registerPrefixFn(IDENT, p.parseIdentifier)
 	p.registerPrefixFn(INT, p.parseIntegerLiteral)
+	p.registerPrefixFn(FLOAT, p.parseFloatLiteral)
 	p.registerPrefixFn(TRUE, p.parseBoolean)
 	p.registerPrefixFn(FALSE, p.parseBoolean)
 	p.registerPrefixFn(BANG, p.parsePrefixExpr)
@@ -420,21 +813,29 @@ func NewParser(input string) *Parser {
 	p.registerPrefixFn(IF, p.parseIfExpr)
 	p.registerPrefixFn(FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefixFn(STRING, p.parseStringLiteral)
+	p.registerPrefixFn(IMPORT, p.parseImportExpr)
+	p.registerPrefixFn(MACRO, p.parseMacroLiteral)
+	p.registerPrefixFn(ASYNC, p.parseAsyncFunctionLiteral)
+	p.registerPrefixFn(AWAIT, p.parseAwaitExpr)
 
 	// 注册中缀解析函数
 	p.registerInfixFn(PLUS, p.parseInfixExpr)
 	p.registerInfixFn(MINUS, p.parseInfixExpr)
 	p.registerInfixFn(STAR, p.parseInfixExpr)
 	p.registerInfixFn(SLASH, p.parseInfixExpr)
+	p.registerInfixFn(PERCENT, p.parseInfixExpr)
+	p.registerInfixFn(STARSTAR, p.parseInfixExpr)
 	p.registerInfixFn(EQ, p.parseInfixExpr)
 	p.registerInfixFn(NE, p.parseInfixExpr)
 	p.registerInfixFn(LT, p.parseInfixExpr)
 	p.registerInfixFn(GT, p.parseInfixExpr)
+	p.registerInfixFn(LE, p.parseInfixExpr)
+	p.registerInfixFn(GE, p.parseInfixExpr)
+	p.registerInfixFn(AND, p.parseInfixExpr)
+	p.registerInfixFn(OR, p.parseInfixExpr)
 	p.registerInfixFn(LPAREN, p.parseCallExpr)
 	p.registerInfixFn(LBRACKET, p.parseIndexExpr)
-
-	// read twice to set curTok and peekTok
-	p.nextToken()
+	p.registerInfixFn(DOT, p.parseDotExpr)
 
 	return p
 }
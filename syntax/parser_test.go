@@ -1,7 +1,10 @@
 package syntax
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"strings"
 	"testing"
 )
 
@@ -241,6 +244,34 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"-a * b",
 			"((-a) * b)",
 		},
+		{
+			"a + b % c",
+			"(a + (b % c))",
+		},
+		{
+			"a * b ** c",
+			"(a * (b ** c))",
+		},
+		{
+			"a ** b ** c",
+			"(a ** (b ** c))",
+		},
+		{
+			"-a ** b",
+			"(-(a ** b))",
+		},
+		{
+			"a ** -b",
+			"(a ** (-b))",
+		},
+		{
+			"a || b && c == d",
+			"(a || (b && (c == d)))",
+		},
+		{
+			"!a && b || c",
+			"(((!a) && b) || c)",
+		},
 		// {
 		// 	"!-a",
 		// 	"(!(-a))",
@@ -360,6 +391,24 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 	}
 }
 
+func TestWordOperators(t *testing.T) {
+	input := "not a and b or c"
+	p := NewParser(input)
+	p.Options = LanguageOptions{WordOperators: true}
+	program, err := p.Parse()
+	checkParserErrors(t, err)
+	if got, want := program.String(), "(((!a) && b) || c)"; got != want {
+		t.Errorf("expected=%q, got=%q", want, got)
+	}
+
+	// 默认不开启，and、or 和 not 仍然是普通的标识符
+	program, err = NewParser("let and = 1; not").Parse()
+	checkParserErrors(t, err)
+	if got, want := program.String(), "let and = 1;not"; got != want {
+		t.Errorf("expected=%q, got=%q", want, got)
+	}
+}
+
 func TestBooleanExpr(t *testing.T) {
 	tests := []struct {
 		input           string
@@ -761,6 +810,76 @@ func TestParsingIndexExprs(t *testing.T) {
 	}
 }
 
+func TestParsingSliceExprs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a[1:3]", "a[1:3]"},
+		{"a[:2]", "a[:2]"},
+		{"a[2:]", "a[2:]"},
+		{"a[:]", "a[:]"},
+		{"a[-2:n + 1]", "a[(-2):(n + 1)]"},
+		{"a[1:][0]", "a[1:][0]"},
+	}
+	for _, tt := range tests {
+		program, err := NewParser(tt.input).Parse()
+		checkParserErrors(t, err)
+		if got := program.String(); got != tt.expected {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	program, err := NewParser("a[1:]").Parse()
+	checkParserErrors(t, err)
+	slice, ok := program.Stmts[0].(*ExprStmt).Expr.(*SliceExpr)
+	if !ok {
+		t.Fatalf("exp not *SliceExpr. got=%T", program.Stmts[0].(*ExprStmt).Expr)
+	}
+	if !testIntegerLiteral(t, slice.Start, 1) {
+		return
+	}
+	if slice.End != nil {
+		t.Errorf("slice.End is not nil. got=%s", slice.End)
+	}
+}
+
+func TestParsingMapLiteralDuplicateKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"a": 1, "a": 2}`, `1:10 duplicate key "a" in map literal`},
+		{`{1: 1, 2: 2, 1: 3}`, "1:14 duplicate key 1 in map literal"},
+		{`{1: 1, 1.0: 2}`, "1:8 duplicate key 1 in map literal"},
+		{`{true: 1, true: 2}`, "duplicate key true in map literal"},
+		{"{\n  \"x\": 1,\n  \"x\": 2\n}", `3:3 duplicate key "x" in map literal`},
+	}
+	for _, tt := range tests {
+		_, err := NewParser(tt.input).Parse()
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected error %q. got=%v", tt.input, tt.expected, err)
+		}
+	}
+
+	// 不是字面量的键只能在运行时比较
+	for _, input := range []string{`{"a": 1, "b": 2}`, `{x: 1, x: 2}`, `{1: 1, "1": 2, 1.5: 3}`} {
+		_, err := NewParser(input).Parse()
+		checkParserErrors(t, err)
+	}
+}
+
+func TestParsingMapLiteralOrder(t *testing.T) {
+	input := `{"z": 1, "a": 2, "m": 3, 1: 4, true: 5}`
+	for i := 0; i < 10; i++ {
+		program, err := NewParser(input).Parse()
+		checkParserErrors(t, err)
+		if got, want := program.String(), "{z:1,a:2,m:3,1:4,true:5,}"; got != want {
+			t.Fatalf("pairs out of source order. expected=%q, got=%q", want, got)
+		}
+	}
+}
+
 func TestParsingEmptyMapLiteral(t *testing.T) {
 	input := "{}"
 
@@ -804,7 +923,8 @@ func TestParsingMapLiteralsStringKeys(t *testing.T) {
 		t.Errorf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
 	}
 
-	for key, value := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		key, value := pair.Key, pair.Value
 		literal, ok := key.(*StringLiteral)
 		if !ok {
 			t.Errorf("key is not StringLiteral. got=%T", key)
@@ -839,7 +959,8 @@ func TestParsingMapLiteralsBooleanKeys(t *testing.T) {
 		t.Errorf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
 	}
 
-	for key, value := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		key, value := pair.Key, pair.Value
 		boolean, ok := key.(*Boolean)
 		if !ok {
 			t.Errorf("key is not BooleanLiteral. got=%T", key)
@@ -875,7 +996,8 @@ func TestParsingMapLiteralsIntegerKeys(t *testing.T) {
 		t.Errorf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
 	}
 
-	for key, value := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		key, value := pair.Key, pair.Value
 		integer, ok := key.(*IntegerLiteral)
 		if !ok {
 			t.Errorf("key is not IntegerLiteral. got=%T", key)
@@ -918,7 +1040,8 @@ func TestParsingMapLiteralsWithExprs(t *testing.T) {
 		},
 	}
 
-	for key, value := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		key, value := pair.Key, pair.Value
 		literal, ok := key.(*StringLiteral)
 		if !ok {
 			t.Errorf("key is not StringLiteral. got=%T", key)
@@ -1076,3 +1199,603 @@ func checkParserErrors(t *testing.T, err error) {
 	t.Errorf("parser error: %s", err.Error())
 	t.FailNow()
 }
+
+func TestImportParsing(t *testing.T) {
+	program, err := NewParser(`import("lib/math.mky") as m; from "lib/str.mky" import (trim, split); import("x")["y"];`).Parse()
+	checkParserErrors(t, err)
+	if len(program.Stmts) != 3 {
+		t.Fatalf("program.Stmts does not contain 3 statements. got=%d", len(program.Stmts))
+	}
+
+	imp, ok := program.Stmts[0].(*ImportStmt)
+	if !ok {
+		t.Fatalf("program.Stmts[0] is not *ImportStmt. got=%T", program.Stmts[0])
+	}
+	if imp.Import.Path.String() != "lib/math.mky" || imp.Alias.Value != "m" {
+		t.Errorf("wrong import statement. got=%s", imp)
+	}
+
+	from, ok := program.Stmts[1].(*FromImportStmt)
+	if !ok {
+		t.Fatalf("program.Stmts[1] is not *FromImportStmt. got=%T", program.Stmts[1])
+	}
+	if len(from.Names) != 2 || !testIdentifier(t, from.Names[0], "trim") || !testIdentifier(t, from.Names[1], "split") {
+		t.Errorf("wrong import names. got=%s", from)
+	}
+
+	stmt, ok := program.Stmts[2].(*ExprStmt)
+	if !ok {
+		t.Fatalf("program.Stmts[2] is not *ExprStmt. got=%T", program.Stmts[2])
+	}
+	if _, ok := stmt.Expr.(*IndexExpr); !ok {
+		t.Errorf("stmt.Expr is not *IndexExpr. got=%T", stmt.Expr)
+	}
+
+	for _, input := range []string{`from "x" import ()`, `import("x") as "m"`, `from x import (a)`} {
+		if _, err := NewParser(input).Parse(); err == nil {
+			t.Errorf("expected parser error for %q", input)
+		}
+	}
+}
+
+func TestMacroLiteralParsing(t *testing.T) {
+	program, err := NewParser(`macro(x, y) { x + y; }`).Parse()
+	checkParserErrors(t, err)
+
+	stmt := program.Stmts[0].(*ExprStmt)
+	macro, ok := stmt.Expr.(*MacroLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expr is not *MacroLiteral. got=%T", stmt.Expr)
+	}
+	if len(macro.Params) != 2 || !testIdentifier(t, macro.Params[0], "x") || !testIdentifier(t, macro.Params[1], "y") {
+		t.Errorf("wrong macro parameters. got=%s", macro)
+	}
+	if macro.String() != "macro(x, y) {(x + y)}" {
+		t.Errorf("wrong String(). got=%q", macro.String())
+	}
+}
+
+func TestFloatLiteralExpr(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14", 3.14},
+		{"1e-9", 1e-9},
+		{"2E+3", 2e3},
+		{"0.5e1", 5},
+	}
+	for _, tt := range tests {
+		program, err := NewParser(tt.input).Parse()
+		checkParserErrors(t, err)
+		stmt := program.Stmts[0].(*ExprStmt)
+		literal, ok := stmt.Expr.(*FloatLiteral)
+		if !ok {
+			t.Fatalf("exp not *FloatLiteral. got=%T", stmt.Expr)
+		}
+		if literal.Value != tt.expected || literal.Raw != tt.input {
+			t.Errorf("wrong literal. got=%v (%q)", literal.Value, literal.Raw)
+		}
+	}
+
+	// 不是浮点数的情况
+	program, err := NewParser("[1][0]; 2e").Parse()
+	checkParserErrors(t, err)
+	if len(program.Stmts) != 3 {
+		t.Errorf("expected 3 statements. got=%s", program)
+	}
+}
+
+func TestAsyncAwaitParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`async fn(x) { x }`, "async fn(x) {x}"},
+		{`await f(1)`, "(await f(1))"},
+		{`await xs[0] + 1`, "((await xs[0]) + 1)"},
+		{`let g = async fn() { await h() };`, "let g = async fn() {(await h())};"},
+	}
+	for _, tt := range tests {
+		program, err := NewParser(tt.input).Parse()
+		checkParserErrors(t, err)
+		if got := program.String(); got != tt.expected {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	program, err := NewParser(`async fn() { 1 }`).Parse()
+	checkParserErrors(t, err)
+	fn, ok := program.Stmts[0].(*ExprStmt).Expr.(*FunctionLiteral)
+	if !ok || !fn.Async {
+		t.Fatalf("expected async *FunctionLiteral. got=%#v", program.Stmts[0])
+	}
+	if start, _ := fn.Span(); start.Col != 1 {
+		t.Errorf("async function should start at the async keyword. got=%v", start)
+	}
+
+	if _, err := NewParser(`async 1`).Parse(); err == nil {
+		t.Errorf("expected error for async without fn")
+	}
+}
+
+func TestRecordParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`record Point {x, y}`, "record Point {x, y}"},
+		{`record Empty {}`, "record Empty {}"},
+		{"record Pair {\n  first,\n  second,\n};", "record Pair {first, second}"},
+		{`p.x`, "p.x"},
+		{`a.b.c + 1`, "(a.b.c + 1)"},
+		{`-p.x`, "(-p.x)"},
+		{`f(1).x[0]`, "f(1).x[0]"},
+		{`p.move(1)`, "p.move(1)"},
+		{`1.5`, "1.5"},
+	}
+	for _, tt := range tests {
+		program, err := NewParser(tt.input).Parse()
+		checkParserErrors(t, err)
+		if got := program.String(); got != tt.expected {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{`record Point {x, x}`, "duplicate field x in record Point"},
+		{`record {x}`, `expected next token to be "identifier"`},
+		{`record P {x y}`, `expected next token to be ","`},
+		{`p.1`, `expected next token to be "identifier"`},
+	}
+	for _, tt := range errors {
+		_, err := NewParser(tt.input).Parse()
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected error %q. got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestFunctionStmtParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		stmt     bool
+	}{
+		{`fn add(x, y) { x + y }`, "fn add(x, y) {(x + y)}", true},
+		{`async fn load() { 1 };`, "async fn load() {1}", true},
+		{`fn add(x, y) { x + y }(1, 2)`, "fn add(x, y) {(x + y)}(1, 2)", false},
+		{`fn(x) { x }`, "fn(x) {x}", false},
+		{`let f = fn g(n) { g(n) };`, "let f = fn g(n) {g(n)};", false},
+	}
+	for _, tt := range tests {
+		program, err := NewParser(tt.input).Parse()
+		checkParserErrors(t, err)
+		if len(program.Stmts) != 1 {
+			t.Fatalf("%s: expected 1 statement. got=%d", tt.input, len(program.Stmts))
+		}
+		if got := program.String(); got != tt.expected {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+		if _, ok := program.Stmts[0].(*FunctionStmt); ok != tt.stmt {
+			t.Errorf("%s: expected FunctionStmt=%t. got=%T", tt.input, tt.stmt, program.Stmts[0])
+		}
+	}
+}
+
+func TestVariadicParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`fn(xs...) { xs }`, "fn(xs...) {xs}"},
+		{`fn(a, b, rest...) { rest }`, "fn(a, b, rest...) {rest}"},
+		{`f(xs...)`, "f(xs...)"},
+		{`f(1, xs..., g(x)...)`, "f(1, xs..., g(x)...)"},
+		{`f(a.b...)`, "f(a.b...)"},
+	}
+	for _, tt := range tests {
+		program, err := NewParser(tt.input).Parse()
+		checkParserErrors(t, err)
+		if got := program.String(); got != tt.expected {
+			t.Errorf("%s: expected %q. got=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{`fn(xs..., y) { xs }`, `expected next token to be ")"`},
+		{`fn(...) { 1 }`, `1:4 expected next token to be "identifier"`},
+		{`macro(xs...) { xs }`, "macro parameters cannot be variadic"},
+		{`[xs...]`, `expected next token to be "]"`},
+	}
+	for _, tt := range errors {
+		_, err := NewParser(tt.input).Parse()
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected error %q. got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestFunctionNoClosures(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []bool // 按函数字面量在源码中出现的顺序
+	}{
+		{`fn(x) { x + 1 }`, []bool{true}},
+		{`fn(x) { fn(y) { x + y } }`, []bool{false, true}},
+		{`fn(x) { let m = macro(y) { y }; x }`, []bool{false}},
+		{`fn(x) { fn(y) { y } }; fn(z) { z }`, []bool{false, true, true}},
+	}
+	for _, tt := range tests {
+		program, err := NewParser(tt.input).Parse()
+		checkParserErrors(t, err)
+		var got []bool
+		var visit func(expr Expr)
+		visit = func(expr Expr) {
+			if fn, ok := expr.(*FunctionLiteral); ok {
+				got = append(got, fn.NoClosures)
+				for _, stmt := range fn.Body.Stmts {
+					if stmt, ok := stmt.(*ExprStmt); ok {
+						visit(stmt.Expr)
+					}
+				}
+			}
+		}
+		for _, stmt := range program.Stmts {
+			visit(stmt.(*ExprStmt).Expr)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+			t.Errorf("%s: expected %v. got=%v", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestParseErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+		stmts    string
+	}{
+		{
+			"let = 1; let x = 2; let y 3; x",
+			[]string{
+				`1:5 expected next token to be "identifier"`,
+				`1:27 expected next token to be "="`,
+			},
+			"let x = 2;x",
+		},
+		{
+			"let f = fn() { let = 1; 2 }; ) ; f",
+			[]string{
+				`1:20 expected next token to be "identifier"`,
+				`1:30 no prefix parse function for ")"`,
+			},
+			"let f = fn() {2};f",
+		},
+		{
+			`let s = "a\q"; let t = 1 +`,
+			[]string{
+				`1:11 unknown escape sequence \q`,
+				`1:27 no prefix parse function for "end of file"`,
+			},
+			"let s = a;",
+		},
+		{
+			"let $ = 1; x $ y; z",
+			[]string{
+				`1:5 expected next token to be "identifier", got "illegal token(literal="$")"`,
+				`1:14 no prefix parse function for "illegal token"`,
+			},
+			"xz",
+		},
+	}
+	for _, tt := range tests {
+		program, err := NewParser(tt.input).Parse()
+		errs, ok := err.(ErrorList)
+		if !ok {
+			t.Fatalf("%s: expected ErrorList. got=%T (%v)", tt.input, err, err)
+		}
+		if len(errs) != len(tt.expected) {
+			t.Errorf("%s: expected %d errors. got=%d:\n%s", tt.input, len(tt.expected), len(errs), errs)
+			continue
+		}
+		for i, want := range tt.expected {
+			if !strings.Contains(errs[i].Error(), want) {
+				t.Errorf("%s: errors[%d] expected %q. got=%q", tt.input, i, want, errs[i])
+			}
+		}
+		if got := program.String(); got != tt.stmts {
+			t.Errorf("%s: expected program %q. got=%q", tt.input, tt.stmts, got)
+		}
+	}
+}
+
+func TestIncompleteInput(t *testing.T) {
+	tests := []struct {
+		input      string
+		incomplete bool
+	}{
+		{"fn(x) {", true},
+		{"let x = ", true},
+		{"f(1,", true},
+		{"if (x) { 1 } else", true},
+		{`"abc`, true},
+		{"/* comment", true},
+		{"}", false},
+		{"let = 1", false},
+		{"let = 1; f(", false}, // 前面的错误不会因为更多的输入而消失
+	}
+	for _, tt := range tests {
+		_, err := NewParser(tt.input).Parse()
+		if err == nil {
+			t.Errorf("%q: expected an error", tt.input)
+			continue
+		}
+		if got := IsIncomplete(err); got != tt.incomplete {
+			t.Errorf("%q: IsIncomplete(%v) = %v, want %v", tt.input, err, got, tt.incomplete)
+		}
+	}
+}
+
+func TestParseDeepNesting(t *testing.T) {
+	const n = 10000
+	tests := []struct {
+		name  string
+		input string
+		pos   string
+	}{
+		{"array", strings.Repeat("[", n) + strings.Repeat("]", n), "1:1009"},
+		{"group", strings.Repeat("(", n) + "1" + strings.Repeat(")", n), "1:1009"},
+		{"prefix", strings.Repeat("-", n) + "1", "1:1009"},
+		{"map", strings.Repeat(`{"a": `, n) + "1" + strings.Repeat("}", n), "1:6004"},
+		{"index", "a" + strings.Repeat("[a", n) + strings.Repeat("]", n), "1:1009"},
+		{"call", strings.Repeat("f(", n) + strings.Repeat(")", n), "1:1009"},
+		{"fn", strings.Repeat("fn() { ", n) + strings.Repeat("}", n), "1:7009"},
+		{"if", strings.Repeat("if (x) { ", n) + strings.Repeat("}", n), "1:9004"},
+		{"infix", strings.Repeat("1 + (", n) + "1" + strings.Repeat(")", n), "1:1676"},
+		{"unterminated", strings.Repeat("[", n), "1:1009"},
+		{"chain", strings.Repeat("1 + ", n) + "1", "1:4005"},
+		{"calls", "f" + strings.Repeat("()", n), "1:2008"},
+		{"dots", "a" + strings.Repeat(".b", n), "1:2008"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser("let x = " + tt.input + "; let y = 1;").Parse()
+			errs, ok := err.(ErrorList)
+			if !ok || len(errs) == 0 {
+				t.Fatalf("expected ErrorList. got=%T (%v)", err, err)
+			}
+			if want := tt.pos + " expression too deeply nested (max depth 1000)"; !strings.Contains(errs[0].Error(), want) {
+				t.Errorf("expected %q. got=%q", want, errs[0])
+			}
+		})
+	}
+}
+
+func TestParseLongChain(t *testing.T) {
+	// 链中的每一环算一层，但不会影响正常长度的表达式
+	input := strings.Repeat("x + ", DefaultMaxDepth-2) + "x"
+	program, err := NewParser(input).Parse()
+	checkParserErrors(t, err)
+	if len(program.Stmts) != 1 {
+		t.Fatalf("expected 1 statement. got=%d", len(program.Stmts))
+	}
+	input = strings.Repeat("[x + x + ", DefaultMaxDepth/2) + "x" + strings.Repeat("]", DefaultMaxDepth/2)
+	if _, err := NewParser(input).Parse(); err == nil || !strings.Contains(err.Error(), "too deeply nested") {
+		t.Errorf("expected nesting error. got=%v", err)
+	}
+}
+
+func TestParseMaxDepth(t *testing.T) {
+	p := NewParser("[[[1]]]; [[[[1]]]]")
+	p.MaxDepth = 4
+	_, err := p.Parse()
+	errs, ok := err.(ErrorList)
+	if !ok || len(errs) != 1 {
+		t.Fatalf("expected 1 error. got=%v", err)
+	}
+	if want := "1:14 expression too deeply nested (max depth 4)"; !strings.Contains(errs[0].Error(), want) {
+		t.Errorf("expected %q. got=%q", want, errs[0])
+	}
+
+	// 出错之后深度恢复为 0，后面的语句不受影响
+	p = NewParser("[[[[[1]]]]]; [[[1]]]")
+	p.MaxDepth = 4
+	program, err := p.Parse()
+	if errs, ok := err.(ErrorList); !ok || len(errs) != 1 {
+		t.Fatalf("expected 1 error. got=%v", err)
+	}
+	if got := program.String(); !strings.HasSuffix(got, "1]]]") {
+		t.Errorf("expected the second statement to parse. got=%q", got)
+	}
+}
+
+// FuzzParser 检查解析器对任意输入都只返回错误而不会 panic 或耗尽栈
+func FuzzParser(f *testing.F) {
+	seeds := []string{
+		"let x = [1, 2 * 3, {\"a\": fn(x, y...) { x(y...) }}];",
+		"if (a) { b } else { c }",
+		"record P {x, y}; let p = P(1, 2); p.x",
+		"import(\"x\") as m; from \"y\" import (a, b);",
+		"macro(x) { quote(unquote(x)) }",
+		"async fn f() { await g() }",
+		"let $ = 1; x $ y",
+		strings.Repeat("[", DefaultMaxDepth+1),
+		strings.Repeat("(", DefaultMaxDepth+1) + strings.Repeat(")", DefaultMaxDepth+1),
+		strings.Repeat("fn() { ", DefaultMaxDepth/2+1),
+		strings.Repeat("!-", DefaultMaxDepth/2+1) + "x",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		program, err := NewParser(input).Parse()
+		if err == nil {
+			_ = program.String()
+		} else if !errors.Is(err, ErrParse) {
+			t.Fatalf("Parse: %v", err)
+		}
+		// 逐条解析的结果也只能是语句、语法错误或 io.EOF
+		p := NewParserReader("fuzz", strings.NewReader(input))
+		for {
+			_, err := p.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				if !errors.Is(err, ErrParse) {
+					t.Fatalf("Next: %v", err)
+				}
+				break
+			}
+		}
+	})
+}
+
+// 每次 Read 最多返回 n 个字节，最后返回 err
+type chunkReader struct {
+	s   string
+	n   int
+	err error
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(r.s) == 0 {
+		return 0, r.err
+	}
+	n := copy(p[:min(len(p), r.n)], r.s)
+	r.s = r.s[n:]
+	return n, nil
+}
+
+func TestParserReader(t *testing.T) {
+	input := "let a = 1; // 注释\nlet s = \"多\nlines\";\n/* block\ncomment */ fn f(x) {\n\tx\n}\na + len(s)"
+	p := NewParserReader("main.mky", &chunkReader{s: input, n: 3, err: io.EOF})
+	var stmts []string
+	for {
+		stmt, err := p.Next()
+		if err == io.EOF {
+			break
+		}
+		checkParserErrors(t, err)
+		stmts = append(stmts, stmt.String())
+	}
+	expected := []string{"let a = 1;", "let s = 多\nlines;", "fn f(x) {x}", "(a + len(s))"}
+	if strings.Join(stmts, "|") != strings.Join(expected, "|") {
+		t.Errorf("wrong statements.\nexpected=%q\ngot=%q", expected, stmts)
+	}
+	if _, err := p.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF after the last statement. got=%v", err)
+	}
+
+	// 语法错误只包含出错的语句，之前的语句已经返回
+	p = NewParserReader("main.mky", strings.NewReader("1;\nlet = 2;\n3"))
+	if stmt, err := p.Next(); err != nil || stmt.String() != "1" {
+		t.Fatalf("expected first statement. got=%v, %v", stmt, err)
+	}
+	if _, err := p.Next(); err == nil || !strings.Contains(err.Error(), "main.mky:2:5 expected next token") {
+		t.Errorf("expected positioned syntax error. got=%v", err)
+	}
+
+	// 读取输入的错误
+	p = NewParserReader("main.mky", &chunkReader{s: "1; 2;", n: 100, err: fmt.Errorf("broken pipe")})
+	if _, err := p.Next(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := p.Next(); err == nil || err.Error() != "broken pipe" {
+		t.Errorf("expected read error. got=%v", err)
+	}
+
+	// Parse 同样可以读取 io.Reader
+	program, err := NewParserReader("main.mky", strings.NewReader(input)).Parse()
+	checkParserErrors(t, err)
+	if len(program.Stmts) != 4 || len(program.Comments) != 2 {
+		t.Errorf("expected 4 statements and 2 comments. got=%d, %d", len(program.Stmts), len(program.Comments))
+	}
+}
+
+func TestNewFileParserAt(t *testing.T) {
+	program, err := NewFileParserAt("doc.md", 10, "let a = 1;\n  a").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, _ := program.Stmts[1].Span()
+	if start.String() != "doc.md:11:3" {
+		t.Errorf("wrong position. got=%s", start)
+	}
+	_, err = NewFileParserAt("doc.md", 10, "let a = 1;\nlet = 2;").Parse()
+	if err == nil || !strings.HasPrefix(err.Error(), "doc.md:11:") {
+		t.Errorf("expected an error on line 11. got=%v", err)
+	}
+}
+
+// 每个节点的 Span 对应的源码正好是该节点的文本，不包括语句末尾的分号
+func TestSpans(t *testing.T) {
+	input := `let s = "a\tb"; export let f = async fn(x, ys...) { return -x ** 2 }
+record P {x, y}
+import("m") as m; from "n" import (a, b)
+if (a && !b) { f(1, [2, 3]...) } else { m.k[1:] + {"k": s}["k"] }
+fn g() { await f }`
+	program, err := NewFileParser("main.mky", input).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(input, "\n")
+	text := func(start, end Position) string {
+		var out []string
+		for line := start.Line; line <= end.Line; line++ {
+			runes := []rune(lines[line-1])
+			from, to := 0, len(runes)
+			if line == start.Line {
+				from = int(start.Col) - 1
+			}
+			if line == end.Line {
+				to = int(end.Col) - 1
+			}
+			out = append(out, string(runes[from:to]))
+		}
+		return strings.Join(out, "\n")
+	}
+
+	got := make(map[string]bool)
+	Inspect(program, func(n Node) bool {
+		if n != nil {
+			got[fmt.Sprintf("%T %s", n, text(n.Span()))] = true
+		}
+		return true
+	})
+	for _, expected := range []string{
+		"*syntax.Program " + input,
+		`*syntax.LetStmt let s = "a\tb"`,
+		`*syntax.StringLiteral "a\tb"`,
+		"*syntax.ExportStmt export let f = async fn(x, ys...) { return -x ** 2 }",
+		"*syntax.FunctionLiteral async fn(x, ys...) { return -x ** 2 }",
+		"*syntax.BlockStmt { return -x ** 2 }",
+		"*syntax.ReturnStmt return -x ** 2",
+		"*syntax.PrefixExpr -x ** 2",
+		"*syntax.InfixExpr x ** 2",
+		"*syntax.RecordStmt record P {x, y}",
+		`*syntax.ImportStmt import("m") as m`,
+		`*syntax.ImportExpr import("m")`,
+		`*syntax.FromImportStmt from "n" import (a, b)`,
+		`*syntax.IfExpr if (a && !b) { f(1, [2, 3]...) } else { m.k[1:] + {"k": s}["k"] }`,
+		"*syntax.CallExpr f(1, [2, 3]...)",
+		"*syntax.SpreadExpr [2, 3]...",
+		"*syntax.ArrayLiteral [2, 3]",
+		"*syntax.SliceExpr m.k[1:]",
+		"*syntax.DotExpr m.k",
+		`*syntax.MapLiteral {"k": s}`,
+		`*syntax.IndexExpr {"k": s}["k"]`,
+		"*syntax.FunctionStmt fn g() { await f }",
+		"*syntax.AwaitExpr await f",
+	} {
+		if !got[expected] {
+			t.Errorf("missing span %q", expected)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package syntax
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
 
 type Token int8
 
@@ -8,30 +11,42 @@ func (t Token) String() string {
 	return tokenNames[t]
 }
 
+// IsKeyword 判断 t 是否是关键字
+func (t Token) IsKeyword() bool {
+	return t >= LET
+}
+
 const (
 	ILLEGAL Token = iota
 	EOF
 
 	IDENT
 	INT
+	FLOAT
 	STRING
 
-	ASSIGN // =
-	PLUS   // +
-	MINUS  // -
-	STAR   // *
-	SLASH  // /
-	BANG   // ！
-	LT     // <
-	LE     // <=
-	GT     // >
-	GE     // >=
-	EQ     // ==
-	NE     // !=
+	ASSIGN   // =
+	PLUS     // +
+	MINUS    // -
+	STAR     // *
+	SLASH    // /
+	PERCENT  // %
+	STARSTAR // **
+	BANG     // ！
+	LT       // <
+	LE       // <=
+	GT       // >
+	GE       // >=
+	EQ       // ==
+	NE       // !=
+	AND      // && 或 and
+	OR       // || 或 or
 
 	COLON     // :
 	COMMA     // ,
 	SEMICOLON // ;
+	DOT       // .
+	ELLIPSIS  // ...
 
 	LPAREN   // (
 	RPAREN   // )
@@ -47,6 +62,14 @@ const (
 	FALSE    // false
 	RETURN   // return
 	FUNCTION // fn
+	IMPORT   // import
+	FROM     // from
+	AS       // as
+	EXPORT   // export
+	MACRO    // macro
+	ASYNC    // async
+	AWAIT    // await
+	RECORD   // record
 )
 
 var tokenNames = [...]string{
@@ -54,24 +77,31 @@ var tokenNames = [...]string{
 	EOF:     "end of file",
 	IDENT:   "identifier",
 	INT:     "int",
+	FLOAT:   "float",
 	STRING:  "string",
 
-	ASSIGN: "=",
-	PLUS:   "+",
-	MINUS:  "-",
-	STAR:   "*",
-	SLASH:  "/",
-	BANG:   "!",
-	LT:     "<",
-	LE:     "<=",
-	GT:     ">",
-	GE:     ">=",
-	EQ:     "==",
-	NE:     "!=",
+	ASSIGN:   "=",
+	PLUS:     "+",
+	MINUS:    "-",
+	STAR:     "*",
+	SLASH:    "/",
+	PERCENT:  "%",
+	STARSTAR: "**",
+	BANG:     "!",
+	LT:       "<",
+	LE:       "<=",
+	GT:       ">",
+	GE:       ">=",
+	EQ:       "==",
+	NE:       "!=",
+	AND:      "&&",
+	OR:       "||",
 
 	COLON:     ":",
 	COMMA:     ",",
 	SEMICOLON: ";",
+	DOT:       ".",
+	ELLIPSIS:  "...",
 
 	LPAREN:   "(",
 	RPAREN:   ")",
@@ -87,6 +117,14 @@ var tokenNames = [...]string{
 	FALSE:    "false",
 	RETURN:   "return",
 	FUNCTION: "fn",
+	IMPORT:   "import",
+	FROM:     "from",
+	AS:       "as",
+	EXPORT:   "export",
+	MACRO:    "macro",
+	ASYNC:    "async",
+	AWAIT:    "await",
+	RECORD:   "record",
 }
 
 var keywords = map[string]Token{
@@ -97,6 +135,28 @@ var keywords = map[string]Token{
 	"false":  FALSE,
 	"return": RETURN,
 	"fn":     FUNCTION,
+	"import": IMPORT,
+	"from":   FROM,
+	"as":     AS,
+	"export": EXPORT,
+	"macro":  MACRO,
+	"async":  ASYNC,
+	"await":  AWAIT,
+	"record": RECORD,
+}
+
+// LanguageOptions 是可选的语言特性，默认全部关闭，关闭时的语法与原来完全相同
+type LanguageOptions struct {
+	// WordOperators 允许使用 and、or 和 not 代替 &&、|| 和 !，它们被解析为相同的词法单元，
+	// 适合教学使用。开启后这三个单词成为关键字，不能再用作变量名
+	WordOperators bool
+}
+
+// 开启 WordOperators 时额外的关键字
+var wordOperators = map[string]Token{
+	"and": AND,
+	"or":  OR,
+	"not": BANG,
 }
 
 // LookupIdent通过检查关键字表来判断给定的标识符是否是关键字。
@@ -109,10 +169,52 @@ func LookupIdent(ident string) Token {
 	return IDENT
 }
 
+// Keywords 返回所有关键字，按字典序排列，不包括 WordOperators 开启的关键字
+func Keywords() []string {
+	names := make([]string, 0, len(keywords))
+	for name := range keywords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupOperator 返回运算符 op 对应的 Token
+func LookupOperator(op string) (Token, bool) {
+	for tok := PLUS; tok <= OR; tok++ {
+		if tokenNames[tok] == op {
+			return tok, true
+		}
+	}
+	return ILLEGAL, false
+}
+
 type TokenValue struct {
 	pos     Position
+	end     Position
 	Type    Token
-	Literal string
+	Literal string // 字符串是转义之后的值，其他词法单元与源码相同
+
+	// Leading 是词法单元之前的空白和注释，只在 Lexer.KeepTrivia 时记录。
+	// EOF 的 Leading 是源码末尾的空白和注释
+	Leading []Trivia
+}
+
+// Pos 返回词法单元的开始位置
+func (t TokenValue) Pos() Position {
+	return t.pos
+}
+
+// End 返回词法单元的结束位置，即最后一个字符之后的位置
+func (t TokenValue) End() Position {
+	return t.end
+}
+
+// Trivia 是两个词法单元之间连续的一段空白，或者一条注释
+type Trivia struct {
+	Pos     Position
+	Text    string // 原文，注释包括 // 或 /* */
+	Comment bool   // 是否为注释，否则是空白
 }
 
 func (t TokenValue) String() string {
@@ -0,0 +1,165 @@
+package syntax
+
+// Visitor 的 Visit 方法在 Walk 遍历到每个节点时被调用。
+// 返回的 w 不为 nil 时，Walk 用 w 访问该节点的每个子节点，最后调用 w.Visit(nil)
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk 以深度优先的顺序遍历语法树：先调用 v.Visit(node)，返回的 visitor w 不为 nil 时，
+// 按源码中的顺序对 node 的每个非 nil 的子节点递归调用 Walk(child, w)，最后调用 w.Visit(nil)。
+// 子节点包括标识符，例如 let 语句的名字、函数的参数和 a.b 中的 b；宏的函数体同样会被遍历
+func Walk(node Node, v Visitor) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		walkStmts(n.Stmts, v)
+
+	case *BlockStmt:
+		walkStmts(n.Stmts, v)
+
+	case *LetStmt:
+		Walk(n.Name, v)
+		Walk(n.Value, v)
+
+	case *ExportStmt:
+		Walk(n.Let, v)
+
+	case *ReturnStmt:
+		Walk(n.Value, v)
+
+	case *ExprStmt:
+		Walk(n.Expr, v)
+
+	case *FunctionStmt:
+		Walk(n.Fn, v)
+
+	case *RecordStmt:
+		Walk(n.Name, v)
+		walkIdents(n.Fields, v)
+
+	case *ImportStmt:
+		Walk(n.Import, v)
+		Walk(n.Alias, v)
+
+	case *FromImportStmt:
+		Walk(n.Path, v)
+		walkIdents(n.Names, v)
+
+	case *PrefixExpr:
+		Walk(n.Right, v)
+
+	case *InfixExpr:
+		Walk(n.Left, v)
+		Walk(n.Right, v)
+
+	case *AwaitExpr:
+		Walk(n.Value, v)
+
+	case *SpreadExpr:
+		Walk(n.Value, v)
+
+	case *IfExpr:
+		Walk(n.Cond, v)
+		Walk(n.Consequence, v)
+		if n.Alternative != nil {
+			Walk(n.Alternative, v)
+		}
+
+	case *FunctionLiteral:
+		if n.Name != nil {
+			Walk(n.Name, v)
+		}
+		walkIdents(n.Params, v)
+		Walk(n.Body, v)
+
+	case *MacroLiteral:
+		walkIdents(n.Params, v)
+		Walk(n.Body, v)
+
+	case *CallExpr:
+		Walk(n.Function, v)
+		walkExprs(n.Args, v)
+
+	case *ArrayLiteral:
+		walkExprs(n.Items, v)
+
+	case *MapLiteral:
+		for _, pair := range n.Pairs {
+			Walk(pair.Key, v)
+			Walk(pair.Value, v)
+		}
+
+	case *IndexExpr:
+		Walk(n.Left, v)
+		Walk(n.Index, v)
+
+	case *SliceExpr:
+		Walk(n.Left, v)
+		if n.Start != nil {
+			Walk(n.Start, v)
+		}
+		if n.End != nil {
+			Walk(n.End, v)
+		}
+
+	case *DotExpr:
+		Walk(n.Left, v)
+		Walk(n.Name, v)
+
+	case *ImportExpr:
+		Walk(n.Path, v)
+
+	case *Identifier, *IntegerLiteral, *FloatLiteral, *StringLiteral, *Boolean:
+		// 没有子节点
+	}
+
+	v.Visit(nil)
+}
+
+func walkStmts(list []Stmt, v Visitor) {
+	for _, stmt := range list {
+		Walk(stmt, v)
+	}
+}
+
+func walkExprs(list []Expr, v Visitor) {
+	for _, expr := range list {
+		Walk(expr, v)
+	}
+}
+
+func walkIdents(list []*Identifier, v Visitor) {
+	for _, ident := range list {
+		Walk(ident, v)
+	}
+}
+
+// inspector 把函数适配为 Visitor
+type inspector func(Node) bool
+
+// Visit implements Visitor.
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect 以深度优先的顺序遍历语法树：先调用 f(node)，f 返回 true 时对 node 的每个非 nil 的子节点
+// 递归调用 Inspect，最后调用 f(nil)。例如找出程序中调用的所有函数名：
+//
+//	syntax.Inspect(program, func(n syntax.Node) bool {
+//		if call, ok := n.(*syntax.CallExpr); ok {
+//			if ident, ok := call.Function.(*syntax.Identifier); ok {
+//				names = append(names, ident.Value)
+//			}
+//		}
+//		return true
+//	})
+func Inspect(node Node, f func(Node) bool) {
+	Walk(node, inspector(f))
+}
@@ -0,0 +1,82 @@
+package syntax
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	program, err := NewParser("let f = fn(a, b) { if (a) { a.x } else { b[1:] } }; f(1, [2]...)").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	depth := 0
+	Inspect(program, func(n Node) bool {
+		if n == nil {
+			depth--
+			return false
+		}
+		got = append(got, strings.Repeat(".", depth)+strings.TrimPrefix(fmt.Sprintf("%T", n), "*syntax."))
+		depth++
+		return true
+	})
+	expected := []string{
+		"Program",
+		".LetStmt",
+		"..Identifier",
+		"..FunctionLiteral",
+		"...Identifier",
+		"...Identifier",
+		"...BlockStmt",
+		"....ExprStmt",
+		".....IfExpr",
+		"......Identifier",
+		"......BlockStmt",
+		".......ExprStmt",
+		"........DotExpr",
+		".........Identifier",
+		".........Identifier",
+		"......BlockStmt",
+		".......ExprStmt",
+		"........SliceExpr",
+		".........Identifier",
+		".........IntegerLiteral",
+		".ExprStmt",
+		"..CallExpr",
+		"...Identifier",
+		"...IntegerLiteral",
+		"...SpreadExpr",
+		"....ArrayLiteral",
+		".....IntegerLiteral",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("wrong traversal.\nexpected=%v\ngot=%v", expected, got)
+	}
+	if depth != 0 {
+		t.Errorf("Visit(nil) was not called once per node, depth=%d", depth)
+	}
+}
+
+func TestInspectPrune(t *testing.T) {
+	program, err := NewParser("let f = fn(x) { x + 1 }; f(2) + 3").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ints []int64
+	Inspect(program, func(n Node) bool {
+		switch n := n.(type) {
+		case *FunctionLiteral:
+			return false
+		case *IntegerLiteral:
+			ints = append(ints, n.Value)
+		}
+		return true
+	})
+	if !reflect.DeepEqual(ints, []int64{2, 3}) {
+		t.Errorf("expected the function body to be skipped, got %v", ints)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hungtcs/monkey-lang/doctest"
+	"github.com/hungtcs/monkey-lang/project"
+	"github.com/hungtcs/monkey-lang/syntax"
+)
+
+// monkey test [flags] [path ...]
+//
+// 运行源码注释中的 `// >>>` 示例，path 可以是文件或目录，
+// 默认为当前项目 monkey.toml 中的 test.paths，不在项目中时为当前目录
+func testCmd(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	verbose := flags.Bool("v", false, "print the output of tested files")
+	mutation := flags.Bool("mutate", false, "run the examples against mutated programs and report surviving mutants")
+	flags.Parse(args)
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		m, root, err := project.Load(".")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		paths = []string{"."}
+		if m != nil {
+			paths = nil
+			for _, path := range m.Test.Paths {
+				paths = append(paths, filepath.Join(root, path))
+			}
+		}
+	}
+	files, err := sourceFiles(paths)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var stdout io.Writer = io.Discard
+	if *verbose {
+		stdout = os.Stdout
+	}
+
+	failed := false
+	for _, filename := range files {
+		if !testFile(filename, stdout, *mutation) {
+			failed = true
+		}
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+func testFile(filename string, stdout io.Writer, mutation bool) bool {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Printf("FAIL\t%s\n\t%s\n", filename, err)
+		return false
+	}
+	src := string(data)
+
+	examples := doctest.Extract(src)
+	if len(examples) == 0 {
+		return true
+	}
+
+	program, err := syntax.NewFileParser(filename, src).Parse()
+	if err != nil {
+		fmt.Printf("FAIL\t%s\n\t%s\n", filename, err)
+		return false
+	}
+	failures, err := doctest.Run(filename, program, examples, stdout)
+	if err != nil {
+		fmt.Printf("FAIL\t%s\n\t%s\n", filename, err)
+		return false
+	}
+	if len(failures) > 0 {
+		fmt.Printf("FAIL\t%s\n", filename)
+		for _, failure := range failures {
+			fmt.Printf("%s:%s\n", filename, failure)
+		}
+		return false
+	}
+	fmt.Printf("ok\t%s\t%d examples\n", filename, len(examples))
+	if mutation {
+		return mutateFile(filename, program, examples)
+	}
+	return true
+}
+
+// 对每个变异体运行示例，示例失败或者超出执行的语句数限制即说明该变异被“杀死”
+func mutateFile(filename string, program *syntax.Program, examples []*doctest.Example) bool {
+	mutants, survived, err := doctest.Mutate(filename, program, examples)
+	if err != nil {
+		fmt.Printf("FAIL\t%s\n\t%s\n", filename, err)
+		return false
+	}
+
+	killed := len(mutants) - len(survived)
+	score := 100.0
+	if len(mutants) > 0 {
+		score = float64(killed) * 100 / float64(len(mutants))
+	}
+	fmt.Printf("mutate\t%s\t%d/%d mutants killed (%.1f%%)\n", filename, killed, len(mutants), score)
+	for _, mutant := range survived {
+		fmt.Printf("\tsurvived %s:%d:%d: %s\n", filename, mutant.Pos.Line, mutant.Pos.Col, mutant.Desc)
+	}
+	return len(survived) == 0
+}
+
+// 展开 paths 中的目录，返回排序后的 .mky 文件列表
+func sourceFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.HasSuffix(path, ".mky") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}